@@ -0,0 +1,72 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// nolint: dupl,golint
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/talos-systems/talos/cmd/osctl/pkg/client"
+	"github.com/talos-systems/talos/cmd/osctl/pkg/helpers"
+	"github.com/talos-systems/talos/internal/app/machined/proto"
+)
+
+var dryRun bool
+
+// applyConfigCmd represents the apply-config command
+var applyConfigCmd = &cobra.Command{
+	Use:   "apply-config <userdata>",
+	Short: "Apply the new userdata to the node",
+	Long: `Validates the userdata, compares it to the running config and applies the
+changes in place or by restarting the affected services. Changes which require
+a reboot are refused, they have to be made in the userdata boot source. With
+--dry-run the changes are only printed.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			helpers.Fatalf("error reading userdata: %s", err)
+		}
+
+		setupClient(func(c *client.Client) {
+			reply, err := c.ApplyUserData(globalCtx, data, dryRun)
+			if err != nil {
+				helpers.Fatalf("error applying userdata: %s", err)
+			}
+
+			applyConfigRender(reply)
+		})
+	},
+}
+
+func applyConfigRender(reply *proto.ApplyUserDataReply) {
+	if len(reply.Changes) == 0 {
+		fmt.Println("no changes")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "SECTION\tACTION\tSERVICE\tREASON")
+	for _, change := range reply.Changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.Section, change.Action, change.Service, change.Reason)
+	}
+	helpers.Should(w.Flush())
+
+	if reply.Applied {
+		fmt.Printf("\napplied (%s)\n", reply.Action)
+	} else {
+		fmt.Printf("\ndry run, nothing applied (%s)\n", reply.Action)
+	}
+}
+
+func init() {
+	applyConfigCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the changes without applying them")
+	applyConfigCmd.Flags().StringVarP(&target, "target", "t", "", "target the specificed node")
+	rootCmd.AddCommand(applyConfigCmd)
+}
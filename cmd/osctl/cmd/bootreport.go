@@ -0,0 +1,86 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// nolint: dupl,golint
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/spf13/cobra"
+	"github.com/talos-systems/talos/cmd/osctl/pkg/client"
+	"github.com/talos-systems/talos/cmd/osctl/pkg/helpers"
+	"github.com/talos-systems/talos/internal/app/machined/proto"
+)
+
+// bootReportCmd represents the boot-report command.
+var bootReportCmd = &cobra.Command{
+	Use:   "boot-report",
+	Short: "Show timing and errors of the boot sequence",
+	Long: `Shows each phase and task of the boot sequence with the duration and the result.
+If the boot failed and the node stays up in the maintenance mode (talos.maintenance=1),
+the report shows the errors which caused the failure.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			helpers.Should(cmd.Usage())
+			os.Exit(1)
+		}
+
+		setupClient(func(c *client.Client) {
+			bootReportRender(c.BootReport(globalCtx))
+		})
+	},
+}
+
+func bootReportRender(reply *proto.BootReportReply, err error) {
+	if err != nil {
+		helpers.Fatalf("error getting boot report: %s", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "PHASE\tTASK\tRESULT\tDURATION\tERROR")
+	for _, phase := range reply.Phases {
+		fmt.Fprintf(w, "%s\t\t%s\t%s\t%s\n", phase.Name, bootResult(phase.Error), bootDuration(phase.Duration), firstLine(phase.Error))
+		for _, task := range phase.Tasks {
+			fmt.Fprintf(w, "\t%s\t%s\t%s\t%s\n", task.Name, task.Result, bootDuration(task.Duration), firstLine(task.Error))
+		}
+	}
+	helpers.Should(w.Flush())
+
+	if reply.Error != "" {
+		fmt.Printf("\nboot failed in %s: %s\n", bootDuration(reply.Duration), reply.Error)
+	} else {
+		fmt.Printf("\nboot succeeded in %s\n", bootDuration(reply.Duration))
+	}
+}
+
+func bootResult(err string) string {
+	if err != "" {
+		return "failure"
+	}
+
+	return "success"
+}
+
+func bootDuration(d *duration.Duration) string {
+	// nolint: errcheck
+	dd, _ := ptypes.Duration(d)
+
+	return dd.Round(time.Millisecond).String()
+}
+
+func firstLine(s string) string {
+	return strings.SplitN(s, "\n", 2)[0]
+}
+
+func init() {
+	bootReportCmd.Flags().StringVarP(&target, "target", "t", "", "target the specificed node")
+	rootCmd.AddCommand(bootReportCmd)
+}
@@ -0,0 +1,173 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// nolint: dupl,golint
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/talos-systems/talos/cmd/osctl/pkg/client"
+	"github.com/talos-systems/talos/cmd/osctl/pkg/helpers"
+	"github.com/talos-systems/talos/internal/app/machined/proto"
+	"github.com/talos-systems/talos/pkg/blockdevice/discovery"
+)
+
+// disksCmd represents the disks command.
+var disksCmd = &cobra.Command{
+	Use:   "disks",
+	Short: "List disks, partitions and file systems",
+	Long: `Lists the disks of the node, with the partitions and the file systems found on them.
+The disk holding the Talos partitions is marked with an asterisk.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			helpers.Should(cmd.Usage())
+			os.Exit(1)
+		}
+
+		setupClient(func(c *client.Client) {
+			disksRender(c.Disks(globalCtx))
+		})
+	},
+}
+
+func disksRender(reply *proto.DisksReply, err error) {
+	if err != nil {
+		helpers.Fatalf("error getting disks: %s", err)
+	}
+
+	var errs []string
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "DEVICE\tSIZE(GB)\tTYPE\tNAME\tFILESYSTEM\tLABEL\tMOUNTED ON")
+	for _, d := range reply.Disks {
+		device := d.DeviceName
+		if d.InstallTarget {
+			device += " *"
+		}
+
+		kind := d.Bus + " hdd"
+		if !d.Rotational {
+			kind = d.Bus + " ssd"
+		}
+		if d.PartitionTable != "" {
+			kind += " " + d.PartitionTable
+		}
+
+		name := strings.TrimSpace(d.Model + " " + d.Serial)
+
+		fmt.Fprintf(w, "%s\t%.02f\t%s\t%s\t%s\t%s\t\n", device, float64(d.Size)*1e-9, kind, name, d.Filesystem, d.Label)
+		if d.Error != "" {
+			errs = append(errs, d.DeviceName+": "+d.Error)
+		}
+
+		for _, p := range d.Partitions {
+			device := p.DeviceName
+			if device == "" {
+				device = fmt.Sprintf("#%d", p.Number)
+			}
+
+			fmt.Fprintf(w, "  %s\t%.02f\t%s\t%s\t%s\t%s\t%s\n", device, float64(p.Size)*1e-9, p.Type, p.Name, p.Filesystem, p.Label, p.MountedOn)
+			if p.Error != "" {
+				errs = append(errs, fmt.Sprintf("%s partition %d: %s", d.DeviceName, p.Number, p.Error))
+			}
+		}
+	}
+	helpers.Should(w.Flush())
+
+	if len(errs) > 0 {
+		fmt.Printf("\nerrors:\n  %s\n", strings.Join(errs, "\n  "))
+	}
+}
+
+// diskHealthCmd represents the disks health command.
+var diskHealthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Show the health of the file systems and the disks",
+	Long: `Shows the space and the inodes used on the mounted file systems, and the health
+the disks report about themselves, as of the last check of the disk monitor.
+The problems found make the diskmon service unhealthy.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			helpers.Should(cmd.Usage())
+			os.Exit(1)
+		}
+
+		setupClient(func(c *client.Client) {
+			diskHealthRender(c.DiskHealth(globalCtx))
+		})
+	},
+}
+
+func diskHealthRender(reply *proto.DiskHealthReply, err error) {
+	if err != nil {
+		helpers.Fatalf("error getting disk health: %s", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "MOUNTED ON\tDEVICE\tFILESYSTEM\tSIZE(GB)\tUSED\tINODES USED\tSTATE")
+	for _, fs := range reply.Filesystems {
+		state := "ok"
+		switch {
+		case fs.Error != "":
+			state = fs.Error
+		case fs.ReadOnly:
+			state = "read only"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.02f\t%s\t%s\t%s\n", fs.MountPoint, fs.Device, fs.Type, float64(fs.Size)*1e-9, percentage(fs.Used, fs.Used+fs.Available), percentage(fs.InodesUsed, fs.Inodes), state)
+	}
+	helpers.Should(w.Flush())
+
+	fmt.Println()
+
+	fmt.Fprintln(w, "DEVICE\tBUS\tSMART\tTEMP(C)\tSPARE\tENDURANCE USED\tMEDIA ERRORS")
+	for _, d := range reply.Disks {
+		if !d.Supported {
+			status := "unsupported"
+			if d.Error != "" {
+				status = d.Error
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t\t\t\t\n", d.DeviceName, d.Bus, status)
+			continue
+		}
+
+		status := "passed"
+		if !d.Passed {
+			status = "failed: " + strings.Join(d.Warnings, ", ")
+		}
+
+		if d.Bus != discovery.BusNVMe {
+			fmt.Fprintf(w, "%s\t%s\t%s\t\t\t\t\n", d.DeviceName, d.Bus, status)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d%%\t%d%%\t%d\n", d.DeviceName, d.Bus, status, d.Temperature, d.AvailableSpare, d.PercentageUsed, d.MediaErrors)
+	}
+	helpers.Should(w.Flush())
+
+	if len(reply.Problems) > 0 {
+		fmt.Printf("\nproblems:\n  %s\n", strings.Join(reply.Problems, "\n  "))
+	}
+}
+
+func percentage(n, total uint64) string {
+	if total == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.0f%%", float64(n)*100/float64(total))
+}
+
+func init() {
+	disksCmd.Flags().StringVarP(&target, "target", "t", "", "target the specificed node")
+	diskHealthCmd.Flags().StringVarP(&target, "target", "t", "", "target the specificed node")
+	disksCmd.AddCommand(diskHealthCmd)
+	rootCmd.AddCommand(disksCmd)
+}
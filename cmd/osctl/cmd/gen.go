@@ -17,6 +17,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/talos-systems/talos/cmd/osctl/pkg/helpers"
 	"github.com/talos-systems/talos/pkg/crypto/x509"
+	"github.com/talos-systems/talos/pkg/userdata/envelope"
 	"github.com/talos-systems/talos/pkg/userdata/token"
 )
 
@@ -202,6 +203,50 @@ var inittokenCmd = &cobra.Command{
 	},
 }
 
+// signingKeyCmd represents the gen signing-key command
+var signingKeyCmd = &cobra.Command{
+	Use:   "signing-key",
+	Short: "Generates an ed25519 key to sign userdata",
+	Long: `Writes the private key to <name>.key and prints the verification key to
+pass in the talos.userdata.verify kernel parameter.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		signer, err := envelope.GenerateSigningKey()
+		if err != nil {
+			helpers.Fatalf("error generating key: %s", err)
+		}
+		keyPEM, err := envelope.MarshalSigningKey(signer)
+		if err != nil {
+			helpers.Fatalf("error encoding key: %s", err)
+		}
+		verificationKey, err := envelope.MarshalVerificationKey(signer.Public())
+		if err != nil {
+			helpers.Fatalf("error encoding verification key: %s", err)
+		}
+		if err := ioutil.WriteFile(name+".key", keyPEM, 0600); err != nil {
+			helpers.Fatalf("error writing key: %s", err)
+		}
+		fmt.Println(verificationKey)
+	},
+}
+
+// identityCmd represents the gen identity command
+var identityCmd = &cobra.Command{
+	Use:   "identity",
+	Short: "Generates a P-256 identity to decrypt userdata",
+	Long: `Writes the identity to <name>.identity and prints the recipient to
+encrypt the userdata to.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		identity, err := envelope.GenerateIdentity()
+		if err != nil {
+			helpers.Fatalf("error generating identity: %s", err)
+		}
+		if err := ioutil.WriteFile(name+".identity", []byte(identity.String()+"\n"), 0600); err != nil {
+			helpers.Fatalf("error writing identity: %s", err)
+		}
+		fmt.Println(identity.Recipient().String())
+	},
+}
+
 func init() {
 	// Certificate Authorities
 	caCmd.Flags().StringVar(&organization, "organization", "", "X.509 distinguished name for the Organization")
@@ -228,7 +273,12 @@ func init() {
 	helpers.Should(cobra.MarkFlagRequired(csrCmd.Flags(), "key"))
 	csrCmd.Flags().StringVar(&ip, "ip", "", "generate the certificate for this IP address")
 	helpers.Should(cobra.MarkFlagRequired(csrCmd.Flags(), "ip"))
+	// Userdata envelope keys
+	signingKeyCmd.Flags().StringVar(&name, "name", "", "the basename of the generated file")
+	helpers.Should(cobra.MarkFlagRequired(signingKeyCmd.Flags(), "name"))
+	identityCmd.Flags().StringVar(&name, "name", "", "the basename of the generated file")
+	helpers.Should(cobra.MarkFlagRequired(identityCmd.Flags(), "name"))
 
-	genCmd.AddCommand(caCmd, keypairCmd, keyCmd, csrCmd, crtCmd, inittokenCmd)
+	genCmd.AddCommand(caCmd, keypairCmd, keyCmd, csrCmd, crtCmd, inittokenCmd, signingKeyCmd, identityCmd)
 	rootCmd.AddCommand(genCmd)
 }
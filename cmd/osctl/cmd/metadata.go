@@ -0,0 +1,59 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// nolint: dupl,golint
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/talos-systems/talos/cmd/osctl/pkg/client"
+	"github.com/talos-systems/talos/cmd/osctl/pkg/helpers"
+	"github.com/talos-systems/talos/internal/app/machined/proto"
+)
+
+// metadataCmd represents the metadata command
+var metadataCmd = &cobra.Command{
+	Use:   "metadata",
+	Short: "Show the platform metadata of the node",
+	Long:  `Shows the instance identity, type, region, zone and addresses as reported by the platform.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 {
+			helpers.Should(cmd.Usage())
+			os.Exit(1)
+		}
+
+		setupClient(func(c *client.Client) {
+			reply, err := c.Metadata(globalCtx)
+			if err != nil {
+				helpers.Fatalf("error getting metadata: %s", err)
+			}
+
+			metadataRender(reply)
+		})
+	},
+}
+
+func metadataRender(reply *proto.MetadataReply) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "PLATFORM\t%s\n", reply.Platform)
+	fmt.Fprintf(w, "DETECTED BY\t%s\n", reply.DetectedBy)
+	fmt.Fprintf(w, "HOSTNAME\t%s\n", reply.Hostname)
+	fmt.Fprintf(w, "INSTANCE ID\t%s\n", reply.InstanceId)
+	fmt.Fprintf(w, "INSTANCE TYPE\t%s\n", reply.InstanceType)
+	fmt.Fprintf(w, "REGION\t%s\n", reply.Region)
+	fmt.Fprintf(w, "ZONE\t%s\n", reply.Zone)
+	fmt.Fprintf(w, "PUBLIC IPS\t%s\n", strings.Join(reply.PublicIps, ","))
+	fmt.Fprintf(w, "PRIVATE IPS\t%s\n", strings.Join(reply.PrivateIps, ","))
+	helpers.Should(w.Flush())
+}
+
+func init() {
+	metadataCmd.Flags().StringVarP(&target, "target", "t", "", "target the specificed node")
+	rootCmd.AddCommand(metadataCmd)
+}
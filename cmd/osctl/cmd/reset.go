@@ -7,17 +7,28 @@ package cmd
 
 import (
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/talos-systems/talos/cmd/osctl/pkg/client"
 	"github.com/talos-systems/talos/cmd/osctl/pkg/helpers"
+	"github.com/talos-systems/talos/pkg/blockdevice"
 )
 
+var wipeMode string
+
 // resetCmd represents the reset command
 var resetCmd = &cobra.Command{
 	Use:   "reset",
 	Short: "Reset a node",
-	Long:  ``,
+	Long: `Reset a node, and optionally wipe the install devices.
+
+The wipe modes are zero, which overwrites the whole devices, discard and
+secure-discard, which discard the blocks of the devices, and partial, which
+only zeroes the partition tables and the first and the last MiB of each
+partition. The devices are wiped by the wipe service once the other services
+are stopped, see osctl service wipe for the progress. The node reboots once
+the devices are wiped.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 0 {
 			helpers.Should(cmd.Usage())
@@ -25,7 +36,7 @@ var resetCmd = &cobra.Command{
 		}
 
 		setupClient(func(c *client.Client) {
-			if err := c.Reset(globalCtx); err != nil {
+			if err := c.Reset(globalCtx, wipeMode); err != nil {
 				helpers.Fatalf("error executing reset: %s", err)
 			}
 		})
@@ -34,5 +45,6 @@ var resetCmd = &cobra.Command{
 
 func init() {
 	resetCmd.Flags().StringVarP(&target, "target", "t", "", "target the specificed node")
+	resetCmd.Flags().StringVar(&wipeMode, "wipe-mode", "", "wipe the install devices: "+strings.Join(blockdevice.WipeMethods, ", "))
 	rootCmd.AddCommand(resetCmd)
 }
@@ -25,7 +25,8 @@ var serviceCmd = &cobra.Command{
 	Short:   "Retrieve the state of a service (or all services), control service state",
 	Long: `Service control command. If run without arguments, lists all the services and their state.
 If service ID is specified, default action 'status' is executed which shows status of a single list service.
-With actions 'start', 'stop', 'restart', service state is updated respectively.`,
+With actions 'start', 'stop', 'restart', service state is updated respectively.
+Action 'restart' also restarts all the running services which depend on the service.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) > 2 {
 			helpers.Should(cmd.Usage())
@@ -54,8 +55,7 @@ With actions 'start', 'stop', 'restart', service state is updated respectively.`
 			case "stop":
 				serviceStop(c, serviceID)
 			case "restart":
-				serviceStop(c, serviceID)
-				serviceStart(c, serviceID)
+				serviceRestart(c, serviceID)
 			default:
 				helpers.Fatalf("unsupported service action: %q", action)
 			}
@@ -63,6 +63,27 @@ With actions 'start', 'stop', 'restart', service state is updated respectively.`
 	},
 }
 
+// serviceGraphCmd represents the service graph command
+var serviceGraphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Show service dependency graph with the state of each service",
+	Long: `Renders the service dependency graph with current state of each service.
+Use --format=dot to get output in the Graphviz DOT format.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		setupClient(func(c *client.Client) {
+			graph, err := c.ServiceGraph(globalCtx, graphFormat)
+			if err != nil {
+				helpers.Fatalf("error getting service graph: %s", err)
+			}
+
+			fmt.Print(graph)
+		})
+	},
+}
+
+var graphFormat string
+
 func serviceList(c *client.Client) {
 	reply, err := c.ServiceList(globalCtx)
 	if err != nil {
@@ -97,6 +118,14 @@ func serviceInfo(c *client.Client, id string) {
 	if svc.Health.LastMessage != "" {
 		fmt.Fprintf(w, "LAST HEALTH MESSAGE\t%s\n", svc.Health.LastMessage)
 	}
+	if svc.Restarts != nil && svc.Restarts.Count > 0 {
+		fmt.Fprintf(w, "RESTARTS\t%d\n", svc.Restarts.Count)
+		if svc.Restarts.LastSignal != "" {
+			fmt.Fprintf(w, "LAST EXIT\tsignal %s\n", svc.Restarts.LastSignal)
+		} else {
+			fmt.Fprintf(w, "LAST EXIT\tcode %d\n", svc.Restarts.LastExitCode)
+		}
+	}
 	label := "EVENTS"
 	for _, event := range svc.Events.Events {
 		// nolint: errcheck
@@ -128,6 +157,15 @@ func serviceStop(c *client.Client, id string) {
 	fmt.Fprintln(os.Stderr, resp)
 }
 
+func serviceRestart(c *client.Client, id string) {
+	resp, err := c.ServiceRestart(globalCtx, id)
+	if err != nil {
+		helpers.Fatalf("error restarting service: %s", err)
+	}
+
+	fmt.Fprintln(os.Stderr, resp)
+}
+
 type serviceInfoWrapper struct {
 	*initproto.ServiceInfo
 }
@@ -165,5 +203,8 @@ func (svc serviceInfoWrapper) HealthStatus() string {
 
 func init() {
 	serviceCmd.Flags().StringVarP(&target, "target", "t", "", "target the specificed node")
+	serviceGraphCmd.Flags().StringVarP(&target, "target", "t", "", "target the specificed node")
+	serviceGraphCmd.Flags().StringVar(&graphFormat, "format", "text", "output format (text or dot)")
+	serviceCmd.AddCommand(serviceGraphCmd)
 	rootCmd.AddCommand(serviceCmd)
 }
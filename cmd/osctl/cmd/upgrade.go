@@ -14,8 +14,10 @@ import (
 )
 
 var (
-	assetURL string
-	local    bool
+	assetURL        string
+	kernelSHA256    string
+	initramfsSHA256 string
+	local           bool
 )
 
 // upgradeCmd represents the processes command
@@ -40,7 +42,9 @@ var upgradeCmd = &cobra.Command{
 
 func init() {
 	upgradeCmd.Flags().BoolVarP(&local, "local", "l", false, "operate in local mode")
-	upgradeCmd.Flags().StringVarP(&assetURL, "url", "u", "", "url hosting upgrade assets (excluding filename)")
+	upgradeCmd.Flags().StringVarP(&assetURL, "url", "u", "", "url hosting upgrade assets (excluding filename), an http(s) URL or oci://registry/repository:tag")
+	upgradeCmd.Flags().StringVar(&kernelSHA256, "kernel-sha256", "", "SHA-256 digest of the kernel, required unless the url is an oci:// reference")
+	upgradeCmd.Flags().StringVar(&initramfsSHA256, "initramfs-sha256", "", "SHA-256 digest of the initramfs, required unless the url is an oci:// reference")
 	upgradeCmd.Flags().StringVarP(&target, "target", "t", "", "target the specificed node")
 	rootCmd.AddCommand(upgradeCmd)
 }
@@ -54,7 +58,7 @@ func remoteUpgrade() error {
 	setupClient(func(c *client.Client) {
 		// TODO: See if we can validate version and prevent
 		// starting upgrades to an unknown version
-		ack, err = c.Upgrade(globalCtx, assetURL)
+		ack, err = c.Upgrade(globalCtx, assetURL, kernelSHA256, initramfsSHA256)
 	})
 
 	if err == nil {
@@ -0,0 +1,131 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/talos-systems/talos/cmd/osctl/pkg/helpers"
+	"github.com/talos-systems/talos/pkg/userdata"
+	"github.com/talos-systems/talos/pkg/userdata/envelope"
+)
+
+var recipient string
+
+// userdataCmd represents the userdata command
+var userdataCmd = &cobra.Command{
+	Use:   "userdata",
+	Short: "Manage userdata files",
+	Long:  ``,
+}
+
+// userdataSealCmd represents the userdata seal command
+var userdataSealCmd = &cobra.Command{
+	Use:   "seal <userdata>",
+	Short: "Sign and encrypt userdata",
+	Long: `Wraps the userdata into the envelope encrypted to the recipient and
+signed with the key, and writes it to stdout. Either of the --key and
+--recipient flags can be omitted to only sign or only encrypt.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if key == "" && recipient == "" {
+			helpers.Fatalf("at least one of --key and --recipient is required")
+		}
+		data, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			helpers.Fatalf("error reading userdata: %s", err)
+		}
+		if recipient != "" {
+			r, err := envelope.ParseRecipient(recipient)
+			if err != nil {
+				helpers.Fatalf("error parsing recipient: %s", err)
+			}
+			if data, err = envelope.Encrypt(data, r); err != nil {
+				helpers.Fatalf("error encrypting userdata: %s", err)
+			}
+		}
+		if key != "" {
+			keyPEM, err := ioutil.ReadFile(key)
+			if err != nil {
+				helpers.Fatalf("error reading key: %s", err)
+			}
+			signer, err := envelope.ParseSigningKey(keyPEM)
+			if err != nil {
+				helpers.Fatalf("error parsing key: %s", err)
+			}
+			if data, err = envelope.Sign(data, signer); err != nil {
+				helpers.Fatalf("error signing userdata: %s", err)
+			}
+		}
+		if _, err = os.Stdout.Write(data); err != nil {
+			helpers.Fatalf("error writing userdata: %s", err)
+		}
+	},
+}
+
+// userdataMigrateCmd represents the userdata migrate command
+var userdataMigrateCmd = &cobra.Command{
+	Use:   "migrate <userdata>...",
+	Short: "Rewrite userdata files to the latest schema version",
+	Long: `Applies the schema migrations to the userdata files in place. Files at the
+latest version are left untouched. Comments are not preserved. Sealed userdata
+has to be migrated before it is signed and encrypted.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, file := range args {
+			info, err := os.Stat(file)
+			if err != nil {
+				helpers.Fatalf("error reading userdata: %s", err)
+			}
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				helpers.Fatalf("error reading userdata: %s", err)
+			}
+			if envelope.IsEnvelope(data) {
+				helpers.Fatalf("%s: sealed userdata can't be migrated", file)
+			}
+			migrated, from, err := userdata.Migrate(data)
+			if err != nil {
+				helpers.Fatalf("%s: %s", file, err)
+			}
+			if bytes.Equal(migrated, data) {
+				fmt.Printf("%s: already at version %q\n", file, from)
+				continue
+			}
+			if err = ioutil.WriteFile(file, migrated, info.Mode()); err != nil {
+				helpers.Fatalf("error writing userdata: %s", err)
+			}
+			fmt.Printf("%s: migrated to version %q\n", file, userdata.CurrentVersion)
+		}
+	},
+}
+
+// userdataSchemaCmd represents the userdata schema command
+var userdataSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the userdata",
+	Long: `Prints the JSON Schema of the latest userdata version to use with editors
+and CI. Unknown keys are not allowed by the schema.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		schema, err := userdata.JSONSchema()
+		if err != nil {
+			helpers.Fatalf("error generating schema: %s", err)
+		}
+		fmt.Println(string(schema))
+	},
+}
+
+func init() {
+	userdataSealCmd.Flags().StringVar(&key, "key", "", "path to the PEM encoded ed25519 or EC signing key")
+	userdataSealCmd.Flags().StringVar(&recipient, "recipient", "", "the X25519 recipient to encrypt the userdata to")
+
+	userdataCmd.AddCommand(userdataSealCmd, userdataMigrateCmd, userdataSchemaCmd)
+	rootCmd.AddCommand(userdataCmd)
+}
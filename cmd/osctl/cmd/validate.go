@@ -6,27 +6,69 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/talos-systems/talos/cmd/osctl/internal/userdata"
+	"github.com/talos-systems/talos/pkg/blockdevice/discovery"
+	ud "github.com/talos-systems/talos/pkg/userdata"
 )
 
+var validateMode string
+
 // validateCmd reads in a userData file and attempts to parse it
 var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate userdata",
-	Long:  ``,
+	Long: `Decodes the userdata rejecting unknown keys, and runs the validation checks.
+With --mode, the checks specific to the environment are run as well.
+With --mode bare-metal (or metal), the disk the install.disk selector picks on this
+machine is reported.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		ud, err := userdata.UserData(userdataFile)
+		data, err := userdata.UserData(userdataFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var mode ud.Mode
+		if validateMode == "" {
+			err = data.Validate()
+		} else {
+			if mode, err = ud.ParseMode(validateMode); err != nil {
+				log.Fatal(err)
+			}
+			err = data.ValidateMode(mode)
+		}
 		if err != nil {
 			log.Fatal(err)
 		}
-		log.Println(ud)
+		fmt.Printf("%s is valid\n", userdataFile)
+		if mode == ud.ModeBareMetal && data.Install != nil && data.Install.Disk != nil {
+			reportDisk(data.Install.Disk)
+		}
 	},
 }
 
+// reportDisk prints the disk the selector picks on the local machine. The
+// userdata is valid even if no disk is picked here, as the selector is
+// resolved on the node.
+func reportDisk(selector *ud.DiskSelector) {
+	disk, err := discovery.Find(selector.Selector())
+	if err != nil {
+		fmt.Printf("install.disk doesn't select a disk on this machine: %v\n", err)
+		return
+	}
+	fmt.Printf("install.disk selects %s\n", disk)
+}
+
 func init() {
+	modes := make([]string, 0, len(ud.Modes))
+	for _, mode := range ud.Modes {
+		modes = append(modes, string(mode))
+	}
+
 	validateCmd.Flags().StringVarP(&userdataFile, "userdata", "u", "", "path or url of userdata file")
+	validateCmd.Flags().StringVar(&validateMode, "mode", "", "the environment to run the additional checks for ("+strings.Join(modes, ", ")+", metal is an alias of bare-metal)")
 	rootCmd.AddCommand(validateCmd)
 }
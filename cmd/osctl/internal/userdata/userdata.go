@@ -11,13 +11,17 @@ import (
 )
 
 // UserData provides an abstraction to call the appropriate method to
-// load user data
+// load user data. The unknown keys are rejected.
 // TODO: Merge this in to internal/pkg/userdata
 func UserData(location string) (userData *ud.UserData, err error) {
 	if strings.HasPrefix(location, "http") {
-		userData, err = ud.Download(location, nil)
+		var b []byte
+		if b, err = ud.Fetch(location); err != nil {
+			return nil, err
+		}
+		userData, err = ud.DecodeStrict(b)
 	} else {
-		userData, err = ud.Open(location)
+		userData, err = ud.OpenStrict(location)
 	}
 	return userData, err
 }
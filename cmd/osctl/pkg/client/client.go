@@ -162,8 +162,8 @@ func (c *Client) Restart(ctx context.Context, namespace string, driver proto.Con
 }
 
 // Reset implements the proto.OSDClient interface.
-func (c *Client) Reset(ctx context.Context) (err error) {
-	_, err = c.initClient.Reset(ctx, &empty.Empty{})
+func (c *Client) Reset(ctx context.Context, wipeMode string) (err error) {
+	_, err = c.initClient.Reset(ctx, &initproto.ResetRequest{WipeMode: wipeMode})
 	return
 }
 
@@ -233,6 +233,16 @@ func (c *Client) DF(ctx context.Context) (*initproto.DFReply, error) {
 	return c.initClient.DF(ctx, &empty.Empty{})
 }
 
+// Disks implements the proto.OSDClient interface.
+func (c *Client) Disks(ctx context.Context) (*initproto.DisksReply, error) {
+	return c.initClient.Disks(ctx, &empty.Empty{})
+}
+
+// DiskHealth implements the proto.OSDClient interface.
+func (c *Client) DiskHealth(ctx context.Context) (*initproto.DiskHealthReply, error) {
+	return c.initClient.DiskHealth(ctx, &empty.Empty{})
+}
+
 // LS implements the proto.OSDClient interface.
 func (c *Client) LS(ctx context.Context, req initproto.LSRequest) (stream initproto.Init_LSClient, err error) {
 	return c.initClient.LS(ctx, &req)
@@ -286,8 +296,8 @@ func (c *Client) CopyOut(ctx context.Context, rootPath string) (io.Reader, <-cha
 
 // Upgrade initiates a Talos upgrade ... and implements the proto.OSDClient
 // interface
-func (c *Client) Upgrade(ctx context.Context, asseturl string) (string, error) {
-	reply, err := c.initClient.Upgrade(ctx, &initproto.UpgradeRequest{Url: asseturl})
+func (c *Client) Upgrade(ctx context.Context, asseturl, kernelSHA256, initramfsSHA256 string) (string, error) {
+	reply, err := c.initClient.Upgrade(ctx, &initproto.UpgradeRequest{Url: asseturl, KernelSha256: kernelSHA256, InitramfsSha256: initramfsSHA256})
 	if err != nil {
 		return "", err
 	}
@@ -318,6 +328,16 @@ func (c *Client) ServiceInfo(ctx context.Context, id string) (*initproto.Service
 	return nil, nil
 }
 
+// ServiceGraph returns service dependency graph rendered in the specified format
+func (c *Client) ServiceGraph(ctx context.Context, format string) (string, error) {
+	reply, err := c.initClient.ServiceGraph(ctx, &initproto.ServiceGraphRequest{Format: format})
+	if err != nil {
+		return "", err
+	}
+
+	return reply.Graph, nil
+}
+
 // Start starts a service.
 func (c *Client) Start(ctx context.Context, id string) (string, error) {
 	r, err := c.initClient.Start(ctx, &initproto.StartRequest{Id: id})
@@ -337,3 +357,29 @@ func (c *Client) Stop(ctx context.Context, id string) (string, error) {
 
 	return r.Resp, nil
 }
+
+// BootReport returns the report of the boot sequence.
+func (c *Client) BootReport(ctx context.Context) (*initproto.BootReportReply, error) {
+	return c.initClient.BootReport(ctx, &empty.Empty{})
+}
+
+// Metadata returns the description of the machine as reported by the platform.
+func (c *Client) Metadata(ctx context.Context) (*initproto.MetadataReply, error) {
+	return c.initClient.Metadata(ctx, &empty.Empty{})
+}
+
+// ApplyUserData submits the new userdata to the node. With dryRun set, only
+// the changes are returned.
+func (c *Client) ApplyUserData(ctx context.Context, data []byte, dryRun bool) (*initproto.ApplyUserDataReply, error) {
+	return c.initClient.ApplyUserData(ctx, &initproto.ApplyUserDataRequest{Userdata: data, DryRun: dryRun})
+}
+
+// ServiceRestart restarts a service and the services which depend on it.
+func (c *Client) ServiceRestart(ctx context.Context, id string) (string, error) {
+	r, err := c.initClient.ServiceRestart(ctx, &initproto.ServiceRestartRequest{Id: id})
+	if err != nil {
+		return "", err
+	}
+
+	return r.Resp, nil
+}
@@ -0,0 +1,245 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package reg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/pkg/errors"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/diskmon"
+	"github.com/talos-systems/talos/internal/app/machined/proto"
+	"github.com/talos-systems/talos/pkg/blockdevice"
+	"github.com/talos-systems/talos/pkg/blockdevice/discovery"
+	"github.com/talos-systems/talos/pkg/blockdevice/probe"
+	"github.com/talos-systems/talos/pkg/blockdevice/table"
+	gptpartition "github.com/talos-systems/talos/pkg/blockdevice/table/gpt/partition"
+	mbrpartition "github.com/talos-systems/talos/pkg/blockdevice/table/mbr/partition"
+	"github.com/talos-systems/talos/pkg/constants"
+)
+
+// Disks implements the proto.InitServer interface.
+func (r *Registrator) Disks(ctx context.Context, in *empty.Empty) (reply *proto.DisksReply, err error) {
+	var disks []*discovery.Disk
+	if disks, err = discovery.List(); err != nil {
+		return nil, errors.Wrap(err, "failed to list the disks")
+	}
+
+	var mounts map[string]string
+	if mounts, err = mountedOn(); err != nil {
+		return nil, err
+	}
+
+	reply = &proto.DisksReply{}
+
+	for _, disk := range disks {
+		d := inspectDisk(disk, mounts)
+
+		if r.Data.Install != nil && r.Data.Install.Ephemeral != nil && r.Data.Install.Ephemeral.Device == disk.Path {
+			d.InstallTarget = true
+		}
+
+		reply.Disks = append(reply.Disks, d)
+	}
+
+	return reply, nil
+}
+
+// DiskHealth implements the proto.InitServer interface.
+func (r *Registrator) DiskHealth(ctx context.Context, in *empty.Empty) (reply *proto.DiskHealthReply, err error) {
+	report := diskmon.Instance().Report()
+	if report == nil {
+		return nil, errors.New("storage not checked yet")
+	}
+
+	reply = &proto.DiskHealthReply{}
+
+	if reply.Checked, err = ptypes.TimestampProto(report.Time); err != nil {
+		return nil, err
+	}
+
+	for _, fs := range report.FileSystems {
+		reply.Filesystems = append(reply.Filesystems, &proto.FileSystemHealth{
+			MountPoint: fs.MountPoint,
+			Device:     fs.Device,
+			Type:       fs.Type,
+			Size:       fs.Size,
+			Used:       fs.Used,
+			Available:  fs.Available,
+			Inodes:     fs.Inodes,
+			InodesUsed: fs.InodesUsed,
+			ReadOnly:   fs.ReadOnly,
+			Error:      fs.Error,
+		})
+	}
+
+	for _, disk := range report.Disks {
+		d := &proto.DiskSMART{
+			DeviceName: disk.Path,
+			Bus:        disk.Bus,
+			Error:      disk.Error,
+		}
+
+		if h := disk.Health; h != nil {
+			d.Supported = true
+			d.Passed = h.Passed
+			d.Warnings = h.Warnings
+			d.Temperature = int32(h.Temperature)
+			d.AvailableSpare = int32(h.AvailableSpare)
+			d.AvailableSpareThreshold = int32(h.AvailableSpareThreshold)
+			d.PercentageUsed = int32(h.PercentageUsed)
+			d.MediaErrors = h.MediaErrors
+		}
+
+		reply.Disks = append(reply.Disks, d)
+	}
+
+	for _, p := range report.Problems {
+		reply.Problems = append(reply.Problems, p.Message)
+	}
+
+	return reply, nil
+}
+
+// inspectDisk reads the partition table and the file systems of the disk.
+// The errors are reported in the disk and the partitions, so that the rest
+// of the disk is still listed.
+func inspectDisk(disk *discovery.Disk, mounts map[string]string) *proto.Disk {
+	d := &proto.Disk{
+		DeviceName: disk.Path,
+		Size:       disk.Size,
+		Model:      disk.Model,
+		Serial:     disk.Serial,
+		Wwn:        disk.WWN,
+		Rotational: disk.Rotational,
+		Bus:        disk.Bus,
+		Links:      disk.Links,
+	}
+
+	bd, err := blockdevice.Open(disk.Path)
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	// nolint: errcheck
+	defer bd.Close()
+
+	pt, err := bd.PartitionTable(false)
+	if err != nil {
+		// There is no partition table, the file system might be on the disk
+		// itself.
+		if d.Filesystem, d.Label, err = inspectFileSystem(disk.Path); err != nil {
+			d.Error = err.Error()
+		}
+
+		return d
+	}
+
+	if err = pt.Read(); err != nil {
+		d.Error = errors.Wrap(err, "failed to read the partition table").Error()
+		return d
+	}
+
+	switch pt.Type() {
+	case table.GPT:
+		d.PartitionTable = "gpt"
+	case table.MBR:
+		d.PartitionTable = "mbr"
+	}
+
+	for _, p := range pt.Partitions() {
+		dp := inspectPartition(disk, p, mounts)
+
+		if dp.Label == constants.EphemeralPartitionLabel || dp.Label == constants.BootPartitionLabel {
+			d.InstallTarget = true
+		}
+
+		d.Partitions = append(d.Partitions, dp)
+	}
+
+	return d
+}
+
+func inspectPartition(disk *discovery.Disk, p table.Partition, mounts map[string]string) *proto.DiskPartition {
+	dp := &proto.DiskPartition{
+		Number: p.No(),
+	}
+
+	switch p := p.(type) {
+	case *gptpartition.Partition:
+		dp.Type = p.Type.String()
+		dp.Uuid = p.ID.String()
+		dp.Name = p.Name
+		dp.Attributes = p.Flags
+	case *mbrpartition.Partition:
+		dp.Type = fmt.Sprintf("0x%02x", p.Type)
+	}
+
+	for _, part := range disk.Partitions {
+		if part.Number == p.No() {
+			dp.DeviceName = part.Path
+			dp.Size = part.Size
+		}
+	}
+
+	if dp.DeviceName == "" {
+		dp.Error = "the kernel has no device for the partition, the partition table might need to be reread"
+		return dp
+	}
+
+	var err error
+	if dp.Filesystem, dp.Label, err = inspectFileSystem(dp.DeviceName); err != nil {
+		dp.Error = err.Error()
+	}
+
+	dp.MountedOn = mounts[dp.DeviceName]
+
+	return dp
+}
+
+func inspectFileSystem(devpath string) (fstype, label string, err error) {
+	sb, err := probe.FileSystem(devpath)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to read the super block")
+	}
+
+	if sb == nil {
+		return "", "", nil
+	}
+
+	return sb.Type(), probe.Label(sb), nil
+}
+
+// mountedOn maps the mounted devices to the first mount point of each.
+func mountedOn() (mounts map[string]string, err error) {
+	var file *os.File
+	if file, err = os.Open("/proc/mounts"); err != nil {
+		return nil, err
+	}
+	// nolint: errcheck
+	defer file.Close()
+
+	mounts = map[string]string{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		if _, ok := mounts[fields[0]]; !ok {
+			mounts[fields[0]] = fields[1]
+		}
+	}
+
+	return mounts, scanner.Err()
+}
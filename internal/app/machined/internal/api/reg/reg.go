@@ -6,28 +6,40 @@ package reg
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
 	"google.golang.org/grpc"
+	yaml "gopkg.in/yaml.v2"
 
+	"github.com/talos-systems/talos/internal/app/machined/internal/apply"
 	"github.com/talos-systems/talos/internal/app/machined/internal/event"
+	"github.com/talos-systems/talos/internal/app/machined/internal/phase"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform"
+	"github.com/talos-systems/talos/internal/app/machined/internal/reset"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system"
 	"github.com/talos-systems/talos/internal/app/machined/proto"
 	"github.com/talos-systems/talos/internal/pkg/upgrade"
 	"github.com/talos-systems/talos/pkg/archiver"
+	"github.com/talos-systems/talos/pkg/blockdevice"
+	"github.com/talos-systems/talos/pkg/blockdevice/probe"
+	"github.com/talos-systems/talos/pkg/blockdevice/util"
 	"github.com/talos-systems/talos/pkg/chunker/stream"
 	"github.com/talos-systems/talos/pkg/constants"
 	"github.com/talos-systems/talos/pkg/userdata"
+	"github.com/talos-systems/talos/pkg/userdata/envelope"
 )
 
 // OSPathSeparator is the string version of the os.PathSeparator
@@ -37,6 +49,8 @@ const OSPathSeparator = string(os.PathSeparator)
 // proto.Init interfaces.
 type Registrator struct {
 	Data *userdata.UserData
+
+	applyMu sync.Mutex
 }
 
 // NewRegistrator builds new Registrator instance
@@ -74,7 +88,7 @@ func (r *Registrator) Shutdown(ctx context.Context, in *empty.Empty) (reply *pro
 // Upgrade initiates a Talos upgrade
 func (r *Registrator) Upgrade(ctx context.Context, in *proto.UpgradeRequest) (data *proto.UpgradeReply, err error) {
 
-	if err = upgrade.NewUpgrade(in.Url); err != nil {
+	if err = upgrade.NewUpgrade(in.Url, in.KernelSha256, in.InitramfsSha256); err != nil {
 		return data, err
 	}
 
@@ -103,8 +117,22 @@ func (r *Registrator) Upgrade(ctx context.Context, in *proto.UpgradeRequest) (da
 	return data, err
 }
 
-// Reset initiates a Talos upgrade
-func (r *Registrator) Reset(ctx context.Context, in *empty.Empty) (data *proto.ResetReply, err error) {
+// Reset initiates a Talos reset, and wipes the install devices if a wipe
+// mode is requested
+func (r *Registrator) Reset(ctx context.Context, in *proto.ResetRequest) (data *proto.ResetReply, err error) {
+	var devices []string
+
+	if in.WipeMode != "" {
+		if !supportedWipeMode(in.WipeMode) {
+			return nil, errors.Errorf("unknown wipe mode %q, expected one of %s", in.WipeMode, strings.Join(blockdevice.WipeMethods, ", "))
+		}
+
+		// Find the devices before the machine config is removed.
+		if devices, err = installDevices(r.Data); err != nil {
+			return nil, errors.Wrap(err, "failed to find the install devices")
+		}
+	}
+
 	// Stop the kubelet.
 	if _, err = r.Stop(ctx, &proto.StopRequest{Id: "kubelet"}); err != nil {
 		return data, err
@@ -120,7 +148,75 @@ func (r *Registrator) Reset(ctx context.Context, in *empty.Empty) (data *proto.R
 		return nil, err
 	}
 
-	return &proto.ResetReply{}, err
+	if in.WipeMode == "" {
+		return &proto.ResetReply{}, err
+	}
+
+	// The devices are wiped by the wipe service once the other services are
+	// stopped and the file systems are unmounted, the progress is recorded as
+	// the events of the service.
+	if err = system.Services(r.Data).LoadAndStart(&reset.Wipe{Devices: devices, Mode: in.WipeMode}); err != nil {
+		return nil, err
+	}
+
+	return &proto.ResetReply{}, nil
+}
+
+// installDevices returns the devices holding the Talos partitions and the
+// extra partitions. The ephemeral device might have been selected by its
+// attributes, in which case it is found by the label of the partition.
+func installDevices(data *userdata.UserData) (devices []string, err error) {
+	seen := map[string]bool{}
+	add := func(dev string) {
+		if dev != "" && !seen[dev] {
+			seen[dev] = true
+			devices = append(devices, dev)
+		}
+	}
+
+	if data.Install != nil {
+		if data.Install.Ephemeral != nil {
+			add(data.Install.Ephemeral.Device)
+		}
+
+		if data.Install.Boot != nil {
+			add(data.Install.Boot.Device)
+		}
+
+		for _, extra := range data.Install.ExtraDevices {
+			add(extra.Device)
+		}
+	}
+
+	if len(devices) > 0 {
+		return devices, nil
+	}
+
+	var dev *probe.ProbedBlockDevice
+	if dev, err = probe.GetDevWithFileSystemLabel(constants.EphemeralPartitionLabel); err != nil {
+		return nil, err
+	}
+	// nolint: errcheck
+	defer dev.Close()
+
+	var devname string
+	if devname, err = util.DevnameFromPartname(dev.Path); err != nil {
+		return nil, err
+	}
+
+	add("/dev/" + devname)
+
+	return devices, nil
+}
+
+func supportedWipeMode(mode string) bool {
+	for _, m := range blockdevice.WipeMethods {
+		if mode == m {
+			return true
+		}
+	}
+
+	return false
 }
 
 // ServiceList returns list of the registered services and their status
@@ -138,6 +234,65 @@ func (r *Registrator) ServiceList(ctx context.Context, in *empty.Empty) (result
 	return result, nil
 }
 
+// BootReport returns the phases and tasks of the boot sequence along with
+// their timing and errors
+//
+// While the boot sequence is still in progress, the report persisted by the
+// previous boot is returned, if any.
+func (r *Registrator) BootReport(ctx context.Context, in *empty.Empty) (reply *proto.BootReportReply, err error) {
+	report := phase.LastReport()
+	if report == nil {
+		if report, err = phase.LoadReport(constants.BootReportPath); err != nil {
+			return nil, errors.New("boot sequence is still in progress")
+		}
+	}
+
+	return report.AsProto(), nil
+}
+
+// Metadata returns the description of the machine as reported by the platform.
+func (r *Registrator) Metadata(ctx context.Context, in *empty.Empty) (reply *proto.MetadataReply, err error) {
+	m, err := platform.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Hostname == "" {
+		// nolint: errcheck
+		m.Hostname, _ = os.Hostname()
+	}
+
+	reply = m.AsProto()
+
+	if detection := platform.LastDetection(); detection != nil {
+		reply.DetectedBy = detection.Source
+	}
+
+	return reply, nil
+}
+
+// ServiceGraph renders the service dependency graph with current service states
+func (r *Registrator) ServiceGraph(ctx context.Context, in *proto.ServiceGraphRequest) (reply *proto.ServiceGraphReply, err error) {
+	graph := system.Services(r.Data).Graph()
+
+	var buf bytes.Buffer
+
+	switch in.Format {
+	case "", "text":
+		err = graph.WriteText(&buf)
+	case "dot":
+		err = graph.WriteDOT(&buf)
+	default:
+		return nil, errors.Errorf("unsupported graph format %q", in.Format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.ServiceGraphReply{Graph: buf.String()}, nil
+}
+
 // Start implements the proto.InitServer interface and starts a
 // service running on Talos.
 func (r *Registrator) Start(ctx context.Context, in *proto.StartRequest) (reply *proto.StartReply, err error) {
@@ -160,6 +315,124 @@ func (r *Registrator) Stop(ctx context.Context, in *proto.StopRequest) (reply *p
 	return reply, err
 }
 
+// ServiceRestart implements the proto.InitServer interface and restarts a
+// service running on Talos along with the services which depend on it.
+func (r *Registrator) ServiceRestart(ctx context.Context, in *proto.ServiceRestartRequest) (reply *proto.ServiceRestartReply, err error) {
+	if err = system.Services(r.Data).Restart(ctx, in.Id); err != nil {
+		return &proto.ServiceRestartReply{}, err
+	}
+
+	reply = &proto.ServiceRestartReply{Resp: fmt.Sprintf("Service %q restarted", in.Id)}
+	return reply, err
+}
+
+// ApplyUserData implements the proto.InitServer interface. The new user data
+// is compared to the running user data, the changes are applied in place or
+// by restarting the affected services. The user data is loaded from the boot
+// source on every boot, so the changes which are only picked up at boot are
+// refused: they have to be made in the boot source.
+//
+// nolint: gocyclo
+func (r *Registrator) ApplyUserData(ctx context.Context, in *proto.ApplyUserDataRequest) (reply *proto.ApplyUserDataReply, err error) {
+	r.applyMu.Lock()
+	defer r.applyMu.Unlock()
+
+	p, err := platform.NewPlatform()
+	if err != nil {
+		return nil, err
+	}
+
+	// the running user data is compared, the saved copy might predate the
+	// changes made by the installer at boot
+	current, _, err := roundTrip(r.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := userdata.DecodeStrict(in.Userdata)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = next.Validate(); err != nil {
+		return nil, err
+	}
+
+	apply.PreserveRuntimeState(p, current, next)
+
+	// both documents go through the same round trip, so that the comparison
+	// is exact
+	next, b, err := roundTrip(next)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := apply.NewPlan(current, next)
+
+	reply = &proto.ApplyUserDataReply{
+		Action: string(plan.Action()),
+	}
+
+	for _, change := range plan.Changes {
+		reply.Changes = append(reply.Changes, &proto.UserDataChange{
+			Section: change.Section,
+			Action:  string(change.Action),
+			Service: change.Service,
+			Reason:  change.Reason,
+		})
+	}
+
+	if in.DryRun || len(plan.Changes) == 0 {
+		return reply, nil
+	}
+
+	if plan.Action() == apply.ActionReboot {
+		return nil, errors.Errorf("changes to %s require a reboot, update the userdata in the boot source instead", strings.Join(plan.Sections(apply.ActionReboot), ", "))
+	}
+
+	log.Printf("applying user data via API: %s", plan.Action())
+
+	if err = ioutil.WriteFile(constants.UserDataPath, b, 0400); err != nil {
+		return nil, err
+	}
+
+	*r.Data = *next
+
+	reply.Applied = true
+
+	if err = plan.Live(p, r.Data); err != nil {
+		return nil, err
+	}
+
+	// services are restarted once the reply is sent, as the API itself might
+	// be among them
+	go func() {
+		for _, id := range plan.Services() {
+			if err := system.Services(r.Data).Restart(context.Background(), id); err != nil {
+				log.Printf("failed to restart service %q: %v", id, err)
+			}
+		}
+	}()
+
+	return reply, nil
+}
+
+// roundTrip encodes and decodes the user data, which normalizes the
+// documents which are compared.
+func roundTrip(data *userdata.UserData) (*userdata.UserData, []byte, error) {
+	b, err := yaml.Marshal(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decoded, err := userdata.DecodeWithKeyring(b, &envelope.Keyring{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return decoded, b, nil
+}
+
 // CopyOut implements the proto.InitServer interface and copies data out of Talos node
 func (r *Registrator) CopyOut(req *proto.CopyOutRequest, s proto.Init_CopyOutServer) error {
 	path := req.RootPath
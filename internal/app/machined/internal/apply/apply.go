@@ -0,0 +1,415 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package apply computes and applies the changes between the running user
+// data and the new document submitted via the API.
+package apply
+
+import (
+	"reflect"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/phase"
+	"github.com/talos-systems/talos/internal/app/machined/internal/phase/network"
+	userdatatask "github.com/talos-systems/talos/internal/app/machined/internal/phase/userdata"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/container"
+	"github.com/talos-systems/talos/internal/app/machined/internal/runtime"
+	"github.com/talos-systems/talos/internal/pkg/installer/manifest"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+// Action describes how a change is applied to the running node.
+type Action string
+
+const (
+	// ActionLive changes are applied in place without disrupting the
+	// workloads.
+	ActionLive Action = "live"
+	// ActionRestart changes are applied by restarting the service and the
+	// services which depend on it.
+	ActionRestart Action = "restart"
+	// ActionReboot changes are only picked up at boot.
+	ActionReboot Action = "reboot"
+)
+
+// severity orders the actions from the least to the most disruptive.
+var severity = map[Action]int{
+	ActionLive:    1,
+	ActionRestart: 2,
+	ActionReboot:  3,
+}
+
+// Change is a single changed section of the user data.
+type Change struct {
+	Section string
+	Action  Action
+	// Service is restarted to pick up the change, if set.
+	Service string
+	Reason  string
+
+	task phase.Task
+}
+
+type rule struct {
+	section string
+	get     func(*userdata.UserData) interface{}
+	action  Action
+	service string
+	reason  string
+	task    func() phase.Task
+}
+
+// rules cover every section of the user data, the sections which are not
+// listed are compared as a whole by their parent.
+var rules = []rule{
+	{
+		section: "files",
+		get:     func(data *userdata.UserData) interface{} { return data.Files },
+		action:  ActionLive,
+		reason:  "files are rewritten, removed files are left in place",
+		task:    userdatatask.NewExtraFilesTask,
+	},
+	{
+		section: "env",
+		get:     func(data *userdata.UserData) interface{} { return data.Env },
+		action:  ActionLive,
+		reason:  "variables are set in machined, services pick them up when restarted",
+		task:    userdatatask.NewExtraEnvVarsTask,
+	},
+	{
+		section: "networking.os.devices",
+		get: func(data *userdata.UserData) interface{} {
+			if data.Networking == nil || data.Networking.OS == nil {
+				return nil
+			}
+			return data.Networking.OS.Devices
+		},
+		action: ActionLive,
+		reason: "interfaces are reconfigured, removed addresses and routes are left in place",
+		task:   network.NewUserDefinedNetworkTask,
+	},
+	{
+		section: "networking.os.nameservers",
+		get: func(data *userdata.UserData) interface{} {
+			if data.Networking == nil || data.Networking.OS == nil {
+				return nil
+			}
+			return data.Networking.OS.Nameservers
+		},
+		action: ActionLive,
+		reason: "resolv.conf is rewritten",
+		task:   network.NewUserDefinedNetworkTask,
+	},
+	{
+		section: "networking.os.hostname",
+		get: func(data *userdata.UserData) interface{} {
+			if data.Networking == nil || data.Networking.OS == nil {
+				return ""
+			}
+			return data.Networking.OS.Hostname
+		},
+		action: ActionReboot,
+		reason: "the node name and the identity certificate are derived from the hostname",
+	},
+	{
+		section: "networking.os.domainname",
+		get: func(data *userdata.UserData) interface{} {
+			if data.Networking == nil || data.Networking.OS == nil {
+				return ""
+			}
+			return data.Networking.OS.Domainname
+		},
+		action: ActionReboot,
+		reason: "the node name and the identity certificate are derived from the hostname",
+	},
+	{
+		section: "services.ntp",
+		get:     service(func(s *userdata.Services) interface{} { return s.NTPd }),
+		action:  ActionLive,
+		service: "ntpd",
+		reason:  "ntpd is restarted to pick up the servers",
+	},
+	{
+		section: "services.kubelet",
+		get:     service(func(s *userdata.Services) interface{} { return s.Kubelet }),
+		action:  ActionRestart,
+		service: "kubelet",
+	},
+	{
+		section: "services.trustd",
+		get:     service(func(s *userdata.Services) interface{} { return s.Trustd }),
+		action:  ActionRestart,
+		service: "trustd",
+	},
+	{
+		section: "services.proxyd",
+		get:     service(func(s *userdata.Services) interface{} { return s.Proxyd }),
+		action:  ActionRestart,
+		service: "proxyd",
+	},
+	{
+		section: "services.osd",
+		get:     service(func(s *userdata.Services) interface{} { return s.OSD }),
+		action:  ActionRestart,
+		service: "osd",
+	},
+	{
+		section: "services.crt",
+		get:     service(func(s *userdata.Services) interface{} { return s.CRT }),
+		action:  ActionRestart,
+		service: "containerd",
+		reason:  "all the services running in containers are restarted as well",
+	},
+	{
+		section: "services.udevd",
+		get:     service(func(s *userdata.Services) interface{} { return s.Udevd }),
+		action:  ActionRestart,
+		service: "udevd",
+	},
+	{
+		section: "services.kubeadm",
+		get:     service(func(s *userdata.Services) interface{} { return s.Kubeadm }),
+		action:  ActionReboot,
+		reason:  "kubeadm only runs at boot",
+	},
+	{
+		section: "services.init",
+		get:     service(func(s *userdata.Services) interface{} { return s.Init }),
+		action:  ActionReboot,
+		reason:  "the CNI is set up at boot",
+	},
+	{
+		section: "security",
+		get:     func(data *userdata.UserData) interface{} { return data.Security },
+		action:  ActionReboot,
+		reason:  "certificates are loaded at boot",
+	},
+	{
+		section: "install",
+		get:     func(data *userdata.UserData) interface{} { return data.Install },
+		action:  ActionReboot,
+		reason:  "the installer and the extra devices only run at boot",
+	},
+	{
+		section: "debug",
+		get:     func(data *userdata.UserData) interface{} { return data.Debug },
+		action:  ActionReboot,
+		reason:  "the console logging is set up at boot",
+	},
+}
+
+// service returns the getter of the service section, nil sections are
+// returned as untyped nil.
+func service(f func(*userdata.Services) interface{}) func(*userdata.UserData) interface{} {
+	return func(data *userdata.UserData) interface{} {
+		if data.Services == nil {
+			return nil
+		}
+
+		v := f(data.Services)
+		if reflect.ValueOf(v).IsNil() {
+			return nil
+		}
+
+		return v
+	}
+}
+
+// Plan is the list of changes between the running and the new user data.
+type Plan struct {
+	Changes []*Change
+}
+
+// NewPlan compares the user data section by section.
+func NewPlan(current, next *userdata.UserData) *Plan {
+	plan := &Plan{}
+
+	for _, r := range rules {
+		if equal(r.get(current), r.get(next)) {
+			continue
+		}
+
+		change := &Change{
+			Section: r.section,
+			Action:  r.action,
+			Service: r.service,
+			Reason:  r.reason,
+		}
+		if r.task != nil {
+			change.task = r.task()
+		}
+
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	return plan
+}
+
+// equal treats nil and empty slices and maps as equal, the same as the YAML
+// encoding does.
+func equal(a, b interface{}) bool {
+	if empty(a) && empty(b) {
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func empty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	default:
+		return false
+	}
+}
+
+// Action returns the most disruptive action of the plan, or an empty string
+// if nothing changed.
+func (p *Plan) Action() (action Action) {
+	for _, change := range p.Changes {
+		if severity[change.Action] > severity[action] {
+			action = change.Action
+		}
+	}
+
+	return action
+}
+
+// Sections returns the changed sections which are applied with the action.
+func (p *Plan) Sections(action Action) []string {
+	sections := []string{}
+
+	for _, change := range p.Changes {
+		if change.Action == action {
+			sections = append(sections, change.Section)
+		}
+	}
+
+	return sections
+}
+
+// Services returns the services to restart, in the order of the changes.
+func (p *Plan) Services() []string {
+	seen := map[string]struct{}{}
+	services := []string{}
+
+	for _, change := range p.Changes {
+		if change.Service == "" {
+			continue
+		}
+		if _, ok := seen[change.Service]; ok {
+			continue
+		}
+
+		seen[change.Service] = struct{}{}
+		services = append(services, change.Service)
+	}
+
+	return services
+}
+
+// Live runs the tasks of the live changes against the new user data. Each
+// task runs once, even if it covers several changed sections.
+func (p *Plan) Live(platform platform.Platform, data *userdata.UserData) error {
+	var result *multierror.Error
+
+	mode := Mode(platform)
+	seen := map[reflect.Type]struct{}{}
+
+	for _, change := range p.Changes {
+		if change.Action != ActionLive || change.task == nil {
+			continue
+		}
+
+		t := reflect.TypeOf(change.task)
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+
+		f := change.task.RuntimeFunc(mode)
+		if f == nil {
+			continue
+		}
+
+		if err := f(platform, data); err != nil {
+			result = multierror.Append(result, errors.Wrapf(err, "failed to apply %s", change.Section))
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+// Mode returns the runtime mode of the platform.
+func Mode(p platform.Platform) runtime.Mode {
+	switch p.(type) {
+	case *container.Container:
+		return runtime.Container
+	default:
+		return runtime.Standard
+	}
+}
+
+// PreserveRuntimeState copies the state machined derives at boot into the
+// new user data, so that it isn't reported as a change and is kept in the
+// saved copy read by the system services.
+func PreserveRuntimeState(p platform.Platform, current, next *userdata.UserData) {
+	if current.Security != nil && current.Security.OS != nil &&
+		next.Security != nil && next.Security.OS != nil && next.Security.OS.Identity == nil {
+		next.Security.OS.Identity = current.Security.OS.Identity
+	}
+
+	if Mode(p) == runtime.Container && next.Services != nil && next.Services.Kubeadm != nil {
+		userdatatask.ContainerOverrides(next)
+	}
+
+	if current.Install != nil && next.Install != nil {
+		preserveInstallDefaults(current.Install, next.Install)
+	}
+}
+
+// preserveInstallDefaults copies the fields the installer fills in on the
+// first boot: the ephemeral device selected by the disk selector, and the
+// defaults of the boot device.
+func preserveInstallDefaults(current, next *userdata.Install) {
+	if next.Disk != nil && (next.Ephemeral == nil || next.Ephemeral.Device == "") &&
+		current.Ephemeral != nil && current.Ephemeral.Device != "" {
+		if next.Ephemeral == nil {
+			next.Ephemeral = &userdata.InstallDevice{}
+		}
+
+		next.Ephemeral.Device = current.Ephemeral.Device
+	}
+
+	if current.Boot == nil || next.Boot == nil {
+		return
+	}
+
+	if next.Boot.Device == "" && current.Ephemeral != nil && current.Boot.Device == current.Ephemeral.Device {
+		next.Boot.Device = current.Boot.Device
+	}
+
+	if next.Boot.Size == 0 && current.Boot.Size == manifest.DefaultSizeBootDevice {
+		next.Boot.Size = current.Boot.Size
+	}
+
+	if next.Boot.Kernel == "" && current.Boot.Kernel == manifest.DefaultKernelURL {
+		next.Boot.Kernel = current.Boot.Kernel
+	}
+
+	if next.Boot.Initramfs == "" && current.Boot.Initramfs == manifest.DefaultInitramfsURL {
+		next.Boot.Initramfs = current.Boot.Initramfs
+	}
+}
@@ -0,0 +1,207 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package apply
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/internal/pkg/installer/manifest"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+type ApplySuite struct {
+	suite.Suite
+}
+
+func TestApplySuite(t *testing.T) {
+	suite.Run(t, new(ApplySuite))
+}
+
+func testData() *userdata.UserData {
+	return &userdata.UserData{
+		Version: userdata.CurrentVersion,
+		Networking: &userdata.Networking{
+			OS: &userdata.OSNet{
+				Hostname: "node",
+				Devices:  []userdata.Device{{Interface: "eth0", DHCP: true}},
+			},
+		},
+		Services: &userdata.Services{
+			Kubelet: &userdata.Kubelet{},
+			Trustd:  &userdata.Trustd{Token: "foo"},
+		},
+		Files: []*userdata.File{{Path: "/etc/foo", Contents: "foo", Permissions: 0644}},
+	}
+}
+
+func (suite *ApplySuite) TestNoChanges() {
+	plan := NewPlan(testData(), testData())
+	suite.Assert().Empty(plan.Changes)
+	suite.Assert().Equal(Action(""), plan.Action())
+	suite.Assert().Empty(plan.Services())
+
+	// nil and empty maps and slices are the same after a round trip through
+	// YAML, a nil service section is not
+	next := testData()
+	next.Env = userdata.Env{}
+	next.Services.OSD = nil
+	current := testData()
+	current.Services.OSD = &userdata.OSD{}
+	suite.Assert().Len(NewPlan(current, next).Changes, 1)
+	current.Services.OSD = nil
+	suite.Assert().Empty(NewPlan(current, next).Changes)
+}
+
+func (suite *ApplySuite) TestLive() {
+	next := testData()
+	next.Files[0].Contents = "bar"
+	next.Env = userdata.Env{"http_proxy": "http://proxy"}
+	next.Networking.OS.Nameservers = []string{"1.1.1.1"}
+	next.Services.NTPd = &userdata.NTPd{Server: "time.cloudflare.com"}
+
+	plan := NewPlan(testData(), next)
+	suite.Assert().Equal([]string{"files", "env", "networking.os.nameservers", "services.ntp"}, sections(plan))
+	suite.Assert().Equal(ActionLive, plan.Action())
+	suite.Assert().Equal([]string{"ntpd"}, plan.Services())
+}
+
+func (suite *ApplySuite) TestRestart() {
+	next := testData()
+	next.Networking.OS.Devices[0].MTU = 9000
+	next.Services.Kubelet.ExtraMounts = nil
+	next.Services.Kubelet.Env = userdata.Env{"foo": "bar"}
+	next.Services.Trustd.Endpoints = []string{"10.0.0.1"}
+
+	plan := NewPlan(testData(), next)
+	suite.Assert().Equal([]string{"networking.os.devices", "services.kubelet", "services.trustd"}, sections(plan))
+	suite.Assert().Equal(ActionRestart, plan.Action())
+	suite.Assert().Equal([]string{"kubelet", "trustd"}, plan.Services())
+	suite.Assert().Equal([]string{"networking.os.devices"}, plan.Sections(ActionLive))
+}
+
+func (suite *ApplySuite) TestReboot() {
+	next := testData()
+	next.Files = nil
+	next.Networking.OS.Hostname = "other"
+	next.Install = &userdata.Install{}
+
+	plan := NewPlan(testData(), next)
+	suite.Assert().Equal([]string{"files", "networking.os.hostname", "install"}, sections(plan))
+	suite.Assert().Equal(ActionReboot, plan.Action())
+	suite.Assert().Equal([]string{"networking.os.hostname", "install"}, plan.Sections(ActionReboot))
+
+	for _, change := range plan.Changes {
+		if change.Action == ActionReboot {
+			suite.Assert().NotEmpty(change.Reason)
+		}
+	}
+}
+
+// TestRulesCoverUserData makes sure that a new section can't be added without
+// deciding how it's applied.
+// TestPreserveInstallDefaults checks that resubmitting the boot source of an
+// installed node isn't reported as a change of the install section.
+func (suite *ApplySuite) TestPreserveInstallDefaults() {
+	install := func() *userdata.Install {
+		return &userdata.Install{
+			Disk: &userdata.DiskSelector{Model: "QEMU"},
+			Boot: &userdata.BootDevice{},
+		}
+	}
+
+	current := testData()
+	current.Install = install()
+	current.Install.Ephemeral = &userdata.InstallDevice{Device: "/dev/sda"}
+	current.Install.Boot.Device = "/dev/sda"
+	current.Install.Boot.Size = manifest.DefaultSizeBootDevice
+	current.Install.Boot.Kernel = manifest.DefaultKernelURL
+	current.Install.Boot.Initramfs = manifest.DefaultInitramfsURL
+
+	next := testData()
+	next.Install = install()
+	suite.Assert().Len(NewPlan(current, next).Changes, 1)
+
+	preserveInstallDefaults(current.Install, next.Install)
+	suite.Assert().Empty(NewPlan(current, next).Changes)
+
+	// a custom kernel removed from the boot source is a change
+	current.Install.Boot.Kernel = "https://assets.example.com/vmlinuz"
+	next = testData()
+	next.Install = install()
+	preserveInstallDefaults(current.Install, next.Install)
+	suite.Assert().Len(NewPlan(current, next).Changes, 1)
+}
+
+func (suite *ApplySuite) TestRulesCoverUserData() {
+	covered := map[string]struct{}{}
+	for _, r := range rules {
+		covered[r.section] = struct{}{}
+	}
+
+	ignored := map[string]struct{}{
+		// always the current version once decoded
+		"version": {},
+		// empty
+		"networking.kubernetes": {},
+	}
+
+	for _, section := range yamlSections(reflect.TypeOf(userdata.UserData{}), "") {
+		if _, ok := ignored[section]; ok {
+			continue
+		}
+
+		if _, ok := covered[section]; ok {
+			continue
+		}
+
+		suite.Failf("section is not covered by the rules", "%q", section)
+	}
+}
+
+// yamlSections lists the YAML keys, descending into the sections which are
+// split across several rules.
+func yamlSections(t reflect.Type, prefix string) (sections []string) {
+	split := map[string]struct{}{
+		"networking":    {},
+		"networking.os": {},
+		"services":      {},
+	}
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		section := strings.TrimPrefix(prefix+"."+name, ".")
+		if _, ok := split[section]; ok {
+			sections = append(sections, yamlSections(field.Type, section)...)
+			continue
+		}
+
+		sections = append(sections, section)
+	}
+
+	return sections
+}
+
+func sections(plan *Plan) []string {
+	result := make([]string, 0, len(plan.Changes))
+	for _, change := range plan.Changes {
+		result = append(result, change.Section)
+	}
+
+	return result
+}
@@ -0,0 +1,367 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package diskmon monitors the health of the storage: the space and the
+// inodes used on the mounted file systems, the file systems the kernel shut
+// down or remounted read only on errors, and the health the disks report
+// about themselves.
+package diskmon
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/talos-systems/talos/pkg/blockdevice/discovery"
+	"github.com/talos-systems/talos/pkg/blockdevice/smart"
+)
+
+// fileSystemTypes are the types of the file systems monitored, the read only
+// images, e.g. the rootfs, are left out.
+var fileSystemTypes = map[string]bool{
+	"xfs":   true,
+	"ext4":  true,
+	"btrfs": true,
+	"vfat":  true,
+}
+
+// FileSystem represents the usage and the state of a mounted file system.
+type FileSystem struct {
+	MountPoint string
+	Device     string
+	Type       string
+	Size       uint64
+	Used       uint64
+	Available  uint64
+	Inodes     uint64
+	InodesUsed uint64
+	ReadOnly   bool
+	Error      string
+}
+
+// Usage returns the percentage of the space used, as reported by df.
+func (fs *FileSystem) Usage() float64 {
+	return percent(fs.Used, fs.Used+fs.Available)
+}
+
+// InodeUsage returns the percentage of the inodes used.
+func (fs *FileSystem) InodeUsage() float64 {
+	return percent(fs.InodesUsed, fs.Inodes)
+}
+
+// Disk represents the health reported by a disk. The health is nil if the
+// disk doesn't report it.
+type Disk struct {
+	Path   string
+	Bus    string
+	Health *smart.Health
+	Error  string
+}
+
+// Problem represents a threshold crossed or an error found. The key
+// identifies the problem across the checks, while the message might change.
+type Problem struct {
+	Key     string
+	Message string
+}
+
+// Report represents the result of a check.
+type Report struct {
+	Time        time.Time
+	FileSystems []*FileSystem
+	Disks       []*Disk
+	Problems    []*Problem
+}
+
+// Monitor checks the storage periodically, and keeps the last report.
+type Monitor struct {
+	opts *Options
+
+	mu     sync.Mutex
+	report *Report
+}
+
+var (
+	instance *Monitor
+	once     sync.Once
+)
+
+// Instance returns the monitor of the node.
+func Instance() *Monitor {
+	once.Do(func() {
+		instance = NewMonitor()
+	})
+
+	return instance
+}
+
+// NewMonitor initializes and returns a Monitor.
+func NewMonitor(setters ...Option) *Monitor {
+	return &Monitor{
+		opts: NewDefaultOptions(setters...),
+	}
+}
+
+// Run checks the storage until the context is canceled. The problems found
+// and resolved since the previous check are passed to the record func.
+func (m *Monitor) Run(ctx context.Context, logOutput io.Writer, record func(message string)) error {
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		report := m.Check()
+
+		previous := m.Report()
+
+		m.mu.Lock()
+		m.report = report
+		m.mu.Unlock()
+
+		for _, message := range changes(previous, report) {
+			fmt.Fprintln(logOutput, message)
+			record(message)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Report returns the last report, nil if the storage wasn't checked yet.
+func (m *Monitor) Report() *Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.report
+}
+
+// Health is the health check of the storage, it fails if the last report
+// has problems.
+func (m *Monitor) Health(ctx context.Context) error {
+	report := m.Report()
+	if report == nil {
+		return errors.New("storage not checked yet")
+	}
+
+	if len(report.Problems) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(report.Problems))
+	for _, p := range report.Problems {
+		messages = append(messages, p.Message)
+	}
+
+	return errors.Errorf("storage degraded: %s", strings.Join(messages, "; "))
+}
+
+// Check checks the file systems and the disks.
+func (m *Monitor) Check() *Report {
+	report := &Report{
+		Time: time.Now(),
+	}
+
+	var err error
+	if report.FileSystems, err = m.fileSystems(); err != nil {
+		report.Problems = append(report.Problems, &Problem{Key: "mounts", Message: fmt.Sprintf("failed to read the mounts: %v", err)})
+	}
+
+	var disks []*discovery.Disk
+	if disks, err = discovery.List(m.opts.DiscoveryOptions...); err != nil {
+		report.Problems = append(report.Problems, &Problem{Key: "disks", Message: fmt.Sprintf("failed to list the disks: %v", err)})
+	}
+
+	for _, d := range disks {
+		disk := &Disk{
+			Path: d.Path,
+			Bus:  d.Bus,
+		}
+
+		disk.Health, err = smart.Read(d.Path, d.Bus)
+		if err != nil && err != smart.ErrUnsupported {
+			// Some of the USB bridges and the RAID controllers don't pass
+			// the commands through, so this isn't a problem.
+			disk.Error = err.Error()
+		}
+
+		report.Disks = append(report.Disks, disk)
+	}
+
+	report.Problems = append(report.Problems, evaluate(report, m.opts)...)
+
+	return report
+}
+
+// fileSystems reads the mount table, and the usage and the state of the
+// file systems on the block devices. The bind mounts of a file system, e.g.
+// the volumes of the pods, share the device number, only the first mount
+// point of each file system is reported.
+func (m *Monitor) fileSystems() (fileSystems []*FileSystem, err error) {
+	var file *os.File
+	if file, err = os.Open(m.opts.MountsPath); err != nil {
+		return nil, err
+	}
+	// nolint: errcheck
+	defer file.Close()
+
+	seen := map[string]bool{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// The optional fields end with a separator, followed by the type,
+		// the source and the super block options.
+		fields := strings.Fields(scanner.Text())
+
+		separator := 6
+		for separator < len(fields) && fields[separator] != "-" {
+			separator++
+		}
+
+		if separator+2 >= len(fields) {
+			continue
+		}
+
+		number, mountpoint, options := fields[2], fields[4], fields[5]
+		fstype, device := fields[separator+1], fields[separator+2]
+
+		if !strings.HasPrefix(device, "/dev/") || !fileSystemTypes[fstype] || seen[number] {
+			continue
+		}
+
+		seen[number] = true
+
+		fs := &FileSystem{
+			MountPoint: mountpoint,
+			Device:     device,
+			Type:       fstype,
+		}
+
+		for _, option := range strings.Split(options, ",") {
+			if option == "ro" {
+				fs.ReadOnly = true
+			}
+		}
+
+		inspect(fs)
+
+		fileSystems = append(fileSystems, fs)
+	}
+
+	return fileSystems, scanner.Err()
+}
+
+// inspect reads the usage of the file system. XFS doesn't remount read only
+// on errors, it shuts down and fails the reads with EIO, so the root
+// directory is read to find out.
+func inspect(fs *FileSystem) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(fs.MountPoint, &stat); err != nil {
+		fs.Error = err.Error()
+		return
+	}
+
+	fs.Size = stat.Blocks * uint64(stat.Bsize)
+	fs.Used = (stat.Blocks - stat.Bfree) * uint64(stat.Bsize)
+	fs.Available = stat.Bavail * uint64(stat.Bsize)
+	fs.Inodes = stat.Files
+	fs.InodesUsed = stat.Files - stat.Ffree
+
+	dir, err := os.Open(fs.MountPoint)
+	if err != nil {
+		fs.Error = err.Error()
+		return
+	}
+	// nolint: errcheck
+	defer dir.Close()
+
+	if _, err = dir.Readdirnames(1); err != nil && err != io.EOF {
+		fs.Error = err.Error()
+	}
+}
+
+// evaluate compares the report against the thresholds.
+func evaluate(report *Report, opts *Options) (problems []*Problem) {
+	for _, fs := range report.FileSystems {
+		switch {
+		case fs.Error != "":
+			problems = append(problems, &Problem{Key: "error:" + fs.MountPoint, Message: fmt.Sprintf("%s: %s", fs.MountPoint, fs.Error)})
+			continue
+		case fs.ReadOnly:
+			problems = append(problems, &Problem{Key: "readonly:" + fs.MountPoint, Message: fmt.Sprintf("%s: mounted read only", fs.MountPoint)})
+		}
+
+		if usage := fs.Usage(); usage >= opts.UsageThreshold {
+			problems = append(problems, &Problem{Key: "usage:" + fs.MountPoint, Message: fmt.Sprintf("%s: %.0f%% of the space used", fs.MountPoint, usage)})
+		}
+
+		if usage := fs.InodeUsage(); usage >= opts.InodeThreshold {
+			problems = append(problems, &Problem{Key: "inodes:" + fs.MountPoint, Message: fmt.Sprintf("%s: %.0f%% of the inodes used", fs.MountPoint, usage)})
+		}
+	}
+
+	for _, disk := range report.Disks {
+		if disk.Health == nil {
+			continue
+		}
+
+		if !disk.Health.Passed {
+			problems = append(problems, &Problem{Key: "smart:" + disk.Path, Message: fmt.Sprintf("%s: %s", disk.Path, strings.Join(disk.Health.Warnings, ", "))})
+		}
+
+		if disk.Bus == discovery.BusNVMe && disk.Health.PercentageUsed >= opts.EnduranceThreshold {
+			problems = append(problems, &Problem{Key: "endurance:" + disk.Path, Message: fmt.Sprintf("%s: %d%% of the endurance used", disk.Path, disk.Health.PercentageUsed)})
+		}
+	}
+
+	return problems
+}
+
+// changes returns the messages describing the problems found and resolved
+// between the reports.
+func changes(previous, current *Report) (messages []string) {
+	old := map[string]*Problem{}
+	if previous != nil {
+		for _, p := range previous.Problems {
+			old[p.Key] = p
+		}
+	}
+
+	for _, p := range current.Problems {
+		if _, ok := old[p.Key]; ok {
+			delete(old, p.Key)
+			continue
+		}
+
+		messages = append(messages, "Degraded: "+p.Message)
+	}
+
+	resolved := make([]string, 0, len(old))
+	for _, p := range old {
+		resolved = append(resolved, "Resolved: "+p.Message)
+	}
+
+	sort.Strings(resolved)
+
+	return append(messages, resolved...)
+}
+
+func percent(n, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(n) * 100 / float64(total)
+}
@@ -0,0 +1,115 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diskmon
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/pkg/blockdevice/discovery"
+	"github.com/talos-systems/talos/pkg/blockdevice/smart"
+)
+
+type DiskMonSuite struct {
+	suite.Suite
+
+	dir string
+}
+
+func (suite *DiskMonSuite) SetupTest() {
+	var err error
+
+	suite.dir, err = ioutil.TempDir("", "diskmon")
+	suite.Require().NoError(err)
+}
+
+func (suite *DiskMonSuite) TearDownTest() {
+	suite.Require().NoError(os.RemoveAll(suite.dir))
+}
+
+func (suite *DiskMonSuite) TestCheck() {
+	// No disks.
+	suite.Require().NoError(os.Mkdir(filepath.Join(suite.dir, "block"), 0700))
+
+	// The bind mounts of /dev/sda3 are only reported once.
+	mounts := filepath.Join(suite.dir, "mountinfo")
+	suite.Require().NoError(ioutil.WriteFile(mounts, []byte(
+		"17 1 7:0 / / ro,relatime - squashfs /dev/loop0 ro\n"+
+			"18 17 0:4 / /proc rw,relatime shared:2 - proc proc rw\n"+
+			"25 17 8:3 / "+suite.dir+" rw,relatime shared:10 - xfs /dev/sda3 rw,attr2\n"+
+			"26 17 8:17 / "+suite.dir+"/missing ro,relatime - ext4 /dev/sdb1 ro\n"+
+			"40 25 8:3 /lib/kubelet/pods "+suite.dir+"/pods rw,relatime shared:10 master:1 - xfs /dev/sda3 rw,attr2\n"+
+			"41 40 8:3 /lib/kubelet/pods/volume "+suite.dir+"/pods/volume rw,relatime shared:10 - xfs /dev/sda3 rw,attr2\n"), 0600))
+
+	m := NewMonitor(
+		WithMountsPath(mounts),
+		WithUsageThreshold(101),
+		WithInodeThreshold(101),
+		WithDiscoveryOptions(discovery.WithSysfsRoot(suite.dir), discovery.WithDevRoot(suite.dir)),
+	)
+
+	suite.Assert().Error(m.Health(context.Background()))
+
+	report := m.Check()
+	suite.Require().Len(report.FileSystems, 2)
+	suite.Assert().Equal("/dev/sda3", report.FileSystems[0].Device)
+	suite.Assert().NotZero(report.FileSystems[0].Size)
+	suite.Assert().Empty(report.FileSystems[0].Error)
+	suite.Assert().True(report.FileSystems[1].ReadOnly)
+	suite.Assert().NotEmpty(report.FileSystems[1].Error)
+
+	suite.Require().Len(report.Problems, 1)
+	suite.Assert().Equal("error:"+suite.dir+"/missing", report.Problems[0].Key)
+}
+
+func (suite *DiskMonSuite) TestEvaluate() {
+	report := &Report{
+		FileSystems: []*FileSystem{
+			{MountPoint: "/var", Used: 95, Available: 5, Inodes: 100, InodesUsed: 10},
+			{MountPoint: "/var/lib/extra", Used: 10, Available: 90, Inodes: 100, InodesUsed: 95, ReadOnly: true},
+		},
+		Disks: []*Disk{
+			{Path: "/dev/nvme0n1", Bus: discovery.BusNVMe, Health: &smart.Health{Passed: true, PercentageUsed: 100}},
+			{Path: "/dev/sda", Bus: discovery.BusSATA, Health: &smart.Health{Warnings: []string{"failing"}}},
+			{Path: "/dev/vda", Bus: discovery.BusVirtIO},
+		},
+	}
+
+	var keys []string
+	for _, p := range evaluate(report, NewDefaultOptions()) {
+		keys = append(keys, p.Key)
+	}
+
+	suite.Assert().Equal([]string{
+		"usage:/var",
+		"readonly:/var/lib/extra",
+		"inodes:/var/lib/extra",
+		"endurance:/dev/nvme0n1",
+		"smart:/dev/sda",
+	}, keys)
+}
+
+func (suite *DiskMonSuite) TestChanges() {
+	previous := &Report{Problems: []*Problem{
+		{Key: "usage:/var", Message: "/var: 91% of the space used"},
+		{Key: "readonly:/var", Message: "/var: mounted read only"},
+	}}
+	current := &Report{Problems: []*Problem{
+		{Key: "usage:/var", Message: "/var: 92% of the space used"},
+		{Key: "smart:/dev/sda", Message: "/dev/sda: failing"},
+	}}
+
+	suite.Assert().Equal([]string{"Degraded: /dev/sda: failing", "Resolved: /var: mounted read only"}, changes(previous, current))
+	suite.Assert().Equal([]string{"Degraded: /var: 91% of the space used", "Degraded: /var: mounted read only"}, changes(nil, previous))
+}
+
+func TestDiskMonSuite(t *testing.T) {
+	suite.Run(t, new(DiskMonSuite))
+}
@@ -0,0 +1,87 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package diskmon
+
+import (
+	"time"
+
+	"github.com/talos-systems/talos/pkg/blockdevice/discovery"
+)
+
+// Options is the functional options struct.
+type Options struct {
+	Interval           time.Duration
+	UsageThreshold     float64
+	InodeThreshold     float64
+	EnduranceThreshold int
+	MountsPath         string
+	DiscoveryOptions   []discovery.Option
+}
+
+// Option is the functional option func.
+type Option func(*Options)
+
+// WithInterval sets the time between the checks.
+func WithInterval(o time.Duration) Option {
+	return func(args *Options) {
+		args.Interval = o
+	}
+}
+
+// WithUsageThreshold sets the percentage of the space of a file system used
+// over which the storage is degraded.
+func WithUsageThreshold(o float64) Option {
+	return func(args *Options) {
+		args.UsageThreshold = o
+	}
+}
+
+// WithInodeThreshold sets the percentage of the inodes of a file system used
+// over which the storage is degraded.
+func WithInodeThreshold(o float64) Option {
+	return func(args *Options) {
+		args.InodeThreshold = o
+	}
+}
+
+// WithEnduranceThreshold sets the percentage of the endurance of an NVMe
+// disk used at which the storage is degraded.
+func WithEnduranceThreshold(o int) Option {
+	return func(args *Options) {
+		args.EnduranceThreshold = o
+	}
+}
+
+// WithMountsPath sets the path of the mount table, in the format of
+// /proc/self/mountinfo.
+func WithMountsPath(o string) Option {
+	return func(args *Options) {
+		args.MountsPath = o
+	}
+}
+
+// WithDiscoveryOptions sets the options used to list the disks.
+func WithDiscoveryOptions(o ...discovery.Option) Option {
+	return func(args *Options) {
+		args.DiscoveryOptions = o
+	}
+}
+
+// NewDefaultOptions initializes a Options struct with default values.
+func NewDefaultOptions(setters ...Option) *Options {
+	opts := &Options{
+		Interval:           time.Minute,
+		UsageThreshold:     90,
+		InodeThreshold:     90,
+		EnduranceThreshold: 100,
+		MountsPath:         "/proc/self/mountinfo",
+	}
+
+	for _, setter := range setters {
+		setter(opts)
+	}
+
+	return opts
+}
@@ -5,8 +5,10 @@
 package phase
 
 import (
+	"fmt"
 	"log"
 	goruntime "runtime"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
@@ -53,48 +55,95 @@ func NewRunner(data *userdata.UserData) (*Runner, error) {
 
 // Run executes sequentially all phases known to a Runner.
 //
-// If any phase fails, Runner aborts immediately.
-func (r *Runner) Run() error {
+// If any phase fails, Runner aborts immediately. The record of the run is
+// available via LastReport.
+func (r *Runner) Run() (err error) {
+	report := &Report{
+		Start: time.Now(),
+	}
+
+	defer func() {
+		report.Duration = time.Since(report.Start)
+		if err != nil {
+			report.Error = err.Error()
+		}
+
+		setLastReport(report)
+	}()
+
 	for _, phase := range r.phases {
-		if err := r.runPhase(phase); err != nil {
-			return errors.Wrapf(err, "error running phase %q", phase.name)
+		phaseReport, phaseErr := r.runPhase(phase)
+		report.Phases = append(report.Phases, phaseReport)
+
+		if phaseErr != nil {
+			return errors.Wrapf(phaseErr, "error running phase %q", phase.name)
 		}
 	}
 
 	return nil
 }
 
+type taskOutcome struct {
+	index  int
+	report TaskReport
+	err    error
+}
+
 // runPhase runs a phase by running all phase tasks concurrently.
-func (r *Runner) runPhase(phase *Phase) error {
-	errCh := make(chan error)
+func (r *Runner) runPhase(phase *Phase) (PhaseReport, error) {
+	outcomeCh := make(chan taskOutcome)
 
 	start := time.Now()
 	log.Printf("[phase]: %s", phase.name)
 
-	for _, task := range phase.tasks {
-		go r.runTask(task, errCh)
+	for i, task := range phase.tasks {
+		go r.runTask(i, task, outcomeCh)
 	}
 
 	var result *multierror.Error
 
+	report := PhaseReport{
+		Name:  phase.name,
+		Start: start,
+		Tasks: make([]TaskReport, len(phase.tasks)),
+	}
+
 	for range phase.tasks {
-		err := <-errCh
-		if err != nil {
-			log.Printf("[phase]: %s error running task: %s", phase.name, err)
+		outcome := <-outcomeCh
+		if outcome.err != nil {
+			log.Printf("[phase]: %s error running task: %s", phase.name, outcome.err)
 		}
-		result = multierror.Append(result, err)
+		result = multierror.Append(result, outcome.err)
+		report.Tasks[outcome.index] = outcome.report
+	}
+
+	report.Duration = time.Since(start)
+	if err := result.ErrorOrNil(); err != nil {
+		report.Error = err.Error()
 	}
 
-	log.Printf("[phase]: %s done, %s", phase.name, time.Since(start))
+	log.Printf("[phase]: %s done, %s", phase.name, report.Duration)
 
-	return result.ErrorOrNil()
+	return report, result.ErrorOrNil()
 }
 
-func (r *Runner) runTask(task Task, errCh chan<- error) {
+func (r *Runner) runTask(index int, task Task, outcomeCh chan<- taskOutcome) {
 	var err error
 
+	start := time.Now()
+	report := TaskReport{
+		Name:   taskName(task),
+		Result: TaskSuccess,
+	}
+
 	defer func() {
-		errCh <- err
+		report.Duration = time.Since(start)
+		if err != nil {
+			report.Result = TaskFailure
+			report.Error = err.Error()
+		}
+
+		outcomeCh <- taskOutcome{index: index, report: report, err: err}
 	}()
 
 	defer func() {
@@ -108,12 +157,19 @@ func (r *Runner) runTask(task Task, errCh chan<- error) {
 	var f RuntimeFunc
 	if f = task.RuntimeFunc(r.mode); f == nil {
 		// A task is not defined for this runtime mode.
+		report.Result = TaskSkipped
 		return
 	}
 
 	err = f(r.platform, r.data)
 }
 
+// taskName returns the name of the task as the name of its type, e.g.
+// "rootfs.SystemDirectory".
+func taskName(task Task) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", task), "*")
+}
+
 // Add adds a phase to a Runner.
 func (r *Runner) Add(phase ...*Phase) {
 	r.phases = append(r.phases, phase...)
@@ -5,8 +5,11 @@
 package phase_test
 
 import (
+	"bytes"
 	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -88,6 +91,17 @@ func (suite *PhaseSuite) TestRunSuccess() {
 	taskErr <- nil
 
 	suite.Require().NoError(<-errCh)
+
+	report := phase.LastReport()
+	suite.Require().NotNil(report)
+	suite.Assert().False(report.Failed())
+	suite.Require().Len(report.Phases, 3)
+	suite.Assert().Equal("phase2", report.Phases[2].Name)
+	suite.Require().Len(report.Phases[2].Tasks, 2)
+	suite.Assert().Equal("phase_test.regularTask", report.Phases[2].Tasks[0].Name)
+	suite.Assert().Equal(phase.TaskSuccess, report.Phases[2].Tasks[0].Result)
+	suite.Assert().Equal("phase_test.nilTask", report.Phases[2].Tasks[1].Name)
+	suite.Assert().Equal(phase.TaskSkipped, report.Phases[2].Tasks[1].Result)
 }
 
 func (suite *PhaseSuite) TestRunFailures() {
@@ -109,6 +123,39 @@ func (suite *PhaseSuite) TestRunFailures() {
 	suite.Assert().Contains(err.Error(), "2 errors occurred")
 	suite.Assert().Contains(err.Error(), "test error")
 	suite.Assert().Contains(err.Error(), "panic recovered: in task")
+
+	report := phase.LastReport()
+	suite.Require().NotNil(report)
+	suite.Assert().True(report.Failed())
+	suite.Require().Len(report.Phases, 2)
+
+	failphase := report.Phases[1]
+	suite.Assert().Equal("failphase", failphase.Name)
+	suite.Assert().NotEmpty(failphase.Error)
+	suite.Require().Len(failphase.Tasks, 3)
+	suite.Assert().Equal(phase.TaskFailure, failphase.Tasks[0].Result)
+	suite.Assert().Contains(failphase.Tasks[0].Error, "panic recovered: in task")
+	suite.Assert().Equal(phase.TaskFailure, failphase.Tasks[1].Result)
+	suite.Assert().Equal("test error", failphase.Tasks[1].Error)
+	suite.Assert().Equal(phase.TaskSkipped, failphase.Tasks[2].Result)
+
+	var buf bytes.Buffer
+	suite.Require().NoError(report.WriteText(&buf))
+	suite.Assert().Contains(buf.String(), "phase_test.regularTask: failure")
+
+	dir, err := ioutil.TempDir("", "talos")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	path := filepath.Join(dir, "log", "boot-report.json")
+	suite.Require().NoError(report.Save(path))
+
+	saved, err := phase.LoadReport(path)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(report.Error, saved.Error)
+	suite.Assert().True(saved.Failed())
+	suite.Require().Len(saved.Phases, 2)
+	suite.Assert().Equal(failphase.Tasks[1].Error, saved.Phases[1].Tasks[1].Error)
 }
 
 func TestPhaseSuite(t *testing.T) {
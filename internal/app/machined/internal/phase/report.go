@@ -0,0 +1,178 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package phase
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/talos-systems/talos/internal/app/machined/proto"
+)
+
+// TaskResult describes the outcome of a task.
+type TaskResult string
+
+// Task results.
+const (
+	TaskSuccess TaskResult = "success"
+	TaskFailure TaskResult = "failure"
+	TaskSkipped TaskResult = "skipped"
+)
+
+// TaskReport is the record of a single task run.
+type TaskReport struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Result   TaskResult    `json:"result"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// PhaseReport is the record of a single phase run.
+type PhaseReport struct {
+	Name     string        `json:"name"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Tasks    []TaskReport  `json:"tasks"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Report is the structured record of the boot sequence.
+type Report struct {
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Phases   []PhaseReport `json:"phases"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Failed returns true if the boot sequence failed.
+func (report *Report) Failed() bool {
+	return report.Error != ""
+}
+
+// WriteText writes human-readable representation of the report.
+func (report *Report) WriteText(w io.Writer) error {
+	for _, phase := range report.Phases {
+		if _, err := fmt.Fprintf(w, "[phase]: %s (%s)\n", phase.Name, phase.Duration); err != nil {
+			return err
+		}
+
+		for _, task := range phase.Tasks {
+			line := fmt.Sprintf("  %s: %s (%s)", task.Name, task.Result, task.Duration)
+			if task.Error != "" {
+				line += ": " + strings.SplitN(task.Error, "\n", 2)[0]
+			}
+
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+
+	result := "succeeded"
+	if report.Failed() {
+		result = "failed: " + report.Error
+	}
+
+	_, err := fmt.Fprintf(w, "boot %s in %s\n", result, report.Duration)
+
+	return err
+}
+
+// AsProto returns protobuf struct with the report.
+func (report *Report) AsProto() *proto.BootReportReply {
+	// nolint: errcheck
+	start, _ := ptypes.TimestampProto(report.Start)
+
+	reply := &proto.BootReportReply{
+		Start:    start,
+		Duration: ptypes.DurationProto(report.Duration),
+		Phases:   make([]*proto.BootPhase, len(report.Phases)),
+		Error:    report.Error,
+	}
+
+	for i, phase := range report.Phases {
+		// nolint: errcheck
+		phaseStart, _ := ptypes.TimestampProto(phase.Start)
+
+		reply.Phases[i] = &proto.BootPhase{
+			Name:     phase.Name,
+			Start:    phaseStart,
+			Duration: ptypes.DurationProto(phase.Duration),
+			Tasks:    make([]*proto.BootTask, len(phase.Tasks)),
+			Error:    phase.Error,
+		}
+
+		for j, task := range phase.Tasks {
+			reply.Phases[i].Tasks[j] = &proto.BootTask{
+				Name:     task.Name,
+				Duration: ptypes.DurationProto(task.Duration),
+				Result:   string(task.Result),
+				Error:    task.Error,
+			}
+		}
+	}
+
+	return reply
+}
+
+// Save persists the report as JSON to the specified path.
+func (report *Report) Save(path string) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// LoadReport reads the report persisted with Save from the specified path.
+func LoadReport(path string) (*Report, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	if err = json.Unmarshal(b, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+var (
+	lastReport   *Report
+	lastReportMu sync.Mutex
+)
+
+// LastReport returns the report of the last boot sequence run by a Runner.
+//
+// If boot sequence hasn't been run yet, LastReport returns nil.
+func LastReport() *Report {
+	lastReportMu.Lock()
+	defer lastReportMu.Unlock()
+
+	return lastReport
+}
+
+func setLastReport(report *Report) {
+	lastReportMu.Lock()
+	defer lastReportMu.Unlock()
+
+	lastReport = report
+}
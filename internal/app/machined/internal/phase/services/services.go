@@ -29,46 +29,63 @@ func (task *Services) RuntimeFunc(mode runtime.Mode) phase.RuntimeFunc {
 }
 
 func (task *Services) runtime(data *userdata.UserData, mode runtime.Mode) (err error) {
-	task.startSystemServices(data, mode)
-	task.startKubernetesServices(data)
+	if err = task.startSystemServices(data, mode); err != nil {
+		return err
+	}
 
-	return nil
+	return task.startKubernetesServices(data)
 }
 
-func (task *Services) startSystemServices(data *userdata.UserData, mode runtime.Mode) {
+func (task *Services) startSystemServices(data *userdata.UserData, mode runtime.Mode) (err error) {
 	svcs := system.Services(data)
 	// Start the services common to all nodes.
-	svcs.Load(
+	if _, err = svcs.Load(
 		&services.MachinedAPI{},
 		&services.Networkd{},
 		&services.Containerd{},
 		&services.Udevd{},
 		&services.OSD{},
 		&services.NTPd{},
-	)
+	); err != nil {
+		return err
+	}
 
 	if mode != runtime.Container {
 		// udevd-trigger is causing stalls/unresponsive stuff when running in local mode
 		// TODO: investigate root cause, but workaround for now is to skip it in container mode
-		svcs.Load(
+		if _, err = svcs.Load(
 			&services.UdevdTrigger{},
-		)
+		); err != nil {
+			return err
+		}
+
+		// The storage of the host isn't monitored from a container.
+		if _, err = svcs.Load(
+			&services.DiskMonitor{},
+		); err != nil {
+			return err
+		}
 	}
 
 	// Start the services common to all master nodes.
 	if data.Services.Kubeadm.IsControlPlane() {
-		svcs.Load(
+		if _, err = svcs.Load(
 			&services.Trustd{},
 			&services.Proxyd{},
-		)
+		); err != nil {
+			return err
+		}
 	}
 
+	return nil
 }
 
-func (task *Services) startKubernetesServices(data *userdata.UserData) {
+func (task *Services) startKubernetesServices(data *userdata.UserData) (err error) {
 	svcs := system.Services(data)
-	svcs.Load(
+	_, err = svcs.Load(
 		&services.Kubelet{},
 		&services.Kubeadm{},
 	)
+
+	return err
 }
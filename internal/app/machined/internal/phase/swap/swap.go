@@ -0,0 +1,137 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package swap
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/phase"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform"
+	"github.com/talos-systems/talos/internal/app/machined/internal/runtime"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/swap"
+	"github.com/talos-systems/talos/pkg/blockdevice/probe"
+	"github.com/talos-systems/talos/pkg/blockdevice/zram"
+	"github.com/talos-systems/talos/pkg/constants"
+	"github.com/talos-systems/talos/pkg/sysctl"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+// zramPriority is the priority of the zram devices, so that they are used
+// before the swap area on the disk.
+const zramPriority = 100
+
+// Swap represents the Swap task.
+type Swap struct{}
+
+// NewSwapTask initializes and returns a Swap task.
+func NewSwapTask() phase.Task {
+	return &Swap{}
+}
+
+// RuntimeFunc returns the runtime function.
+func (task *Swap) RuntimeFunc(mode runtime.Mode) phase.RuntimeFunc {
+	switch mode {
+	case runtime.Standard:
+		return task.runtime
+	default:
+		return nil
+	}
+}
+
+func (task *Swap) runtime(platform platform.Platform, data *userdata.UserData) (err error) {
+	if data.Install == nil || !data.Install.Swap.Enabled() {
+		return nil
+	}
+
+	s := data.Install.Swap
+
+	for _, z := range s.Zram {
+		var path string
+		if path, err = zram.Create(uint64(z.Size), z.Algorithm); err != nil {
+			return err
+		}
+
+		if err = activate(path, swap.WithPriority(zramPriority)); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case s.Partition():
+		var dev *probe.ProbedBlockDevice
+		if dev, err = probe.GetDevWithFileSystemLabel(constants.SwapPartitionLabel); err != nil {
+			// The swap partition is only created on install.
+			log.Printf("WARNING: no partition labeled %s was found, the node has to be reinstalled to use it: %v", constants.SwapPartitionLabel, err)
+			break
+		}
+
+		if err = swap.Swapon(dev.Path); err != nil {
+			return err
+		}
+
+		log.Printf("activated swap partition %s", dev.Path)
+	case s.File():
+		if err = file(constants.SwapFile, int64(s.Size)); err != nil {
+			return errors.Wrap(err, "failed to create the swap file")
+		}
+
+		if err = activate(constants.SwapFile); err != nil {
+			return err
+		}
+	}
+
+	if s.Swappiness != nil {
+		if err = sysctl.WriteSystemProperty(&sysctl.SystemProperty{Key: "vm.swappiness", Value: strconv.Itoa(*s.Swappiness)}); err != nil {
+			return errors.Wrap(err, "failed to set swappiness")
+		}
+	}
+
+	return nil
+}
+
+// activate writes the swap header, and activates the swap area.
+func activate(path string, setters ...swap.Option) (err error) {
+	if err = swap.MakeSwap(path); err != nil {
+		return errors.Wrapf(err, "failed to make swap on %s", path)
+	}
+
+	if err = swap.Swapon(path, setters...); err != nil {
+		return err
+	}
+
+	log.Printf("activated swap on %s", path)
+
+	return nil
+}
+
+// file creates the swap file, or recreates it if the size changed. The swap
+// file must not have holes, so the blocks are allocated up front.
+func file(path string, size int64) (err error) {
+	if info, statErr := os.Stat(path); statErr == nil && info.Size() == size {
+		return nil
+	}
+
+	if err = os.RemoveAll(path); err != nil {
+		return err
+	}
+
+	var f *os.File
+	if f, err = os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600); err != nil {
+		return err
+	}
+	// nolint: errcheck
+	defer f.Close()
+
+	if err = unix.Fallocate(int(f.Fd()), 0, 0, size); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
@@ -5,13 +5,17 @@
 package userdata
 
 import (
-	"fmt"
+	"log"
 
 	"github.com/talos-systems/talos/internal/app/machined/internal/phase"
 	"github.com/talos-systems/talos/internal/app/machined/internal/platform"
 	"github.com/talos-systems/talos/internal/app/machined/internal/runtime"
+	"github.com/talos-systems/talos/internal/pkg/encryption"
 	"github.com/talos-systems/talos/internal/pkg/mount"
 	"github.com/talos-systems/talos/internal/pkg/mount/manager"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/luks"
+	"github.com/talos-systems/talos/pkg/blockdevice/probe"
+	"github.com/talos-systems/talos/pkg/blockdevice/util"
 	"github.com/talos-systems/talos/pkg/userdata"
 	"golang.org/x/sys/unix"
 )
@@ -35,10 +39,14 @@ func (task *ExtraDevices) runtime(platform platform.Platform, data *userdata.Use
 	}
 
 	mountpoints := mount.NewMountPoints()
-	for _, extra := range data.Install.ExtraDevices {
-		for i, part := range extra.Partitions {
-			devname := fmt.Sprintf("%s%d", extra.Device, i+1)
-			mountpoints.Set(devname, mount.NewMountPoint(devname, part.MountPoint, "xfs", unix.MS_NOATIME, ""))
+	for i, extra := range data.Install.ExtraDevices {
+		for j, part := range extra.Partitions {
+			label := data.Install.ExtraPartitionLabel(i, j)
+			var source, fstype string
+			if source, fstype, err = extraPartition(extra.Device, label, j+1, part); err != nil {
+				return err
+			}
+			mountpoints.Set(label, mount.NewMountPoint(source, part.MountPoint, fstype, unix.MS_NOATIME, "", mount.WithEncryption(part.Encryption, encryption.UserDataOptions(data)...)))
 		}
 	}
 
@@ -49,3 +57,25 @@ func (task *ExtraDevices) runtime(platform platform.Platform, data *userdata.Use
 
 	return nil
 }
+
+// extraPartition finds the partition by the file system label, so that the
+// order of the partitions on the device doesn't matter. The partitions created
+// without the labels are found by the position in the userdata.
+func extraPartition(device, label string, position int, part *userdata.ExtraDevicePartition) (source, fstype string, err error) {
+	dev, probeErr := probe.DevForFileSystemLabel(device, label)
+	if probeErr == nil {
+		return dev.Path, dev.SuperBlock.Type(), nil
+	}
+
+	if source, err = util.PartPath(device, position); err != nil {
+		return "", "", err
+	}
+
+	log.Printf("WARNING: no partition labeled %s was found on %s, using %s: %v", label, device, source, probeErr)
+
+	if part.Encryption != nil {
+		return source, luks.Type, nil
+	}
+
+	return source, part.FileSystemType(), nil
+}
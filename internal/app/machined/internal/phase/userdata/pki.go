@@ -53,9 +53,27 @@ func (task *PKI) runtime(platform platform.Platform, data *userdata.UserData) (e
 	if err != nil {
 		return errors.Wrap(err, "failed to create trustd client")
 	}
-	if err = generator.Identity(data); err != nil {
+	if err = generator.Identity(data, identityOptions(platform)...); err != nil {
 		return errors.Wrap(err, "failed to generate identity")
 	}
 
 	return nil
 }
+
+// identityOptions attaches the platform proof of the machine identity to the
+// certificate request, if the platform provides one.
+func identityOptions(p platform.Platform) []gen.IdentityOption {
+	provider, ok := p.(platform.IdentityProvider)
+	if !ok {
+		return nil
+	}
+
+	signature, err := provider.InstanceIdentity()
+	if err != nil {
+		log.Printf("failed to fetch %s instance identity, requesting certificate without it: %v", p.Name(), err)
+
+		return nil
+	}
+
+	return []gen.IdentityOption{gen.WithInstanceIdentity(signature)}
+}
@@ -7,10 +7,10 @@ package userdata
 import (
 	"io/ioutil"
 	"log"
-	"os"
 
 	"github.com/talos-systems/talos/internal/app/machined/internal/phase"
 	"github.com/talos-systems/talos/internal/app/machined/internal/platform"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/baremetal"
 	"github.com/talos-systems/talos/internal/app/machined/internal/runtime"
 	"github.com/talos-systems/talos/pkg/constants"
 	"github.com/talos-systems/talos/pkg/userdata"
@@ -31,23 +31,24 @@ func (task *SaveUserData) RuntimeFunc(mode runtime.Mode) phase.RuntimeFunc {
 }
 
 func (task *SaveUserData) runtime(platform platform.Platform, data *userdata.UserData) (err error) {
-	if _, err = os.Stat(constants.UserDataPath); os.IsNotExist(err) {
-		log.Println("saving userdata to disk")
-
-		var dataBytes []byte
-		dataBytes, err = yaml.Marshal(data)
-		if err != nil {
-			return err
-		}
+	var dataBytes []byte
+	dataBytes, err = yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
 
-		if err = ioutil.WriteFile(constants.UserDataPath, dataBytes, 0400); err != nil {
+	// the cache is refreshed at every boot, so that it is current when the
+	// user data sources are unreachable at the next one
+	if _, ok := platform.(*baremetal.BareMetal); ok {
+		if err = ioutil.WriteFile(constants.UserDataCachePath, dataBytes, 0400); err != nil {
 			return err
 		}
-
-		return nil
 	}
 
-	log.Println("refusing to overwrite userdata on disk")
+	// the saved copy is read by the system services, it is replaced on every
+	// boot so that they run with the same user data as machined, the changes
+	// applied via the API last until the next boot
+	log.Println("saving userdata to disk")
 
-	return nil
+	return ioutil.WriteFile(constants.UserDataPath, dataBytes, 0400)
 }
@@ -58,6 +58,15 @@ func (task *UserData) container(platform platform.Platform, data *userdata.UserD
 	}
 	*data = *d
 
+	ContainerOverrides(data)
+
+	return nil
+}
+
+// ContainerOverrides adjusts the user data for running in a container: the
+// kubeadm preflight checks and the kubelet and kube-proxy settings which
+// don't work inside a container are relaxed.
+func ContainerOverrides(data *userdata.UserData) {
 	data.Services.Kubeadm.IgnorePreflightErrors = []string{"FileContent--proc-sys-net-bridge-bridge-nf-call-iptables", "Swap", "SystemVerification"}
 	initConfiguration, ok := data.Services.Kubeadm.Configuration.(*kubeadmapi.InitConfiguration)
 	if ok {
@@ -66,6 +75,4 @@ func (task *UserData) container(platform platform.Platform, data *userdata.UserD
 		maxPerCore := int32(0)
 		initConfiguration.ClusterConfiguration.ComponentConfigs.KubeProxy.Conntrack.MaxPerCore = &maxPerCore
 	}
-
-	return nil
 }
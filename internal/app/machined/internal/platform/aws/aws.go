@@ -5,14 +5,13 @@
 package aws
 
 import (
-	"crypto/x509"
-	"encoding/pem"
-	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
+	"time"
 
-	"github.com/fullsailor/pkcs7"
+	"github.com/pkg/errors"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
+	"github.com/talos-systems/talos/internal/pkg/ec2"
 	"github.com/talos-systems/talos/internal/pkg/mount"
 	"github.com/talos-systems/talos/internal/pkg/mount/manager"
 	"github.com/talos-systems/talos/internal/pkg/mount/manager/owned"
@@ -26,26 +25,14 @@ const (
 	AWSPKCS7Endpoint = "http://169.254.169.254/latest/dynamic/instance-identity/pkcs7"
 	// AWSHostnameEndpoint is the local EC2 endpoint for the hostname.
 	AWSHostnameEndpoint = "http://169.254.169.254/latest/meta-data/hostname"
-	// AWSPublicCertificate is the AWS public certificate for the regions
-	// provided by an AWS account.
-	AWSPublicCertificate = `-----BEGIN CERTIFICATE-----
-MIIC7TCCAq0CCQCWukjZ5V4aZzAJBgcqhkjOOAQDMFwxCzAJBgNVBAYTAlVTMRkw
-FwYDVQQIExBXYXNoaW5ndG9uIFN0YXRlMRAwDgYDVQQHEwdTZWF0dGxlMSAwHgYD
-VQQKExdBbWF6b24gV2ViIFNlcnZpY2VzIExMQzAeFw0xMjAxMDUxMjU2MTJaFw0z
-ODAxMDUxMjU2MTJaMFwxCzAJBgNVBAYTAlVTMRkwFwYDVQQIExBXYXNoaW5ndG9u
-IFN0YXRlMRAwDgYDVQQHEwdTZWF0dGxlMSAwHgYDVQQKExdBbWF6b24gV2ViIFNl
-cnZpY2VzIExMQzCCAbcwggEsBgcqhkjOOAQBMIIBHwKBgQCjkvcS2bb1VQ4yt/5e
-ih5OO6kK/n1Lzllr7D8ZwtQP8fOEpp5E2ng+D6Ud1Z1gYipr58Kj3nssSNpI6bX3
-VyIQzK7wLclnd/YozqNNmgIyZecN7EglK9ITHJLP+x8FtUpt3QbyYXJdmVMegN6P
-hviYt5JH/nYl4hh3Pa1HJdskgQIVALVJ3ER11+Ko4tP6nwvHwh6+ERYRAoGBAI1j
-k+tkqMVHuAFcvAGKocTgsjJem6/5qomzJuKDmbJNu9Qxw3rAotXau8Qe+MBcJl/U
-hhy1KHVpCGl9fueQ2s6IL0CaO/buycU1CiYQk40KNHCcHfNiZbdlx1E9rpUp7bnF
-lRa2v1ntMX3caRVDdbtPEWmdxSCYsYFDk4mZrOLBA4GEAAKBgEbmeve5f8LIE/Gf
-MNmP9CM5eovQOGx5ho8WqD+aTebs+k2tn92BBPqeZqpWRa5P/+jrdKml1qx4llHW
-MXrs3IgIb6+hUIB+S8dz8/mmO0bpr76RoZVCXYab2CZedFut7qc3WUH9+EUAH5mw
-vSeDCOUMYQR7R9LINYwouHIziqQYMAkGByqGSM44BAMDLwAwLAIUWXBlk40xTwSw
-7HX32MxXYruse9ACFBNGmdX2ZBrVNGrN9N2f6ROk0k9K
------END CERTIFICATE-----`
+	// AWSIdentityDocumentEndpoint is the local EC2 endpoint for the instance
+	// identity document.
+	AWSIdentityDocumentEndpoint = "http://169.254.169.254/latest/dynamic/instance-identity/document"
+	// AWSPublicIPv4Endpoint is the local EC2 endpoint for the public IPv4
+	// address.
+	AWSPublicIPv4Endpoint = "http://169.254.169.254/latest/meta-data/public-ipv4"
+	// AWSTokenEndpoint is the local EC2 endpoint for the IMDSv2 session token.
+	AWSTokenEndpoint = "http://169.254.169.254/latest/api/token"
 )
 
 // AWS is the concrete type that implements the platform.Platform interface.
@@ -55,56 +42,14 @@ type AWS struct{}
 // against the appropriate AWS public certificate. See
 // https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/instance-identity-documents.html
 func IsEC2() (b bool) {
-	resp, err := http.Get(AWSPKCS7Endpoint)
-	if err != nil {
-		return
-	}
-	// nolint: errcheck
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("failed to download PKCS7 signature: %d\n", resp.StatusCode)
-		return
-	}
-
-	data, err := ioutil.ReadAll(resp.Body)
+	signature, err := newIMDSClient(metadata.WithRetries(1)).Fetch(AWSPKCS7Endpoint)
 	if err != nil {
-		fmt.Println(err)
+		log.Printf("failed to download PKCS7 signature: %v", err)
 		return
 	}
 
-	data = append([]byte("-----BEGIN PKCS7-----\n"), data...)
-	data = append(data, []byte("\n-----END PKCS7-----\n")...)
-
-	pemBlock, _ := pem.Decode(data)
-	if pemBlock == nil {
-		log.Println("failed to decode PEM block")
-		return
-	}
-
-	p7, err := pkcs7.Parse(pemBlock.Bytes)
-	if err != nil {
-		log.Printf("failed to parse PKCS7 signature: %v\n", err)
-		return
-	}
-
-	pemBlock, _ = pem.Decode([]byte(AWSPublicCertificate))
-	if pemBlock == nil {
-		log.Println("failed to decode PEM block")
-		return
-	}
-
-	certificate, err := x509.ParseCertificate(pemBlock.Bytes)
-	if err != nil {
-		log.Printf("failed to parse X509 certificate: %v\n", err)
-		return
-	}
-
-	p7.Certificates = []*x509.Certificate{certificate}
-
-	err = p7.Verify()
-	if err != nil {
-		log.Printf("failed to verify PKCS7 signature: %v", err)
+	if _, err = ec2.VerifyIdentity(signature); err != nil {
+		log.Println(err)
 		return
 	}
 
@@ -119,15 +64,24 @@ func (a *AWS) Name() string {
 }
 
 // UserData implements the platform.Platform interface.
-func (a *AWS) UserData() (*userdata.UserData, error) {
-	return userdata.Download(AWSUserDataEndpoint)
+func (a *AWS) UserData() (data *userdata.UserData, err error) {
+	var b []byte
+	if b, err = newIMDSClient(metadata.WithRetries(10), metadata.WithMaxWait(64*time.Second)).Fetch(AWSUserDataEndpoint); err != nil {
+		return nil, errors.Wrap(err, "failed to download user data")
+	}
+
+	if data, err = userdata.Decode(b); err != nil {
+		return nil, err
+	}
+
+	return data, data.Validate()
 }
 
 // Initialize implements the platform.Platform interface and handles additional system setup.
 // nolint: dupl
 func (a *AWS) Initialize(data *userdata.UserData) (err error) {
 	var mountpoints *mount.Points
-	mountpoints, err = owned.MountPointsFromLabels()
+	mountpoints, err = owned.MountPointsFromLabels(data)
 	if err != nil {
 		return err
 	}
@@ -138,7 +92,7 @@ func (a *AWS) Initialize(data *userdata.UserData) (err error) {
 		return err
 	}
 
-	hostnameBytes, err := hostname()
+	hostnameBytes, err := newIMDSClient().Fetch(AWSHostnameEndpoint)
 	if err != nil {
 		return err
 	}
@@ -156,17 +110,59 @@ func (a *AWS) Initialize(data *userdata.UserData) (err error) {
 	return err
 }
 
-func hostname() (hostname []byte, err error) {
-	resp, err := http.Get(AWSHostnameEndpoint)
+// Metadata implements the platform.Platform interface.
+func (a *AWS) Metadata() (*metadata.Metadata, error) {
+	client := newIMDSClient()
+
+	var doc ec2.IdentityDocument
+	if err := client.FetchJSON(AWSIdentityDocumentEndpoint, &doc); err != nil {
+		return nil, err
+	}
+
+	m := &metadata.Metadata{
+		Platform:     a.Name(),
+		InstanceID:   doc.InstanceID,
+		InstanceType: doc.InstanceType,
+		Region:       doc.Region,
+		Zone:         doc.AvailabilityZone,
+	}
+
+	if doc.PrivateIP != "" {
+		m.PrivateIPs = []string{doc.PrivateIP}
+	}
+
+	// public IPv4 is not available for the instances in the private subnets
+	publicIP, err := client.Fetch(AWSPublicIPv4Endpoint)
+	switch {
+	case err == nil:
+		m.PublicIPs = []string{string(publicIP)}
+	case errors.Cause(err) != metadata.ErrNotFound:
+		return nil, err
+	}
+
+	hostname, err := client.Fetch(AWSHostnameEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Hostname = string(hostname)
+
+	return m, nil
+}
+
+// InstanceIdentity implements the platform.IdentityProvider interface.
+//
+// The PKCS7 signature of the instance identity document is verified before
+// it is handed out.
+func (a *AWS) InstanceIdentity() ([]byte, error) {
+	signature, err := newIMDSClient().Fetch(AWSPKCS7Endpoint)
 	if err != nil {
-		return
+		return nil, errors.Wrap(err, "failed to download PKCS7 signature")
 	}
-	// nolint: errcheck
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return hostname, fmt.Errorf("failed to fetch hostname from metadata service: %d", resp.StatusCode)
+	if _, err = ec2.VerifyIdentity(signature); err != nil {
+		return nil, err
 	}
 
-	return ioutil.ReadAll(resp.Body)
+	return signature, nil
 }
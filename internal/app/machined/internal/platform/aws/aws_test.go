@@ -4,11 +4,102 @@
 
 package aws_test
 
-import "testing"
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
 
-func TestEmpty(t *testing.T) {
-	// added for accurate coverage estimation
-	//
-	// please remove it once any unit-test is added
-	// for this package
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/aws"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
+)
+
+type IMDSSuite struct {
+	suite.Suite
+}
+
+// imds emulates the instance metadata service, with IMDSv1 optionally
+// disabled.
+func imds(v1 bool) *httptest.Server {
+	ts, _ := imdsCounter(v1)
+
+	return ts
+}
+
+// imdsCounter emulates the instance metadata service and counts the token
+// requests.
+func imdsCounter(v1 bool) (*httptest.Server, *int32) {
+	var tokenRequests int32
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/latest/api/token" && r.Method == "PUT":
+			atomic.AddInt32(&tokenRequests, 1)
+
+			if r.Header.Get(aws.AWSTokenTTLHeader) != "21600" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			fmt.Fprint(w, "token")
+		case r.URL.Path == "/latest/meta-data/hostname":
+			if r.Header.Get(aws.AWSTokenHeader) != "token" && !v1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			fmt.Fprint(w, "ip-10-0-0-10.ec2.internal")
+		default:
+			http.NotFound(w, r)
+		}
+	})), &tokenRequests
+}
+
+func (suite *IMDSSuite) TestV2() {
+	ts := imds(false)
+	defer ts.Close()
+
+	b, err := aws.NewIMDSClient(ts.URL + "/latest/api/token").Fetch(ts.URL + "/latest/meta-data/hostname")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("ip-10-0-0-10.ec2.internal", string(b))
+}
+
+func (suite *IMDSSuite) TestTokenCached() {
+	ts, tokenRequests := imdsCounter(false)
+	defer ts.Close()
+
+	client := aws.NewIMDSClient(ts.URL + "/latest/api/token")
+
+	for i := 0; i < 3; i++ {
+		b, err := client.Fetch(ts.URL + "/latest/meta-data/hostname")
+		suite.Require().NoError(err)
+		suite.Assert().Equal("ip-10-0-0-10.ec2.internal", string(b))
+	}
+
+	suite.Assert().EqualValues(1, atomic.LoadInt32(tokenRequests))
+}
+
+func (suite *IMDSSuite) TestFallbackV1() {
+	ts := imds(true)
+	defer ts.Close()
+
+	b, err := aws.NewIMDSClient(ts.URL + "/no-token").Fetch(ts.URL + "/latest/meta-data/hostname")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("ip-10-0-0-10.ec2.internal", string(b))
+}
+
+func (suite *IMDSSuite) TestV2Required() {
+	ts := imds(false)
+	defer ts.Close()
+
+	_, err := aws.NewIMDSClient(ts.URL+"/no-token", metadata.WithRetries(1)).Fetch(ts.URL + "/latest/meta-data/hostname")
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), "401")
+}
+
+func TestIMDSSuite(t *testing.T) {
+	suite.Run(t, new(IMDSSuite))
 }
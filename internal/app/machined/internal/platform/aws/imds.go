@@ -0,0 +1,157 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
+)
+
+const (
+	// AWSTokenHeader is the HTTP header which carries the IMDSv2 session
+	// token.
+	AWSTokenHeader = "X-aws-ec2-metadata-token"
+	// AWSTokenTTLHeader is the HTTP header which specifies the lifetime of the
+	// requested IMDSv2 session token in seconds.
+	AWSTokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+
+	tokenTTL      = 6 * time.Hour
+	tokenTimeout  = 2 * time.Second
+	tokenAttempts = 3
+	// the token is renewed ahead of the expiration, so that it doesn't expire
+	// in flight
+	tokenRenewal = time.Minute
+	// fallbackTTL is how long the IMDSv1 fallback is used before the token is
+	// requested again
+	fallbackTTL = time.Minute
+)
+
+// tokenSource requests the IMDSv2 session token and caches it until it
+// expires.
+type tokenSource struct {
+	endpoint string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// tokens is shared by the clients of the local instance metadata service.
+var tokens = &tokenSource{endpoint: AWSTokenEndpoint}
+
+// headers implements the metadata.WithHeaderFunc function.
+func (s *tokenSource) headers(ctx context.Context) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Now().After(s.expires) {
+		token, err := fetchToken(ctx, s.endpoint)
+
+		switch {
+		case err == nil:
+			s.token = token
+			s.expires = time.Now().Add(tokenTTL - tokenRenewal)
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		default:
+			log.Printf("IMDSv2 is not available, falling back to IMDSv1: %v", err)
+
+			s.token = ""
+			s.expires = time.Now().Add(fallbackTTL)
+		}
+	}
+
+	if s.token == "" {
+		return nil, nil
+	}
+
+	return map[string]string{AWSTokenHeader: s.token}, nil
+}
+
+// NewIMDSClient returns the instance metadata service client which
+// authenticates with the IMDSv2 session token requested from tokenEndpoint.
+//
+// The token is requested on the first use and cached until it expires. If
+// the token is not available, the client falls back to IMDSv1. The token
+// request uses a short timeout, as the response is silently dropped if the
+// instance metadata hop limit is exceeded.
+func NewIMDSClient(tokenEndpoint string, opts ...metadata.ClientOption) *metadata.Client {
+	return newClient(&tokenSource{endpoint: tokenEndpoint}, opts...)
+}
+
+// Probe reports whether the instance metadata service serves the instance
+// identity document. The probe gives up once the context is canceled.
+func Probe(ctx context.Context) bool {
+	_, err := newIMDSClient(metadata.WithRetries(1)).FetchContext(ctx, AWSIdentityDocumentEndpoint)
+
+	return err == nil
+}
+
+// newIMDSClient returns the client of the local instance metadata service
+// which shares the session token with the other clients.
+func newIMDSClient(opts ...metadata.ClientOption) *metadata.Client {
+	return newClient(tokens, opts...)
+}
+
+func newClient(source *tokenSource, opts ...metadata.ClientOption) *metadata.Client {
+	return metadata.NewClient(append([]metadata.ClientOption{metadata.WithHeaderFunc(source.headers)}, opts...)...)
+}
+
+func fetchToken(ctx context.Context, endpoint string) (token string, err error) {
+	client := &http.Client{Timeout: tokenTimeout}
+
+	for attempt := 0; attempt < tokenAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(time.Second):
+			}
+		}
+
+		var (
+			req  *http.Request
+			resp *http.Response
+		)
+
+		if req, err = http.NewRequest("PUT", endpoint, nil); err != nil {
+			return "", err
+		}
+
+		req.Header.Set(AWSTokenTTLHeader, strconv.Itoa(int(tokenTTL.Seconds())))
+
+		if resp, err = client.Do(req.WithContext(ctx)); err != nil {
+			err = fmt.Errorf("failed to request session token, check the metadata hop limit: %v", err)
+			continue
+		}
+
+		var b []byte
+		b, err = ioutil.ReadAll(resp.Body)
+		// nolint: errcheck
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			// IMDSv1 only endpoints respond with 403 or 405
+			return "", fmt.Errorf("failed to request session token: %d", resp.StatusCode)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		return strings.TrimSpace(string(b)), nil
+	}
+
+	return "", err
+}
@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"net/http"
 
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
 	"github.com/talos-systems/talos/internal/pkg/mount"
 	"github.com/talos-systems/talos/internal/pkg/mount/manager"
 	"github.com/talos-systems/talos/internal/pkg/mount/manager/owned"
@@ -22,6 +23,8 @@ const (
 	AzureUserDataEndpoint = "http://169.254.169.254/metadata/instance/compute/customData?api-version=2019-06-01&format=text"
 	// AzureHostnameEndpoint is the local endpoint for the hostname.
 	AzureHostnameEndpoint = "http://169.254.169.254/metadata/instance/compute/name?api-version=2019-06-01&format=text"
+	// AzureMetadataEndpoint is the local endpoint for the instance metadata.
+	AzureMetadataEndpoint = "http://169.254.169.254/metadata/instance?api-version=2019-06-01"
 	// AzureInternalEndpoint is the Azure Internal Channel IP
 	// https://blogs.msdn.microsoft.com/mast/2015/05/18/what-is-the-ip-address-168-63-129-16/
 	AzureInternalEndpoint = "http://168.63.129.16"
@@ -48,7 +51,7 @@ func (a *Azure) UserData() (*userdata.UserData, error) {
 // nolint: dupl
 func (a *Azure) Initialize(data *userdata.UserData) (err error) {
 	var mountpoints *mount.Points
-	mountpoints, err = owned.MountPointsFromLabels()
+	mountpoints, err = owned.MountPointsFromLabels(data)
 	if err != nil {
 		return err
 	}
@@ -76,6 +79,63 @@ func (a *Azure) Initialize(data *userdata.UserData) (err error) {
 	return err
 }
 
+// InstanceMetadata is the subset of the Azure instance metadata used by Talos.
+type InstanceMetadata struct {
+	Compute struct {
+		Name     string `json:"name"`
+		VMID     string `json:"vmId"`
+		VMSize   string `json:"vmSize"`
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+	} `json:"compute"`
+	Network struct {
+		Interface []struct {
+			IPv4 struct {
+				IPAddress []struct {
+					PrivateIPAddress string `json:"privateIpAddress"`
+					PublicIPAddress  string `json:"publicIpAddress"`
+				} `json:"ipAddress"`
+			} `json:"ipv4"`
+		} `json:"interface"`
+	} `json:"network"`
+}
+
+// Metadata implements the platform.Platform interface.
+func (a *Azure) Metadata() (*metadata.Metadata, error) {
+	var instance InstanceMetadata
+	if err := metadata.FetchJSON(AzureMetadataEndpoint, map[string]string{"Metadata": "true"}, &instance); err != nil {
+		return nil, err
+	}
+
+	m := &metadata.Metadata{
+		Platform:     a.Name(),
+		Hostname:     instance.Compute.Name,
+		InstanceID:   instance.Compute.VMID,
+		InstanceType: instance.Compute.VMSize,
+		Region:       instance.Compute.Location,
+	}
+
+	// Azure zones are numbered within the location, e.g. "1", so the zone is
+	// qualified with the location the same way the Azure cloud provider does
+	if instance.Compute.Zone != "" {
+		m.Zone = instance.Compute.Location + "-" + instance.Compute.Zone
+	}
+
+	for _, iface := range instance.Network.Interface {
+		for _, address := range iface.IPv4.IPAddress {
+			if address.PrivateIPAddress != "" {
+				m.PrivateIPs = append(m.PrivateIPs, address.PrivateIPAddress)
+			}
+
+			if address.PublicIPAddress != "" {
+				m.PublicIPs = append(m.PublicIPs, address.PublicIPAddress)
+			}
+		}
+	}
+
+	return m, nil
+}
+
 func hostname() (hostname []byte, err error) {
 	var req *http.Request
 	var resp *http.Response
@@ -5,24 +5,19 @@
 package baremetal
 
 import (
-	"io/ioutil"
-	"os"
-	"path"
 	"path/filepath"
 
 	"github.com/pkg/errors"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/nocloud"
 	"github.com/talos-systems/talos/internal/pkg/installer"
 	"github.com/talos-systems/talos/internal/pkg/kernel"
 	"github.com/talos-systems/talos/internal/pkg/mount"
 	"github.com/talos-systems/talos/internal/pkg/mount/manager"
 	"github.com/talos-systems/talos/internal/pkg/mount/manager/owned"
-	"github.com/talos-systems/talos/pkg/blockdevice/probe"
+	"github.com/talos-systems/talos/internal/pkg/network"
 	"github.com/talos-systems/talos/pkg/constants"
 	"github.com/talos-systems/talos/pkg/userdata"
-
-	"golang.org/x/sys/unix"
-
-	yaml "gopkg.in/yaml.v2"
 )
 
 const (
@@ -38,56 +33,104 @@ func (b *BareMetal) Name() string {
 }
 
 // UserData implements the platform.Platform interface.
+//
+// User data is read from the NoCloud seed if ds=nocloud(-net) kernel
+// parameter is specified, otherwise it is loaded from the sources listed in
+// talos.userdata, see ParseLayers.
 func (b *BareMetal) UserData() (data *userdata.UserData, err error) {
-	var option *string
-	if option = kernel.ProcCmdline().Get(constants.KernelParamUserData).First(); option == nil {
-		return data, errors.Errorf("no user data option was found")
+	var dataSource *nocloud.DataSource
+	if ds := kernel.ProcCmdline().Get(nocloud.KernelParamDataSource).First(); ds != nil {
+		if dataSource, err = nocloud.ParseDataSource(*ds); err != nil {
+			return data, err
+		}
 	}
 
-	if *option == constants.UserDataCIData {
-		var dev *probe.ProbedBlockDevice
-		dev, err = probe.GetDevWithFileSystemLabel(constants.UserDataCIData)
-		if err != nil {
-			return data, errors.Errorf("failed to find %s iso: %v", constants.UserDataCIData, err)
-		}
-		if err = os.Mkdir(mnt, 0700); err != nil {
-			return data, errors.Errorf("failed to mkdir: %v", err)
-		}
-		if err = unix.Mount(dev.Path, mnt, dev.SuperBlock.Type(), unix.MS_RDONLY, ""); err != nil {
-			return data, errors.Errorf("failed to mount iso: %v", err)
-		}
-		var dataBytes []byte
-		dataBytes, err = ioutil.ReadFile(path.Join(mnt, "user-data"))
-		if err != nil {
-			return data, errors.Errorf("read user data: %s", err.Error())
-		}
-		if err = unix.Unmount(mnt, 0); err != nil {
-			return data, errors.Errorf("failed to unmount: %v", err)
-		}
-		if err = yaml.Unmarshal(dataBytes, &data); err != nil {
-			return data, errors.Errorf("unmarshal user data: %s", err.Error())
+	switch {
+	case dataSource != nil && dataSource.SeedFrom != "":
+		data, err = nocloud.Load(&nocloud.Seed{URL: dataSource.SeedFrom}, network.InterfaceByMAC)
+	case dataSource != nil:
+		data, err = cidata()
+	default:
+		var layers []Layer
+		if layers, err = ParseLayers(userDataOptions()); err != nil {
+			return data, err
 		}
 
-		return data, nil
+		return Load(layers, newCacheSource())
+	}
+
+	if err != nil {
+		return data, err
+	}
+
+	if dataSource.Hostname != "" && data.Networking.OS.Hostname == "" {
+		data.Networking.OS.Hostname = dataSource.Hostname
 	}
 
-	return userdata.Download(*option)
+	return data, nil
 }
 
-// Initialize provides the functionality to install talos by downloading the
-// required artifacts and writing them to a target device.
-// nolint: dupl
-func (b *BareMetal) Initialize(data *userdata.UserData) (err error) {
-	var endpoint *string
-	if endpoint = kernel.ProcCmdline().Get(constants.KernelParamUserData).First(); endpoint == nil {
-		return errors.Errorf("failed to find %s in kernel parameters", constants.KernelParamUserData)
+// userDataOptions returns all the values of the talos.userdata kernel
+// parameter.
+func userDataOptions() []string {
+	return paramValues(kernel.ProcCmdline(), constants.KernelParamUserData)
+}
+
+// paramValues returns all the values of the kernel parameter.
+func paramValues(cmdline *kernel.Cmdline, key string) (values []string) {
+	param := cmdline.Get(key)
+	if param == nil {
+		return nil
+	}
+
+	for i := 0; param.Get(i) != nil; i++ {
+		values = append(values, *param.Get(i))
+	}
+
+	return values
+}
+
+// InstallCmdline returns the kernel command line of the installed system.
+//
+// The user data source and the keys which verify and decrypt the user data
+// are carried over from the current command line, so that the installed
+// system reads the user data the same way.
+func InstallCmdline(current *kernel.Cmdline, data *userdata.UserData) (*kernel.Cmdline, error) {
+	options := paramValues(current, constants.KernelParamUserData)
+	ds := current.Get(nocloud.KernelParamDataSource).First()
+	if len(options) == 0 && ds == nil {
+		return nil, errors.Errorf("failed to find %s or %s in kernel parameters", constants.KernelParamUserData, nocloud.KernelParamDataSource)
 	}
 	cmdline := kernel.NewDefaultCmdline()
 	cmdline.Append("initrd", filepath.Join("/", "default", "initramfs.xz"))
 	cmdline.Append(constants.KernelParamPlatform, "bare-metal")
-	cmdline.Append(constants.KernelParamUserData, *endpoint)
+	for _, key := range []string{
+		constants.KernelParamUserData,
+		constants.KernelParamUserDataVerify,
+		constants.KernelParamUserDataIdentity,
+		constants.KernelParamUserDataKeyring,
+	} {
+		for _, value := range paramValues(current, key) {
+			cmdline.Append(key, value)
+		}
+	}
+	if ds != nil {
+		cmdline.Append(nocloud.KernelParamDataSource, *ds)
+	}
 
-	if err = cmdline.AppendAll(data.Install.ExtraKernelArgs); err != nil {
+	if err := cmdline.AppendAll(data.Install.ExtraKernelArgs); err != nil {
+		return nil, err
+	}
+
+	return cmdline, nil
+}
+
+// Initialize provides the functionality to install talos by downloading the
+// required artifacts and writing them to a target device.
+// nolint: dupl
+func (b *BareMetal) Initialize(data *userdata.UserData) (err error) {
+	cmdline, err := InstallCmdline(kernel.ProcCmdline(), data)
+	if err != nil {
 		return err
 	}
 
@@ -95,7 +138,7 @@ func (b *BareMetal) Initialize(data *userdata.UserData) (err error) {
 	// An err case should only happen if no partitions
 	// with matching labels were found
 	var mountpoints *mount.Points
-	mountpoints, err = owned.MountPointsFromLabels()
+	mountpoints, err = owned.MountPointsFromLabels(data)
 	if err != nil {
 		// No previous installation was found, attempt an install
 		var i *installer.Installer
@@ -107,7 +150,7 @@ func (b *BareMetal) Initialize(data *userdata.UserData) (err error) {
 			return errors.Wrap(err, "failed to install")
 		}
 
-		mountpoints, err = owned.MountPointsFromLabels()
+		mountpoints, err = owned.MountPointsFromLabels(data)
 		if err != nil {
 			return err
 		}
@@ -120,3 +163,25 @@ func (b *BareMetal) Initialize(data *userdata.UserData) (err error) {
 
 	return nil
 }
+
+// Metadata implements the platform.Platform interface.
+//
+// Bare metal machines are described only by the NoCloud ds= kernel
+// parameter, if specified.
+func (b *BareMetal) Metadata() (*metadata.Metadata, error) {
+	m := &metadata.Metadata{
+		Platform: b.Name(),
+	}
+
+	if ds := kernel.ProcCmdline().Get(nocloud.KernelParamDataSource).First(); ds != nil {
+		dataSource, err := nocloud.ParseDataSource(*ds)
+		if err != nil {
+			return nil, err
+		}
+
+		m.Hostname = dataSource.Hostname
+		m.InstanceID = dataSource.InstanceID
+	}
+
+	return m, nil
+}
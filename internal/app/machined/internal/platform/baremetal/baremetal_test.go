@@ -4,11 +4,156 @@
 
 package baremetal_test
 
-import "testing"
+import (
+	"errors"
+	"testing"
 
-func TestEmpty(t *testing.T) {
-	// added for accurate coverage estimation
-	//
-	// please remove it once any unit-test is added
-	// for this package
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/baremetal"
+	"github.com/talos-systems/talos/internal/pkg/kernel"
+	"github.com/talos-systems/talos/pkg/constants"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+type BareMetalSuite struct {
+	suite.Suite
+}
+
+// staticSource returns the document, or fails if it is empty.
+type staticSource struct {
+	name    string
+	doc     string
+	fetched int
+}
+
+func (s *staticSource) Fetch() ([]byte, error) {
+	s.fetched++
+
+	if s.doc == "" {
+		return nil, errors.New("unreachable")
+	}
+
+	return []byte(s.doc), nil
+}
+
+func (s *staticSource) String() string {
+	return s.name
+}
+
+const baseDoc = `version: "1"
+services:
+  init:
+    cni: flannel
+  kubeadm:
+    configuration: |
+      apiVersion: kubeadm.k8s.io/v1beta1
+      kind: JoinConfiguration
+      discovery:
+        bootstrapToken:
+          token: 1qbsj9.3oz5hsk6grdfp98b
+          unsafeSkipCAVerification: true
+          apiServerEndpoint: 1.2.3.4:6443
+  trustd:
+    username: test
+    password: test
+    endpoints:
+    - 1.2.3.4
+`
+
+func (suite *BareMetalSuite) TestParseLayers() {
+	layers, err := baremetal.ParseLayers([]string{
+		"https://10.0.0.1/base.yaml,tftp://10.0.0.1/base.yaml,cidata",
+		"file://CONFIG/talos/node.yaml",
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(layers, 2)
+
+	suite.Require().Len(layers[0], 3)
+	suite.Assert().Equal("https://10.0.0.1/base.yaml", layers[0][0].String())
+	suite.Assert().Equal("tftp://10.0.0.1/base.yaml", layers[0][1].String())
+	suite.Assert().Equal("cidata", layers[0][2].String())
+
+	suite.Require().Len(layers[1], 1)
+	suite.Assert().Equal("file://CONFIG/talos/node.yaml", layers[1][0].String())
+}
+
+func (suite *BareMetalSuite) TestParseLayersInvalid() {
+	for _, values := range [][]string{
+		nil,
+		{"ftp://10.0.0.1/base.yaml"},
+		{"file:///talos/node.yaml"},
+		{"file://CONFIG"},
+	} {
+		_, err := baremetal.ParseLayers(values)
+		suite.Assert().Error(err, "%v", values)
+	}
+}
+
+func (suite *BareMetalSuite) TestLoadFallback() {
+	primary := &staticSource{name: "primary"}
+	secondary := &staticSource{name: "secondary", doc: baseDoc}
+	overlay := &staticSource{name: "overlay", doc: "networking:\n  os:\n    hostname: node-1\n"}
+	cache := &staticSource{name: "cache"}
+
+	data, err := baremetal.Load([]baremetal.Layer{{primary, secondary}, {overlay}}, cache)
+	suite.Require().NoError(err)
+
+	suite.Assert().Equal("test", data.Services.Trustd.Username)
+	suite.Assert().Equal("node-1", data.Networking.OS.Hostname)
+	suite.Assert().Equal(1, primary.fetched)
+	suite.Assert().Equal(0, cache.fetched)
+}
+
+func (suite *BareMetalSuite) TestLoadCache() {
+	base := &staticSource{name: "base", doc: baseDoc}
+	overlay := &staticSource{name: "overlay"}
+	cache := &staticSource{name: "cache", doc: baseDoc + "networking:\n  os:\n    hostname: cached\n"}
+
+	data, err := baremetal.Load([]baremetal.Layer{{base}, {overlay}}, cache)
+	suite.Require().NoError(err)
+
+	suite.Assert().Equal("cached", data.Networking.OS.Hostname)
+}
+
+func (suite *BareMetalSuite) TestLoadNoCache() {
+	_, err := baremetal.Load([]baremetal.Layer{{&staticSource{name: "base"}}}, &staticSource{name: "cache"})
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), "base")
+	suite.Assert().Contains(err.Error(), "cache")
+}
+
+func (suite *BareMetalSuite) TestInstallCmdline() {
+	current := kernel.NewCmdline("talos.platform=bare-metal talos.userdata=http://10.0.0.1/userdata.yaml " +
+		"talos.userdata.verify=ed25519:AAAA talos.userdata.verify=ecdsa:BBBB " +
+		"talos.userdata.identity=p256-identity:CCCC talos.userdata.keyring=/etc/talos/node.keyring")
+
+	data := &userdata.UserData{Install: &userdata.Install{ExtraKernelArgs: []string{"ip=dhcp"}}}
+
+	cmdline, err := baremetal.InstallCmdline(current, data)
+	suite.Require().NoError(err)
+
+	for key, values := range map[string][]string{
+		constants.KernelParamUserData:         {"http://10.0.0.1/userdata.yaml"},
+		constants.KernelParamUserDataVerify:   {"ed25519:AAAA", "ecdsa:BBBB"},
+		constants.KernelParamUserDataIdentity: {"p256-identity:CCCC"},
+		constants.KernelParamUserDataKeyring:  {"/etc/talos/node.keyring"},
+	} {
+		param := cmdline.Get(key)
+		suite.Require().NotNil(param, key)
+
+		for i, value := range values {
+			suite.Require().NotNil(param.Get(i), key)
+			suite.Assert().Equal(value, *param.Get(i), key)
+		}
+	}
+
+	suite.Assert().True(cmdline.Get("ip").Contains("dhcp"))
+
+	_, err = baremetal.InstallCmdline(kernel.NewCmdline("talos.platform=bare-metal"), data)
+	suite.Assert().Error(err)
+}
+
+func TestBareMetalSuite(t *testing.T) {
+	suite.Run(t, new(BareMetalSuite))
 }
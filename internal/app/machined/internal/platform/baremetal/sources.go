@@ -0,0 +1,279 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package baremetal
+
+import (
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/nocloud"
+	"github.com/talos-systems/talos/internal/pkg/network"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/luks"
+	"github.com/talos-systems/talos/pkg/blockdevice/probe"
+	"github.com/talos-systems/talos/pkg/constants"
+	"github.com/talos-systems/talos/pkg/userdata"
+
+	"golang.org/x/sys/unix"
+)
+
+// fallbackRetries is the number of download attempts for a source which has
+// fallbacks, the last source is retried with the download defaults.
+const fallbackRetries = 3
+
+// Source is a single location of the user data.
+type Source interface {
+	// Fetch returns the plain user data document, with the envelope
+	// opened.
+	Fetch() ([]byte, error)
+	String() string
+}
+
+// Layer is the ordered list of the sources of one user data document, the
+// first source which can be fetched is used.
+type Layer []Source
+
+// ParseLayers parses the values of the talos.userdata kernel parameter. Each
+// value is a layer, and lists the sources separated by commas:
+//
+//	talos.userdata=https://a/base.yaml,tftp://b/base.yaml talos.userdata=file://CONFIG/node.yaml
+//
+// The supported sources are http(s) and tftp URLs, cidata, and file URLs with
+// the file system label as the host.
+func ParseLayers(values []string) (layers []Layer, err error) {
+	for _, value := range values {
+		var layer Layer
+
+		options := strings.Split(value, ",")
+		for i, option := range options {
+			var source Source
+			if source, err = parseSource(option, i < len(options)-1); err != nil {
+				return nil, err
+			}
+
+			layer = append(layer, source)
+		}
+
+		layers = append(layers, layer)
+	}
+
+	if len(layers) == 0 {
+		return nil, errors.Errorf("no user data option was found")
+	}
+
+	return layers, nil
+}
+
+func parseSource(option string, hasFallback bool) (Source, error) {
+	if option == constants.UserDataCIData {
+		return &cidataSource{}, nil
+	}
+
+	u, err := url.Parse(option)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid user data source %q", option)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "tftp":
+		source := &urlSource{url: option}
+		if hasFallback {
+			source.options = append(source.options, userdata.WithRetries(fallbackRetries))
+		}
+
+		return source, nil
+	case "file":
+		if u.Host == "" || u.Path == "" || path.Clean(u.Path) == "/" {
+			return nil, errors.Errorf("user data source %q must specify the file system label and the path", option)
+		}
+
+		return &fileSource{label: u.Host, path: path.Clean(u.Path)}, nil
+	default:
+		return nil, errors.Errorf("unsupported user data source %q", option)
+	}
+}
+
+// Fetch returns the first document of the layer which can be fetched.
+func (l Layer) Fetch() ([]byte, error) {
+	var result *multierror.Error
+
+	for _, source := range l {
+		b, err := source.Fetch()
+		if err == nil {
+			return b, nil
+		}
+
+		log.Printf("failed to fetch user data from %s: %v", source, err)
+		result = multierror.Append(result, errors.Wrap(err, source.String()))
+	}
+
+	return nil, result.ErrorOrNil()
+}
+
+// Load fetches the layers and merges them in order. If any of the layers
+// can't be fetched, the cached copy of the user data saved at the previous
+// boot is used instead.
+func Load(layers []Layer, cache Source) (data *userdata.UserData, err error) {
+	docs := make([][]byte, 0, len(layers))
+
+	for _, layer := range layers {
+		var b []byte
+		if b, err = layer.Fetch(); err != nil {
+			break
+		}
+
+		docs = append(docs, b)
+	}
+
+	if err != nil {
+		b, cacheErr := cache.Fetch()
+		if cacheErr != nil {
+			return nil, multierror.Append(err, errors.Wrap(cacheErr, "failed to read the cached user data"))
+		}
+
+		log.Printf("WARNING: all user data sources of a layer failed, using the cached copy from %s", cache)
+
+		docs = [][]byte{b}
+	}
+
+	if data, err = userdata.Merge(docs...); err != nil {
+		return nil, err
+	}
+
+	return data, data.Validate()
+}
+
+// urlSource downloads the user data over http(s) or tftp.
+type urlSource struct {
+	url     string
+	options []userdata.Option
+}
+
+func (s *urlSource) Fetch() ([]byte, error) {
+	b, err := userdata.Fetch(s.url, s.options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return userdata.OpenEnvelope(b)
+}
+
+func (s *urlSource) String() string {
+	return s.url
+}
+
+// fileSource reads the user data from the file system with the label.
+type fileSource struct {
+	label string
+	path  string
+}
+
+func (s *fileSource) Fetch() ([]byte, error) {
+	b, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return userdata.OpenEnvelope(b)
+}
+
+func (s *fileSource) read() (b []byte, err error) {
+	err = withFileSystem(s.label, func(root string) (err error) {
+		b, err = ioutil.ReadFile(filepath.Join(root, s.path))
+
+		return err
+	})
+
+	return b, err
+}
+
+func (s *fileSource) String() string {
+	return "file://" + s.label + s.path
+}
+
+// cidataSource reads the NoCloud seed from the volume labeled cidata.
+type cidataSource struct{}
+
+// Fetch loads the NoCloud seed, which is already decoded together with the
+// network configuration, so the result is marshaled back.
+func (s *cidataSource) Fetch() ([]byte, error) {
+	data, err := cidata()
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(data)
+}
+
+func (s *cidataSource) String() string {
+	return constants.UserDataCIData
+}
+
+// cacheSource reads the user data cached on the EPHEMERAL partition. The
+// cached copy is saved after the envelope was opened, so it is read as is.
+type cacheSource struct {
+	fileSource
+}
+
+func newCacheSource() Source {
+	return &cacheSource{
+		fileSource: fileSource{
+			label: constants.EphemeralPartitionLabel,
+			path:  strings.TrimPrefix(constants.UserDataCachePath, constants.EphemeralMountPoint),
+		},
+	}
+}
+
+func (s *cacheSource) Fetch() ([]byte, error) {
+	return s.read()
+}
+
+// cidata reads the NoCloud seed from the volume labeled cidata.
+func cidata() (data *userdata.UserData, err error) {
+	err = withFileSystem(constants.UserDataCIData, func(root string) (err error) {
+		data, err = nocloud.Load(&nocloud.Directory{Root: root}, network.InterfaceByMAC)
+
+		return err
+	})
+
+	return data, err
+}
+
+// withFileSystem mounts the file system with the label read-only for the
+// duration of f.
+func withFileSystem(label string, f func(root string) error) (err error) {
+	var dev *probe.ProbedBlockDevice
+	dev, err = probe.GetDevWithFileSystemLabel(label)
+	if err != nil {
+		return errors.Errorf("failed to find %s file system: %v", label, err)
+	}
+	// The key of an encrypted partition is only known once the userdata is
+	// loaded, so the cached userdata on an encrypted EPHEMERAL is skipped.
+	if dev.SuperBlock.Type() == luks.Type {
+		return errors.Errorf("%s is encrypted and can't be opened before the userdata is loaded", label)
+	}
+	if err = os.MkdirAll(mnt, 0700); err != nil {
+		return errors.Errorf("failed to mkdir: %v", err)
+	}
+	if err = unix.Mount(dev.Path, mnt, dev.SuperBlock.Type(), unix.MS_RDONLY, ""); err != nil {
+		return errors.Errorf("failed to mount %s: %v", label, err)
+	}
+
+	err = f(mnt)
+
+	if unmountErr := unix.Unmount(mnt, 0); unmountErr != nil && err == nil {
+		err = errors.Errorf("failed to unmount: %v", unmountErr)
+	}
+
+	return err
+}
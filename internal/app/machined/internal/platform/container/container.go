@@ -9,9 +9,8 @@ import (
 	"os"
 
 	"github.com/pkg/errors"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
 	"github.com/talos-systems/talos/pkg/userdata"
-
-	"gopkg.in/yaml.v2"
 )
 
 // Container is a platform for installing Talos via an Container image.
@@ -32,8 +31,7 @@ func (c *Container) UserData() (data *userdata.UserData, err error) {
 	if decoded, err = base64.StdEncoding.DecodeString(s); err != nil {
 		return nil, err
 	}
-	data = &userdata.UserData{}
-	if err = yaml.Unmarshal(decoded, data); err != nil {
+	if data, err = userdata.Decode(decoded); err != nil {
 		return nil, err
 	}
 
@@ -44,3 +42,8 @@ func (c *Container) UserData() (data *userdata.UserData, err error) {
 func (c *Container) Initialize(data *userdata.UserData) error {
 	return nil
 }
+
+// Metadata implements the platform.Platform interface.
+func (c *Container) Metadata() (*metadata.Metadata, error) {
+	return &metadata.Metadata{Platform: c.Name()}, nil
+}
@@ -0,0 +1,338 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package platform
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/aws"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/azure"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/googlecloud"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/openstack"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/packet"
+	"github.com/talos-systems/talos/internal/pkg/hypervisor"
+	"github.com/talos-systems/talos/internal/pkg/network"
+	"github.com/talos-systems/talos/pkg/blockdevice/probe"
+	"github.com/talos-systems/talos/pkg/constants"
+)
+
+// DefaultPlatform is used when none of the probes identifies the platform.
+const DefaultPlatform = "bare-metal"
+
+// Detection describes how the platform was determined.
+type Detection struct {
+	Platform string
+	// Source is the human readable evidence, e.g. the matching DMI field.
+	Source   string
+	Duration time.Duration
+}
+
+type detector struct {
+	name    string
+	timeout time.Duration
+	detect  func(ctx context.Context) (platform, source string)
+}
+
+// detectors are probed in order, the first match wins. Local probes go
+// first, as metadata endpoints require the network and might time out.
+var detectors = []detector{
+	{name: "DMI", timeout: time.Second, detect: detectDMI},
+	{name: "hypervisor", timeout: time.Second, detect: detectHypervisor},
+	{name: "config drive", timeout: 10 * time.Second, detect: detectConfigDrive},
+	{name: "metadata service", timeout: 30 * time.Second, detect: detectMetadataService},
+}
+
+var (
+	detectionMu   sync.Mutex
+	lastDetection *Detection
+	detected      *Detection
+)
+
+// LastDetection returns the description of how the current platform was
+// determined.
+func LastDetection() *Detection {
+	detectionMu.Lock()
+	defer detectionMu.Unlock()
+
+	return lastDetection
+}
+
+func setLastDetection(detection *Detection) {
+	detectionMu.Lock()
+	defer detectionMu.Unlock()
+
+	lastDetection = detection
+}
+
+// Detect identifies the platform by probing DMI vendor strings, hypervisor
+// CPUID signature, labeled config drives and metadata endpoints.
+//
+// The result is cached, as probes might take a while.
+func Detect() *Detection {
+	detectionMu.Lock()
+	defer detectionMu.Unlock()
+
+	if detected != nil {
+		return detected
+	}
+
+	start := time.Now()
+
+	detected = &Detection{
+		Platform: DefaultPlatform,
+		Source:   "default, no platform was detected",
+	}
+
+	for _, d := range detectors {
+		platform, source := runDetector(d)
+		if platform == "" {
+			continue
+		}
+
+		detected = &Detection{
+			Platform: platform,
+			Source:   d.name + " " + source,
+		}
+
+		break
+	}
+
+	detected.Duration = time.Since(start)
+
+	log.Printf("detected platform %q via %s in %s", detected.Platform, detected.Source, detected.Duration.Round(time.Millisecond))
+
+	return detected
+}
+
+func runDetector(d detector) (platform, source string) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	type result struct {
+		platform, source string
+	}
+
+	ch := make(chan result, 1)
+
+	go func() {
+		platform, source := d.detect(ctx)
+		ch <- result{platform, source}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.platform, r.source
+	case <-ctx.Done():
+		log.Printf("platform detection via %s timed out", d.name)
+
+		return "", ""
+	}
+}
+
+// dmiRoot is the sysfs directory with the DMI (SMBIOS) identification.
+var dmiRoot = "/sys/class/dmi/id"
+
+// dmiRules match the DMI fields to the platforms, the value is matched as
+// a case insensitive substring.
+var dmiRules = []struct {
+	field    string
+	value    string
+	platform string
+}{
+	{"sys_vendor", "Amazon EC2", "aws"},
+	{"bios_version", "amazon", "aws"},
+	// Azure reports the generic Hyper-V vendor, but the asset tag is unique
+	{"chassis_asset_tag", "7783-7084-3265-9085-8269-3286-77", "azure"},
+	{"product_name", "Google Compute Engine", "googlecloud"},
+	{"sys_vendor", "VMware", "vmware"},
+	{"product_name", "OpenStack", "openstack"},
+	{"sys_vendor", "OpenStack", "openstack"},
+}
+
+func detectDMI(ctx context.Context) (platform, source string) {
+	for _, rule := range dmiRules {
+		b, err := ioutil.ReadFile(filepath.Join(dmiRoot, rule.field))
+		if err != nil {
+			continue
+		}
+
+		value := strings.TrimSpace(string(b))
+
+		if strings.Contains(strings.ToLower(value), strings.ToLower(rule.value)) {
+			return rule.platform, fmt.Sprintf("%s %q", rule.field, value)
+		}
+	}
+
+	return "", ""
+}
+
+func detectHypervisor(ctx context.Context) (platform, source string) {
+	// KVM, Xen and Hyper-V are used by the several platforms, so only
+	// VMware signature is conclusive
+	if vendor := hypervisor.Vendor(); vendor == hypervisor.VMware {
+		return "vmware", fmt.Sprintf("CPUID %q", vendor)
+	}
+
+	return "", ""
+}
+
+func detectConfigDrive(ctx context.Context) (platform, source string) {
+	for _, drive := range []struct {
+		label    string
+		platform string
+	}{
+		{openstack.ConfigDriveLabel, "openstack"},
+		{constants.UserDataCIData, "bare-metal"},
+	} {
+		if ctx.Err() != nil {
+			return "", ""
+		}
+
+		if _, err := probe.GetDevWithFileSystemLabel(drive.label); err == nil {
+			return drive.platform, fmt.Sprintf("label %q", drive.label)
+		}
+	}
+
+	return "", ""
+}
+
+type endpoint struct {
+	platform string
+	url      string
+	headers  map[string]string
+	check    func(*http.Response) bool
+	// probe replaces the plain GET request, e.g. to authenticate first
+	probe func(ctx context.Context) bool
+}
+
+// endpoints are listed in the order of preference, e.g. OpenStack also
+// serves the EC2 compatible metadata.
+var endpoints = []endpoint{
+	{
+		platform: "openstack",
+		url:      openstack.OpenStackMetadataEndpoint + "/" + openstack.MetaDataFile,
+	},
+	{
+		platform: "aws",
+		url:      aws.AWSIdentityDocumentEndpoint,
+		probe:    aws.Probe,
+	},
+	{
+		platform: "googlecloud",
+		url:      googlecloud.GCMetadataEndpoint,
+		headers:  map[string]string{"Metadata-Flavor": "Google"},
+		check: func(resp *http.Response) bool {
+			return resp.Header.Get("Metadata-Flavor") == "Google"
+		},
+	},
+	{
+		platform: "azure",
+		url:      azure.AzureMetadataEndpoint,
+		headers:  map[string]string{"Metadata": "true"},
+	},
+	{
+		platform: "packet",
+		url:      packet.PacketMetadataEndpoint,
+	},
+}
+
+func detectMetadataService(ctx context.Context) (platform, source string) {
+	// Setup basic networking for the purposes of reaching the metadata
+	// endpoints.
+	if err := network.InitNetwork(); err != nil {
+		log.Printf("skipping metadata service detection: %v", err)
+
+		return "", ""
+	}
+
+	if ctx.Err() != nil {
+		return "", ""
+	}
+
+	return probeEndpoints(ctx, endpoints)
+}
+
+// probeEndpoints queries all the endpoints concurrently and returns the first
+// responding one in the order of the list.
+//
+// The probes still in flight are canceled as soon as the result is known.
+func probeEndpoints(ctx context.Context, endpoints []endpoint) (platform, source string) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		index int
+		found bool
+	}
+
+	// buffered, so that the canceled probes don't block
+	results := make(chan result, len(endpoints))
+
+	for i := range endpoints {
+		go func(i int) {
+			results <- result{i, probeEndpoint(ctx, endpoints[i])}
+		}(i)
+	}
+
+	found := make([]*bool, len(endpoints))
+
+	for range endpoints {
+		r := <-results
+		found[r.index] = &r.found
+
+		// the result is known once all the preferred endpoints have failed
+		for i, e := range endpoints {
+			if found[i] == nil {
+				break
+			}
+
+			if *found[i] {
+				return e.platform, e.url
+			}
+		}
+	}
+
+	return "", ""
+}
+
+func probeEndpoint(ctx context.Context, e endpoint) bool {
+	if e.probe != nil {
+		return e.probe(ctx)
+	}
+
+	req, err := http.NewRequest("GET", e.url, nil)
+	if err != nil {
+		return false
+	}
+
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return false
+	}
+	// nolint: errcheck
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+
+	if e.check != nil {
+		return e.check(resp)
+	}
+
+	return true
+}
@@ -0,0 +1,157 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package platform
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DetectSuite struct {
+	suite.Suite
+
+	dmiRoot string
+}
+
+func (suite *DetectSuite) SetupTest() {
+	var err error
+
+	suite.dmiRoot = dmiRoot
+
+	dmiRoot, err = ioutil.TempDir("", "talos")
+	suite.Require().NoError(err)
+}
+
+func (suite *DetectSuite) TearDownTest() {
+	suite.Require().NoError(os.RemoveAll(dmiRoot))
+
+	dmiRoot = suite.dmiRoot
+}
+
+func (suite *DetectSuite) writeDMI(fields map[string]string) {
+	for field, value := range fields {
+		suite.Require().NoError(ioutil.WriteFile(filepath.Join(dmiRoot, field), []byte(value+"\n"), 0644))
+	}
+}
+
+func (suite *DetectSuite) TestDMI() {
+	for _, t := range []struct {
+		fields   map[string]string
+		platform string
+	}{
+		{map[string]string{"sys_vendor": "Amazon EC2", "product_name": "m5.large"}, "aws"},
+		{map[string]string{"sys_vendor": "Xen", "bios_version": "4.2.amazon"}, "aws"},
+		{map[string]string{"sys_vendor": "Microsoft Corporation", "chassis_asset_tag": "7783-7084-3265-9085-8269-3286-77"}, "azure"},
+		{map[string]string{"sys_vendor": "Google", "product_name": "Google Compute Engine"}, "googlecloud"},
+		{map[string]string{"sys_vendor": "VMware, Inc.", "product_name": "VMware7,1"}, "vmware"},
+		{map[string]string{"sys_vendor": "OpenStack Foundation", "product_name": "OpenStack Nova"}, "openstack"},
+		{map[string]string{"sys_vendor": "Microsoft Corporation", "product_name": "Virtual Machine"}, ""},
+		{map[string]string{"sys_vendor": "QEMU", "product_name": "Standard PC (Q35 + ICH9, 2009)"}, ""},
+	} {
+		suite.Require().NoError(os.RemoveAll(dmiRoot))
+		suite.Require().NoError(os.MkdirAll(dmiRoot, 0755))
+
+		suite.writeDMI(t.fields)
+
+		platform, source := detectDMI(context.Background())
+		suite.Assert().Equal(t.platform, platform, "%v", t.fields)
+
+		if t.platform != "" {
+			suite.Assert().NotEmpty(source)
+		}
+	}
+}
+
+func (suite *DetectSuite) TestProbeEndpoints() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ec2":
+			w.WriteHeader(http.StatusOK)
+		case "/gce":
+			if r.Header.Get("Metadata-Flavor") == "Google" {
+				w.Header().Set("Metadata-Flavor", "Google")
+			}
+
+			w.WriteHeader(http.StatusOK)
+		case "/slow":
+			time.Sleep(time.Second)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	flavor := func(resp *http.Response) bool {
+		return resp.Header.Get("Metadata-Flavor") == "Google"
+	}
+
+	platform, source := probeEndpoints(ctx, []endpoint{
+		{platform: "slow", url: ts.URL + "/slow"},
+		{platform: "openstack", url: ts.URL + "/openstack"},
+		{platform: "gce-without-header", url: ts.URL + "/gce", check: flavor},
+		{platform: "googlecloud", url: ts.URL + "/gce", headers: map[string]string{"Metadata-Flavor": "Google"}, check: flavor},
+		{platform: "aws", url: ts.URL + "/ec2"},
+	})
+
+	suite.Assert().Equal("googlecloud", platform)
+	suite.Assert().Equal(ts.URL+"/gce", source)
+
+	platform, _ = probeEndpoints(context.Background(), []endpoint{
+		{platform: "openstack", url: ts.URL + "/openstack"},
+	})
+
+	suite.Assert().Equal("", platform)
+}
+
+func (suite *DetectSuite) TestProbeEndpointsCancel() {
+	canceled := make(chan struct{})
+
+	platform, _ := probeEndpoints(context.Background(), []endpoint{
+		{platform: "aws", probe: func(ctx context.Context) bool { return true }},
+		{platform: "stuck", probe: func(ctx context.Context) bool {
+			<-ctx.Done()
+			close(canceled)
+
+			return false
+		}},
+	})
+
+	suite.Assert().Equal("aws", platform)
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		suite.Assert().Fail("the probe in flight should be canceled")
+	}
+}
+
+func (suite *DetectSuite) TestRunDetectorTimeout() {
+	platform, _ := runDetector(detector{
+		name:    "stuck",
+		timeout: 100 * time.Millisecond,
+		detect: func(ctx context.Context) (string, string) {
+			time.Sleep(time.Second)
+
+			return "aws", "too late"
+		},
+	})
+
+	suite.Assert().Equal("", platform)
+}
+
+func TestDetectSuite(t *testing.T) {
+	suite.Run(t, new(DetectSuite))
+}
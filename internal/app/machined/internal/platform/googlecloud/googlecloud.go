@@ -5,6 +5,11 @@
 package googlecloud
 
 import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
 	"github.com/talos-systems/talos/internal/pkg/mount"
 	"github.com/talos-systems/talos/internal/pkg/mount/manager"
 	"github.com/talos-systems/talos/internal/pkg/mount/manager/owned"
@@ -14,6 +19,9 @@ import (
 const (
 	// GCUserDataEndpoint is the local metadata endpoint inside of DO
 	GCUserDataEndpoint = "http://metadata.google.internal/computeMetadata/v1/instance/attributes/user-data"
+	// GCMetadataEndpoint is the local metadata endpoint for the instance
+	// metadata.
+	GCMetadataEndpoint = "http://metadata.google.internal/computeMetadata/v1/instance/?recursive=true"
 )
 
 // GoogleCloud is the concrete type that implements the platform.Platform interface.
@@ -32,7 +40,7 @@ func (gc *GoogleCloud) UserData() (data *userdata.UserData, err error) {
 // Initialize implements the platform.Platform interface and handles additional system setup.
 func (gc *GoogleCloud) Initialize(data *userdata.UserData) (err error) {
 	var mountpoints *mount.Points
-	mountpoints, err = owned.MountPointsFromLabels()
+	mountpoints, err = owned.MountPointsFromLabels(data)
 	if err != nil {
 		return err
 	}
@@ -41,3 +49,56 @@ func (gc *GoogleCloud) Initialize(data *userdata.UserData) (err error) {
 
 	return m.MountAll()
 }
+
+// InstanceMetadata is the subset of the Google Cloud instance metadata used
+// by Talos.
+type InstanceMetadata struct {
+	ID                uint64 `json:"id"`
+	Hostname          string `json:"hostname"`
+	MachineType       string `json:"machineType"`
+	Zone              string `json:"zone"`
+	NetworkInterfaces []struct {
+		IP            string `json:"ip"`
+		AccessConfigs []struct {
+			ExternalIP string `json:"externalIp"`
+		} `json:"accessConfigs"`
+	} `json:"networkInterfaces"`
+}
+
+// Metadata implements the platform.Platform interface.
+func (gc *GoogleCloud) Metadata() (*metadata.Metadata, error) {
+	var instance InstanceMetadata
+	if err := metadata.FetchJSON(GCMetadataEndpoint, map[string]string{"Metadata-Flavor": "Google"}, &instance); err != nil {
+		return nil, err
+	}
+
+	// zone and machine type are resource paths, e.g.
+	// "projects/123/zones/us-central1-a"
+	zone := path.Base(instance.Zone)
+
+	m := &metadata.Metadata{
+		Platform:     gc.Name(),
+		Hostname:     strings.Split(instance.Hostname, ".")[0],
+		InstanceID:   strconv.FormatUint(instance.ID, 10),
+		InstanceType: path.Base(instance.MachineType),
+		Zone:         zone,
+	}
+
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		m.Region = zone[:i]
+	}
+
+	for _, iface := range instance.NetworkInterfaces {
+		if iface.IP != "" {
+			m.PrivateIPs = append(m.PrivateIPs, iface.IP)
+		}
+
+		for _, config := range iface.AccessConfigs {
+			if config.ExternalIP != "" {
+				m.PublicIPs = append(m.PublicIPs, config.ExternalIP)
+			}
+		}
+	}
+
+	return m, nil
+}
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 
 	"github.com/pkg/errors"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
 	"github.com/talos-systems/talos/internal/pkg/installer"
 	"github.com/talos-systems/talos/internal/pkg/kernel"
 	"github.com/talos-systems/talos/pkg/blockdevice/probe"
@@ -109,3 +110,8 @@ func (i *ISO) Initialize(data *userdata.UserData) (err error) {
 
 	return nil
 }
+
+// Metadata implements the platform.Platform interface.
+func (i *ISO) Metadata() (*metadata.Metadata, error) {
+	return &metadata.Metadata{Platform: i.Name()}, nil
+}
@@ -0,0 +1,298 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package metadata provides the platform independent description of the
+// machine as reported by the platform, e.g. the cloud metadata service.
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/talos-systems/talos/internal/app/machined/proto"
+)
+
+const (
+	// LabelZone is the Kubernetes node label which holds the zone of the node.
+	LabelZone = "failure-domain.beta.kubernetes.io/zone"
+	// LabelRegion is the Kubernetes node label which holds the region of the
+	// node.
+	LabelRegion = "failure-domain.beta.kubernetes.io/region"
+)
+
+// ErrNotFound is returned by Fetch when the metadata endpoint doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Metadata describes the machine as reported by the platform.
+//
+// Fields which are not known to the platform are left empty.
+type Metadata struct {
+	Platform     string
+	Hostname     string
+	InstanceID   string
+	InstanceType string
+	Region       string
+	Zone         string
+	PublicIPs    []string
+	PrivateIPs   []string
+}
+
+// Labels returns the Kubernetes node labels which describe the topology of
+// the machine.
+func (m *Metadata) Labels() map[string]string {
+	labels := map[string]string{}
+
+	if m.Region != "" {
+		labels[LabelRegion] = m.Region
+	}
+
+	if m.Zone != "" {
+		labels[LabelZone] = m.Zone
+	}
+
+	return labels
+}
+
+// LabelsString formats the topology labels as the value of the kubelet
+// --node-labels flag.
+func (m *Metadata) LabelsString() string {
+	return m.MergeLabels("")
+}
+
+// MergeLabels adds the topology labels to the value of the kubelet
+// --node-labels flag. Labels which are already set take precedence over the
+// ones reported by the platform.
+func (m *Metadata) MergeLabels(labels string) string {
+	merged := m.Labels()
+
+	for _, pair := range strings.Split(labels, ",") {
+		if pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) == 2 {
+			merged[kv[0]] = kv[1]
+		} else {
+			merged[kv[0]] = ""
+		}
+	}
+
+	pairs := make([]string, 0, len(merged))
+	for k, v := range merged {
+		pairs = append(pairs, k+"="+v)
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// AsProto converts the metadata to the API representation.
+func (m *Metadata) AsProto() *proto.MetadataReply {
+	return &proto.MetadataReply{
+		Platform:     m.Platform,
+		Hostname:     m.Hostname,
+		InstanceId:   m.InstanceID,
+		InstanceType: m.InstanceType,
+		Region:       m.Region,
+		Zone:         m.Zone,
+		PublicIps:    m.PublicIPs,
+		PrivateIps:   m.PrivateIPs,
+	}
+}
+
+// Client fetches the metadata from the platform metadata service.
+//
+// Requests are retried with the exponential backoff on the network and the
+// server errors.
+type Client struct {
+	client     *http.Client
+	headers    map[string]string
+	headerFunc func(ctx context.Context) (map[string]string, error)
+	retries    int
+	maxWait    time.Duration
+}
+
+// ClientOption configures the Client.
+type ClientOption func(*Client)
+
+// WithHeaders specifies the HTTP headers sent with every request, e.g. the
+// session token.
+func WithHeaders(headers map[string]string) ClientOption {
+	return func(c *Client) {
+		for k, v := range headers {
+			c.headers[k] = v
+		}
+	}
+}
+
+// WithHeaderFunc specifies the function which returns the HTTP headers sent
+// with every request, e.g. the session token which expires.
+func WithHeaderFunc(f func(ctx context.Context) (map[string]string, error)) ClientOption {
+	return func(c *Client) {
+		c.headerFunc = f
+	}
+}
+
+// WithRetries specifies how many times the request is attempted before
+// failing.
+func WithRetries(retries int) ClientOption {
+	return func(c *Client) {
+		c.retries = retries
+	}
+}
+
+// WithMaxWait specifies the maximum amount of time to wait between the
+// attempts.
+func WithMaxWait(wait time.Duration) ClientOption {
+	return func(c *Client) {
+		c.maxWait = wait
+	}
+}
+
+// WithTimeout specifies the timeout of the single attempt.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.client.Timeout = timeout
+	}
+}
+
+// NewClient initializes and returns a Client.
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		headers: map[string]string{},
+		retries: 5,
+		maxWait: 16 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Fetch reads the metadata endpoint.
+//
+// ErrNotFound is returned if the endpoint doesn't exist.
+func (c *Client) Fetch(endpoint string) ([]byte, error) {
+	return c.FetchContext(context.Background(), endpoint)
+}
+
+// FetchContext reads the metadata endpoint, giving up once the context is
+// canceled.
+func (c *Client) FetchContext(ctx context.Context, endpoint string) (b []byte, err error) {
+	for attempt := 0; attempt < c.retries; attempt++ {
+		if attempt > 0 {
+			if err = c.backoff(ctx, attempt); err != nil {
+				return nil, err
+			}
+		}
+
+		var retry bool
+
+		if b, retry, err = c.fetch(ctx, endpoint); err == nil || !retry {
+			return b, err
+		}
+
+		log.Printf("attempt %d to fetch %s failed: %v", attempt+1, endpoint, err)
+	}
+
+	return nil, err
+}
+
+// FetchJSON reads the metadata endpoint and decodes the JSON response into v.
+func (c *Client) FetchJSON(endpoint string, v interface{}) error {
+	b, err := c.Fetch(endpoint)
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(b, v); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal %s", endpoint)
+	}
+
+	return nil
+}
+
+func (c *Client) fetch(ctx context.Context, endpoint string) (b []byte, retry bool, err error) {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	if c.headerFunc != nil {
+		var headers map[string]string
+		if headers, err = c.headerFunc(ctx); err != nil {
+			return nil, false, err
+		}
+
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	// nolint: errcheck
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, false, errors.Wrap(ErrNotFound, endpoint)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return nil, true, fmt.Errorf("failed to fetch %s from metadata service: %d", endpoint, resp.StatusCode)
+	default:
+		return nil, false, fmt.Errorf("failed to fetch %s from metadata service: %d", endpoint, resp.StatusCode)
+	}
+
+	if b, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, true, err
+	}
+
+	return b, false, nil
+}
+
+func (c *Client) backoff(ctx context.Context, attempt int) error {
+	wait := time.Second << uint(attempt-1)
+	if wait > c.maxWait {
+		wait = c.maxWait
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// Fetch reads the metadata endpoint with the default Client.
+func Fetch(endpoint string, headers map[string]string) ([]byte, error) {
+	return NewClient(WithHeaders(headers)).Fetch(endpoint)
+}
+
+// FetchJSON reads the metadata endpoint with the default Client and decodes
+// the JSON response into v.
+func FetchJSON(endpoint string, headers map[string]string, v interface{}) error {
+	return NewClient(WithHeaders(headers)).FetchJSON(endpoint, v)
+}
@@ -0,0 +1,161 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package metadata_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
+)
+
+type MetadataSuite struct {
+	suite.Suite
+}
+
+func (suite *MetadataSuite) TestLabels() {
+	m := &metadata.Metadata{
+		Region: "us-east-1",
+		Zone:   "us-east-1a",
+	}
+
+	suite.Assert().Equal(map[string]string{
+		metadata.LabelRegion: "us-east-1",
+		metadata.LabelZone:   "us-east-1a",
+	}, m.Labels())
+	suite.Assert().Equal("failure-domain.beta.kubernetes.io/region=us-east-1,failure-domain.beta.kubernetes.io/zone=us-east-1a", m.LabelsString())
+
+	m = &metadata.Metadata{
+		Zone: "ams1",
+	}
+
+	suite.Assert().Equal("failure-domain.beta.kubernetes.io/zone=ams1", m.LabelsString())
+	suite.Assert().Equal("", (&metadata.Metadata{}).LabelsString())
+}
+
+func (suite *MetadataSuite) TestMergeLabels() {
+	m := &metadata.Metadata{
+		Region: "us-east-1",
+		Zone:   "us-east-1a",
+	}
+
+	suite.Assert().Equal("failure-domain.beta.kubernetes.io/region=us-east-1,failure-domain.beta.kubernetes.io/zone=us-east-1a", m.MergeLabels(""))
+	suite.Assert().Equal("failure-domain.beta.kubernetes.io/region=us-east-1,failure-domain.beta.kubernetes.io/zone=custom,role=worker",
+		m.MergeLabels("role=worker,failure-domain.beta.kubernetes.io/zone=custom"))
+	suite.Assert().Equal("role=worker", (&metadata.Metadata{}).MergeLabels("role=worker"))
+}
+
+func (suite *MetadataSuite) TestAsProto() {
+	m := &metadata.Metadata{
+		Platform:     "AWS",
+		Hostname:     "ip-10-0-0-1",
+		InstanceID:   "i-1234567890abcdef0",
+		InstanceType: "m5.large",
+		Region:       "us-east-1",
+		Zone:         "us-east-1a",
+		PublicIPs:    []string{"1.2.3.4"},
+		PrivateIPs:   []string{"10.0.0.1"},
+	}
+
+	reply := m.AsProto()
+	suite.Assert().Equal("AWS", reply.Platform)
+	suite.Assert().Equal("ip-10-0-0-1", reply.Hostname)
+	suite.Assert().Equal("i-1234567890abcdef0", reply.InstanceId)
+	suite.Assert().Equal("m5.large", reply.InstanceType)
+	suite.Assert().Equal("us-east-1", reply.Region)
+	suite.Assert().Equal("us-east-1a", reply.Zone)
+	suite.Assert().Equal([]string{"1.2.3.4"}, reply.PublicIps)
+	suite.Assert().Equal([]string{"10.0.0.1"}, reply.PrivateIps)
+}
+
+func (suite *MetadataSuite) TestFetch() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/document":
+			if r.Header.Get("Metadata") != "true" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			fmt.Fprint(w, `{"instanceId": "i-1234567890abcdef0", "region": "us-east-1"}`)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	var doc struct {
+		InstanceID string `json:"instanceId"`
+		Region     string `json:"region"`
+	}
+
+	suite.Require().NoError(metadata.FetchJSON(ts.URL+"/document", map[string]string{"Metadata": "true"}, &doc))
+	suite.Assert().Equal("i-1234567890abcdef0", doc.InstanceID)
+	suite.Assert().Equal("us-east-1", doc.Region)
+
+	_, err := metadata.Fetch(ts.URL+"/document", nil)
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), "400")
+
+	_, err = metadata.Fetch(ts.URL+"/public-ipv4", nil)
+	suite.Require().Error(err)
+	suite.Assert().Equal(metadata.ErrNotFound, errors.Cause(err))
+
+	_, err = metadata.NewClient(metadata.WithRetries(2), metadata.WithMaxWait(time.Millisecond)).Fetch(ts.URL + "/broken")
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), "500")
+}
+
+func (suite *MetadataSuite) TestClientRetries() {
+	var requests int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			if atomic.AddInt32(&requests, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			fmt.Fprint(w, "ok")
+		case "/forbidden":
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	client := metadata.NewClient(
+		metadata.WithRetries(3),
+		metadata.WithMaxWait(time.Millisecond),
+		metadata.WithHeaders(map[string]string{"X-Token": "secret"}),
+	)
+
+	b, err := client.Fetch(ts.URL + "/flaky")
+	suite.Require().NoError(err)
+	suite.Assert().Equal("ok", string(b))
+	suite.Assert().EqualValues(3, atomic.LoadInt32(&requests))
+
+	atomic.StoreInt32(&requests, 0)
+
+	// client errors are not retried
+	_, err = client.Fetch(ts.URL + "/forbidden")
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), "401")
+	suite.Assert().EqualValues(1, atomic.LoadInt32(&requests))
+}
+
+func TestMetadataSuite(t *testing.T) {
+	suite.Run(t, new(MetadataSuite))
+}
@@ -0,0 +1,439 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package nocloud
+
+import (
+	"log"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	talosnet "github.com/talos-systems/talos/pkg/net"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+// NetworkConfig is the cloud-init network configuration, either version 1
+// or version 2 (netplan).
+//
+// See https://cloudinit.readthedocs.io/en/latest/topics/network-config.html.
+type NetworkConfig struct {
+	Version int `yaml:"version"`
+
+	// Version 1
+	Config []NetworkConfigV1Entry `yaml:"config"`
+
+	// Version 2
+	Ethernets map[string]NetworkConfigV2Device `yaml:"ethernets"`
+	Bonds     map[string]NetworkConfigV2Bond   `yaml:"bonds"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+//
+// Network configuration might be wrapped into the top-level "network" key.
+func (n *NetworkConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain NetworkConfig
+
+	var wrapped struct {
+		Network *plain `yaml:"network"`
+	}
+
+	if err := unmarshal(&wrapped); err != nil {
+		return err
+	}
+
+	if wrapped.Network != nil {
+		*n = NetworkConfig(*wrapped.Network)
+
+		return nil
+	}
+
+	return unmarshal((*plain)(n))
+}
+
+// NetworkConfigV1Entry is a single entry of the version 1 configuration.
+type NetworkConfigV1Entry struct {
+	Type           string                  `yaml:"type"`
+	Name           string                  `yaml:"name"`
+	MACAddress     string                  `yaml:"mac_address"`
+	MTU            int                     `yaml:"mtu"`
+	Subnets        []NetworkConfigV1Subnet `yaml:"subnets"`
+	BondInterfaces []string                `yaml:"bond_interfaces"`
+	Params         map[string]string       `yaml:"params"`
+	Address        stringList              `yaml:"address"`
+	Search         stringList              `yaml:"search"`
+}
+
+// NetworkConfigV1Subnet describes addressing in the version 1 configuration.
+type NetworkConfigV1Subnet struct {
+	Type           string                 `yaml:"type"`
+	Address        string                 `yaml:"address"`
+	Netmask        string                 `yaml:"netmask"`
+	Gateway        string                 `yaml:"gateway"`
+	DNSNameservers []string               `yaml:"dns_nameservers"`
+	DNSSearch      []string               `yaml:"dns_search"`
+	Routes         []NetworkConfigV1Route `yaml:"routes"`
+}
+
+// NetworkConfigV1Route describes a route in the version 1 configuration.
+type NetworkConfigV1Route struct {
+	Network string `yaml:"network"`
+	Netmask string `yaml:"netmask"`
+	Gateway string `yaml:"gateway"`
+}
+
+// NetworkConfigV2Device describes an ethernet device in the version 2
+// configuration.
+type NetworkConfigV2Device struct {
+	Match struct {
+		MACAddress string `yaml:"macaddress"`
+		Name       string `yaml:"name"`
+	} `yaml:"match"`
+	SetName     string   `yaml:"set-name"`
+	DHCP4       bool     `yaml:"dhcp4"`
+	DHCP6       bool     `yaml:"dhcp6"`
+	Addresses   []string `yaml:"addresses"`
+	Gateway4    string   `yaml:"gateway4"`
+	Gateway6    string   `yaml:"gateway6"`
+	MTU         int      `yaml:"mtu"`
+	Nameservers struct {
+		Addresses []string `yaml:"addresses"`
+		Search    []string `yaml:"search"`
+	} `yaml:"nameservers"`
+	Routes []struct {
+		To  string `yaml:"to"`
+		Via string `yaml:"via"`
+	} `yaml:"routes"`
+}
+
+// NetworkConfigV2Bond describes a bond in the version 2 configuration.
+type NetworkConfigV2Bond struct {
+	NetworkConfigV2Device `yaml:",inline"`
+
+	Interfaces []string `yaml:"interfaces"`
+	Parameters struct {
+		Mode               string `yaml:"mode"`
+		TransmitHashPolicy string `yaml:"transmit-hash-policy"`
+		LACPRate           string `yaml:"lacp-rate"`
+	} `yaml:"parameters"`
+}
+
+// stringList accepts both a single string and a list of strings.
+type stringList []string
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (l *stringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*l = strings.Fields(s)
+
+		return nil
+	}
+
+	return unmarshal((*[]string)(l))
+}
+
+// OSNet translates the network configuration into the userdata network
+// configuration.
+//
+// Userdata supports a single address per device, so only the first
+// address of each device is used.
+func (n *NetworkConfig) OSNet(interfaceName InterfaceNameFunc) (*userdata.OSNet, error) {
+	switch n.Version {
+	case 0:
+		// no network configuration
+		return &userdata.OSNet{}, nil
+	case 1:
+		return n.osNetV1(interfaceName)
+	case 2:
+		return n.osNetV2(interfaceName)
+	default:
+		return nil, errors.Errorf("unsupported network config version %d", n.Version)
+	}
+}
+
+// nolint: gocyclo
+func (n *NetworkConfig) osNetV1(interfaceName InterfaceNameFunc) (*userdata.OSNet, error) {
+	osNet := &userdata.OSNet{}
+
+	names := make(map[string]string)
+	bonded := make(map[string]bool)
+
+	for _, entry := range n.Config {
+		switch entry.Type {
+		case "physical":
+			names[entry.Name] = resolveName(interfaceName, entry.MACAddress, entry.Name)
+		case "bond":
+			for _, member := range entry.BondInterfaces {
+				bonded[member] = true
+			}
+		}
+	}
+
+	for _, entry := range n.Config {
+		switch entry.Type {
+		case "nameserver":
+			osNet.Nameservers = appendUnique(osNet.Nameservers, entry.Address...)
+			if osNet.Domainname == "" && len(entry.Search) > 0 {
+				osNet.Domainname = entry.Search[0]
+			}
+
+			continue
+		case "physical", "bond":
+		default:
+			log.Printf("ignoring network config entry %q of unsupported type %q", entry.Name, entry.Type)
+			continue
+		}
+
+		if bonded[entry.Name] {
+			continue
+		}
+
+		device := userdata.Device{
+			Interface: entry.Name,
+			MTU:       entry.MTU,
+		}
+
+		if name, ok := names[entry.Name]; ok {
+			device.Interface = name
+		}
+
+		if entry.Type == "bond" {
+			device.Bond = &userdata.Bond{
+				Mode:       entry.Params["bond-mode"],
+				HashPolicy: entry.Params["bond-xmit-hash-policy"],
+				LACPRate:   entry.Params["bond-lacp-rate"],
+			}
+
+			for _, member := range entry.BondInterfaces {
+				if name, ok := names[member]; ok {
+					member = name
+				}
+
+				device.Bond.Interfaces = append(device.Bond.Interfaces, member)
+			}
+		}
+
+		for _, subnet := range entry.Subnets {
+			osNet.Nameservers = appendUnique(osNet.Nameservers, subnet.DNSNameservers...)
+			if osNet.Domainname == "" && len(subnet.DNSSearch) > 0 {
+				osNet.Domainname = subnet.DNSSearch[0]
+			}
+
+			if device.DHCP || device.CIDR != "" {
+				log.Printf("ignoring extra subnet of %q", entry.Name)
+				continue
+			}
+
+			switch subnet.Type {
+			case "dhcp", "dhcp4", "dhcp6":
+				device.DHCP = true
+			case "static", "static6":
+				cidr, err := talosnet.FormatCIDR(subnet.Address, subnet.Netmask)
+				if err != nil {
+					return nil, errors.Wrapf(err, "interface %q", entry.Name)
+				}
+
+				device.CIDR = cidr
+
+				if subnet.Gateway != "" {
+					device.Routes = append(device.Routes, defaultRoute(subnet.Gateway))
+				}
+
+				for _, route := range subnet.Routes {
+					var dst string
+					if dst, err = talosnet.FormatCIDR(route.Network, route.Netmask); err != nil {
+						return nil, errors.Wrapf(err, "interface %q route", entry.Name)
+					}
+
+					device.Routes = append(device.Routes, userdata.Route{Network: dst, Gateway: route.Gateway})
+				}
+			default:
+				log.Printf("ignoring subnet of %q of unsupported type %q", entry.Name, subnet.Type)
+			}
+		}
+
+		if device.DHCP || device.CIDR != "" {
+			osNet.Devices = append(osNet.Devices, device)
+		}
+	}
+
+	return osNet, nil
+}
+
+func (n *NetworkConfig) osNetV2(interfaceName InterfaceNameFunc) (*userdata.OSNet, error) {
+	osNet := &userdata.OSNet{}
+
+	names := make(map[string]string, len(n.Ethernets))
+	bonded := make(map[string]bool)
+
+	for id, ethernet := range n.Ethernets {
+		name := id
+		switch {
+		case ethernet.SetName != "":
+			name = ethernet.SetName
+		case ethernet.Match.Name != "" && !strings.ContainsAny(ethernet.Match.Name, "*?["):
+			name = ethernet.Match.Name
+		}
+
+		names[id] = resolveName(interfaceName, ethernet.Match.MACAddress, name)
+	}
+
+	for _, bond := range n.Bonds {
+		for _, member := range bond.Interfaces {
+			bonded[member] = true
+		}
+	}
+
+	for _, id := range sortedKeys(n.Ethernets) {
+		if bonded[id] {
+			continue
+		}
+
+		device, err := osNetV2Device(osNet, names[id], n.Ethernets[id])
+		if err != nil {
+			return nil, err
+		}
+
+		if device.DHCP || device.CIDR != "" {
+			osNet.Devices = append(osNet.Devices, *device)
+		}
+	}
+
+	bondIDs := make([]string, 0, len(n.Bonds))
+	for id := range n.Bonds {
+		bondIDs = append(bondIDs, id)
+	}
+
+	sort.Strings(bondIDs)
+
+	for _, id := range bondIDs {
+		bond := n.Bonds[id]
+
+		device, err := osNetV2Device(osNet, id, bond.NetworkConfigV2Device)
+		if err != nil {
+			return nil, err
+		}
+
+		device.Bond = &userdata.Bond{
+			Mode:       bond.Parameters.Mode,
+			HashPolicy: bond.Parameters.TransmitHashPolicy,
+			LACPRate:   bond.Parameters.LACPRate,
+		}
+
+		for _, member := range bond.Interfaces {
+			if name, ok := names[member]; ok {
+				member = name
+			}
+
+			device.Bond.Interfaces = append(device.Bond.Interfaces, member)
+		}
+
+		if device.DHCP || device.CIDR != "" {
+			osNet.Devices = append(osNet.Devices, *device)
+		}
+	}
+
+	return osNet, nil
+}
+
+func osNetV2Device(osNet *userdata.OSNet, name string, ethernet NetworkConfigV2Device) (*userdata.Device, error) {
+	device := &userdata.Device{
+		Interface: name,
+		MTU:       ethernet.MTU,
+	}
+
+	osNet.Nameservers = appendUnique(osNet.Nameservers, ethernet.Nameservers.Addresses...)
+	if osNet.Domainname == "" && len(ethernet.Nameservers.Search) > 0 {
+		osNet.Domainname = ethernet.Nameservers.Search[0]
+	}
+
+	switch {
+	case ethernet.DHCP4 || ethernet.DHCP6:
+		device.DHCP = true
+	case len(ethernet.Addresses) > 0:
+		if len(ethernet.Addresses) > 1 {
+			log.Printf("ignoring extra addresses of %q", name)
+		}
+
+		cidr, err := talosnet.FormatCIDR(ethernet.Addresses[0], "")
+		if err != nil {
+			return nil, errors.Wrapf(err, "interface %q", name)
+		}
+
+		device.CIDR = cidr
+	}
+
+	for _, gateway := range []string{ethernet.Gateway4, ethernet.Gateway6} {
+		if gateway != "" {
+			device.Routes = append(device.Routes, defaultRoute(gateway))
+		}
+	}
+
+	for _, route := range ethernet.Routes {
+		dst := route.To
+		if dst == "default" {
+			dst = defaultRoute(route.Via).Network
+		}
+
+		device.Routes = append(device.Routes, userdata.Route{Network: dst, Gateway: route.Via})
+	}
+
+	return device, nil
+}
+
+func resolveName(interfaceName InterfaceNameFunc, mac, name string) string {
+	if mac == "" {
+		return name
+	}
+
+	resolved, err := interfaceName(mac)
+	if err != nil {
+		log.Printf("using interface name %q: %s", name, err)
+
+		return name
+	}
+
+	return resolved
+}
+
+func defaultRoute(gateway string) userdata.Route {
+	network := "0.0.0.0/0"
+	if ip := net.ParseIP(gateway); ip != nil && ip.To4() == nil {
+		network = "::/0"
+	}
+
+	return userdata.Route{Network: network, Gateway: gateway}
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			list = append(list, value)
+		}
+	}
+
+	return list
+}
+
+func sortedKeys(m map[string]NetworkConfigV2Device) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,237 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package nocloud implements the cloud-init NoCloud datasource.
+//
+// See https://cloudinit.readthedocs.io/en/latest/topics/datasources/nocloud.html.
+package nocloud
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/talos-systems/talos/pkg/userdata"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// KernelParamDataSource is the cloud-init kernel parameter which
+	// specifies the datasource, e.g. ds=nocloud-net;s=http://10.0.0.1/.
+	KernelParamDataSource = "ds"
+
+	// UserDataFile is the name of the user data file in the seed.
+	UserDataFile = "user-data"
+	// MetaDataFile is the name of the instance metadata file in the seed.
+	MetaDataFile = "meta-data"
+	// NetworkConfigFile is the name of the network configuration file in the
+	// seed.
+	NetworkConfigFile = "network-config"
+)
+
+// ErrNotFound is returned by the Source when the requested file is missing.
+var ErrNotFound = errors.New("not found")
+
+// Source provides access to the NoCloud seed files.
+type Source interface {
+	Fetch(name string) ([]byte, error)
+}
+
+// Directory reads the seed from the local directory, e.g. mounted cidata
+// volume.
+type Directory struct {
+	Root string
+}
+
+// Fetch implements the Source interface.
+func (d *Directory) Fetch(name string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(d.Root, name))
+	if os.IsNotExist(err) {
+		return nil, errors.Wrap(ErrNotFound, name)
+	}
+
+	return b, err
+}
+
+// Seed reads the seed from the HTTP(S) URL.
+type Seed struct {
+	// URL is the prefix of the seed files, e.g. http://10.0.0.1/seed/.
+	URL string
+}
+
+// Fetch implements the Source interface.
+func (s *Seed) Fetch(name string) ([]byte, error) {
+	url := s.URL
+	if !strings.HasSuffix(url, "/") {
+		url += "/"
+	}
+
+	url += name
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	// nolint: errcheck
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errors.Wrap(ErrNotFound, name)
+	default:
+		return nil, fmt.Errorf("failed to fetch %s from seed: %d", url, resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// DataSource is the parsed value of the ds= kernel parameter.
+type DataSource struct {
+	Name       string
+	SeedFrom   string
+	Hostname   string
+	InstanceID string
+}
+
+// ParseDataSource parses the value of the ds= kernel parameter, e.g.
+// "nocloud-net;s=http://10.0.0.1/;h=node-1".
+func ParseDataSource(ds string) (*DataSource, error) {
+	parts := strings.Split(ds, ";")
+
+	dataSource := &DataSource{
+		Name: parts[0],
+	}
+
+	if dataSource.Name != "nocloud" && dataSource.Name != "nocloud-net" {
+		return nil, errors.Errorf("unsupported datasource %q", dataSource.Name)
+	}
+
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("invalid datasource option %q", part)
+		}
+
+		switch kv[0] {
+		case "s", "seedfrom":
+			dataSource.SeedFrom = kv[1]
+		case "h", "local-hostname":
+			dataSource.Hostname = kv[1]
+		case "i", "instance-id":
+			dataSource.InstanceID = kv[1]
+		}
+	}
+
+	return dataSource, nil
+}
+
+// MetaData is the NoCloud meta-data document.
+type MetaData struct {
+	InstanceID    string `yaml:"instance-id"`
+	LocalHostname string `yaml:"local-hostname"`
+	SeedFrom      string `yaml:"seedfrom"`
+}
+
+// InterfaceNameFunc resolves name of the network interface by the MAC address.
+type InterfaceNameFunc func(mac string) (string, error)
+
+// Load reads the user data from the source and applies the hostname and the
+// network configuration found in the seed.
+//
+// If the meta-data specifies seedfrom, the seed is read from that URL
+// instead. Network configuration from the seed is used only if the user data
+// doesn't specify network devices explicitly.
+// nolint: gocyclo
+func Load(source Source, interfaceName InterfaceNameFunc) (data *userdata.UserData, err error) {
+	var metadata MetaData
+	if err = fetchYAML(source, MetaDataFile, &metadata); err != nil {
+		return nil, err
+	}
+
+	if _, remote := source.(*Seed); metadata.SeedFrom != "" && !remote {
+		source = &Seed{URL: metadata.SeedFrom}
+
+		if err = fetchYAML(source, MetaDataFile, &metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	var b []byte
+	if b, err = source.Fetch(UserDataFile); err != nil {
+		return nil, errors.Wrap(err, "failed to read user data")
+	}
+
+	if data, err = userdata.Decode(b); err != nil {
+		return nil, err
+	}
+
+	var networkConfig NetworkConfig
+	if err = fetchYAML(source, NetworkConfigFile, &networkConfig); err != nil {
+		return nil, err
+	}
+
+	var osNet *userdata.OSNet
+	if osNet, err = networkConfig.OSNet(interfaceName); err != nil {
+		return nil, errors.Wrap(err, "failed to translate network config")
+	}
+
+	// Stub out networking
+	if data.Networking == nil {
+		data.Networking = &userdata.Networking{}
+	}
+	if data.Networking.OS == nil {
+		data.Networking.OS = &userdata.OSNet{}
+	}
+
+	if len(data.Networking.OS.Devices) == 0 {
+		data.Networking.OS.Devices = osNet.Devices
+	}
+
+	if len(data.Networking.OS.Nameservers) == 0 {
+		data.Networking.OS.Nameservers = osNet.Nameservers
+	}
+
+	if data.Networking.OS.Domainname == "" {
+		data.Networking.OS.Domainname = osNet.Domainname
+	}
+
+	if data.Networking.OS.Hostname == "" && metadata.LocalHostname != "" {
+		parts := strings.SplitN(metadata.LocalHostname, ".", 2)
+		data.Networking.OS.Hostname = parts[0]
+
+		if len(parts) > 1 && data.Networking.OS.Domainname == "" {
+			data.Networking.OS.Domainname = parts[1]
+		}
+	}
+
+	return data, data.Validate()
+}
+
+// fetchYAML reads optional YAML document from the source.
+func fetchYAML(source Source, name string, v interface{}) error {
+	b, err := source.Fetch(name)
+	if err != nil {
+		if errors.Cause(err) == ErrNotFound {
+			return nil
+		}
+
+		return errors.Wrapf(err, "failed to read %s", name)
+	}
+
+	if err = yaml.Unmarshal(b, v); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal %s", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package nocloud_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/nocloud"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+type NoCloudSuite struct {
+	suite.Suite
+}
+
+func interfaceName(mac string) (string, error) {
+	names := map[string]string{
+		"52:54:00:12:34:00": "ens3",
+		"52:54:00:12:34:01": "ens4",
+	}
+
+	if name, ok := names[mac]; ok {
+		return name, nil
+	}
+
+	return "", errors.Errorf("unknown MAC %s", mac)
+}
+
+func (suite *NoCloudSuite) TestNetworkConfigV1() {
+	data, err := nocloud.Load(&nocloud.Directory{Root: "testdata/v1"}, interfaceName)
+	suite.Require().NoError(err)
+
+	osNet := data.Networking.OS
+
+	suite.Assert().Equal("talos-1", osNet.Hostname)
+	suite.Assert().Equal("example.org", osNet.Domainname)
+	suite.Assert().Equal([]string{"192.168.1.2", "8.8.8.8"}, osNet.Nameservers)
+	suite.Assert().Equal([]userdata.Device{
+		{
+			Interface: "ens3",
+			CIDR:      "192.168.1.10/24",
+			MTU:       1500,
+			Routes: []userdata.Route{
+				{Network: "0.0.0.0/0", Gateway: "192.168.1.1"},
+				{Network: "10.0.0.0/8", Gateway: "192.168.1.254"},
+			},
+		},
+		{
+			Interface: "bond0",
+			DHCP:      true,
+			Bond: &userdata.Bond{
+				Mode:       "802.3ad",
+				HashPolicy: "layer3+4",
+				LACPRate:   "fast",
+				Interfaces: []string{"ens4", "eth2"},
+			},
+		},
+	}, osNet.Devices)
+}
+
+func (suite *NoCloudSuite) TestNetworkConfigV2() {
+	data, err := nocloud.Load(&nocloud.Directory{Root: "testdata/v2"}, interfaceName)
+	suite.Require().NoError(err)
+
+	osNet := data.Networking.OS
+
+	suite.Assert().Equal("talos-2", osNet.Hostname)
+	suite.Assert().Equal("example.org", osNet.Domainname)
+	suite.Assert().Equal([]string{"192.168.1.2"}, osNet.Nameservers)
+	suite.Assert().Equal([]userdata.Device{
+		{
+			Interface: "ens3",
+			CIDR:      "192.168.1.10/24",
+			Routes: []userdata.Route{
+				{Network: "0.0.0.0/0", Gateway: "192.168.1.1"},
+				{Network: "10.0.0.0/8", Gateway: "192.168.1.254"},
+			},
+		},
+		{
+			Interface: "bond0",
+			DHCP:      true,
+			MTU:       9000,
+			Bond: &userdata.Bond{
+				Mode:       "802.3ad",
+				HashPolicy: "layer3+4",
+				LACPRate:   "fast",
+				Interfaces: []string{"ens4", "eth2"},
+			},
+		},
+	}, osNet.Devices)
+}
+
+func (suite *NoCloudSuite) TestSeed() {
+	ts := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer ts.Close()
+
+	data, err := nocloud.Load(&nocloud.Seed{URL: ts.URL + "/v1"}, interfaceName)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("talos-1", data.Networking.OS.Hostname)
+	suite.Assert().Len(data.Networking.OS.Devices, 2)
+
+	_, err = nocloud.Load(&nocloud.Seed{URL: ts.URL + "/nothing/"}, interfaceName)
+	suite.Require().Error(err)
+	suite.Assert().Equal(nocloud.ErrNotFound, errors.Cause(err))
+}
+
+func (suite *NoCloudSuite) TestSeedFrom() {
+	ts := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "talos")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(dir, nocloud.MetaDataFile), []byte("seedfrom: "+ts.URL+"/v2/\n"), 0644))
+
+	data, err := nocloud.Load(&nocloud.Directory{Root: dir}, interfaceName)
+	suite.Require().NoError(err)
+	suite.Assert().Equal("talos-2", data.Networking.OS.Hostname)
+}
+
+func (suite *NoCloudSuite) TestParseDataSource() {
+	ds, err := nocloud.ParseDataSource("nocloud-net;s=http://10.0.0.1:8000/seed/;h=node-1")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(&nocloud.DataSource{
+		Name:     "nocloud-net",
+		SeedFrom: "http://10.0.0.1:8000/seed/",
+		Hostname: "node-1",
+	}, ds)
+
+	ds, err = nocloud.ParseDataSource("nocloud")
+	suite.Require().NoError(err)
+	suite.Assert().Empty(ds.SeedFrom)
+
+	_, err = nocloud.ParseDataSource("ec2")
+	suite.Require().Error(err)
+}
+
+func (suite *NoCloudSuite) TestUnsupportedVersion() {
+	_, err := (&nocloud.NetworkConfig{Version: 3}).OSNet(interfaceName)
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), "unsupported network config version 3")
+}
+
+func TestNoCloudSuite(t *testing.T) {
+	suite.Run(t, new(NoCloudSuite))
+}
@@ -0,0 +1,210 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package openstack
+
+import (
+	"log"
+
+	"github.com/pkg/errors"
+
+	talosnet "github.com/talos-systems/talos/pkg/net"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+// NetworkData is the OpenStack network_data.json document.
+//
+// See https://docs.openstack.org/nova/latest/user/metadata.html#openstack-format-metadata.
+type NetworkData struct {
+	Links    []NetworkLink    `json:"links"`
+	Networks []Network        `json:"networks"`
+	Services []NetworkService `json:"services"`
+}
+
+// NetworkLink describes a network interface.
+type NetworkLink struct {
+	ID             string   `json:"id"`
+	Type           string   `json:"type"`
+	MAC            string   `json:"ethernet_mac_address"`
+	MTU            int      `json:"mtu"`
+	BondLinks      []string `json:"bond_links"`
+	BondMode       string   `json:"bond_mode"`
+	BondHashPolicy string   `json:"bond_xmit_hash_policy"`
+	VLANLink       string   `json:"vlan_link"`
+	VLANID         int      `json:"vlan_id"`
+}
+
+// Network describes addressing of the network interface.
+type Network struct {
+	ID        string           `json:"id"`
+	Type      string           `json:"type"`
+	Link      string           `json:"link"`
+	IPAddress string           `json:"ip_address"`
+	Netmask   string           `json:"netmask"`
+	Routes    []NetworkRoute   `json:"routes"`
+	Services  []NetworkService `json:"services"`
+}
+
+// NetworkRoute describes a route.
+type NetworkRoute struct {
+	Network string `json:"network"`
+	Netmask string `json:"netmask"`
+	Gateway string `json:"gateway"`
+}
+
+// NetworkService describes a network service, e.g. DNS.
+type NetworkService struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+}
+
+// InterfaceNameFunc resolves name of the network interface by the MAC address.
+type InterfaceNameFunc func(mac string) (string, error)
+
+// OSNet translates the network description into the userdata network
+// configuration.
+//
+// Physical links are named after the host interfaces with matching MAC
+// addresses, bond links keep the OpenStack link ID as the name. VLAN links
+// are skipped, as userdata doesn't support VLAN devices. Only the first
+// network of each link is used, as userdata supports a single address per
+// device.
+// nolint: gocyclo
+func (n *NetworkData) OSNet(interfaceName InterfaceNameFunc) (*userdata.OSNet, error) {
+	osNet := &userdata.OSNet{}
+
+	names := make(map[string]string, len(n.Links))
+	links := make(map[string]*NetworkLink, len(n.Links))
+	bonded := make(map[string]bool)
+
+	for i := range n.Links {
+		link := &n.Links[i]
+		links[link.ID] = link
+
+		switch link.Type {
+		case "bond":
+			names[link.ID] = link.ID
+		case "vlan":
+			log.Printf("ignoring link %q: VLAN %d on link %q is not supported", link.ID, link.VLANID, link.VLANLink)
+			continue
+		default:
+			name, err := interfaceName(link.MAC)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to resolve interface for link %q", link.ID)
+			}
+
+			names[link.ID] = name
+		}
+
+		for _, member := range link.BondLinks {
+			bonded[member] = true
+		}
+	}
+
+	devices := make(map[string]*userdata.Device)
+	order := []string{}
+
+	for _, network := range n.Networks {
+		link, ok := links[network.Link]
+		if !ok {
+			return nil, errors.Errorf("network %q refers to unknown link %q", network.ID, network.Link)
+		}
+
+		if bonded[link.ID] {
+			continue
+		}
+
+		if link.Type == "vlan" {
+			log.Printf("ignoring network %q on VLAN link %q", network.ID, link.ID)
+			continue
+		}
+
+		device, ok := devices[link.ID]
+		if !ok {
+			device = &userdata.Device{
+				Interface: names[link.ID],
+				MTU:       link.MTU,
+			}
+
+			if link.Type == "bond" {
+				device.Bond = &userdata.Bond{
+					Mode:       link.BondMode,
+					HashPolicy: link.BondHashPolicy,
+				}
+
+				for _, member := range link.BondLinks {
+					device.Bond.Interfaces = append(device.Bond.Interfaces, names[member])
+				}
+			}
+
+			devices[link.ID] = device
+			order = append(order, link.ID)
+		}
+
+		osNet.Nameservers = appendNameservers(osNet.Nameservers, network.Services)
+
+		if device.DHCP || device.CIDR != "" {
+			log.Printf("ignoring network %q, link %q is already configured", network.ID, link.ID)
+			continue
+		}
+
+		switch network.Type {
+		case "ipv4_dhcp", "ipv6_dhcp":
+			device.DHCP = true
+		case "ipv4", "ipv6":
+			cidr, err := talosnet.FormatCIDR(network.IPAddress, network.Netmask)
+			if err != nil {
+				return nil, errors.Wrapf(err, "network %q", network.ID)
+			}
+
+			device.CIDR = cidr
+
+			for _, route := range network.Routes {
+				var dst string
+				if dst, err = talosnet.FormatCIDR(route.Network, route.Netmask); err != nil {
+					return nil, errors.Wrapf(err, "network %q route", network.ID)
+				}
+
+				device.Routes = append(device.Routes, userdata.Route{
+					Network: dst,
+					Gateway: route.Gateway,
+				})
+			}
+		default:
+			log.Printf("ignoring network %q of unsupported type %q", network.ID, network.Type)
+		}
+	}
+
+	osNet.Nameservers = appendNameservers(osNet.Nameservers, n.Services)
+
+	for _, id := range order {
+		if devices[id].DHCP || devices[id].CIDR != "" {
+			osNet.Devices = append(osNet.Devices, *devices[id])
+		}
+	}
+
+	return osNet, nil
+}
+
+func appendNameservers(nameservers []string, services []NetworkService) []string {
+	for _, service := range services {
+		if service.Type != "dns" {
+			continue
+		}
+
+		found := false
+		for _, nameserver := range nameservers {
+			if nameserver == service.Address {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			nameservers = append(nameservers, service.Address)
+		}
+	}
+
+	return nameservers
+}
@@ -0,0 +1,217 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package openstack
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
+	"github.com/talos-systems/talos/internal/pkg/mount"
+	"github.com/talos-systems/talos/internal/pkg/mount/manager"
+	"github.com/talos-systems/talos/internal/pkg/mount/manager/owned"
+	"github.com/talos-systems/talos/internal/pkg/network"
+	"github.com/talos-systems/talos/pkg/blockdevice/probe"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+const (
+	// OpenStackMetadataEndpoint is the local endpoint of the OpenStack
+	// metadata service.
+	OpenStackMetadataEndpoint = "http://169.254.169.254/openstack/latest"
+	// ConfigDriveLabel is the file system label of the OpenStack config drive.
+	ConfigDriveLabel = "config-2"
+
+	// UserDataFile is the name of the user data file in the metadata.
+	UserDataFile = "user_data"
+	// MetaDataFile is the name of the instance metadata file in the metadata.
+	MetaDataFile = "meta_data.json"
+	// NetworkDataFile is the name of the network configuration file in the
+	// metadata.
+	NetworkDataFile = "network_data.json"
+
+	mnt = "/mnt"
+)
+
+// OpenStack is the concrete type that implements the platform.Platform interface.
+type OpenStack struct{}
+
+// Name implements the platform.Platform interface.
+func (o *OpenStack) Name() string {
+	return "OpenStack"
+}
+
+// UserData implements the platform.Platform interface.
+//
+// Config drive takes precedence over the metadata service.
+func (o *OpenStack) UserData() (data *userdata.UserData, err error) {
+	err = withSource(func(source Source) (loadErr error) {
+		data, loadErr = Load(source, network.InterfaceByMAC)
+
+		return loadErr
+	})
+
+	return data, err
+}
+
+// Initialize implements the platform.Platform interface and handles additional system setup.
+// nolint: dupl
+func (o *OpenStack) Initialize(data *userdata.UserData) (err error) {
+	var mountpoints *mount.Points
+	mountpoints, err = owned.MountPointsFromLabels(data)
+	if err != nil {
+		return err
+	}
+
+	m := manager.NewManager(mountpoints)
+	if err = m.MountAll(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Metadata implements the platform.Platform interface.
+//
+// OpenStack doesn't report the region of the instance.
+func (o *OpenStack) Metadata() (m *metadata.Metadata, err error) {
+	var md MetaData
+
+	if err = withSource(func(source Source) error {
+		return fetchJSON(source, MetaDataFile, &md)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &metadata.Metadata{
+		Platform:   o.Name(),
+		Hostname:   strings.Split(md.Hostname, ".")[0],
+		InstanceID: md.UUID,
+		Zone:       md.AvailabilityZone,
+	}, nil
+}
+
+// MetaData is the subset of the OpenStack meta_data.json document used by Talos.
+type MetaData struct {
+	UUID             string `json:"uuid"`
+	Hostname         string `json:"hostname"`
+	Name             string `json:"name"`
+	AvailabilityZone string `json:"availability_zone"`
+}
+
+// Load reads the user data from the source and applies the hostname and
+// the network configuration found in the metadata.
+//
+// Network configuration from the metadata is used only if the user data
+// doesn't specify network devices explicitly.
+// nolint: gocyclo
+func Load(source Source, interfaceName InterfaceNameFunc) (data *userdata.UserData, err error) {
+	var b []byte
+	if b, err = source.Fetch(UserDataFile); err != nil {
+		return nil, errors.Wrap(err, "failed to read user data")
+	}
+
+	if data, err = userdata.Decode(b); err != nil {
+		return nil, err
+	}
+
+	var metadata MetaData
+	if err = fetchJSON(source, MetaDataFile, &metadata); err != nil {
+		return nil, err
+	}
+
+	var networkData NetworkData
+	if err = fetchJSON(source, NetworkDataFile, &networkData); err != nil {
+		return nil, err
+	}
+
+	var osNet *userdata.OSNet
+	if osNet, err = networkData.OSNet(interfaceName); err != nil {
+		return nil, errors.Wrap(err, "failed to translate network data")
+	}
+
+	// Stub out networking
+	if data.Networking == nil {
+		data.Networking = &userdata.Networking{}
+	}
+	if data.Networking.OS == nil {
+		data.Networking.OS = &userdata.OSNet{}
+	}
+
+	if len(data.Networking.OS.Devices) == 0 {
+		data.Networking.OS.Devices = osNet.Devices
+	}
+
+	if len(data.Networking.OS.Nameservers) == 0 {
+		data.Networking.OS.Nameservers = osNet.Nameservers
+	}
+
+	if data.Networking.OS.Hostname == "" {
+		// OpenStack hostnames might be FQDNs, e.g. "node-1.novalocal"
+		data.Networking.OS.Hostname = strings.Split(metadata.Hostname, ".")[0]
+	}
+
+	return data, data.Validate()
+}
+
+// fetchJSON reads optional JSON document from the source.
+func fetchJSON(source Source, name string, v interface{}) error {
+	b, err := source.Fetch(name)
+	if err != nil {
+		if errors.Cause(err) == ErrNotFound {
+			return nil
+		}
+
+		return errors.Wrapf(err, "failed to read %s", name)
+	}
+
+	if err = json.Unmarshal(b, v); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal %s", name)
+	}
+
+	return nil
+}
+
+// withSource calls f with the config drive as the source, falling back to
+// the metadata service if the config drive is not available.
+func withSource(f func(Source) error) error {
+	unmount, err := mountConfigDrive()
+	if err != nil {
+		log.Printf("config drive is not available, using metadata service: %v", err)
+
+		return f(&MetadataService{Endpoint: OpenStackMetadataEndpoint})
+	}
+
+	// nolint: errcheck
+	defer unmount()
+
+	return f(&ConfigDrive{Root: mnt})
+}
+
+// mountConfigDrive finds the config drive by the file system label and
+// mounts it read-only.
+func mountConfigDrive() (unmount func() error, err error) {
+	var dev *probe.ProbedBlockDevice
+	if dev, err = probe.GetDevWithFileSystemLabel(ConfigDriveLabel); err != nil {
+		return nil, errors.Errorf("failed to find %s config drive: %v", ConfigDriveLabel, err)
+	}
+
+	if err = os.MkdirAll(mnt, 0700); err != nil {
+		return nil, errors.Errorf("failed to mkdir: %v", err)
+	}
+
+	if err = unix.Mount(dev.Path, mnt, dev.SuperBlock.Type(), unix.MS_RDONLY, ""); err != nil {
+		return nil, errors.Errorf("failed to mount config drive: %v", err)
+	}
+
+	return func() error {
+		return unix.Unmount(mnt, 0)
+	}, nil
+}
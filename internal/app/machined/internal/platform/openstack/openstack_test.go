@@ -0,0 +1,228 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package openstack_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/openstack"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/iso9660"
+	"github.com/talos-systems/talos/pkg/blockdevice/probe"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+type OpenStackSuite struct {
+	suite.Suite
+}
+
+func interfaceName(mac string) (string, error) {
+	names := map[string]string{
+		"fa:16:3e:00:00:01": "eth0",
+		"fa:16:3e:00:00:02": "eth1",
+		"fa:16:3e:00:00:03": "eth2",
+	}
+
+	if name, ok := names[mac]; ok {
+		return name, nil
+	}
+
+	return "", errors.Errorf("unknown MAC %s", mac)
+}
+
+func (suite *OpenStackSuite) assertUserData(data *userdata.UserData) {
+	suite.Require().NotNil(data.Networking)
+	suite.Require().NotNil(data.Networking.OS)
+
+	osNet := data.Networking.OS
+
+	suite.Assert().Equal("talos-1", osNet.Hostname)
+	suite.Assert().Equal([]string{"10.0.0.2", "8.8.8.8"}, osNet.Nameservers)
+	suite.Assert().Equal([]userdata.Device{
+		{
+			Interface: "eth0",
+			CIDR:      "10.0.0.5/24",
+			MTU:       1450,
+			Routes: []userdata.Route{
+				{Network: "0.0.0.0/0", Gateway: "10.0.0.1"},
+			},
+		},
+		{
+			Interface: "bond0",
+			DHCP:      true,
+			MTU:       9000,
+			Bond: &userdata.Bond{
+				Mode:       "802.3ad",
+				HashPolicy: "layer3+4",
+				Interfaces: []string{"eth1", "eth2"},
+			},
+		},
+	}, osNet.Devices)
+}
+
+func (suite *OpenStackSuite) TestConfigDrive() {
+	data, err := openstack.Load(&openstack.ConfigDrive{Root: "testdata/config-drive"}, interfaceName)
+	suite.Require().NoError(err)
+
+	suite.assertUserData(data)
+}
+
+func (suite *OpenStackSuite) TestMetadataService() {
+	ts := httptest.NewServer(http.FileServer(http.Dir("testdata/config-drive")))
+	defer ts.Close()
+
+	data, err := openstack.Load(&openstack.MetadataService{Endpoint: ts.URL + "/openstack/latest"}, interfaceName)
+	suite.Require().NoError(err)
+
+	suite.assertUserData(data)
+}
+
+func (suite *OpenStackSuite) TestMetadataServiceNoNetworkData() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openstack/latest/user_data" {
+			http.NotFound(w, r)
+			return
+		}
+
+		http.ServeFile(w, r, "testdata/config-drive/openstack/latest/user_data")
+	}))
+	defer ts.Close()
+
+	data, err := openstack.Load(&openstack.MetadataService{Endpoint: ts.URL + "/openstack/latest"}, interfaceName)
+	suite.Require().NoError(err)
+	suite.Assert().Empty(data.Networking.OS.Devices)
+	suite.Assert().Empty(data.Networking.OS.Hostname)
+
+	_, err = openstack.Load(&openstack.MetadataService{Endpoint: ts.URL + "/nothing"}, interfaceName)
+	suite.Require().Error(err)
+	suite.Assert().Equal(openstack.ErrNotFound, errors.Cause(err))
+}
+
+func (suite *OpenStackSuite) TestNetworkDataUnknownLink() {
+	networkData := openstack.NetworkData{
+		Networks: []openstack.Network{
+			{ID: "network0", Type: "ipv4_dhcp", Link: "tap0"},
+		},
+	}
+
+	_, err := networkData.OSNet(interfaceName)
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), `network "network0" refers to unknown link "tap0"`)
+}
+
+func (suite *OpenStackSuite) TestConfigDriveImage() {
+	dir, err := ioutil.TempDir("", "talos")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	in, err := os.Open("testdata/config-2.iso.gz")
+	suite.Require().NoError(err)
+	defer in.Close() // nolint: errcheck
+
+	gz, err := gzip.NewReader(in)
+	suite.Require().NoError(err)
+
+	path := filepath.Join(dir, "config-2.iso")
+
+	out, err := os.Create(path)
+	suite.Require().NoError(err)
+
+	_, err = io.Copy(out, gz)
+	suite.Require().NoError(err)
+	suite.Require().NoError(out.Close())
+
+	sb, err := probe.FileSystem(path)
+	suite.Require().NoError(err)
+	suite.Require().IsType(&iso9660.SuperBlock{}, sb)
+	suite.Assert().Equal(openstack.ConfigDriveLabel, string(bytes.Trim(sb.(*iso9660.SuperBlock).VolumeID[:], " \x00")))
+
+	// extract the image the way the kernel presents it once mounted
+	root := filepath.Join(dir, "mnt")
+	suite.Require().NoError(extractISO(path, root))
+
+	data, err := openstack.Load(&openstack.ConfigDrive{Root: root}, interfaceName)
+	suite.Require().NoError(err)
+
+	suite.assertUserData(data)
+}
+
+// extractISO copies the files of the ISO 9660 image into the directory,
+// mapping the names as the Linux isofs driver does by default.
+func extractISO(path, root string) error {
+	const sectorSize = 2048
+
+	image, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var walk func(record []byte, dir string) error
+
+	walk = func(record []byte, dir string) error {
+		extent := binary.LittleEndian.Uint32(record[2:6]) * sectorSize
+		size := binary.LittleEndian.Uint32(record[10:14])
+
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+
+		for offset := extent; offset < extent+size; {
+			length := uint32(image[offset])
+			if length == 0 {
+				// records don't span sectors, skip to the next one
+				offset = (offset/sectorSize + 1) * sectorSize
+				continue
+			}
+
+			entry := image[offset : offset+length]
+			offset += length
+
+			name := string(entry[33 : 33+entry[32]])
+			if name == "\x00" || name == "\x01" {
+				continue
+			}
+
+			name = strings.ToLower(strings.TrimSuffix(name, ";1"))
+
+			if entry[25]&0x02 != 0 {
+				if err := walk(entry, filepath.Join(dir, name)); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			start := binary.LittleEndian.Uint32(entry[2:6]) * sectorSize
+			end := start + binary.LittleEndian.Uint32(entry[10:14])
+
+			if err := ioutil.WriteFile(filepath.Join(dir, name), image[start:end], 0600); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	// root directory record of the primary volume descriptor
+	pvd := image[16*sectorSize:]
+
+	return walk(pvd[156:190], root)
+}
+
+func TestOpenStackSuite(t *testing.T) {
+	suite.Run(t, new(OpenStackSuite))
+}
@@ -0,0 +1,72 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package openstack
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned by the Source when the requested file is missing.
+var ErrNotFound = errors.New("not found")
+
+// Source provides access to the OpenStack metadata files.
+type Source interface {
+	Fetch(name string) ([]byte, error)
+}
+
+// ConfigDrive reads the metadata from the mounted config drive.
+type ConfigDrive struct {
+	// Root is the mount point of the config drive.
+	Root string
+}
+
+// Fetch implements the Source interface.
+func (c *ConfigDrive) Fetch(name string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(c.Root, "openstack", "latest", name))
+	if os.IsNotExist(err) {
+		return nil, errors.Wrap(ErrNotFound, name)
+	}
+
+	return b, err
+}
+
+// MetadataService reads the metadata from the OpenStack metadata service.
+type MetadataService struct {
+	// Endpoint is the URL of the latest metadata version, e.g.
+	// http://169.254.169.254/openstack/latest.
+	Endpoint string
+}
+
+// Fetch implements the Source interface.
+func (m *MetadataService) Fetch(name string) ([]byte, error) {
+	url := strings.TrimSuffix(m.Endpoint, "/") + "/" + name
+
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	// nolint: errcheck
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, errors.Wrap(ErrNotFound, name)
+	default:
+		return nil, fmt.Errorf("failed to fetch %s from metadata service: %d", name, resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	"github.com/pkg/errors"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
 	"github.com/talos-systems/talos/internal/pkg/installer"
 	"github.com/talos-systems/talos/internal/pkg/kernel"
 	"github.com/talos-systems/talos/internal/pkg/mount"
@@ -20,6 +21,9 @@ import (
 const (
 	// PacketUserDataEndpoint is the local metadata endpoint for Packet.
 	PacketUserDataEndpoint = "https://metadata.packet.net/userdata"
+	// PacketMetadataEndpoint is the local metadata endpoint for the instance
+	// metadata.
+	PacketMetadataEndpoint = "https://metadata.packet.net/metadata"
 )
 
 // Packet is a discoverer for non-cloud environments.
@@ -56,7 +60,7 @@ func (p *Packet) Initialize(data *userdata.UserData) (err error) {
 	// An err case should only happen if no partitions
 	// with matching labels were found
 	var mountpoints *mount.Points
-	mountpoints, err = owned.MountPointsFromLabels()
+	mountpoints, err = owned.MountPointsFromLabels(data)
 	if err != nil {
 		// No previous installation was found, attempt an install
 		var i *installer.Installer
@@ -68,7 +72,7 @@ func (p *Packet) Initialize(data *userdata.UserData) (err error) {
 			return errors.Wrap(err, "failed to install")
 		}
 
-		mountpoints, err = owned.MountPointsFromLabels()
+		mountpoints, err = owned.MountPointsFromLabels(data)
 		if err != nil {
 			return err
 		}
@@ -81,3 +85,48 @@ func (p *Packet) Initialize(data *userdata.UserData) (err error) {
 
 	return nil
 }
+
+// InstanceMetadata is the subset of the Packet instance metadata used by
+// Talos.
+type InstanceMetadata struct {
+	ID       string `json:"id"`
+	Hostname string `json:"hostname"`
+	Plan     string `json:"plan"`
+	Facility string `json:"facility"`
+	Network  struct {
+		Addresses []struct {
+			Address string `json:"address"`
+			Public  bool   `json:"public"`
+		} `json:"addresses"`
+	} `json:"network"`
+}
+
+// Metadata implements the platform.Platform interface.
+//
+// Packet facilities are not split into zones, so the facility is reported
+// both as the region and the zone.
+func (p *Packet) Metadata() (*metadata.Metadata, error) {
+	var instance InstanceMetadata
+	if err := metadata.FetchJSON(PacketMetadataEndpoint, nil, &instance); err != nil {
+		return nil, err
+	}
+
+	m := &metadata.Metadata{
+		Platform:     p.Name(),
+		Hostname:     instance.Hostname,
+		InstanceID:   instance.ID,
+		InstanceType: instance.Plan,
+		Region:       instance.Facility,
+		Zone:         instance.Facility,
+	}
+
+	for _, address := range instance.Network.Addresses {
+		if address.Public {
+			m.PublicIPs = append(m.PublicIPs, address.Address)
+		} else {
+			m.PrivateIPs = append(m.PrivateIPs, address.Address)
+		}
+	}
+
+	return m, nil
+}
@@ -6,6 +6,7 @@ package platform
 
 import (
 	"os"
+	"sync"
 
 	"github.com/pkg/errors"
 	"github.com/talos-systems/talos/internal/app/machined/internal/platform/aws"
@@ -14,6 +15,8 @@ import (
 	"github.com/talos-systems/talos/internal/app/machined/internal/platform/container"
 	"github.com/talos-systems/talos/internal/app/machined/internal/platform/googlecloud"
 	"github.com/talos-systems/talos/internal/app/machined/internal/platform/iso"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/openstack"
 	"github.com/talos-systems/talos/internal/app/machined/internal/platform/packet"
 	"github.com/talos-systems/talos/internal/app/machined/internal/platform/vmware"
 	"github.com/talos-systems/talos/internal/pkg/kernel"
@@ -26,25 +29,43 @@ type Platform interface {
 	Name() string
 	UserData() (*userdata.UserData, error)
 	Initialize(*userdata.UserData) error
+	Metadata() (*metadata.Metadata, error)
+}
+
+// IdentityProvider is implemented by the platforms which can prove the
+// identity of the machine to trustd, e.g. with the signed instance identity
+// document.
+type IdentityProvider interface {
+	InstanceIdentity() ([]byte, error)
 }
 
 // NewPlatform is a helper func for discovering the current platform.
 //
-// nolint: gocyclo
+// The platform is taken from the talos.platform kernel parameter or the
+// PLATFORM environment variable. If neither is set, the platform is detected
+// automatically, see Detect.
 func NewPlatform() (p Platform, err error) {
-	var platform string
+	var detection *Detection
+
 	if p := kernel.ProcCmdline().Get(constants.KernelParamPlatform).First(); p != nil {
-		platform = *p
+		detection = &Detection{Platform: *p, Source: "kernel parameter " + constants.KernelParamPlatform}
 	}
 
 	if p, ok := os.LookupEnv("PLATFORM"); ok {
-		platform = p
+		detection = &Detection{Platform: p, Source: "environment variable PLATFORM"}
 	}
 
-	if platform == "" {
-		return nil, errors.New("failed to determine platform")
+	if detection == nil {
+		detection = Detect()
 	}
 
+	setLastDetection(detection)
+
+	return newPlatform(detection.Platform)
+}
+
+// nolint: gocyclo
+func newPlatform(platform string) (p Platform, err error) {
 	switch platform {
 	case "aws":
 		p = &aws.AWS{}
@@ -58,6 +79,8 @@ func NewPlatform() (p Platform, err error) {
 		p = &googlecloud.GoogleCloud{}
 	case "iso":
 		p = &iso.ISO{}
+	case "openstack":
+		p = &openstack.OpenStack{}
 	case "packet":
 		p = &packet.Packet{}
 	case "vmware":
@@ -68,3 +91,36 @@ func NewPlatform() (p Platform, err error) {
 
 	return p, nil
 }
+
+var (
+	metadataMu     sync.Mutex
+	cachedMetadata *metadata.Metadata
+)
+
+// Metadata returns the metadata reported by the current platform. The
+// metadata doesn't change while the machine runs, so it is fetched once and
+// kept after the first successful fetch. A failed fetch is not kept, the next
+// call fetches the metadata again.
+func Metadata() (*metadata.Metadata, error) {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+
+	if cachedMetadata == nil {
+		p, err := NewPlatform()
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := p.Metadata()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch %s metadata", p.Name())
+		}
+
+		cachedMetadata = m
+	}
+
+	// The callers might fill in the missing fields.
+	m := *cachedMetadata
+
+	return &m, nil
+}
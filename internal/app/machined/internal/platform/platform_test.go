@@ -0,0 +1,55 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package platform
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MetadataSuite struct {
+	suite.Suite
+}
+
+func (suite *MetadataSuite) SetupTest() {
+	cachedMetadata = nil
+}
+
+func (suite *MetadataSuite) TearDownTest() {
+	cachedMetadata = nil
+
+	suite.Require().NoError(os.Unsetenv("PLATFORM"))
+}
+
+// TestMetadataCached checks that the metadata is fetched once it was fetched
+// successfully, and that a failed fetch is retried.
+func (suite *MetadataSuite) TestMetadataCached() {
+	suite.Require().NoError(os.Setenv("PLATFORM", "unknown"))
+
+	_, err := Metadata()
+	suite.Require().Error(err)
+
+	suite.Require().NoError(os.Setenv("PLATFORM", "container"))
+
+	m, err := Metadata()
+	suite.Require().NoError(err)
+	suite.Assert().Equal("Container", m.Platform)
+
+	// The caller's changes don't leak into the cache.
+	m.Hostname = "worker-1"
+
+	suite.Require().NoError(os.Setenv("PLATFORM", "unknown"))
+
+	m, err = Metadata()
+	suite.Require().NoError(err)
+	suite.Assert().Equal("Container", m.Platform)
+	suite.Assert().Empty(m.Hostname)
+}
+
+func TestMetadataSuite(t *testing.T) {
+	suite.Run(t, new(MetadataSuite))
+}
@@ -7,7 +7,10 @@ package vmware
 import (
 	"encoding/base64"
 	"fmt"
+	"io/ioutil"
+	"strings"
 
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
 	"github.com/talos-systems/talos/internal/pkg/kernel"
 	"github.com/talos-systems/talos/internal/pkg/mount"
 	"github.com/talos-systems/talos/internal/pkg/mount/manager"
@@ -16,10 +19,11 @@ import (
 	"github.com/talos-systems/talos/pkg/userdata"
 	"github.com/vmware/vmw-guestinfo/rpcvmx"
 	"github.com/vmware/vmw-guestinfo/vmcheck"
-
-	yaml "gopkg.in/yaml.v2"
 )
 
+// productUUIDPath is the path to the BIOS UUID of the virtual machine.
+const productUUIDPath = "/sys/class/dmi/id/product_uuid"
+
 // VMware is the concrete type that implements the platform.Platform interface.
 type VMware struct{}
 
@@ -60,8 +64,8 @@ func (vmw *VMware) UserData() (data *userdata.UserData, err error) {
 			return data, fmt.Errorf("failed to decode guestinfo.%s: %v", constants.VMwareGuestInfoUserDataKey, err)
 		}
 
-		if err = yaml.Unmarshal(b, &data); err != nil {
-			return data, fmt.Errorf("unmarshal user data: %s", err.Error())
+		if data, err = userdata.Decode(b); err != nil {
+			return data, err
 		}
 	}
 
@@ -71,7 +75,7 @@ func (vmw *VMware) UserData() (data *userdata.UserData, err error) {
 // Initialize implements the platform.Platform interface and handles additional system setup.
 func (vmw *VMware) Initialize(data *userdata.UserData) (err error) {
 	var mountpoints *mount.Points
-	mountpoints, err = owned.MountPointsFromLabels()
+	mountpoints, err = owned.MountPointsFromLabels(data)
 	if err != nil {
 		return err
 	}
@@ -80,3 +84,41 @@ func (vmw *VMware) Initialize(data *userdata.UserData) (err error) {
 
 	return m.MountAll()
 }
+
+// Metadata implements the platform.Platform interface.
+//
+// vSphere doesn't describe the topology of the virtual machine, so the
+// region and the zone are read from the guestinfo, if set.
+func (vmw *VMware) Metadata() (*metadata.Metadata, error) {
+	ok, err := vmcheck.IsVirtualWorld()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("not a virtual world")
+	}
+
+	m := &metadata.Metadata{
+		Platform: vmw.Name(),
+	}
+
+	uuid, err := ioutil.ReadFile(productUUIDPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read instance ID: %v", err)
+	}
+
+	m.InstanceID = strings.TrimSpace(string(uuid))
+
+	config := rpcvmx.NewConfig()
+
+	if m.Region, err = config.String(constants.VMwareGuestInfoRegionKey, ""); err != nil {
+		return nil, fmt.Errorf("failed to get guestinfo.%s: %v", constants.VMwareGuestInfoRegionKey, err)
+	}
+
+	if m.Zone, err = config.String(constants.VMwareGuestInfoZoneKey, ""); err != nil {
+		return nil, fmt.Errorf("failed to get guestinfo.%s: %v", constants.VMwareGuestInfoZoneKey, err)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,178 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package reset
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/luks"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/swap"
+	"github.com/talos-systems/talos/pkg/constants"
+)
+
+const mapperPrefix = "/dev/mapper/"
+
+// mountEntry is a line of /proc/self/mounts.
+type mountEntry struct {
+	source  string
+	target  string
+	options string
+}
+
+// release turns off the swap areas, unmounts the file systems which might be
+// backed by the devices in the reverse order of the mounts, and closes the
+// LUKS mappings, so that nothing is written to the devices as they are wiped.
+func release(devices []string) error {
+	if err := swapoff(); err != nil {
+		return err
+	}
+
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return err
+	}
+	// nolint: errcheck
+	defer f.Close()
+
+	entries, err := parseMounts(f)
+	if err != nil {
+		return err
+	}
+
+	mappings := []string{}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if !releasable(entry, devices) {
+			continue
+		}
+
+		if err = unmount(entry.target); err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(entry.source, mapperPrefix) {
+			mappings = append(mappings, strings.TrimPrefix(entry.source, mapperPrefix))
+		}
+	}
+
+	for _, name := range mappings {
+		if err = luks.Close(name); err != nil {
+			return errors.Wrapf(err, "failed to close the mapping %s", name)
+		}
+	}
+
+	return nil
+}
+
+// releasable reports whether the mount might be backed by the devices: the
+// partitions of the devices and the LUKS mappings, the mounts under /var and
+// /boot, and the overlays with the layers on /var.
+func releasable(entry mountEntry, devices []string) bool {
+	for _, dev := range devices {
+		if onDevice(entry.source, dev) {
+			return true
+		}
+	}
+
+	if strings.HasPrefix(entry.source, mapperPrefix) {
+		return true
+	}
+
+	for _, dir := range []string{constants.EphemeralMountPoint, constants.BootMountPoint} {
+		if entry.target == dir || strings.HasPrefix(entry.target, dir+"/") {
+			return true
+		}
+	}
+
+	return strings.Contains(entry.options, "="+constants.EphemeralMountPoint+"/")
+}
+
+// onDevice reports whether the path is the device or one of its partitions.
+func onDevice(path, dev string) bool {
+	if path == dev {
+		return true
+	}
+
+	suffix := strings.TrimPrefix(strings.TrimPrefix(path, dev), "p")
+	if !strings.HasPrefix(path, dev) || suffix == "" {
+		return false
+	}
+
+	for _, c := range suffix {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseMounts(r io.Reader) (entries []mountEntry, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+
+		entries = append(entries, mountEntry{source: fields[0], target: fields[1], options: fields[3]})
+	}
+
+	return entries, scanner.Err()
+}
+
+// unmount unmounts the target, the file systems still in use, e.g. by the
+// log of the API service, are detached.
+func unmount(target string) error {
+	err := unix.Unmount(target, 0)
+	if err == unix.EBUSY {
+		log.Printf("%s is busy, detaching", target)
+
+		err = unix.Unmount(target, unix.MNT_DETACH)
+	}
+
+	if err != nil && err != unix.EINVAL {
+		return errors.Wrapf(err, "failed to unmount %s", target)
+	}
+
+	return nil
+}
+
+// swapoff turns off the swap areas on the disks, the zram devices are left as
+// is.
+func swapoff() error {
+	f, err := os.Open("/proc/swaps")
+	if err != nil {
+		return err
+	}
+	// nolint: errcheck
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// Skip the header.
+	scanner.Scan()
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(filepath.Base(fields[0]), "zram") {
+			continue
+		}
+
+		if err = swap.Swapoff(fields[0]); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
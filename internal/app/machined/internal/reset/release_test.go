@@ -0,0 +1,56 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package reset
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ReleaseSuite struct {
+	suite.Suite
+}
+
+func TestReleaseSuite(t *testing.T) {
+	suite.Run(t, new(ReleaseSuite))
+}
+
+func (suite *ReleaseSuite) TestReleasable() {
+	mounts := `rootfs / rootfs rw 0 0
+/dev/loop0 / squashfs ro 0 0
+tmpfs /run tmpfs rw 0 0
+/dev/sda3 /boot xfs rw 0 0
+/dev/mapper/luks-ephemeral /var xfs rw 0 0
+overlay /etc/kubernetes overlay rw,lowerdir=/etc/kubernetes,upperdir=/var/system/overlays/etc-kubernetes-diff,workdir=/var/system/overlays/etc-kubernetes-workdir 0 0
+shm /var/run/containerd/shm tmpfs rw 0 0
+/dev/nvme0n1p1 /var/mnt/extra xfs rw 0 0
+/dev/sdb1 /opt xfs rw 0 0
+/dev/sda10 /srv xfs rw 0 0
+`
+
+	entries, err := parseMounts(strings.NewReader(mounts))
+	suite.Require().NoError(err)
+	suite.Require().Len(entries, 10)
+
+	released := []string{}
+
+	for _, entry := range entries {
+		if releasable(entry, []string{"/dev/sda", "/dev/nvme0n1"}) {
+			released = append(released, entry.target)
+		}
+	}
+
+	suite.Assert().Equal([]string{"/boot", "/var", "/etc/kubernetes", "/var/run/containerd/shm", "/var/mnt/extra", "/srv"}, released)
+}
+
+func (suite *ReleaseSuite) TestOnDevice() {
+	suite.Assert().True(onDevice("/dev/sda", "/dev/sda"))
+	suite.Assert().True(onDevice("/dev/sda1", "/dev/sda"))
+	suite.Assert().True(onDevice("/dev/nvme0n1p2", "/dev/nvme0n1"))
+	suite.Assert().False(onDevice("/dev/sdab1", "/dev/sda"))
+	suite.Assert().False(onDevice("/dev/sdb1", "/dev/sda"))
+}
@@ -0,0 +1,110 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package reset wipes the install devices of the node once the services are
+// stopped and the file systems are unmounted.
+package reset
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/event"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/conditions"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/goroutine"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/recorder"
+	"github.com/talos-systems/talos/internal/pkg/installer"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+// apiServiceID is the service kept running during the wipe, so that the
+// progress can be followed in the events of the wipe service.
+const apiServiceID = "machined-api"
+
+// logPath keeps the log of the service on tmpfs, as /var is unmounted before
+// the wipe.
+const logPath = "/run/system"
+
+// Wipe implements the Service interface. It stops the other services,
+// unmounts the file systems on the devices, wipes the devices and reboots the
+// node. The progress is recorded as the events of the service.
+type Wipe struct {
+	Devices []string
+	Mode    string
+}
+
+// ID implements the Service interface.
+func (w *Wipe) ID(data *userdata.UserData) string {
+	return "wipe"
+}
+
+// PreFunc implements the Service interface.
+func (w *Wipe) PreFunc(ctx context.Context, data *userdata.UserData) error {
+	return nil
+}
+
+// PostFunc implements the Service interface.
+func (w *Wipe) PostFunc(data *userdata.UserData) (err error) {
+	return nil
+}
+
+// Condition implements the Service interface.
+func (w *Wipe) Condition(data *userdata.UserData) conditions.Condition {
+	return nil
+}
+
+// DependsOn implements the Service interface.
+func (w *Wipe) DependsOn(data *userdata.UserData) []string {
+	return nil
+}
+
+// Runner implements the Service interface.
+func (w *Wipe) Runner(data *userdata.UserData) (runner.Runner, error) {
+	return recorder.New(func(record recorder.Func) runner.Runner {
+		return goroutine.NewRunner(data, w.ID(data), func(ctx context.Context, data *userdata.UserData, logOutput io.Writer) error {
+			return w.run(ctx, data, record)
+		}, runner.WithLogPath(logPath))
+	}), nil
+}
+
+func (w *Wipe) run(ctx context.Context, data *userdata.UserData, record recorder.Func) error {
+	record("stopping the services")
+
+	ids := []string{}
+
+	for _, svc := range system.Services(data).List() {
+		if id := svc.AsProto().Id; id != w.ID(data) && id != apiServiceID {
+			ids = append(ids, id)
+		}
+	}
+
+	if err := system.Services(data).Stop(ctx, ids...); err != nil {
+		return errors.Wrap(err, "failed to stop the services")
+	}
+
+	record("unmounting the file systems")
+
+	if err := release(w.Devices); err != nil {
+		return err
+	}
+
+	unix.Sync()
+
+	for _, dev := range w.Devices {
+		if err := installer.WipeDevice(dev, w.Mode, record); err != nil {
+			return err
+		}
+	}
+
+	record(fmt.Sprintf("wiped %d device(s), rebooting", len(w.Devices)))
+	event.Bus().Publish(event.Reboot)
+
+	return nil
+}
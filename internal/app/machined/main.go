@@ -5,8 +5,11 @@
 package main
 
 import (
+	"bytes"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -19,17 +22,20 @@ import (
 	"github.com/talos-systems/talos/internal/app/machined/internal/phase/platform"
 	"github.com/talos-systems/talos/internal/app/machined/internal/phase/rootfs"
 	"github.com/talos-systems/talos/internal/app/machined/internal/phase/security"
-	"github.com/talos-systems/talos/internal/app/machined/internal/phase/services"
+	servicestask "github.com/talos-systems/talos/internal/app/machined/internal/phase/services"
 	"github.com/talos-systems/talos/internal/app/machined/internal/phase/signal"
+	"github.com/talos-systems/talos/internal/app/machined/internal/phase/swap"
 	"github.com/talos-systems/talos/internal/app/machined/internal/phase/sysctls"
 	userdatatask "github.com/talos-systems/talos/internal/app/machined/internal/phase/userdata"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/services"
+	"github.com/talos-systems/talos/internal/pkg/kernel"
 	"github.com/talos-systems/talos/pkg/constants"
 	"github.com/talos-systems/talos/pkg/startup"
 	"github.com/talos-systems/talos/pkg/userdata"
 )
 
-func run() (err error) {
+func run(data *userdata.UserData) (err error) {
 	if err = startup.RandSeed(); err != nil {
 		return err
 	}
@@ -38,7 +44,6 @@ func run() (err error) {
 		return errors.New("error setting PATH")
 	}
 
-	data := &userdata.UserData{}
 	phaserunner, err := phase.NewRunner(data)
 	if err != nil {
 		return err
@@ -89,6 +94,10 @@ func run() (err error) {
 			"setup /var",
 			rootfs.NewVarDirectoriesTask(),
 		),
+		phase.NewPhase(
+			"swap",
+			swap.NewSwapTask(),
+		),
 		phase.NewPhase(
 			"save userdata",
 			userdatatask.NewSaveUserDataTask(),
@@ -96,7 +105,7 @@ func run() (err error) {
 		phase.NewPhase(
 			"service setup",
 			acpi.NewHandlerTask(),
-			services.NewServicesTask(),
+			servicestask.NewServicesTask(),
 			signal.NewHandlerTask(),
 		),
 	)
@@ -108,6 +117,71 @@ func run() (err error) {
 	return nil
 }
 
+// saveBootReport persists the report of the boot sequence, so that it is
+// available after reboot.
+func saveBootReport() {
+	report := phase.LastReport()
+	if report == nil {
+		return
+	}
+
+	if err := report.Save(constants.BootReportPath); err != nil {
+		log.Printf("failed to save boot report: %s", err)
+	}
+}
+
+// maintenanceMode returns true if the node should stay up on boot failure.
+func maintenanceMode() bool {
+	if p := kernel.ProcCmdline().Get(constants.KernelParamMaintenance).First(); p != nil {
+		// nolint: errcheck
+		enabled, _ := strconv.ParseBool(*p)
+
+		return enabled
+	}
+
+	return false
+}
+
+// enterMaintenance logs the boot report and loads the services required to
+// retrieve the boot report and debug the node over the API.
+//
+// The remote API (osd) requires the PKI from the userdata, so if the boot
+// failed before the userdata was loaded, only the local API is available.
+func enterMaintenance(data *userdata.UserData, bootErr error) {
+	log.Printf("boot failed: %s", bootErr)
+
+	if report := phase.LastReport(); report != nil {
+		var buf bytes.Buffer
+
+		// nolint: errcheck
+		report.WriteText(&buf)
+
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			log.Print(line)
+		}
+	}
+
+	log.Printf("entering maintenance mode")
+
+	svcs := []system.Service{
+		&services.MachinedAPI{},
+	}
+
+	if data.Security != nil && data.Security.OS != nil {
+		svcs = append(svcs,
+			&services.Networkd{},
+			&services.Containerd{},
+			&services.OSD{},
+		)
+	} else {
+		log.Printf("userdata is not available, the API is only served on %s", constants.InitSocketPath)
+	}
+
+	if _, err := system.Services(data).Load(svcs...); err != nil {
+		log.Printf("failed to load services in maintenance mode: %s", err)
+	}
+}
+
 func recovery() {
 	if r := recover(); r != nil {
 		log.Printf("recovered from: %+v\n", r)
@@ -173,14 +247,26 @@ func main() {
 	event.Bus().Subscribe(events)
 	defer event.Bus().Unsubscribe(events)
 
+	data := &userdata.UserData{}
+
 	// run startup phases
-	if err := run(); err != nil {
-		panic(errors.Wrap(err, "boot failed"))
+	err := run(data)
+
+	saveBootReport()
+
+	if err != nil {
+		if !maintenanceMode() {
+			panic(errors.Wrap(err, "boot failed"))
+		}
+
+		// stay up with the services which were loaded before the failure,
+		// so that the node can be debugged
+		enterMaintenance(data, err)
 	}
 
 	// start services
-	system.Services(nil).StartAll()
-	defer system.Services(nil).Shutdown()
+	system.Services(data).StartAll()
+	defer system.Services(data).Shutdown()
 
 	// wait for events
 	for {
@@ -28,6 +28,7 @@ const (
 	StateFinished
 	StateFailed
 	StateSkipped
+	StateCrashLoop
 )
 
 func (state ServiceState) String() string {
@@ -48,6 +49,8 @@ func (state ServiceState) String() string {
 		return "Failed"
 	case StateSkipped:
 		return "Skipped"
+	case StateCrashLoop:
+		return "CrashLoop"
 	default:
 		return "Unknown"
 	}
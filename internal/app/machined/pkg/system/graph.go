@@ -0,0 +1,220 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package system
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+// DependencyGraph is a snapshot of the service dependency graph.
+type DependencyGraph struct {
+	runners      map[string]*ServiceRunner
+	dependencies map[string][]string
+}
+
+// NewDependencyGraph builds dependency graph for the list of service runners.
+func NewDependencyGraph(runners ...*ServiceRunner) *DependencyGraph {
+	graph := &DependencyGraph{
+		runners:      make(map[string]*ServiceRunner, len(runners)),
+		dependencies: make(map[string][]string, len(runners)),
+	}
+
+	for _, svcrunner := range runners {
+		graph.runners[svcrunner.id] = svcrunner
+		graph.dependencies[svcrunner.id] = svcrunner.service.DependsOn(svcrunner.userData)
+	}
+
+	return graph
+}
+
+// Validate checks that all the dependencies are registered and that there are
+// no dependency cycles.
+func (graph *DependencyGraph) Validate() error {
+	var result *multierror.Error
+
+	for _, id := range graph.ids() {
+		for _, dependency := range graph.dependencies[id] {
+			if _, ok := graph.runners[dependency]; !ok {
+				result = multierror.Append(result, errors.Errorf("service %q depends on unknown service %q", id, dependency))
+			}
+		}
+	}
+
+	if cycle := graph.findCycle(); cycle != nil {
+		result = multierror.Append(result, errors.Errorf("dependency cycle detected: %s", strings.Join(cycle, " -> ")))
+	}
+
+	return result.ErrorOrNil()
+}
+
+// Sorted returns service runners sorted in the topological order: dependencies
+// come before services which depend on them.
+//
+// Services which are not ordered by dependencies (and services in the cycles)
+// are sorted by ID.
+func (graph *DependencyGraph) Sorted() []*ServiceRunner {
+	result := make([]*ServiceRunner, 0, len(graph.runners))
+	visited := make(map[string]bool, len(graph.runners))
+
+	var visit func(id string)
+	visit = func(id string) {
+		if visited[id] {
+			return
+		}
+		visited[id] = true
+
+		dependencies := append([]string(nil), graph.dependencies[id]...)
+		sort.Strings(dependencies)
+
+		for _, dependency := range dependencies {
+			if _, ok := graph.runners[dependency]; ok {
+				visit(dependency)
+			}
+		}
+
+		result = append(result, graph.runners[id])
+	}
+
+	for _, id := range graph.ids() {
+		visit(id)
+	}
+
+	return result
+}
+
+// ReverseDependencies returns map of service ID to the list of IDs of the
+// services which depend on it directly.
+func (graph *DependencyGraph) ReverseDependencies() map[string][]string {
+	reverseDependencies := make(map[string][]string)
+
+	for _, id := range graph.ids() {
+		for _, dependency := range graph.dependencies[id] {
+			reverseDependencies[dependency] = append(reverseDependencies[dependency], id)
+		}
+	}
+
+	return reverseDependencies
+}
+
+// WriteText writes human-readable representation of the graph with the
+// current state of each service.
+func (graph *DependencyGraph) WriteText(w io.Writer) error {
+	for _, svcrunner := range graph.Sorted() {
+		if _, err := fmt.Fprintf(w, "%s [%s]\n", svcrunner.id, svcrunner.stateDescription()); err != nil {
+			return err
+		}
+
+		for _, dependency := range graph.dependencies[svcrunner.id] {
+			description := "not registered"
+			if dep, ok := graph.runners[dependency]; ok {
+				description = dep.stateDescription()
+			}
+
+			if _, err := fmt.Fprintf(w, "  -> %s [%s]\n", dependency, description); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteDOT writes graph in the Graphviz DOT format with the current state of
+// each service.
+func (graph *DependencyGraph) WriteDOT(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "digraph services {"); err != nil {
+		return err
+	}
+
+	sorted := graph.Sorted()
+
+	for _, svcrunner := range sorted {
+		if _, err := fmt.Fprintf(w, "\t%q [label=%q];\n", svcrunner.id, svcrunner.id+"\n"+svcrunner.stateDescription()); err != nil {
+			return err
+		}
+	}
+
+	for _, svcrunner := range sorted {
+		for _, dependency := range graph.dependencies[svcrunner.id] {
+			if _, err := fmt.Fprintf(w, "\t%q -> %q;\n", svcrunner.id, dependency); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintln(w, "}")
+
+	return err
+}
+
+func (graph *DependencyGraph) ids() []string {
+	ids := make([]string, 0, len(graph.runners))
+	for id := range graph.runners {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// findCycle returns the first dependency cycle found as a path of service IDs.
+func (graph *DependencyGraph) findCycle() []string {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+
+	marks := make(map[string]int, len(graph.runners))
+
+	var path []string
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		switch marks[id] {
+		case done:
+			return nil
+		case inProgress:
+			for i := range path {
+				if path[i] == id {
+					return append(append([]string(nil), path[i:]...), id)
+				}
+			}
+		}
+
+		marks[id] = inProgress
+		path = append(path, id)
+
+		for _, dependency := range graph.dependencies[id] {
+			if _, ok := graph.runners[dependency]; !ok {
+				continue
+			}
+
+			if cycle := visit(dependency); cycle != nil {
+				return cycle
+			}
+		}
+
+		path = path[:len(path)-1]
+		marks[id] = done
+
+		return nil
+	}
+
+	for _, id := range graph.ids() {
+		if cycle := visit(id); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
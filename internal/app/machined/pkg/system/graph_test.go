@@ -0,0 +1,112 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package system_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system"
+)
+
+type DependencyGraphSuite struct {
+	suite.Suite
+}
+
+func (suite *DependencyGraphSuite) graph(services ...*MockService) *system.DependencyGraph {
+	runners := make([]*system.ServiceRunner, len(services))
+	for i := range services {
+		runners[i] = system.NewServiceRunner(services[i], nil)
+	}
+
+	return system.NewDependencyGraph(runners...)
+}
+
+func (suite *DependencyGraphSuite) TestValidate() {
+	suite.Assert().NoError(suite.graph(
+		&MockService{name: "containerd"},
+		&MockService{name: "osd", dependencies: []string{"containerd"}},
+		&MockService{name: "kubelet", dependencies: []string{"containerd", "kubeadm"}},
+		&MockService{name: "kubeadm", dependencies: []string{"containerd"}},
+	).Validate())
+
+	err := suite.graph(
+		&MockService{name: "containerd"},
+		&MockService{name: "kubelet", dependencies: []string{"containerd", "kubeadn"}},
+	).Validate()
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), `service "kubelet" depends on unknown service "kubeadn"`)
+
+	err = suite.graph(
+		&MockService{name: "a", dependencies: []string{"b"}},
+		&MockService{name: "b", dependencies: []string{"c"}},
+		&MockService{name: "c", dependencies: []string{"a"}},
+		&MockService{name: "d", dependencies: []string{"a"}},
+	).Validate()
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), "dependency cycle detected: a -> b -> c -> a")
+}
+
+func (suite *DependencyGraphSuite) TestSorted() {
+	graph := suite.graph(
+		&MockService{name: "kubelet", dependencies: []string{"kubeadm", "containerd"}},
+		&MockService{name: "udevd"},
+		&MockService{name: "kubeadm", dependencies: []string{"containerd"}},
+		&MockService{name: "containerd"},
+		&MockService{name: "osd", dependencies: []string{"containerd"}},
+	)
+
+	ids := []string{}
+	for _, svcrunner := range graph.Sorted() {
+		ids = append(ids, svcrunner.AsProto().Id)
+	}
+
+	suite.Assert().Equal([]string{"containerd", "kubeadm", "kubelet", "osd", "udevd"}, ids)
+}
+
+func (suite *DependencyGraphSuite) TestReverseDependencies() {
+	graph := suite.graph(
+		&MockService{name: "kubelet", dependencies: []string{"kubeadm", "containerd"}},
+		&MockService{name: "udevd"},
+		&MockService{name: "kubeadm", dependencies: []string{"containerd"}},
+		&MockService{name: "containerd"},
+		&MockService{name: "osd", dependencies: []string{"containerd"}},
+	)
+
+	suite.Assert().Equal(map[string][]string{
+		"containerd": {"kubeadm", "kubelet", "osd"},
+		"kubeadm":    {"kubelet"},
+	}, graph.ReverseDependencies())
+}
+
+func (suite *DependencyGraphSuite) TestWrite() {
+	graph := suite.graph(
+		&MockService{name: "containerd"},
+		&MockService{name: "kubelet", dependencies: []string{"containerd"}},
+	)
+
+	var buf bytes.Buffer
+
+	suite.Require().NoError(graph.WriteText(&buf))
+	suite.Assert().Equal(`containerd [Initialized]
+kubelet [Initialized]
+  -> containerd [Initialized]
+`, buf.String())
+
+	buf.Reset()
+
+	suite.Require().NoError(graph.WriteDOT(&buf))
+	suite.Assert().Equal(`digraph services {
+	"containerd" [label="containerd\nInitialized"];
+	"kubelet" [label="kubelet\nInitialized"];
+	"kubelet" -> "containerd";
+}
+`, buf.String())
+}
+
+func TestDependencyGraphSuite(t *testing.T) {
+	suite.Run(t, new(DependencyGraphSuite))
+}
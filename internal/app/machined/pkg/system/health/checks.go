@@ -0,0 +1,128 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// HTTPGet builds a check which performs HTTP GET request to the URL and
+// expects the response to have the specified status code.
+func HTTPGet(url string, expectedStatus int) Check {
+	return httpGet(http.DefaultClient, url, expectedStatus)
+}
+
+// HTTPSGet builds a check which performs HTTPS GET request to the URL with
+// the specified TLS config and expects the response to have the specified status code.
+func HTTPSGet(url string, expectedStatus int, config *tls.Config) Check {
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: config,
+		},
+	}
+
+	return httpGet(client, url, expectedStatus)
+}
+
+func httpGet(client *http.Client, url string, expectedStatus int) Check {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return err
+		}
+		req = req.WithContext(ctx)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		// nolint: errcheck
+		defer resp.Body.Close()
+
+		if resp.StatusCode != expectedStatus {
+			return errors.Errorf("expected HTTP status %d, got %s", expectedStatus, resp.Status)
+		}
+
+		return nil
+	}
+}
+
+// TCPConnect builds a check which verifies that TCP connection can be
+// established to the address.
+func TCPConnect(address string) Check {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+}
+
+// GRPC builds a check which queries the gRPC health protocol of the server
+// listening on the address.
+//
+// Network is either "tcp" or "unix", service is the name of the service
+// to check (empty string checks the server as a whole).
+func GRPC(network, address, service string) Check {
+	return func(ctx context.Context) error {
+		conn, err := grpc.DialContext(ctx, address,
+			grpc.WithInsecure(),
+			grpc.WithBlock(),
+			grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			}),
+		)
+		if err != nil {
+			return err
+		}
+		// nolint: errcheck
+		defer conn.Close()
+
+		resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+
+		if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+			return errors.Errorf("unexpected serving status: %s", resp.Status)
+		}
+
+		return nil
+	}
+}
+
+// FileExists builds a check which verifies that the file exists.
+func FileExists(path string) Check {
+	return func(ctx context.Context) error {
+		_, err := os.Stat(path)
+
+		return err
+	}
+}
+
+// All builds a check which succeeds only if all the checks succeed.
+func All(checks ...Check) Check {
+	return func(ctx context.Context) error {
+		for _, check := range checks {
+			if err := check(ctx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
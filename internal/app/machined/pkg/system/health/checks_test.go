@@ -0,0 +1,120 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package health_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/health"
+)
+
+type ChecksSuite struct {
+	suite.Suite
+}
+
+func (suite *ChecksSuite) check(check health.Check) error {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+
+	return check(ctx)
+}
+
+func (suite *ChecksSuite) TestHTTPGet() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	suite.Assert().NoError(suite.check(health.HTTPGet(server.URL+"/healthz", http.StatusOK)))
+	suite.Assert().EqualError(suite.check(health.HTTPGet(server.URL+"/other", http.StatusOK)), "expected HTTP status 200, got 404 Not Found")
+	suite.Assert().NoError(suite.check(health.HTTPGet(server.URL+"/other", http.StatusNotFound)))
+}
+
+func (suite *ChecksSuite) TestHTTPSGet() {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	suite.Assert().NoError(suite.check(health.HTTPSGet(server.URL, http.StatusNoContent, server.Client().Transport.(*http.Transport).TLSClientConfig)))
+	suite.Assert().Error(suite.check(health.HTTPSGet(server.URL, http.StatusNoContent, nil)))
+}
+
+func (suite *ChecksSuite) TestTCPConnect() {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+
+	address := l.Addr().String()
+
+	suite.Assert().NoError(suite.check(health.TCPConnect(address)))
+
+	suite.Require().NoError(l.Close())
+
+	suite.Assert().Error(suite.check(health.TCPConnect(address)))
+}
+
+func (suite *ChecksSuite) TestGRPC() {
+	tmpDir, err := ioutil.TempDir("", "talos")
+	suite.Require().NoError(err)
+	// nolint: errcheck
+	defer os.RemoveAll(tmpDir)
+
+	socketPath := filepath.Join(tmpDir, "grpc.sock")
+
+	l, err := net.Listen("unix", socketPath)
+	suite.Require().NoError(err)
+
+	healthServer := grpchealth.NewServer()
+
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+
+	// nolint: errcheck
+	go server.Serve(l)
+	defer server.Stop()
+
+	suite.Assert().NoError(suite.check(health.GRPC("unix", socketPath, "")))
+
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	suite.Assert().EqualError(suite.check(health.GRPC("unix", socketPath, "")), "unexpected serving status: NOT_SERVING")
+}
+
+func (suite *ChecksSuite) TestFileExists() {
+	tmpDir, err := ioutil.TempDir("", "talos")
+	suite.Require().NoError(err)
+	// nolint: errcheck
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "file")
+
+	suite.Assert().Error(suite.check(health.FileExists(path)))
+
+	suite.Require().NoError(ioutil.WriteFile(path, nil, 0644))
+
+	suite.Assert().NoError(suite.check(health.FileExists(path)))
+	suite.Assert().Error(suite.check(health.All(health.FileExists(path), health.FileExists(path+".missing"))))
+}
+
+func TestChecksSuite(t *testing.T) {
+	suite.Run(t, new(ChecksSuite))
+}
@@ -135,7 +135,7 @@ func (c *containerdRunner) Run(eventSink events.Recorder) error {
 	case status := <-statusC:
 		code := status.ExitCode()
 		if code != 0 {
-			return errors.Errorf("task %q failed: exit code %d", c.args.ID, code)
+			return errors.Wrapf(&runner.ExitError{Code: int(code)}, "task %q failed", c.args.ID)
 		}
 		return nil
 	case <-c.stop:
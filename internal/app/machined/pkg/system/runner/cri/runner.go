@@ -257,7 +257,7 @@ WAIT:
 				return nil
 			}
 
-			return errors.Errorf("container exited with code %d (%s)", status.ExitCode, status.Reason)
+			return errors.Wrapf(&runner.ExitError{Code: int(status.ExitCode)}, "container exited (%s)", status.Reason)
 		default:
 			return errors.Errorf("container in unexpected state (%d)", status.State)
 		}
@@ -123,7 +123,7 @@ func (p *processRunner) run(eventSink events.Recorder) error {
 	select {
 	case err = <-waitCh:
 		// process exited
-		return err
+		return exitError(err)
 	case <-p.stop:
 		// graceful stop the service
 		eventSink(events.StateStopping, "Sending SIGTERM to %s", p)
@@ -149,6 +149,25 @@ func (p *processRunner) run(eventSink events.Recorder) error {
 	return nil
 }
 
+// exitError converts process exit status into runner.ExitError
+func exitError(err error) error {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return err
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok {
+		return err
+	}
+
+	if status.Signaled() {
+		return &runner.ExitError{Signal: status.Signal()}
+	}
+
+	return &runner.ExitError{Code: status.ExitStatus()}
+}
+
 func (p *processRunner) String() string {
 	return fmt.Sprintf("Process(%q)", p.args.ProcessArgs)
 }
@@ -0,0 +1,44 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package recorder wraps a runner to record the messages of the service as
+// its events.
+package recorder
+
+import (
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/events"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner"
+)
+
+// Func records the message as an event of the service.
+type Func func(message string)
+
+type recorder struct {
+	runner.Runner
+
+	eventSink events.Recorder
+}
+
+// New wraps the runner built by newRunner, which is passed the func recording
+// the messages as the events of the service while the runner runs.
+func New(newRunner func(record Func) runner.Runner) runner.Runner {
+	r := &recorder{}
+	r.Runner = newRunner(r.record)
+
+	return r
+}
+
+func (r *recorder) Run(eventSink events.Recorder) error {
+	r.eventSink = eventSink
+
+	return r.Runner.Run(eventSink)
+}
+
+func (r *recorder) record(message string) {
+	if r.eventSink == nil {
+		return
+	}
+
+	r.eventSink(events.StateRunning, "%s", message)
+}
@@ -0,0 +1,67 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package recorder_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/events"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/recorder"
+)
+
+type RecorderSuite struct {
+	suite.Suite
+}
+
+type MockRunner struct {
+	record recorder.Func
+}
+
+func (m *MockRunner) Open(ctx context.Context) error {
+	return nil
+}
+
+func (m *MockRunner) Close() error {
+	return nil
+}
+
+func (m *MockRunner) Run(eventSink events.Recorder) error {
+	m.record("wiping /dev/sda")
+
+	return nil
+}
+
+func (m *MockRunner) Stop() error {
+	return nil
+}
+
+func (m *MockRunner) String() string {
+	return "MockRunner()"
+}
+
+func (suite *RecorderSuite) TestRecord() {
+	r := recorder.New(func(record recorder.Func) runner.Runner {
+		return &MockRunner{record: record}
+	})
+
+	suite.Assert().Equal("MockRunner()", r.String())
+
+	recorded := []string{}
+
+	suite.Require().NoError(r.Run(func(state events.ServiceState, message string, args ...interface{}) {
+		recorded = append(recorded, fmt.Sprintf("%s: %s", state, fmt.Sprintf(message, args...)))
+	}))
+
+	suite.Assert().Equal([]string{"Running: wiping /dev/sda"}, recorded)
+}
+
+func TestRecorderSuite(t *testing.T) {
+	suite.Run(t, new(RecorderSuite))
+}
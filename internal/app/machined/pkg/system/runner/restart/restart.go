@@ -7,8 +7,13 @@ package restart
 import (
 	"context"
 	"fmt"
+	"math"
+	"math/rand"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/events"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner"
 )
@@ -17,6 +22,9 @@ type restarter struct {
 	wrappedRunner runner.Runner
 	opts          *Options
 
+	statsMu sync.Mutex
+	stats   runner.RestartStats
+
 	stop    chan struct{}
 	stopped chan struct{}
 }
@@ -41,8 +49,21 @@ func New(wrapRunner runner.Runner, opts ...Option) runner.Runner {
 type Options struct {
 	// Type describes the service's restart policy.
 	Type Type
-	// RestartInterval is the interval between restarts for failed runs
+	// RestartInterval is the initial interval between restarts for failed runs
 	RestartInterval time.Duration
+	// MaxRestartInterval caps the exponential backoff between restarts,
+	// zero disables the cap
+	MaxRestartInterval time.Duration
+	// ResetInterval is the duration of the run after which the backoff
+	// and the crash loop detection are reset
+	ResetInterval time.Duration
+	// Jitter is the fraction of the restart interval to randomize
+	Jitter float64
+	// CrashLoopThreshold is the number of consecutive short runs which
+	// is considered a crash loop
+	CrashLoopThreshold int
+	// CrashLoopAction is the action taken when crash loop is detected
+	CrashLoopAction CrashLoopAction
 }
 
 // Option is the functional option func.
@@ -73,11 +94,81 @@ func (t Type) String() string {
 	}
 }
 
+// CrashLoopAction represents the action taken when crash loop is detected.
+type CrashLoopAction int
+
+const (
+	// KeepTrying will continue restarting the process with the backoff.
+	KeepTrying CrashLoopAction = iota
+	// GiveUp will stop restarting the process.
+	GiveUp
+	// Reboot will reboot the node.
+	Reboot
+)
+
+func (a CrashLoopAction) String() string {
+	switch a {
+	case KeepTrying:
+		return "KeepTrying"
+	case GiveUp:
+		return "GiveUp"
+	case Reboot:
+		return "Reboot"
+	default:
+		return "Unknown"
+	}
+}
+
+// CrashLoopError is returned by the runner when it stops on a crash loop. The
+// runner doesn't reboot the node itself, the service layer decides on the
+// reboot requested by the Reboot action.
+type CrashLoopError struct {
+	Action   CrashLoopAction
+	Failures int
+	Err      error
+}
+
+func (e *CrashLoopError) Error() string {
+	if e.Action == Reboot {
+		return fmt.Sprintf("crash loop detected, reboot requested after %d restarts: %v", e.Failures, e.Err)
+	}
+
+	return fmt.Sprintf("crash loop detected, giving up after %d restarts: %v", e.Failures, e.Err)
+}
+
+// RebootRequested reports whether the runner stopped on a crash loop with the
+// Reboot action.
+func RebootRequested(err error) bool {
+	e, ok := errors.Cause(err).(*CrashLoopError)
+
+	return ok && e.Action == Reboot
+}
+
+// ParseCrashLoopAction parses the crash loop action name as specified in the
+// userdata.
+func ParseCrashLoopAction(action string) (CrashLoopAction, error) {
+	switch action {
+	case "", "keep-trying":
+		return KeepTrying, nil
+	case "give-up":
+		return GiveUp, nil
+	case "reboot":
+		return Reboot, nil
+	default:
+		return KeepTrying, errors.Errorf("unknown crash loop action %q", action)
+	}
+}
+
 // DefaultOptions describes the default options to a runner.
 func DefaultOptions() *Options {
 	return &Options{
-		Type:            Forever,
-		RestartInterval: 5 * time.Second,
+		Type:               Forever,
+		RestartInterval:    5 * time.Second,
+		MaxRestartInterval: 5 * time.Minute,
+		ResetInterval:      10 * time.Minute,
+		Jitter:             0.1,
+		CrashLoopThreshold: 5,
+		CrashLoopAction:    KeepTrying,
 	}
 }
 
@@ -95,6 +186,41 @@ func WithRestartInterval(interval time.Duration) Option {
 	}
 }
 
+// WithMaxRestartInterval sets the upper bound of the interval between restarts
+func WithMaxRestartInterval(interval time.Duration) Option {
+	return func(args *Options) {
+		args.MaxRestartInterval = interval
+	}
+}
+
+// WithResetInterval sets the run duration after which backoff is reset
+func WithResetInterval(interval time.Duration) Option {
+	return func(args *Options) {
+		args.ResetInterval = interval
+	}
+}
+
+// WithJitter sets the fraction of the restart interval to randomize
+func WithJitter(jitter float64) Option {
+	return func(args *Options) {
+		args.Jitter = jitter
+	}
+}
+
+// WithCrashLoopThreshold sets the number of consecutive failures which is considered a crash loop
+func WithCrashLoopThreshold(threshold int) Option {
+	return func(args *Options) {
+		args.CrashLoopThreshold = threshold
+	}
+}
+
+// WithCrashLoopAction sets the action taken when crash loop is detected
+func WithCrashLoopAction(action CrashLoopAction) Option {
+	return func(args *Options) {
+		args.CrashLoopAction = action
+	}
+}
+
 // Open implements the Runner interface
 func (r *restarter) Open(ctx context.Context) error {
 	return r.wrappedRunner.Open(ctx)
@@ -105,8 +231,12 @@ func (r *restarter) Open(ctx context.Context) error {
 func (r *restarter) Run(eventSink events.Recorder) error {
 	defer close(r.stopped)
 
+	failures := 0
+	crashLooping := false
+
 	for {
 		errCh := make(chan error)
+		started := time.Now()
 
 		go func() {
 			errCh <- r.wrappedRunner.Run(eventSink)
@@ -130,6 +260,12 @@ func (r *restarter) Run(eventSink events.Recorder) error {
 
 		switch r.opts.Type {
 		case Once:
+			// There are no restarts to count, so the first failure is
+			// the crash loop.
+			if err != nil && r.opts.CrashLoopAction == Reboot {
+				return &CrashLoopError{Action: Reboot, Failures: 1, Err: err}
+			}
+
 			return err
 		case UntilSuccess:
 			if err == nil {
@@ -144,13 +280,84 @@ func (r *restarter) Run(eventSink events.Recorder) error {
 			}
 		}
 
+		r.recordExit(err)
+
+		// Only the consecutive failed runs count towards the crash loop, a
+		// clean exit or a long enough run resets the count.
+		if err == nil || time.Since(started) >= r.opts.ResetInterval {
+			failures = 0
+			crashLooping = false
+		}
+
+		if err != nil {
+			failures++
+		}
+
+		if r.opts.CrashLoopThreshold > 0 && failures >= r.opts.CrashLoopThreshold {
+			if !crashLooping {
+				eventSink(events.StateCrashLoop, "Runner %s failed %d times in a row, crash loop action %s", r.wrappedRunner, failures, r.opts.CrashLoopAction)
+				crashLooping = true
+			}
+
+			if r.opts.CrashLoopAction != KeepTrying {
+				return &CrashLoopError{Action: r.opts.CrashLoopAction, Failures: failures, Err: err}
+			}
+		}
+
 		select {
 		case <-r.stop:
 			eventSink(events.StateStopping, "Aborting restart sequence")
 			return nil
-		case <-time.After(r.opts.RestartInterval):
+		case <-time.After(r.backoff(failures)):
 		}
+
+		r.statsMu.Lock()
+		r.stats.Restarts++
+		r.statsMu.Unlock()
+	}
+}
+
+// backoff calculates the interval before the next restart: interval is doubled
+// on every consecutive failure up to the cap if one is set, and randomized with
+// the jitter.
+func (r *restarter) backoff(failures int) time.Duration {
+	interval := r.opts.RestartInterval
+
+	for i := 1; i < failures && interval > 0 && interval <= math.MaxInt64/2; i++ {
+		if r.opts.MaxRestartInterval > 0 && interval >= r.opts.MaxRestartInterval {
+			break
+		}
+
+		interval *= 2
 	}
+
+	if r.opts.MaxRestartInterval > 0 && interval > r.opts.MaxRestartInterval {
+		interval = r.opts.MaxRestartInterval
+	}
+
+	if r.opts.Jitter > 0 {
+		interval += time.Duration(r.opts.Jitter * (2*rand.Float64() - 1) * float64(interval))
+	}
+
+	return interval
+}
+
+func (r *restarter) recordExit(err error) {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	if exitErr, ok := errors.Cause(err).(*runner.ExitError); ok {
+		r.stats.LastExitCode = exitErr.Code
+		r.stats.LastSignal = exitErr.Signal
+	}
+}
+
+// RestartStats implements the runner.RestartStatsProvider interface
+func (r *restarter) RestartStats() runner.RestartStats {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	return r.stats
 }
 
 // Stop implements the Runner interface
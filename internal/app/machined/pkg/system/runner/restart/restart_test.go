@@ -9,12 +9,14 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/suite"
 
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/events"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/restart"
 )
 
@@ -171,6 +173,229 @@ func (suite *RestartSuite) TestRunForever() {
 	suite.Assert().Equal(4, mock.times)
 }
 
+func (suite *RestartSuite) TestRunCrashLoopGiveUp() {
+	mock := MockRunner{
+		exitCh: make(chan error),
+	}
+
+	r := restart.New(&mock,
+		restart.WithType(restart.Forever),
+		restart.WithRestartInterval(time.Millisecond),
+		restart.WithCrashLoopThreshold(3),
+		restart.WithCrashLoopAction(restart.GiveUp),
+	)
+	suite.Assert().NoError(r.Open(context.Background()))
+	defer func() { suite.Assert().NoError(r.Close()) }()
+
+	errCh := make(chan error)
+
+	go func() {
+		errCh <- r.Run(MockEventSink)
+	}()
+
+	mock.exitCh <- &runner.ExitError{Code: 1}
+	mock.exitCh <- &runner.ExitError{Code: 2}
+	mock.exitCh <- &runner.ExitError{Signal: syscall.SIGSEGV}
+
+	suite.Assert().EqualError(<-errCh, "crash loop detected, giving up after 3 restarts: killed by signal segmentation fault")
+	suite.Assert().NoError(r.Stop())
+	suite.Assert().Equal(3, mock.times)
+
+	stats := r.(runner.RestartStatsProvider).RestartStats()
+	suite.Assert().EqualValues(2, stats.Restarts)
+	suite.Assert().Equal(0, stats.LastExitCode)
+	suite.Assert().Equal(syscall.SIGSEGV, stats.LastSignal)
+}
+
+func (suite *RestartSuite) TestRunCrashLoopReboot() {
+	mock := MockRunner{
+		exitCh: make(chan error),
+	}
+
+	r := restart.New(&mock,
+		restart.WithType(restart.Forever),
+		restart.WithRestartInterval(time.Millisecond),
+		restart.WithCrashLoopThreshold(2),
+		restart.WithCrashLoopAction(restart.Reboot),
+	)
+	suite.Assert().NoError(r.Open(context.Background()))
+	defer func() { suite.Assert().NoError(r.Close()) }()
+
+	var crashLoops int
+
+	sink := func(state events.ServiceState, message string, args ...interface{}) {
+		if state == events.StateCrashLoop {
+			crashLoops++
+		}
+	}
+
+	errCh := make(chan error)
+
+	go func() {
+		errCh <- r.Run(sink)
+	}()
+
+	mock.exitCh <- &runner.ExitError{Code: 1}
+	mock.exitCh <- &runner.ExitError{Code: 1}
+
+	err := <-errCh
+	suite.Assert().True(restart.RebootRequested(err), "%v", err)
+	suite.Assert().Equal(1, crashLoops)
+	suite.Assert().NoError(r.Stop())
+
+	suite.Assert().False(restart.RebootRequested(errors.New("failed")))
+}
+
+// TestRunCleanExits makes sure the clean exits don't count towards the crash
+// loop, and the crash loop event is emitted once.
+func (suite *RestartSuite) TestRunCleanExits() {
+	mock := MockRunner{
+		exitCh: make(chan error),
+	}
+
+	r := restart.New(&mock,
+		restart.WithType(restart.Forever),
+		restart.WithRestartInterval(time.Millisecond),
+		restart.WithCrashLoopThreshold(2),
+	)
+	suite.Assert().NoError(r.Open(context.Background()))
+	defer func() { suite.Assert().NoError(r.Close()) }()
+
+	var crashLoops int
+
+	sink := func(state events.ServiceState, message string, args ...interface{}) {
+		if state == events.StateCrashLoop {
+			crashLoops++
+		}
+	}
+
+	errCh := make(chan error)
+
+	go func() {
+		errCh <- r.Run(sink)
+	}()
+
+	failed := errors.New("failed")
+
+	for _, err := range []error{nil, failed, nil, failed, nil} {
+		mock.exitCh <- err
+	}
+
+	suite.Assert().Equal(0, crashLoops)
+
+	for i := 0; i < 4; i++ {
+		mock.exitCh <- failed
+	}
+
+	suite.Assert().NoError(r.Stop())
+	suite.Assert().NoError(<-errCh)
+	suite.Assert().Equal(1, crashLoops)
+}
+
+func (suite *RestartSuite) TestRunBackoff() {
+	mock := MockRunner{
+		exitCh: make(chan error),
+	}
+
+	r := restart.New(&mock,
+		restart.WithType(restart.Forever),
+		restart.WithRestartInterval(10*time.Millisecond),
+		restart.WithMaxRestartInterval(40*time.Millisecond),
+		restart.WithJitter(0),
+	)
+	suite.Assert().NoError(r.Open(context.Background()))
+	defer func() { suite.Assert().NoError(r.Close()) }()
+
+	errCh := make(chan error)
+
+	go func() {
+		errCh <- r.Run(MockEventSink)
+	}()
+
+	failed := errors.New("failed")
+
+	var intervals []time.Duration
+
+	last := time.Now()
+
+	for i := 0; i < 5; i++ {
+		mock.exitCh <- failed
+
+		now := time.Now()
+		intervals = append(intervals, now.Sub(last))
+		last = now
+	}
+
+	suite.Assert().NoError(r.Stop())
+	suite.Assert().NoError(<-errCh)
+
+	// first send is immediate, then 10ms, 20ms, 40ms, 40ms (capped)
+	for i, expected := range []time.Duration{0, 10, 20, 40, 40} {
+		suite.Assert().True(intervals[i] >= expected*time.Millisecond, "interval %d: %s < %dms", i, intervals[i], expected)
+	}
+
+	suite.Assert().EqualValues(4, r.(runner.RestartStatsProvider).RestartStats().Restarts)
+}
+
+func (suite *RestartSuite) TestRunBackoffUncapped() {
+	mock := MockRunner{
+		exitCh: make(chan error),
+	}
+
+	r := restart.New(&mock,
+		restart.WithType(restart.Forever),
+		restart.WithRestartInterval(10*time.Millisecond),
+		restart.WithMaxRestartInterval(0),
+		restart.WithJitter(0),
+	)
+	suite.Assert().NoError(r.Open(context.Background()))
+	defer func() { suite.Assert().NoError(r.Close()) }()
+
+	errCh := make(chan error)
+
+	go func() {
+		errCh <- r.Run(MockEventSink)
+	}()
+
+	failed := errors.New("failed")
+
+	var intervals []time.Duration
+
+	last := time.Now()
+
+	for i := 0; i < 5; i++ {
+		mock.exitCh <- failed
+
+		now := time.Now()
+		intervals = append(intervals, now.Sub(last))
+		last = now
+	}
+
+	suite.Assert().NoError(r.Stop())
+	suite.Assert().NoError(<-errCh)
+
+	// without the cap the interval keeps doubling: 10ms, 20ms, 40ms, 80ms
+	for i, expected := range []time.Duration{0, 10, 20, 40, 80} {
+		suite.Assert().True(intervals[i] >= expected*time.Millisecond, "interval %d: %s < %dms", i, intervals[i], expected)
+	}
+}
+
+func (suite *RestartSuite) TestParseCrashLoopAction() {
+	for name, expected := range map[string]restart.CrashLoopAction{
+		"":            restart.KeepTrying,
+		"keep-trying": restart.KeepTrying,
+		"give-up":     restart.GiveUp,
+		"reboot":      restart.Reboot,
+	} {
+		action, err := restart.ParseCrashLoopAction(name)
+		suite.Assert().NoError(err)
+		suite.Assert().Equal(expected, action)
+	}
+
+	_, err := restart.ParseCrashLoopAction("explode")
+	suite.Assert().EqualError(err, `unknown crash loop action "explode"`)
+}
+
 func TestRestartSuite(t *testing.T) {
 	suite.Run(t, new(RestartSuite))
 }
@@ -7,6 +7,7 @@ package runner
 import (
 	"context"
 	"fmt"
+	"syscall"
 	"time"
 
 	"github.com/containerd/containerd"
@@ -25,6 +26,36 @@ type Runner interface {
 	Close() error
 }
 
+// ExitError is returned by runners when the process terminates with non-zero
+// exit code or is killed by a signal.
+type ExitError struct {
+	Code   int
+	Signal syscall.Signal
+}
+
+func (e *ExitError) Error() string {
+	if e.Signal != 0 {
+		return fmt.Sprintf("killed by signal %s", e.Signal)
+	}
+
+	return fmt.Sprintf("exit code %d", e.Code)
+}
+
+// RestartStats describes the restart history of the runner.
+type RestartStats struct {
+	// Restarts is the number of times the runner was restarted.
+	Restarts uint32
+	// LastExitCode is the exit code of the last failed run.
+	LastExitCode int
+	// LastSignal is the signal which terminated the last failed run.
+	LastSignal syscall.Signal
+}
+
+// RestartStatsProvider is implemented by runners which keep track of restarts.
+type RestartStatsProvider interface {
+	RestartStats() RestartStats
+}
+
 // Args represents the required options for services.
 type Args struct {
 	ID          string
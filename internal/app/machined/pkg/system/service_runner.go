@@ -12,10 +12,12 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/talos-systems/talos/internal/app/machined/internal/event"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/conditions"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/events"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/health"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/restart"
 	"github.com/talos-systems/talos/internal/app/machined/proto"
 	"github.com/talos-systems/talos/pkg/userdata"
 )
@@ -39,8 +41,15 @@ type ServiceRunner struct {
 
 	healthState health.State
 
+	runnr runner.Runner
+
 	stateSubscribers map[StateEvent][]chan<- struct{}
 
+	// stopHook is called once the service leaves the running state, until
+	// it runs again
+	stopHook func(id string)
+	armed    bool
+
 	ctxMu     sync.Mutex
 	ctx       context.Context
 	ctxCancel context.CancelFunc
@@ -76,10 +85,25 @@ func (svcrunner *ServiceRunner) UpdateState(newstate events.ServiceState, messag
 
 	log.Printf("service[%s](%s): %s", svcrunner.id, svcrunner.state, event.Message)
 
+	// The service finishing on its own, e.g. a service run once, is not a
+	// stop, a stop requested is handled in run.
+	var stopped bool
+
+	switch newstate {
+	case events.StateRunning:
+		svcrunner.armed = true
+	case events.StateWaiting, events.StateStopping, events.StateFailed, events.StateCrashLoop:
+		stopped = svcrunner.disarmLocked()
+	}
+
 	isUp := svcrunner.inStateLocked(StateEventUp)
 	isDown := svcrunner.inStateLocked(StateEventDown)
 	svcrunner.mu.Unlock()
 
+	if stopped {
+		go svcrunner.stopHook(svcrunner.id)
+	}
+
 	if isUp {
 		svcrunner.notifyEvent(StateEventUp)
 	}
@@ -88,6 +112,14 @@ func (svcrunner *ServiceRunner) UpdateState(newstate events.ServiceState, messag
 	}
 }
 
+// disarmLocked reports whether the stop hook should be called, once per run.
+func (svcrunner *ServiceRunner) disarmLocked() bool {
+	fire := svcrunner.armed && svcrunner.stopHook != nil
+	svcrunner.armed = false
+
+	return fire
+}
+
 func (svcrunner *ServiceRunner) healthUpdate(change health.StateChange) {
 	svcrunner.mu.Lock()
 
@@ -214,6 +246,11 @@ func (svcrunner *ServiceRunner) Start() {
 
 	if err := svcrunner.run(ctx, runnr); err != nil {
 		svcrunner.UpdateState(events.StateFailed, "Failed running service: %v", err)
+
+		if restart.RebootRequested(err) {
+			log.Printf("service[%s]: crash loop detected, rebooting", svcrunner.id)
+			event.Bus().Publish(event.Reboot)
+		}
 	} else {
 		svcrunner.UpdateState(events.StateFinished, "Service finished successfully")
 	}
@@ -238,6 +275,10 @@ func (svcrunner *ServiceRunner) run(ctx context.Context, runnr runner.Runner) er
 	// nolint: errcheck
 	defer runnr.Close()
 
+	svcrunner.mu.Lock()
+	svcrunner.runnr = runnr
+	svcrunner.mu.Unlock()
+
 	errCh := make(chan error)
 
 	go func() {
@@ -282,6 +323,14 @@ func (svcrunner *ServiceRunner) run(ctx context.Context, runnr runner.Runner) er
 
 	select {
 	case <-ctx.Done():
+		svcrunner.mu.Lock()
+		stopped := svcrunner.disarmLocked()
+		svcrunner.mu.Unlock()
+
+		if stopped {
+			go svcrunner.stopHook(svcrunner.id)
+		}
+
 		err := runnr.Stop()
 		<-errCh
 		if err != nil {
@@ -310,12 +359,50 @@ func (svcrunner *ServiceRunner) AsProto() *proto.ServiceInfo {
 	svcrunner.mu.Lock()
 	defer svcrunner.mu.Unlock()
 
-	return &proto.ServiceInfo{
+	info := &proto.ServiceInfo{
 		Id:     svcrunner.id,
 		State:  svcrunner.state.String(),
 		Events: svcrunner.events.AsProto(events.MaxEventsToKeep),
 		Health: svcrunner.healthState.AsProto(),
 	}
+
+	if provider, ok := svcrunner.runnr.(runner.RestartStatsProvider); ok {
+		stats := provider.RestartStats()
+
+		info.Restarts = &proto.ServiceRestarts{
+			Count:        stats.Restarts,
+			LastExitCode: int32(stats.LastExitCode),
+		}
+
+		if stats.LastSignal != 0 {
+			info.Restarts.LastSignal = stats.LastSignal.String()
+		}
+	}
+
+	return info
+}
+
+// stateDescription returns short description of the service state including
+// health status for running healthchecked services.
+func (svcrunner *ServiceRunner) stateDescription() string {
+	svcrunner.mu.Lock()
+	state := svcrunner.state
+	svcrunner.mu.Unlock()
+
+	if _, ok := svcrunner.service.(HealthcheckedService); !ok || state != events.StateRunning {
+		return state.String()
+	}
+
+	health := svcrunner.healthState.Get()
+
+	switch {
+	case health.Healthy == nil:
+		return state.String() + ", health unknown"
+	case *health.Healthy:
+		return state.String() + ", healthy"
+	default:
+		return state.String() + ", unhealthy"
+	}
 }
 
 // Subscribe to a specific event for this service.
@@ -137,6 +137,49 @@ func (suite *ServiceRunnerSuite) TestFullFlowHealthChanges() {
 	}, sr)
 }
 
+// TestUpWaitsForHealth checks that the dependents of a service with a health
+// check only start once the check passes.
+func (suite *ServiceRunnerSuite) TestUpWaitsForHealth() {
+	m := MockHealthcheckedService{
+		MockService: MockService{
+			condition: conditions.None(),
+		},
+	}
+	m.SetHealthy(false)
+
+	sr := system.NewServiceRunner(&m, nil)
+
+	notifyCh := make(chan struct{}, 1)
+	sr.Subscribe(system.StateEventUp, notifyCh)
+	defer sr.Unsubscribe(system.StateEventUp, notifyCh)
+
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		sr.Start()
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	select {
+	case <-notifyCh:
+		suite.Require().Fail("service should not be up before it is healthy")
+	default:
+	}
+
+	m.SetHealthy(true)
+
+	select {
+	case <-notifyCh:
+	case <-time.After(time.Second):
+		suite.Require().Fail("service should be up once it is healthy")
+	}
+
+	sr.Shutdown()
+
+	<-finished
+}
+
 func (suite *ServiceRunnerSuite) TestWaitingDescriptionChange() {
 	oldWaitConditionCheckInterval := system.WaitConditionCheckInterval
 	system.WaitConditionCheckInterval = 10 * time.Millisecond
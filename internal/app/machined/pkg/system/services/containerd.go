@@ -9,10 +9,7 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/containerd/containerd"
 	"github.com/containerd/containerd/defaults"
-	"github.com/pkg/errors"
-	"google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/conditions"
@@ -72,30 +69,13 @@ func (c *Containerd) Runner(data *userdata.UserData) (runner.Runner, error) {
 		runner.WithEnv(env),
 	),
 		restart.WithType(restart.Forever),
+		crashLoopAction(data, c.ID(data)),
 	), nil
 }
 
 // HealthFunc implements the HealthcheckedService interface
 func (c *Containerd) HealthFunc(*userdata.UserData) health.Check {
-	return func(ctx context.Context) error {
-		client, err := containerd.New(constants.ContainerdAddress)
-		if err != nil {
-			return err
-		}
-		// nolint: errcheck
-		defer client.Close()
-
-		resp, err := client.HealthService().Check(ctx, &grpc_health_v1.HealthCheckRequest{})
-		if err != nil {
-			return err
-		}
-
-		if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
-			return errors.Errorf("unexpected serving status: %d", resp.Status)
-		}
-
-		return nil
-	}
+	return health.GRPC("unix", constants.ContainerdAddress, "")
 }
 
 // HealthSettings implements the HealthcheckedService interface
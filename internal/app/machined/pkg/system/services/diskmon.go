@@ -0,0 +1,79 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package services
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/talos-systems/talos/internal/app/machined/internal/diskmon"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/conditions"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/health"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/goroutine"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/recorder"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+// DiskMonitor implements the Service interface. It serves as the concrete
+// type with the required methods.
+type DiskMonitor struct{}
+
+// ID implements the Service interface.
+func (d *DiskMonitor) ID(data *userdata.UserData) string {
+	return "diskmon"
+}
+
+// PreFunc implements the Service interface.
+func (d *DiskMonitor) PreFunc(ctx context.Context, data *userdata.UserData) error {
+	return nil
+}
+
+// PostFunc implements the Service interface.
+func (d *DiskMonitor) PostFunc(data *userdata.UserData) (err error) {
+	return nil
+}
+
+// Condition implements the Service interface.
+func (d *DiskMonitor) Condition(data *userdata.UserData) conditions.Condition {
+	return nil
+}
+
+// DependsOn implements the Service interface.
+func (d *DiskMonitor) DependsOn(data *userdata.UserData) []string {
+	return nil
+}
+
+// Runner implements the Service interface. The problems found by the monitor
+// are recorded as the events of the service.
+func (d *DiskMonitor) Runner(data *userdata.UserData) (runner.Runner, error) {
+	return recorder.New(func(record recorder.Func) runner.Runner {
+		return goroutine.NewRunner(data, d.ID(data), func(ctx context.Context, data *userdata.UserData, logOutput io.Writer) error {
+			return diskmon.Instance().Run(ctx, logOutput, record)
+		})
+	}), nil
+}
+
+// HealthFunc implements the HealthcheckedService interface
+func (d *DiskMonitor) HealthFunc(*userdata.UserData) health.Check {
+	return diskmon.Instance().Health
+}
+
+// HealthSettings implements the HealthcheckedService interface
+func (d *DiskMonitor) HealthSettings(*userdata.UserData) *health.Settings {
+	return &health.Settings{
+		// Leave the time for the first check.
+		InitialDelay: 5 * time.Second,
+		Period:       10 * time.Second,
+		Timeout:      time.Second,
+	}
+}
+
+// Verify healthchecked interface
+var (
+	_ system.HealthcheckedService = &DiskMonitor{}
+)
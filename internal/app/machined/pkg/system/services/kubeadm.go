@@ -21,6 +21,7 @@ import (
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/conditions"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/containerd"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/restart"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/services/kubeadm"
 	"github.com/talos-systems/talos/internal/app/trustd/proto"
 	"github.com/talos-systems/talos/pkg/constants"
@@ -166,6 +167,9 @@ func (k *Kubeadm) Runner(data *userdata.UserData) (runner.Runner, error) {
 
 	ignorePreflightErrors := []string{"cri", "kubeletversion", "numcpu", "ipvsproxiercheck"}
 	ignorePreflightErrors = append(ignorePreflightErrors, data.Services.Kubeadm.IgnorePreflightErrors...)
+	if data.Install != nil && data.Install.Swap.Enabled() {
+		ignorePreflightErrors = append(ignorePreflightErrors, "Swap")
+	}
 	ignore := "--ignore-preflight-errors=" + strings.Join(ignorePreflightErrors, ",")
 
 	switch data.Services.Kubeadm.Configuration.(type) {
@@ -210,7 +214,7 @@ func (k *Kubeadm) Runner(data *userdata.UserData) (runner.Runner, error) {
 		env = append(env, fmt.Sprintf("%s=%s", key, val))
 	}
 
-	return containerd.NewRunner(
+	return restart.New(containerd.NewRunner(
 		data,
 		&args,
 		runner.WithNamespace(criconstants.K8sContainerdNamespace),
@@ -224,5 +228,8 @@ func (k *Kubeadm) Runner(data *userdata.UserData) (runner.Runner, error) {
 			oci.WithParentCgroupDevices,
 			oci.WithPrivileged,
 		),
+	),
+		restart.WithType(restart.Once),
+		crashLoopAction(data, k.ID(data)),
 	), nil
 }
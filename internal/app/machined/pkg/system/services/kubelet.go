@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -15,9 +16,10 @@ import (
 	"github.com/containerd/containerd/oci"
 	criconstants "github.com/containerd/cri/pkg/constants"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
-	"github.com/pkg/errors"
 
 	"github.com/talos-systems/talos/internal/app/machined/internal/cni"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform"
+	"github.com/talos-systems/talos/internal/app/machined/internal/platform/metadata"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/conditions"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/health"
@@ -83,6 +85,22 @@ func (k *Kubelet) Runner(data *userdata.UserData) (runner.Runner, error) {
 	argsString := strings.TrimPrefix(string(fileBytes), "KUBELET_KUBEADM_ARGS=\"")
 	argsString = strings.TrimSuffix(argsString, "\"\n")
 	args.ProcessArgs = append(args.ProcessArgs, strings.Split(argsString, " ")...)
+
+	if data.Services != nil && data.Services.Kubelet != nil && data.Services.Kubelet.TopologyLabels {
+		if m := topologyMetadata(topologyTimeout); m != nil {
+			args.ProcessArgs = mergeNodeLabels(args.ProcessArgs, m)
+		}
+	}
+
+	// The kubelet refuses to start with swap on, and the kubelet configuration
+	// is shared by the nodes joining the cluster, so the flag is set per node.
+	// The memorySwap setting of the kubelet configuration is not set, the
+	// shipped kubelet doesn't support it, so the swap of the pods is not
+	// limited.
+	if data.Install != nil && data.Install.Swap.Enabled() {
+		args.ProcessArgs = append(args.ProcessArgs, "--fail-swap-on=false")
+	}
+
 	// Set the required kubelet mounts.
 	mounts := []specs.Mount{
 		{Type: "bind", Destination: "/dev", Source: "/dev", Options: []string{"rbind", "rshared", "rw"}},
@@ -133,33 +151,65 @@ func (k *Kubelet) Runner(data *userdata.UserData) (runner.Runner, error) {
 		),
 	),
 		restart.WithType(restart.Forever),
+		crashLoopAction(data, k.ID(data)),
 	), nil
 }
 
-// HealthFunc implements the HealthcheckedService interface
-func (k *Kubelet) HealthFunc(*userdata.UserData) health.Check {
-	return func(ctx context.Context) error {
-		req, err := http.NewRequest("GET", "http://127.0.0.1:10248/healthz", nil)
-		if err != nil {
-			return err
-		}
-		req = req.WithContext(ctx)
+// topologyTimeout is how long kubelet start waits for the platform metadata.
+const topologyTimeout = 10 * time.Second
 
-		resp, err := http.DefaultClient.Do(req)
+// topologyMetadata returns the metadata reported by the platform, which
+// holds the region and the zone of the node. The metadata is fetched in the
+// background: failure to fetch it in time shouldn't prevent kubelet from
+// starting, so the error is only logged. The platform keeps the metadata once
+// it is fetched, so metadata arriving late is picked up on the next start, and
+// a failed fetch is retried on the next start.
+func topologyMetadata(timeout time.Duration) *metadata.Metadata {
+	done := make(chan *metadata.Metadata, 1)
+
+	go func() {
+		m, err := platform.Metadata()
 		if err != nil {
-			return err
+			log.Printf("failed to fetch metadata for topology labels: %v", err)
 		}
-		// nolint: errcheck
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			return errors.Errorf("expected HTTP status OK, got %s", resp.Status)
-		}
+		done <- m
+	}()
 
+	select {
+	case m := <-done:
+		return m
+	case <-time.After(timeout):
+		log.Printf("timed out fetching metadata for topology labels, starting kubelet without them")
 		return nil
 	}
 }
 
+// mergeNodeLabels adds the topology labels to the --node-labels flag set by
+// kubeadm, as repeating the flag would override the configured labels.
+func mergeNodeLabels(args []string, m *metadata.Metadata) []string {
+	const flag = "--node-labels="
+
+	for i, arg := range args {
+		if strings.HasPrefix(arg, flag) {
+			args[i] = flag + m.MergeLabels(strings.TrimPrefix(arg, flag))
+
+			return args
+		}
+	}
+
+	if labels := m.LabelsString(); labels != "" {
+		args = append(args, flag+labels)
+	}
+
+	return args
+}
+
+// HealthFunc implements the HealthcheckedService interface
+func (k *Kubelet) HealthFunc(*userdata.UserData) health.Check {
+	return health.HTTPGet("http://127.0.0.1:10248/healthz", http.StatusOK)
+}
+
 // HealthSettings implements the HealthcheckedService interface
 func (k *Kubelet) HealthSettings(*userdata.UserData) *health.Settings {
 	settings := health.DefaultSettings
@@ -8,9 +8,12 @@ import (
 	"context"
 
 	"github.com/talos-systems/talos/internal/app/machined/internal/api"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/conditions"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/health"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/goroutine"
+	"github.com/talos-systems/talos/pkg/constants"
 	"github.com/talos-systems/talos/pkg/userdata"
 )
 
@@ -47,3 +50,18 @@ func (c *MachinedAPI) DependsOn(data *userdata.UserData) []string {
 func (c *MachinedAPI) Runner(data *userdata.UserData) (runner.Runner, error) {
 	return goroutine.NewRunner(data, "machined-api", api.NewService().Main), nil
 }
+
+// HealthFunc implements the HealthcheckedService interface
+func (c *MachinedAPI) HealthFunc(*userdata.UserData) health.Check {
+	return health.GRPC("unix", constants.InitSocketPath, "")
+}
+
+// HealthSettings implements the HealthcheckedService interface
+func (c *MachinedAPI) HealthSettings(*userdata.UserData) *health.Settings {
+	return &health.DefaultSettings
+}
+
+// Verify healthchecked interface
+var (
+	_ system.HealthcheckedService = &MachinedAPI{}
+)
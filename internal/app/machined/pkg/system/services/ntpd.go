@@ -8,11 +8,15 @@ package services
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	containerdapi "github.com/containerd/containerd"
 	"github.com/containerd/containerd/oci"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/conditions"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/health"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/containerd"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/restart"
@@ -31,6 +35,10 @@ func (n *NTPd) ID(data *userdata.UserData) string {
 
 // PreFunc implements the Service interface.
 func (n *NTPd) PreFunc(ctx context.Context, data *userdata.UserData) error {
+	if err := os.MkdirAll(filepath.Dir(constants.NtpdSocketPath), 0700); err != nil {
+		return err
+	}
+
 	return containerd.Import(constants.SystemContainerdNamespace, &containerd.ImportRequest{
 		Path: "/usr/images/ntpd.tar",
 		Options: []containerdapi.ImportOpt{
@@ -64,6 +72,7 @@ func (n *NTPd) Runner(data *userdata.UserData) (runner.Runner, error) {
 
 	mounts := []specs.Mount{
 		{Type: "bind", Destination: constants.UserDataPath, Source: constants.UserDataPath, Options: []string{"rbind", "ro"}},
+		{Type: "bind", Destination: filepath.Dir(constants.NtpdSocketPath), Source: filepath.Dir(constants.NtpdSocketPath), Options: []string{"rbind", "rw"}},
 	}
 
 	env := []string{}
@@ -82,5 +91,21 @@ func (n *NTPd) Runner(data *userdata.UserData) (runner.Runner, error) {
 		),
 	),
 		restart.WithType(restart.Forever),
+		crashLoopAction(data, n.ID(data)),
 	), nil
 }
+
+// HealthFunc implements the HealthcheckedService interface
+func (n *NTPd) HealthFunc(*userdata.UserData) health.Check {
+	return health.GRPC("unix", constants.NtpdSocketPath, "")
+}
+
+// HealthSettings implements the HealthcheckedService interface
+func (n *NTPd) HealthSettings(*userdata.UserData) *health.Settings {
+	return &health.DefaultSettings
+}
+
+// Verify healthchecked interface
+var (
+	_ system.HealthcheckedService = &NTPd{}
+)
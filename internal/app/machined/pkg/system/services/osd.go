@@ -8,7 +8,6 @@ package services
 import (
 	"context"
 	"fmt"
-	"net"
 	"path/filepath"
 
 	containerdapi "github.com/containerd/containerd"
@@ -93,20 +92,13 @@ func (o *OSD) Runner(data *userdata.UserData) (runner.Runner, error) {
 		),
 	),
 		restart.WithType(restart.Forever),
+		crashLoopAction(data, o.ID(data)),
 	), nil
 }
 
 // HealthFunc implements the HealthcheckedService interface
 func (o *OSD) HealthFunc(*userdata.UserData) health.Check {
-	return func(ctx context.Context) error {
-		var d net.Dialer
-		conn, err := d.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", "127.0.0.1", constants.OsdPort))
-		if err != nil {
-			return err
-		}
-
-		return conn.Close()
-	}
+	return health.TCPConnect(fmt.Sprintf("%s:%d", "127.0.0.1", constants.OsdPort))
 }
 
 // HealthSettings implements the HealthcheckedService interface
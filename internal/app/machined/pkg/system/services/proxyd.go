@@ -8,7 +8,8 @@ package services
 import (
 	"context"
 	"fmt"
-	"net"
+	"os"
+	"path/filepath"
 
 	containerdapi "github.com/containerd/containerd"
 	"github.com/containerd/containerd/oci"
@@ -34,6 +35,10 @@ func (p *Proxyd) ID(data *userdata.UserData) string {
 
 // PreFunc implements the Service interface.
 func (p *Proxyd) PreFunc(ctx context.Context, data *userdata.UserData) error {
+	if err := os.MkdirAll(filepath.Dir(constants.ProxydSocketPath), 0700); err != nil {
+		return err
+	}
+
 	return containerd.Import(constants.SystemContainerdNamespace, &containerd.ImportRequest{
 		Path: "/usr/images/proxyd.tar",
 		Options: []containerdapi.ImportOpt{
@@ -71,6 +76,7 @@ func (p *Proxyd) Runner(data *userdata.UserData) (runner.Runner, error) {
 		{Type: "bind", Destination: "/tmp", Source: "/tmp", Options: []string{"rbind", "rshared", "rw"}},
 		{Type: "bind", Destination: "/etc/kubernetes", Source: "/etc/kubernetes", Options: []string{"rbind", "ro"}},
 		{Type: "bind", Destination: constants.UserDataPath, Source: constants.UserDataPath, Options: []string{"rbind", "ro"}},
+		{Type: "bind", Destination: filepath.Dir(constants.ProxydSocketPath), Source: filepath.Dir(constants.ProxydSocketPath), Options: []string{"rbind", "rw"}},
 	}
 
 	env := []string{}
@@ -90,20 +96,16 @@ func (p *Proxyd) Runner(data *userdata.UserData) (runner.Runner, error) {
 		),
 	),
 		restart.WithType(restart.Forever),
+		crashLoopAction(data, p.ID(data)),
 	), nil
 }
 
 // HealthFunc implements the HealthcheckedService interface
 func (p *Proxyd) HealthFunc(*userdata.UserData) health.Check {
-	return func(ctx context.Context) error {
-		var d net.Dialer
-		conn, err := d.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", "127.0.0.1", 443))
-		if err != nil {
-			return err
-		}
-
-		return conn.Close()
-	}
+	return health.All(
+		health.TCPConnect(fmt.Sprintf("%s:%d", "127.0.0.1", 443)),
+		health.GRPC("unix", constants.ProxydSocketPath, ""),
+	)
 }
 
 // HealthSettings implements the HealthcheckedService interface
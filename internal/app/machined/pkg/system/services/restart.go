@@ -0,0 +1,60 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package services
+
+import (
+	"log"
+
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/restart"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+// commonOptions returns the userdata options common to all services for the
+// service with the specified ID.
+//
+// nolint: gocyclo
+func commonOptions(data *userdata.UserData, id string) *userdata.CommonServiceOptions {
+	if data.Services == nil {
+		return nil
+	}
+
+	switch {
+	case id == "containerd" && data.Services.CRT != nil:
+		return &data.Services.CRT.CommonServiceOptions
+	case id == "kubeadm" && data.Services.Kubeadm != nil:
+		return &data.Services.Kubeadm.CommonServiceOptions
+	case id == "kubelet" && data.Services.Kubelet != nil:
+		return &data.Services.Kubelet.CommonServiceOptions
+	case id == "ntpd" && data.Services.NTPd != nil:
+		return &data.Services.NTPd.CommonServiceOptions
+	case id == "osd" && data.Services.OSD != nil:
+		return &data.Services.OSD.CommonServiceOptions
+	case id == "proxyd" && data.Services.Proxyd != nil:
+		return &data.Services.Proxyd.CommonServiceOptions
+	case id == "trustd" && data.Services.Trustd != nil:
+		return &data.Services.Trustd.CommonServiceOptions
+	case id == "udevd" && data.Services.Udevd != nil:
+		return &data.Services.Udevd.CommonServiceOptions
+	default:
+		return nil
+	}
+}
+
+// crashLoopAction returns the restart option with the crash loop action
+// configured in the userdata for the service.
+func crashLoopAction(data *userdata.UserData, id string) restart.Option {
+	var action string
+
+	if opts := commonOptions(data, id); opts != nil {
+		action = opts.CrashLoopAction
+	}
+
+	parsed, err := restart.ParseCrashLoopAction(action)
+	if err != nil {
+		log.Printf("service[%s]: %v, using default", id, err)
+	}
+
+	return restart.WithCrashLoopAction(parsed)
+}
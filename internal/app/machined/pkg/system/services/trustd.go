@@ -8,7 +8,6 @@ package services
 import (
 	"context"
 	"fmt"
-	"net"
 
 	containerdapi "github.com/containerd/containerd"
 	"github.com/containerd/containerd/oci"
@@ -89,20 +88,13 @@ func (t *Trustd) Runner(data *userdata.UserData) (runner.Runner, error) {
 		),
 	),
 		restart.WithType(restart.Forever),
+		crashLoopAction(data, t.ID(data)),
 	), nil
 }
 
 // HealthFunc implements the HealthcheckedService interface
 func (t *Trustd) HealthFunc(*userdata.UserData) health.Check {
-	return func(ctx context.Context) error {
-		var d net.Dialer
-		conn, err := d.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", "127.0.0.1", constants.TrustdPort))
-		if err != nil {
-			return err
-		}
-
-		return conn.Close()
-	}
+	return health.TCPConnect(fmt.Sprintf("%s:%d", "127.0.0.1", constants.TrustdPort))
 }
 
 // HealthSettings implements the HealthcheckedService interface
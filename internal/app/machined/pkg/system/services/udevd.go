@@ -9,7 +9,9 @@ import (
 	"fmt"
 	"os/exec"
 
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/conditions"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/health"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/process"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system/runner/restart"
@@ -73,5 +75,21 @@ func (c *Udevd) Runner(data *userdata.UserData) (runner.Runner, error) {
 		runner.WithEnv(env),
 	),
 		restart.WithType(restart.Forever),
+		crashLoopAction(data, c.ID(data)),
 	), nil
 }
+
+// HealthFunc implements the HealthcheckedService interface
+func (c *Udevd) HealthFunc(*userdata.UserData) health.Check {
+	return health.FileExists("/run/udev/control")
+}
+
+// HealthSettings implements the HealthcheckedService interface
+func (c *Udevd) HealthSettings(*userdata.UserData) *health.Settings {
+	return &health.DefaultSettings
+}
+
+// Verify healthchecked interface
+var (
+	_ system.HealthcheckedService = &Udevd{}
+)
@@ -7,7 +7,7 @@ package system
 import (
 	"context"
 	"fmt"
-	"sort"
+	"log"
 	"sync"
 	"time"
 
@@ -27,13 +27,18 @@ type singleton struct {
 	// List of running services at the moment.
 	//
 	// Service might be in any state, but service ID in the map
-	// implies ServiceRunner.Start() method is running at the momemnt
+	// implies ServiceRunner.Start() method is running at the momemnt,
+	// channel is closed once ServiceRunner.Start() returns
 	runningMu sync.Mutex
-	running   map[string]struct{}
+	running   map[string]chan struct{}
 
 	mu          sync.Mutex
 	wg          sync.WaitGroup
 	terminating bool
+
+	// restartMu serializes the restarts of the services and of their
+	// dependents
+	restartMu sync.Mutex
 }
 
 var instance *singleton
@@ -46,7 +51,7 @@ func Services(data *userdata.UserData) *singleton {
 		instance = &singleton{
 			UserData: data,
 			state:    make(map[string]*ServiceRunner),
-			running:  make(map[string]struct{}),
+			running:  make(map[string]chan struct{}),
 		}
 	})
 	return instance
@@ -54,15 +59,25 @@ func Services(data *userdata.UserData) *singleton {
 
 // Load adds service to the list of services managed by the runner.
 //
+// Load validates the dependency graph of the services: all the dependencies
+// should be loaded before or together with the service, and there should be
+// no dependency cycles. If validation fails, none of the services are loaded.
+//
 // Load returns service IDs for each of the services.
-func (s *singleton) Load(services ...Service) []string {
+func (s *singleton) Load(services ...Service) ([]string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.terminating {
-		return nil
+		return nil, nil
 	}
 
 	ids := make([]string, 0, len(services))
+	runners := make([]*ServiceRunner, 0, len(s.state)+len(services))
+	loaded := make(map[string]*ServiceRunner, len(services))
+
+	for _, svcrunner := range s.state {
+		runners = append(runners, svcrunner)
+	}
 
 	for _, service := range services {
 		id := service.ID(s.UserData)
@@ -73,16 +88,33 @@ func (s *singleton) Load(services ...Service) []string {
 			continue
 		}
 
+		if _, exists := loaded[id]; exists {
+			continue
+		}
+
 		svcrunner := NewServiceRunner(service, s.UserData)
+		svcrunner.stopHook = s.restartDependents
+		loaded[id] = svcrunner
+		runners = append(runners, svcrunner)
+	}
+
+	if err := NewDependencyGraph(runners...).Validate(); err != nil {
+		return nil, errors.Wrap(err, "error validating service dependencies")
+	}
+
+	for id, svcrunner := range loaded {
 		s.state[id] = svcrunner
 	}
 
-	return ids
+	return ids, nil
 }
 
 // Start will invoke the service's Pre, Condition, and Type funcs. If the any
 // error occurs in the Pre or Condition invocations, it is up to the caller to
 // to restart the service.
+//
+// The services wait for their dependencies to be up before they run, so the
+// services can be started in any order.
 func (s *singleton) Start(serviceIDs ...string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -96,12 +128,14 @@ func (s *singleton) Start(serviceIDs ...string) error {
 		svcrunner := s.state[id]
 		if svcrunner == nil {
 			multiErr = multierror.Append(multiErr, errors.Errorf("service %q not defined", id))
+			continue
 		}
 
 		s.runningMu.Lock()
 		_, running := s.running[id]
+		done := make(chan struct{})
 		if !running {
-			s.running[id] = struct{}{}
+			s.running[id] = done
 		}
 		s.runningMu.Unlock()
 
@@ -115,6 +149,7 @@ func (s *singleton) Start(serviceIDs ...string) error {
 			defer func() {
 				s.runningMu.Lock()
 				delete(s.running, id)
+				close(done)
 				s.runningMu.Unlock()
 			}()
 			defer s.wg.Done()
@@ -140,12 +175,13 @@ func (s *singleton) StartAll() {
 }
 
 // LoadAndStart combines Load and Start into single call.
-func (s *singleton) LoadAndStart(services ...Service) {
-	err := s.Start(s.Load(services...)...)
+func (s *singleton) LoadAndStart(services ...Service) error {
+	ids, err := s.Load(services...)
 	if err != nil {
-		// should never happen
-		panic(err)
+		return err
 	}
+
+	return s.Start(ids...)
 }
 
 // Shutdown all the services
@@ -162,23 +198,31 @@ func (s *singleton) Shutdown() {
 	}
 	s.mu.Unlock()
 
-	// build reverse dependencies
-	reverseDependencies := make(map[string][]string)
+	// wait max 30 seconds for reverse deps to shut down
+	shutdownCtx, shutdownCtxCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer shutdownCtxCancel()
 
-	for name, svcrunner := range stateCopy {
-		for _, dependency := range svcrunner.service.DependsOn(s.UserData) {
-			reverseDependencies[dependency] = append(reverseDependencies[dependency], name)
-		}
+	s.shutdownOrdered(shutdownCtx, stateCopy)
+
+	s.wg.Wait()
+}
+
+// shutdownOrdered shuts down the services in the reverse dependency order:
+// each service is shut down once all the services (from the set) which depend
+// on it are down, or once the context is canceled.
+func (s *singleton) shutdownOrdered(ctx context.Context, services map[string]*ServiceRunner) {
+	runners := make([]*ServiceRunner, 0, len(services))
+	for _, svcrunner := range services {
+		runners = append(runners, svcrunner)
 	}
 
+	// build reverse dependencies
+	reverseDependencies := NewDependencyGraph(runners...).ReverseDependencies()
+
 	// shutdown all the services waiting for rev deps
 	var shutdownWg sync.WaitGroup
 
-	// wait max 30 seconds for reverse deps to shut down
-	shutdownCtx, shutdownCtxCancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer shutdownCtxCancel()
-
-	for name, svcrunner := range stateCopy {
+	for name, svcrunner := range services {
 		shutdownWg.Add(1)
 		go func(svcrunner *ServiceRunner, reverseDeps []string) {
 			defer shutdownWg.Done()
@@ -188,14 +232,117 @@ func (s *singleton) Shutdown() {
 			}
 
 			// nolint: errcheck
-			_ = conditions.WaitForAll(conds...).Wait(shutdownCtx)
+			_ = conditions.WaitForAll(conds...).Wait(ctx)
 
 			svcrunner.Shutdown()
 		}(svcrunner, reverseDependencies[name])
 	}
 	shutdownWg.Wait()
+}
 
-	s.wg.Wait()
+// Restart restarts the service. The running services which depend on it are
+// restarted as the service goes down, see restartDependents.
+func (s *singleton) Restart(ctx context.Context, id string) error {
+	s.restartMu.Lock()
+	defer s.restartMu.Unlock()
+
+	s.mu.Lock()
+	if s.terminating {
+		s.mu.Unlock()
+		return nil
+	}
+
+	svcrunner, ok := s.state[id]
+	if !ok {
+		s.mu.Unlock()
+		return errors.Errorf("service %q not defined", id)
+	}
+	s.mu.Unlock()
+
+	svcrunner.Shutdown()
+
+	if err := s.waitStopped(ctx, id); err != nil {
+		return errors.Wrapf(err, "error waiting for service %q to stop", id)
+	}
+
+	return s.Start(id)
+}
+
+// restartDependents restarts the running services which depend directly on
+// the service, once the service leaves the running state: it is stopped or
+// restarted, or its process exited. The dependents wait for the service to be
+// up again, and their own dependents are restarted the same way as they go
+// down.
+func (s *singleton) restartDependents(id string) {
+	s.restartMu.Lock()
+	defer s.restartMu.Unlock()
+
+	s.mu.Lock()
+	if s.terminating {
+		s.mu.Unlock()
+		return
+	}
+
+	serviceIDs := []string{}
+
+	s.runningMu.Lock()
+	for _, dependent := range s.graphLocked().ReverseDependencies()[id] {
+		if _, running := s.running[dependent]; running {
+			serviceIDs = append(serviceIDs, dependent)
+		}
+	}
+	s.runningMu.Unlock()
+
+	runners := make([]*ServiceRunner, 0, len(serviceIDs))
+	for _, dependent := range serviceIDs {
+		runners = append(runners, s.state[dependent])
+	}
+	s.mu.Unlock()
+
+	if len(serviceIDs) == 0 {
+		return
+	}
+
+	log.Printf("service[%s]: restarting the dependent services %v", id, serviceIDs)
+
+	for _, svcrunner := range runners {
+		svcrunner.Shutdown()
+	}
+
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer ctxCancel()
+
+	if err := s.waitStopped(ctx, serviceIDs...); err != nil {
+		log.Printf("service[%s]: error waiting for the dependent services to stop: %v", id, err)
+		return
+	}
+
+	if err := s.Start(serviceIDs...); err != nil {
+		log.Printf("service[%s]: error starting the dependent services: %v", id, err)
+	}
+}
+
+// waitStopped waits for ServiceRunner.Start() to return for each of the
+// services.
+func (s *singleton) waitStopped(ctx context.Context, serviceIDs ...string) error {
+	s.runningMu.Lock()
+	chans := make([]chan struct{}, 0, len(serviceIDs))
+	for _, id := range serviceIDs {
+		if done, running := s.running[id]; running {
+			chans = append(chans, done)
+		}
+	}
+	s.runningMu.Unlock()
+
+	for _, done := range chans {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
 }
 
 // List returns snapshot of ServiceRunner instances
@@ -203,16 +350,24 @@ func (s *singleton) List() (result []*ServiceRunner) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	result = make([]*ServiceRunner, 0, len(s.state))
+	return s.graphLocked().Sorted()
+}
+
+// Graph returns snapshot of the service dependency graph
+func (s *singleton) Graph() *DependencyGraph {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.graphLocked()
+}
+
+func (s *singleton) graphLocked() *DependencyGraph {
+	runners := make([]*ServiceRunner, 0, len(s.state))
 	for _, svcrunner := range s.state {
-		result = append(result, svcrunner)
+		runners = append(runners, svcrunner)
 	}
 
-	// TODO: results should be sorted properly with topological sort on dependencies
-	//       but, we don't have dependencies yet, so sort by service id for now to get stable order
-	sort.Slice(result, func(i, j int) bool { return result[i].id < result[j].id })
-
-	return
+	return NewDependencyGraph(runners...)
 }
 
 // Stop will initiate a shutdown of the specified service.
@@ -231,6 +386,7 @@ func (s *singleton) Stop(ctx context.Context, serviceIDs ...string) (err error)
 	stateCopy := make(map[string]*ServiceRunner)
 	for _, id := range serviceIDs {
 		if _, ok := s.state[id]; !ok {
+			s.mu.Unlock()
 			return fmt.Errorf("service not found: %s", id)
 		}
 		stateCopy[id] = s.state[id]
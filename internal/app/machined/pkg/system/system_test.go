@@ -11,19 +11,124 @@ import (
 
 	"github.com/stretchr/testify/suite"
 	"github.com/talos-systems/talos/internal/app/machined/pkg/system"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/conditions"
+	"github.com/talos-systems/talos/internal/app/machined/pkg/system/events"
 )
 
 type SystemServicesSuite struct {
 	suite.Suite
 }
 
+func (suite *SystemServicesSuite) TestLoadInvalidDependencies() {
+	_, err := system.Services(nil).Load(
+		&MockService{name: "invalid-a", dependencies: []string{"invalid-b"}},
+		&MockService{name: "invalid-b", dependencies: []string{"invalid-a"}},
+		&MockService{name: "invalid-c", dependencies: []string{"invalid-d"}},
+	)
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), `service "invalid-c" depends on unknown service "invalid-d"`)
+	suite.Assert().Contains(err.Error(), "dependency cycle detected: invalid-a -> invalid-b -> invalid-a")
+
+	// nothing should be loaded
+	suite.Assert().Empty(system.Services(nil).List())
+}
+
+func (suite *SystemServicesSuite) TestRestart() {
+	suite.Require().NoError(system.Services(nil).LoadAndStart(
+		&MockService{name: "restart-a"},
+		&MockService{name: "restart-b", dependencies: []string{"restart-a"}},
+		&MockService{name: "restart-c", dependencies: []string{"restart-b"}},
+		&MockService{name: "restart-d"},
+	))
+
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer ctxCancel()
+
+	waitUp := func() {
+		suite.Require().NoError(conditions.WaitForAll(
+			system.WaitForService(system.StateEventUp, "restart-a"),
+			system.WaitForService(system.StateEventUp, "restart-b"),
+			system.WaitForService(system.StateEventUp, "restart-c"),
+			system.WaitForService(system.StateEventUp, "restart-d"),
+		).Wait(ctx))
+	}
+
+	waitUp()
+
+	suite.Require().NoError(system.Services(nil).Restart(ctx, "restart-a"))
+
+	// the dependents are restarted as the services they depend on go down
+	suite.Require().NoError(suite.waitFinished(ctx, "restart-a", "restart-b", "restart-c"))
+
+	waitUp()
+
+	suite.Assert().False(suite.finished("restart-d"))
+
+	suite.Assert().EqualError(system.Services(nil).Restart(ctx, "restart-e"), `service "restart-e" not defined`)
+}
+
+// TestRestartOnStop makes sure the dependents are restarted when the service is
+// stopped outside of Restart, and wait for it to be started again.
+func (suite *SystemServicesSuite) TestRestartOnStop() {
+	suite.Require().NoError(system.Services(nil).LoadAndStart(
+		&MockService{name: "cascade-a"},
+		&MockService{name: "cascade-b", dependencies: []string{"cascade-a"}},
+	))
+
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer ctxCancel()
+
+	suite.Require().NoError(conditions.WaitForAll(
+		system.WaitForService(system.StateEventUp, "cascade-a"),
+		system.WaitForService(system.StateEventUp, "cascade-b"),
+	).Wait(ctx))
+
+	suite.Require().NoError(system.Services(nil).Stop(ctx, "cascade-a"))
+	suite.Require().NoError(suite.waitFinished(ctx, "cascade-b"))
+
+	suite.Require().NoError(system.Services(nil).Start("cascade-a"))
+	suite.Require().NoError(system.WaitForService(system.StateEventUp, "cascade-b").Wait(ctx))
+}
+
+// finished reports whether the service has finished at least once.
+func (suite *SystemServicesSuite) finished(id string) bool {
+	for _, svcrunner := range system.Services(nil).List() {
+		info := svcrunner.AsProto()
+		if info.Id != id {
+			continue
+		}
+
+		for _, event := range info.Events.Events {
+			if event.State == events.StateFinished.String() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (suite *SystemServicesSuite) waitFinished(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		for !suite.finished(id) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	return nil
+}
+
 func (suite *SystemServicesSuite) TestStartShutdown() {
-	system.Services(nil).LoadAndStart(
+	suite.Require().NoError(system.Services(nil).LoadAndStart(
 		&MockService{name: "containerd"},
 		&MockService{name: "proxyd", dependencies: []string{"containerd"}},
 		&MockService{name: "trustd", dependencies: []string{"containerd", "proxyd"}},
 		&MockService{name: "osd", dependencies: []string{"containerd"}},
-	)
+	))
 	time.Sleep(10 * time.Millisecond)
 	system.Services(nil).Shutdown()
 }
@@ -33,6 +138,7 @@ func TestSystemServicesSuite(t *testing.T) {
 }
 
 func (suite *SystemServicesSuite) TestStartStop() {
+	// nolint: errcheck
 	system.Services(nil).LoadAndStart(
 		&MockService{name: "yolo"},
 	)
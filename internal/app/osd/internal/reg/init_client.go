@@ -50,7 +50,7 @@ func (c *InitServiceClient) Upgrade(ctx context.Context, in *proto.UpgradeReques
 }
 
 // Reset executes the init Reset() API.
-func (c *InitServiceClient) Reset(ctx context.Context, in *empty.Empty) (data *proto.ResetReply, err error) {
+func (c *InitServiceClient) Reset(ctx context.Context, in *proto.ResetRequest) (data *proto.ResetReply, err error) {
 	return c.InitClient.Reset(ctx, in)
 }
 
@@ -64,11 +64,36 @@ func (c *InitServiceClient) Stop(ctx context.Context, in *proto.StopRequest) (da
 	return c.InitClient.Stop(ctx, in)
 }
 
+// ServiceRestart executes the init ServiceRestart() API.
+func (c *InitServiceClient) ServiceRestart(ctx context.Context, in *proto.ServiceRestartRequest) (data *proto.ServiceRestartReply, err error) {
+	return c.InitClient.ServiceRestart(ctx, in)
+}
+
+// BootReport executes the init BootReport() API.
+func (c *InitServiceClient) BootReport(ctx context.Context, in *empty.Empty) (data *proto.BootReportReply, err error) {
+	return c.InitClient.BootReport(ctx, in)
+}
+
+// Metadata executes the init Metadata() API.
+func (c *InitServiceClient) Metadata(ctx context.Context, in *empty.Empty) (data *proto.MetadataReply, err error) {
+	return c.InitClient.Metadata(ctx, in)
+}
+
+// ApplyUserData executes the init ApplyUserData() API.
+func (c *InitServiceClient) ApplyUserData(ctx context.Context, in *proto.ApplyUserDataRequest) (data *proto.ApplyUserDataReply, err error) {
+	return c.InitClient.ApplyUserData(ctx, in)
+}
+
 // ServiceList executes the init ServiceList() API.
 func (c *InitServiceClient) ServiceList(ctx context.Context, in *empty.Empty) (data *proto.ServiceListReply, err error) {
 	return c.InitClient.ServiceList(ctx, in)
 }
 
+// ServiceGraph executes the init ServiceGraph() API.
+func (c *InitServiceClient) ServiceGraph(ctx context.Context, in *proto.ServiceGraphRequest) (data *proto.ServiceGraphReply, err error) {
+	return c.InitClient.ServiceGraph(ctx, in)
+}
+
 func copyClientServer(msg interface{}, client grpc.ClientStream, srv grpc.ServerStream) error {
 	for {
 		err := client.RecvMsg(msg)
@@ -115,3 +140,13 @@ func (c *InitServiceClient) LS(req *proto.LSRequest, srv proto.Init_LSServer) er
 func (c *InitServiceClient) DF(ctx context.Context, in *empty.Empty) (reply *proto.DFReply, err error) {
 	return c.InitClient.DF(ctx, in)
 }
+
+// Disks implements the proto.InitServer interface.
+func (c *InitServiceClient) Disks(ctx context.Context, in *empty.Empty) (reply *proto.DisksReply, err error) {
+	return c.InitClient.Disks(ctx, in)
+}
+
+// DiskHealth implements the proto.InitServer interface.
+func (c *InitServiceClient) DiskHealth(ctx context.Context, in *empty.Empty) (reply *proto.DiskHealthReply, err error) {
+	return c.InitClient.DiskHealth(ctx, in)
+}
@@ -6,12 +6,16 @@ package reg
 
 import (
 	"context"
+	stdlibx509 "crypto/x509"
+	"encoding/pem"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
 
+	"github.com/pkg/errors"
 	"github.com/talos-systems/talos/internal/app/trustd/proto"
+	"github.com/talos-systems/talos/internal/pkg/ec2"
 	"github.com/talos-systems/talos/pkg/crypto/x509"
 	"github.com/talos-systems/talos/pkg/userdata"
 	"google.golang.org/grpc"
@@ -20,7 +24,8 @@ import (
 // Registrator is the concrete type that implements the factory.Registrator and
 // proto.TrustdServer interfaces.
 type Registrator struct {
-	Data *userdata.OSSecurity
+	Data     *userdata.OSSecurity
+	Identity *userdata.InstanceIdentity
 }
 
 // Register implements the factory.Registrator interface.
@@ -32,6 +37,15 @@ func (r *Registrator) Register(s *grpc.Server) {
 func (r *Registrator) Certificate(ctx context.Context, in *proto.CertificateRequest) (resp *proto.CertificateResponse, err error) {
 	// TODO: Verify that the request is coming from the IP addresss declared in
 	// the CSR.
+	switch {
+	case len(in.InstanceIdentity) > 0:
+		if err = r.verifyInstanceIdentity(in); err != nil {
+			return nil, errors.Wrap(err, "instance identity verification failed")
+		}
+	case r.Identity.Enforced():
+		return nil, errors.New("instance identity document is required")
+	}
+
 	signed, err := x509.NewCertificateFromCSRBytes(r.Data.CA.Crt, r.Data.CA.Key, in.Csr)
 	if err != nil {
 		return
@@ -45,6 +59,43 @@ func (r *Registrator) Certificate(ctx context.Context, in *proto.CertificateRequ
 	return resp, nil
 }
 
+// verifyIdentity is replaced in tests, as the documents are signed by AWS.
+var verifyIdentity = ec2.VerifyIdentity
+
+// verifyInstanceIdentity verifies the signed EC2 instance identity document,
+// checks that the instance runs in one of the allowed accounts and that the
+// private IP of the instance is one of the IPs in the CSR.
+func (r *Registrator) verifyInstanceIdentity(in *proto.CertificateRequest) error {
+	doc, err := verifyIdentity(in.InstanceIdentity)
+	if err != nil {
+		return err
+	}
+
+	if !r.Identity.AccountAllowed(doc.AccountID) {
+		return errors.Errorf("instance %s runs in account %s which is not allowed", doc.InstanceID, doc.AccountID)
+	}
+
+	pemBlock, _ := pem.Decode(in.Csr)
+	if pemBlock == nil {
+		return errors.New("failed to decode CSR")
+	}
+
+	csr, err := stdlibx509.ParseCertificateRequest(pemBlock.Bytes)
+	if err != nil {
+		return err
+	}
+
+	for _, ip := range csr.IPAddresses {
+		if ip.String() == doc.PrivateIP {
+			log.Printf("verified instance identity of %s (%s) in %s", doc.InstanceID, doc.PrivateIP, doc.Region)
+
+			return nil
+		}
+	}
+
+	return errors.Errorf("private IP %s of instance %s is not in the CSR", doc.PrivateIP, doc.InstanceID)
+}
+
 // ReadFile implements the proto.TrustdServer interface.
 func (r *Registrator) ReadFile(ctx context.Context, in *proto.ReadFileRequest) (resp *proto.ReadFileResponse, err error) {
 	var b []byte
@@ -2,13 +2,100 @@
  * License, v. 2.0. If a copy of the MPL was not distributed with this
  * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
 
-package reg_test
+package reg
 
-import "testing"
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"testing"
 
-func TestEmpty(t *testing.T) {
-	// added for accurate coverage estimation
-	//
-	// please remove it once any unit-test is added
-	// for this package
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/internal/app/trustd/proto"
+	"github.com/talos-systems/talos/internal/pkg/ec2"
+	"github.com/talos-systems/talos/pkg/crypto/x509"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+type RegSuite struct {
+	suite.Suite
+
+	security *userdata.OSSecurity
+	csr      []byte
+}
+
+func (suite *RegSuite) SetupSuite() {
+	ca, err := x509.NewSelfSignedCertificateAuthority()
+	suite.Require().NoError(err)
+
+	suite.security = &userdata.OSSecurity{
+		CA: &x509.PEMEncodedCertificateAndKey{Crt: ca.CrtPEM, Key: ca.KeyPEM},
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+
+	csr, err := x509.NewCertificateSigningRequest(key, x509.IPAddresses([]net.IP{net.ParseIP("10.0.0.10")}))
+	suite.Require().NoError(err)
+
+	suite.csr = csr.X509CertificateRequestPEM
+
+	verifyIdentity = func(signature []byte) (*ec2.IdentityDocument, error) {
+		return &ec2.IdentityDocument{
+			AccountID:  string(signature),
+			InstanceID: "i-1234567890abcdef0",
+			PrivateIP:  "10.0.0.10",
+		}, nil
+	}
+}
+
+func (suite *RegSuite) TearDownSuite() {
+	verifyIdentity = ec2.VerifyIdentity
+}
+
+func (suite *RegSuite) TestCertificate() {
+	for _, tt := range []struct {
+		name     string
+		identity *userdata.InstanceIdentity
+		document string
+		err      string
+	}{
+		{name: "optional"},
+		{name: "optional with document", document: "123456789012"},
+		{name: "required", identity: &userdata.InstanceIdentity{Required: true}, err: "instance identity document is required"},
+		{name: "required with document", identity: &userdata.InstanceIdentity{Required: true}, document: "123456789012"},
+		{name: "pinned account", identity: &userdata.InstanceIdentity{AccountIDs: []string{"123456789012"}}, document: "123456789012"},
+		{name: "pinned account without document", identity: &userdata.InstanceIdentity{AccountIDs: []string{"123456789012"}}, err: "instance identity document is required"},
+		{name: "other account", identity: &userdata.InstanceIdentity{AccountIDs: []string{"123456789012"}}, document: "210987654321", err: "account 210987654321 which is not allowed"},
+	} {
+		r := &Registrator{Data: suite.security, Identity: tt.identity}
+
+		resp, err := r.Certificate(context.Background(), &proto.CertificateRequest{
+			Csr:              suite.csr,
+			InstanceIdentity: []byte(tt.document),
+		})
+
+		if tt.err != "" {
+			suite.Assert().Error(err, tt.name)
+
+			if err != nil {
+				suite.Assert().Contains(err.Error(), tt.err, tt.name)
+			}
+
+			continue
+		}
+
+		suite.Assert().NoError(err, tt.name)
+
+		if err == nil {
+			suite.Assert().NotEmpty(resp.Crt, tt.name)
+		}
+	}
+}
+
+func TestRegSuite(t *testing.T) {
+	suite.Run(t, new(RegSuite))
 }
@@ -52,7 +52,7 @@ func main() {
 	}
 
 	err = factory.ListenAndServe(
-		&reg.Registrator{Data: data.Security.OS},
+		&reg.Registrator{Data: data.Security.OS, Identity: data.Services.Trustd.InstanceIdentity},
 		factory.Port(constants.TrustdPort),
 		factory.ServerOptions(
 			grpc.Creds(
@@ -0,0 +1,99 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package ec2 verifies the EC2 instance identity documents.
+//
+// See https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/instance-identity-documents.html.
+package ec2
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+
+	"github.com/fullsailor/pkcs7"
+	"github.com/pkg/errors"
+)
+
+// PublicCertificate is the AWS public certificate for the regions provided by
+// an AWS account.
+const PublicCertificate = `-----BEGIN CERTIFICATE-----
+MIIC7TCCAq0CCQCWukjZ5V4aZzAJBgcqhkjOOAQDMFwxCzAJBgNVBAYTAlVTMRkw
+FwYDVQQIExBXYXNoaW5ndG9uIFN0YXRlMRAwDgYDVQQHEwdTZWF0dGxlMSAwHgYD
+VQQKExdBbWF6b24gV2ViIFNlcnZpY2VzIExMQzAeFw0xMjAxMDUxMjU2MTJaFw0z
+ODAxMDUxMjU2MTJaMFwxCzAJBgNVBAYTAlVTMRkwFwYDVQQIExBXYXNoaW5ndG9u
+IFN0YXRlMRAwDgYDVQQHEwdTZWF0dGxlMSAwHgYDVQQKExdBbWF6b24gV2ViIFNl
+cnZpY2VzIExMQzCCAbcwggEsBgcqhkjOOAQBMIIBHwKBgQCjkvcS2bb1VQ4yt/5e
+ih5OO6kK/n1Lzllr7D8ZwtQP8fOEpp5E2ng+D6Ud1Z1gYipr58Kj3nssSNpI6bX3
+VyIQzK7wLclnd/YozqNNmgIyZecN7EglK9ITHJLP+x8FtUpt3QbyYXJdmVMegN6P
+hviYt5JH/nYl4hh3Pa1HJdskgQIVALVJ3ER11+Ko4tP6nwvHwh6+ERYRAoGBAI1j
+k+tkqMVHuAFcvAGKocTgsjJem6/5qomzJuKDmbJNu9Qxw3rAotXau8Qe+MBcJl/U
+hhy1KHVpCGl9fueQ2s6IL0CaO/buycU1CiYQk40KNHCcHfNiZbdlx1E9rpUp7bnF
+lRa2v1ntMX3caRVDdbtPEWmdxSCYsYFDk4mZrOLBA4GEAAKBgEbmeve5f8LIE/Gf
+MNmP9CM5eovQOGx5ho8WqD+aTebs+k2tn92BBPqeZqpWRa5P/+jrdKml1qx4llHW
+MXrs3IgIb6+hUIB+S8dz8/mmO0bpr76RoZVCXYab2CZedFut7qc3WUH9+EUAH5mw
+vSeDCOUMYQR7R9LINYwouHIziqQYMAkGByqGSM44BAMDLwAwLAIUWXBlk40xTwSw
+7HX32MxXYruse9ACFBNGmdX2ZBrVNGrN9N2f6ROk0k9K
+-----END CERTIFICATE-----`
+
+// IdentityDocument is the EC2 instance identity document.
+type IdentityDocument struct {
+	AccountID        string `json:"accountId"`
+	InstanceID       string `json:"instanceId"`
+	InstanceType     string `json:"instanceType"`
+	ImageID          string `json:"imageId"`
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availabilityZone"`
+	PrivateIP        string `json:"privateIp"`
+}
+
+// VerifyIdentity verifies the PKCS7 signature of the instance identity
+// document against the AWS public certificate and returns the signed
+// document.
+//
+// The signature is expected in the format of the instance-identity/pkcs7
+// metadata endpoint, i.e. base64 without the PEM header.
+func VerifyIdentity(signature []byte) (*IdentityDocument, error) {
+	return Verify(signature, []byte(PublicCertificate))
+}
+
+// Verify verifies the PKCS7 signature of the instance identity document
+// against the PEM encoded certificate and returns the signed document.
+func Verify(signature, certificatePEM []byte) (*IdentityDocument, error) {
+	data := "-----BEGIN PKCS7-----\n" + strings.TrimSpace(string(signature)) + "\n-----END PKCS7-----\n"
+
+	pemBlock, _ := pem.Decode([]byte(data))
+	if pemBlock == nil {
+		return nil, errors.New("failed to decode PKCS7 PEM block")
+	}
+
+	p7, err := pkcs7.Parse(pemBlock.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse PKCS7 signature")
+	}
+
+	pemBlock, _ = pem.Decode(certificatePEM)
+	if pemBlock == nil {
+		return nil, errors.New("failed to decode certificate PEM block")
+	}
+
+	certificate, err := x509.ParseCertificate(pemBlock.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse X509 certificate")
+	}
+
+	p7.Certificates = []*x509.Certificate{certificate}
+
+	if err = p7.Verify(); err != nil {
+		return nil, errors.Wrap(err, "failed to verify PKCS7 signature")
+	}
+
+	doc := &IdentityDocument{}
+	if err = json.Unmarshal(p7.Content, doc); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal identity document")
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,113 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ec2_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fullsailor/pkcs7"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/internal/pkg/ec2"
+)
+
+const document = `{
+  "accountId" : "123456789012",
+  "availabilityZone" : "us-east-1a",
+  "imageId" : "ami-0123456789abcdef0",
+  "instanceId" : "i-1234567890abcdef0",
+  "instanceType" : "m5.large",
+  "privateIp" : "10.0.0.10",
+  "region" : "us-east-1"
+}`
+
+type EC2Suite struct {
+	suite.Suite
+
+	certificate    *x509.Certificate
+	certificatePEM []byte
+	key            *rsa.PrivateKey
+}
+
+func (suite *EC2Suite) SetupSuite() {
+	suite.key, suite.certificate, suite.certificatePEM = suite.newCertificate()
+}
+
+func (suite *EC2Suite) newCertificate() (*rsa.PrivateKey, *x509.Certificate, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"Amazon Web Services LLC"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	suite.Require().NoError(err)
+
+	certificate, err := x509.ParseCertificate(der)
+	suite.Require().NoError(err)
+
+	return key, certificate, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// sign produces the signature in the format of the instance-identity/pkcs7
+// endpoint.
+func (suite *EC2Suite) sign(content []byte, certificate *x509.Certificate, key *rsa.PrivateKey) []byte {
+	sd, err := pkcs7.NewSignedData(content)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(sd.AddSigner(certificate, key, pkcs7.SignerInfoConfig{}))
+
+	der, err := sd.Finish()
+	suite.Require().NoError(err)
+
+	return []byte(base64.StdEncoding.EncodeToString(der))
+}
+
+func (suite *EC2Suite) TestVerify() {
+	doc, err := ec2.Verify(suite.sign([]byte(document), suite.certificate, suite.key), suite.certificatePEM)
+	suite.Require().NoError(err)
+
+	suite.Assert().Equal(&ec2.IdentityDocument{
+		AccountID:        "123456789012",
+		InstanceID:       "i-1234567890abcdef0",
+		InstanceType:     "m5.large",
+		ImageID:          "ami-0123456789abcdef0",
+		Region:           "us-east-1",
+		AvailabilityZone: "us-east-1a",
+		PrivateIP:        "10.0.0.10",
+	}, doc)
+}
+
+func (suite *EC2Suite) TestVerifyOtherSigner() {
+	key, certificate, _ := suite.newCertificate()
+
+	_, err := ec2.Verify(suite.sign([]byte(document), certificate, key), suite.certificatePEM)
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), "failed to verify PKCS7 signature")
+}
+
+func (suite *EC2Suite) TestVerifyGarbage() {
+	_, err := ec2.Verify([]byte("garbage"), suite.certificatePEM)
+	suite.Require().Error(err)
+
+	_, err = ec2.VerifyIdentity(suite.sign([]byte(document), suite.certificate, suite.key))
+	suite.Require().Error(err)
+}
+
+func TestEC2Suite(t *testing.T) {
+	suite.Run(t, new(EC2Suite))
+}
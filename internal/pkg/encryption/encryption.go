@@ -0,0 +1,127 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package encryption formats and opens the LUKS2 encrypted partitions, with
+// the key read from the source set in the userdata.
+package encryption
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/luks"
+	"github.com/talos-systems/talos/pkg/blockdevice/probe"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+// Format encrypts the partition, and opens the mapping. The label is set on
+// the LUKS2 header, so that the partition is found by label before it is
+// opened, and is the name of the mapping. It returns the name of the
+// mapping.
+func Format(partname, label string, e *userdata.Encryption, setters ...Option) (string, error) {
+	id := uuid.New().String()
+
+	key, err := Key(e, id, label, setters...)
+	if err != nil {
+		return "", err
+	}
+
+	if err = luks.Format(partname, key, luks.WithLabel(label), luks.WithUUID(id)); err != nil {
+		return "", errors.Wrapf(err, "failed to encrypt %s", partname)
+	}
+
+	name := mappingName(partname, label)
+	if err = luks.Open(partname, name, key); err != nil {
+		return "", errors.Wrapf(err, "failed to open %s", partname)
+	}
+
+	return name, nil
+}
+
+// Open opens the mapping of the encrypted partition, unless it is already
+// open. It returns the name of the mapping.
+func Open(partname string, e *userdata.Encryption, setters ...Option) (string, error) {
+	if e == nil {
+		return "", errors.Errorf("%s is encrypted, but no encryption key is configured", partname)
+	}
+
+	sb, err := header(partname)
+	if err != nil {
+		return "", err
+	}
+
+	name := mappingName(partname, sb.GetLabel())
+	if _, err = os.Stat(luks.MappedPath(name)); err == nil {
+		return name, nil
+	}
+
+	key, err := Key(e, sb.GetUUID(), sb.GetLabel(), setters...)
+	if err != nil {
+		return "", err
+	}
+
+	if err = luks.Open(partname, name, key); err != nil {
+		return "", errors.Wrapf(err, "failed to open %s", partname)
+	}
+
+	return name, nil
+}
+
+// Resize grows the mapping of the encrypted partition to the size of the
+// partition.
+func Resize(partname, name string, e *userdata.Encryption, setters ...Option) error {
+	sb, err := header(partname)
+	if err != nil {
+		return err
+	}
+
+	key, err := Key(e, sb.GetUUID(), sb.GetLabel(), setters...)
+	if err != nil {
+		return err
+	}
+
+	return luks.Resize(name, key)
+}
+
+// Key returns the key of the partition, from the source set in the
+// encryption. The options apply to the requests to the key server.
+func Key(e *userdata.Encryption, id, label string, setters ...Option) ([]byte, error) {
+	switch {
+	case e.Passphrase != "":
+		return []byte(e.Passphrase), nil
+	case e.KeyFile != "":
+		return readKeyFile(e)
+	case e.KeyServer != "":
+		return fetchKey(e.KeyServer, id, label, NewDefaultOptions(setters...))
+	default:
+		return nil, errors.New("no source of the encryption key")
+	}
+}
+
+func header(partname string) (*luks.SuperBlock, error) {
+	sb, err := probe.FileSystem(partname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the LUKS header of %s", partname)
+	}
+
+	header, ok := sb.(*luks.SuperBlock)
+	if !ok {
+		return nil, errors.Errorf("%s has no LUKS header", partname)
+	}
+
+	return header, nil
+}
+
+// mappingName returns the name of the mapping, the label of the partition,
+// or the name of the partition device for the partitions without a label.
+func mappingName(partname, label string) string {
+	if label != "" {
+		return label
+	}
+
+	return filepath.Base(partname) + "_crypt"
+}
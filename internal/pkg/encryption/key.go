@@ -0,0 +1,195 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/talos-systems/talos/pkg/blockdevice/probe"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+const keyServerRetries = 10
+
+// keyServerWait is the time to wait before the first retry, it doubles on
+// each retry.
+var keyServerWait = time.Second
+
+// KeyRequest is the body of the request sent to the key server. The label is
+// the same on every node, the node is identified by the hostname and
+// authenticated by the Authorization header.
+type KeyRequest struct {
+	UUID     string `json:"uuid"`
+	Label    string `json:"label"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// KeyResponse is the body of the response of the key server, the key is
+// base64 encoded.
+type KeyResponse struct {
+	Key string `json:"key"`
+}
+
+// fetchKey requests the key of the partition from the key server. The
+// request is retried on network errors and server errors, since the network
+// might not be fully up yet.
+func fetchKey(server, id, label string, opts *Options) (key []byte, err error) {
+	// The credentials of the node and the key are never sent in cleartext.
+	if u, parseErr := url.Parse(server); parseErr != nil || u.Scheme != "https" {
+		return nil, errors.Errorf("key server %q must be an https URL", server)
+	}
+
+	body, err := json.Marshal(&KeyRequest{UUID: id, Label: label, Hostname: opts.Hostname})
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newKeyServerClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	wait := keyServerWait
+
+	for attempt := 0; attempt < keyServerRetries; attempt++ {
+		if attempt > 0 {
+			log.Printf("retrying the key server %s in %s: %v", server, wait, err)
+			time.Sleep(wait)
+			wait *= 2
+		}
+
+		var retry bool
+		if key, retry, err = requestKey(client, server, body, opts); err == nil || !retry {
+			return key, err
+		}
+	}
+
+	return nil, errors.Wrapf(err, "failed to get the key of %s from %s", label, server)
+}
+
+// newKeyServerClient returns the HTTP client with the timeout, which trusts
+// the CA bundle in addition to the system roots.
+func newKeyServerClient(opts *Options) (*http.Client, error) {
+	transport := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{},
+	}
+
+	if len(opts.CABundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if ok := pool.AppendCertsFromPEM(opts.CABundle); !ok {
+			return nil, errors.New("failed to parse the CA bundle")
+		}
+
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return errors.Errorf("refusing the redirect of the key server to %s://%s", req.URL.Scheme, req.URL.Host)
+			}
+
+			return nil
+		},
+	}, nil
+}
+
+func requestKey(client *http.Client, server string, body []byte, opts *Options) (key []byte, retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, server, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if opts.Authorization != "" {
+		req.Header.Set("Authorization", opts.Authorization)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	// nolint: errcheck
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode >= http.StatusInternalServerError, errors.Errorf("key server returned %s", resp.Status)
+	}
+
+	var reply KeyResponse
+	if err = json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, false, errors.Wrap(err, "invalid key server response")
+	}
+
+	if key, err = base64.StdEncoding.DecodeString(reply.Key); err != nil {
+		return nil, false, errors.Wrap(err, "invalid key")
+	}
+
+	if len(key) == 0 {
+		return nil, false, errors.New("key server returned an empty key")
+	}
+
+	return key, false, nil
+}
+
+// readKeyFile reads the key from the file system with the label. The file
+// system is mounted read only for the time of the read, since the boot media
+// is not mounted yet when the partitions are opened.
+func readKeyFile(e *userdata.Encryption) (key []byte, err error) {
+	label, path, err := e.KeyFileLocation()
+	if err != nil {
+		return nil, err
+	}
+
+	dev, err := probe.GetDevWithFileSystemLabel(label)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the key file system %s", label)
+	}
+
+	dir, err := ioutil.TempDir("", "key")
+	if err != nil {
+		return nil, err
+	}
+	// nolint: errcheck
+	defer os.Remove(dir)
+
+	if err = unix.Mount(dev.Path, dir, dev.SuperBlock.Type(), unix.MS_RDONLY, ""); err != nil {
+		return nil, errors.Wrapf(err, "failed to mount %s", dev.Path)
+	}
+	// nolint: errcheck
+	defer unix.Unmount(dir, 0)
+
+	if key, err = ioutil.ReadFile(filepath.Join(dir, path)); err != nil {
+		return nil, errors.Wrap(err, "failed to read the key file")
+	}
+
+	if len(key) == 0 {
+		return nil, errors.Errorf("key file %s is empty", e.KeyFile)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,157 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package encryption
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+type KeySuite struct {
+	suite.Suite
+
+	requests       []KeyRequest
+	authorizations []string
+	statuses       []int
+	server         *httptest.Server
+}
+
+func (suite *KeySuite) SetupTest() {
+	keyServerWait = time.Millisecond
+
+	suite.requests = nil
+	suite.authorizations = nil
+	suite.statuses = nil
+	suite.server = httptest.NewTLSServer(suite.handler())
+}
+
+// trust returns the option trusting the certificate of the test server.
+func (suite *KeySuite) trust() Option {
+	return WithCABundle(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: suite.server.Certificate().Raw}))
+}
+
+func (suite *KeySuite) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req KeyRequest
+		suite.Require().NoError(json.NewDecoder(r.Body).Decode(&req))
+		suite.requests = append(suite.requests, req)
+		suite.authorizations = append(suite.authorizations, r.Header.Get("Authorization"))
+
+		if len(suite.statuses) > 0 {
+			status := suite.statuses[0]
+			suite.statuses = suite.statuses[1:]
+			w.WriteHeader(status)
+
+			return
+		}
+
+		// nolint: errcheck
+		json.NewEncoder(w).Encode(&KeyResponse{Key: base64.StdEncoding.EncodeToString([]byte("key of " + req.Label))})
+	})
+}
+
+func (suite *KeySuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *KeySuite) TestPassphrase() {
+	key, err := Key(&userdata.Encryption{Passphrase: "secret"}, "", "EPHEMERAL")
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]byte("secret"), key)
+}
+
+func (suite *KeySuite) TestKeyServer() {
+	key, err := Key(&userdata.Encryption{KeyServer: suite.server.URL}, "4f2a5e1c", "EPHEMERAL", suite.trust())
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]byte("key of EPHEMERAL"), key)
+	suite.Assert().Equal([]KeyRequest{{UUID: "4f2a5e1c", Label: "EPHEMERAL"}}, suite.requests)
+}
+
+func (suite *KeySuite) TestKeyServerRetry() {
+	suite.statuses = []int{http.StatusServiceUnavailable, http.StatusBadGateway}
+
+	key, err := Key(&userdata.Encryption{KeyServer: suite.server.URL}, "4f2a5e1c", "EXTRA1", suite.trust())
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]byte("key of EXTRA1"), key)
+	suite.Assert().Len(suite.requests, 3)
+}
+
+func (suite *KeySuite) TestKeyServerDenied() {
+	suite.statuses = []int{http.StatusForbidden}
+
+	_, err := Key(&userdata.Encryption{KeyServer: suite.server.URL}, "4f2a5e1c", "EPHEMERAL", suite.trust())
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), "403")
+	suite.Assert().Len(suite.requests, 1)
+}
+
+// TestKeyServerUserData checks that the node is authenticated with the trustd
+// credentials, and that the server certificate is verified with the install
+// CA bundle.
+func (suite *KeySuite) TestKeyServerUserData() {
+	server := httptest.NewTLSServer(suite.handler())
+	defer server.Close()
+
+	data := &userdata.UserData{
+		Install: &userdata.Install{
+			Download: &userdata.AssetDownload{
+				CABundle: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})),
+			},
+		},
+		Networking: &userdata.Networking{OS: &userdata.OSNet{Hostname: "worker-1"}},
+		Services:   &userdata.Services{Trustd: &userdata.Trustd{Token: "secret"}},
+	}
+
+	key, err := Key(&userdata.Encryption{KeyServer: server.URL}, "4f2a5e1c", "EPHEMERAL", UserDataOptions(data)...)
+	suite.Require().NoError(err)
+	suite.Assert().Equal([]byte("key of EPHEMERAL"), key)
+	suite.Assert().Equal([]KeyRequest{{UUID: "4f2a5e1c", Label: "EPHEMERAL", Hostname: "worker-1"}}, suite.requests)
+	suite.Assert().Equal([]string{"Bearer secret"}, suite.authorizations)
+
+	// The server certificate isn't trusted without the bundle.
+	data.Install.Download = nil
+	_, err = Key(&userdata.Encryption{KeyServer: server.URL}, "4f2a5e1c", "EPHEMERAL", append(UserDataOptions(data), WithTimeout(time.Second))...)
+	suite.Require().Error(err)
+	suite.Assert().Len(suite.requests, 1)
+}
+
+// TestKeyServerPlainHTTP checks that the credentials are never sent to a key
+// server without TLS.
+func (suite *KeySuite) TestKeyServerPlainHTTP() {
+	server := httptest.NewServer(suite.handler())
+	defer server.Close()
+
+	_, err := Key(&userdata.Encryption{KeyServer: server.URL}, "4f2a5e1c", "EPHEMERAL", WithAuthorization("Bearer secret"))
+	suite.Require().Error(err)
+	suite.Assert().Empty(suite.requests)
+}
+
+// TestKeyServerRedirect checks that a redirect to a plain HTTP URL is not
+// followed.
+func (suite *KeySuite) TestKeyServerRedirect() {
+	plain := httptest.NewServer(suite.handler())
+	defer plain.Close()
+
+	server := httptest.NewTLSServer(http.RedirectHandler(plain.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	_, err := Key(&userdata.Encryption{KeyServer: server.URL}, "4f2a5e1c", "EPHEMERAL", WithAuthorization("Bearer secret"),
+		WithCABundle(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})))
+	suite.Require().Error(err)
+	suite.Assert().Empty(suite.requests)
+}
+
+func TestKeySuite(t *testing.T) {
+	suite.Run(t, new(KeySuite))
+}
@@ -0,0 +1,105 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package encryption
+
+import (
+	"encoding/base64"
+	"os"
+	"time"
+
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+// defaultKeyServerTimeout is the timeout of each request to the key server.
+const defaultKeyServerTimeout = 30 * time.Second
+
+// Options is the functional options struct of the key server requests.
+type Options struct {
+	CABundle      []byte
+	Authorization string
+	Hostname      string
+	Timeout       time.Duration
+}
+
+// Option is the functional option func.
+type Option func(*Options)
+
+// WithCABundle sets the PEM encoded certificates trusted in addition to the
+// system roots.
+func WithCABundle(o []byte) Option {
+	return func(args *Options) {
+		args.CABundle = o
+	}
+}
+
+// WithAuthorization sets the Authorization header of the requests, which
+// authenticates the node to the key server.
+func WithAuthorization(o string) Option {
+	return func(args *Options) {
+		args.Authorization = o
+	}
+}
+
+// WithHostname sets the hostname of the node sent with the requests.
+func WithHostname(o string) Option {
+	return func(args *Options) {
+		args.Hostname = o
+	}
+}
+
+// WithTimeout sets the timeout of each request.
+func WithTimeout(o time.Duration) Option {
+	return func(args *Options) {
+		args.Timeout = o
+	}
+}
+
+// NewDefaultOptions initializes an Options struct with default values.
+func NewDefaultOptions(setters ...Option) *Options {
+	opts := &Options{
+		Timeout: defaultKeyServerTimeout,
+	}
+
+	for _, setter := range setters {
+		setter(opts)
+	}
+
+	return opts
+}
+
+// UserDataOptions returns the options set in the userdata: the CA bundle of
+// the install downloads, the trustd credentials of the node, and the
+// hostname.
+func UserDataOptions(data *userdata.UserData) (opts []Option) {
+	if data == nil {
+		return nil
+	}
+
+	if data.Install != nil && data.Install.Download != nil && data.Install.Download.CABundle != "" {
+		opts = append(opts, WithCABundle([]byte(data.Install.Download.CABundle)))
+	}
+
+	if data.Services != nil && data.Services.Trustd != nil {
+		// The same precedence as the trustd credentials.
+		switch t := data.Services.Trustd; {
+		case t.Username != "" && t.Password != "":
+			opts = append(opts, WithAuthorization("Basic "+base64.StdEncoding.EncodeToString([]byte(t.Username+":"+t.Password))))
+		case t.Token != "":
+			opts = append(opts, WithAuthorization("Bearer "+t.Token))
+		}
+	}
+
+	hostname := ""
+	if data.Networking != nil && data.Networking.OS != nil {
+		hostname = data.Networking.OS.Hostname
+	}
+
+	if hostname == "" {
+		// nolint: errcheck
+		hostname, _ = os.Hostname()
+	}
+
+	return append(opts, WithHostname(hostname))
+}
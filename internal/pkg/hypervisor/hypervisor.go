@@ -0,0 +1,22 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package hypervisor identifies the hypervisor the machine is running under.
+package hypervisor
+
+// Vendor signatures reported via CPUID leaf 0x40000000.
+const (
+	KVM    = "KVMKVMKVM"
+	HyperV = "Microsoft Hv"
+	VMware = "VMwareVMware"
+	Xen    = "XenVMMXenVMM"
+	QEMU   = "TCGTCGTCGTCG"
+)
+
+// Vendor returns the hypervisor vendor signature, or an empty string if the
+// machine is not virtualized or the architecture doesn't support the
+// detection.
+func Vendor() string {
+	return vendor()
+}
@@ -0,0 +1,30 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package hypervisor
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// cpuid is implemented in hypervisor_amd64.s.
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+func vendor() string {
+	// bit 31 of ECX of leaf 1 is reserved for the hypervisor use and is
+	// always set by the hypervisors
+	if _, _, ecx, _ := cpuid(1, 0); ecx&(1<<31) == 0 {
+		return ""
+	}
+
+	_, ebx, ecx, edx := cpuid(0x40000000, 0)
+
+	b := make([]byte, 12)
+	binary.LittleEndian.PutUint32(b[0:], ebx)
+	binary.LittleEndian.PutUint32(b[4:], ecx)
+	binary.LittleEndian.PutUint32(b[8:], edx)
+
+	return strings.TrimRight(string(b), "\x00")
+}
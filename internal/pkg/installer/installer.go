@@ -5,11 +5,10 @@
 package installer
 
 import (
-	"io"
+	"fmt"
 	"io/ioutil"
-	"os"
+	"log"
 	"path/filepath"
-	"unsafe"
 
 	"github.com/pkg/errors"
 	"github.com/talos-systems/talos/internal/pkg/installer/bootloader/syslinux"
@@ -18,9 +17,9 @@ import (
 	"github.com/talos-systems/talos/internal/pkg/mount"
 	"github.com/talos-systems/talos/internal/pkg/mount/manager"
 	"github.com/talos-systems/talos/internal/pkg/mount/manager/owned"
+	"github.com/talos-systems/talos/pkg/blockdevice"
 	"github.com/talos-systems/talos/pkg/constants"
 	"github.com/talos-systems/talos/pkg/userdata"
-	"golang.org/x/sys/unix"
 )
 
 // Installer represents the installer logic. It serves as the entrypoint to all
@@ -54,10 +53,8 @@ func (i *Installer) Install() (err error) {
 		return nil
 	}
 
-	if i.data.Install.Wipe {
-		if err = wipe(i.manifest); err != nil {
-			return errors.Wrap(err, "failed to wipe device(s)")
-		}
+	if err = wipe(i.manifest); err != nil {
+		return errors.Wrap(err, "failed to wipe device(s)")
 	}
 
 	// Partition and format the block device(s).
@@ -72,7 +69,7 @@ func (i *Installer) Install() (err error) {
 	// look for mountpoints across all target devices
 	for dev := range i.manifest.Targets {
 		var mp *mount.Points
-		mp, err = owned.MountPointsForDevice(dev)
+		mp, err = owned.MountPointsForDevice(dev, i.data)
 		if err != nil {
 			return err
 		}
@@ -101,7 +98,7 @@ func (i *Installer) Install() (err error) {
 				if err = syslinux.Prepare(target.Device); err != nil {
 					return err
 				}
-			case constants.EphemeralPartitionLabel:
+			case constants.EphemeralPartitionLabel, constants.SwapPartitionLabel:
 				continue
 			}
 
@@ -141,33 +138,57 @@ func (i *Installer) Install() (err error) {
 	return nil
 }
 
+// wipe wipes the target devices with the wipe mode of their partitions. The
+// devices without a wipe mode are left as is.
 func wipe(manifest *manifest.Manifest) (err error) {
-	var zero *os.File
-	if zero, err = os.Open("/dev/zero"); err != nil {
-		return err
-	}
-
-	for dev := range manifest.Targets {
-		var f *os.File
-		if f, err = os.OpenFile(dev, os.O_RDWR, os.ModeDevice); err != nil {
-			return err
-		}
-		var size uint64
-		if _, _, ret := unix.Syscall(unix.SYS_IOCTL, f.Fd(), unix.BLKGETSIZE64, uintptr(unsafe.Pointer(&size))); ret != 0 {
-			return errors.Errorf("failed to got block device size: %v", ret)
+	for dev, targets := range manifest.Targets {
+		var mode string
+		for _, target := range targets {
+			if target.WipeMode != "" {
+				mode = target.WipeMode
+				break
+			}
 		}
-		if _, err = io.CopyN(f, zero, int64(size)); err != nil {
-			return err
+
+		if mode == "" {
+			continue
 		}
 
-		if err = f.Close(); err != nil {
+		if err = WipeDevice(dev, mode, func(message string) { log.Println(message) }); err != nil {
 			return err
 		}
 	}
 
-	if err = zero.Close(); err != nil {
+	return nil
+}
+
+// WipeDevice wipes the device with the wipe mode, and reports the progress
+// to the record func.
+func WipeDevice(dev, mode string, record func(message string)) (err error) {
+	var bd *blockdevice.BlockDevice
+	if bd, err = blockdevice.Open(dev); err != nil {
 		return err
 	}
+	// nolint: errcheck
+	defer bd.Close()
+
+	record(fmt.Sprintf("wiping %s (%s)", dev, mode))
+
+	last := 0
+	progress := func(done, total uint64) {
+		if total == 0 {
+			return
+		}
+
+		if percent := int(done * 100 / total); percent/10 != last/10 {
+			last = percent
+			record(fmt.Sprintf("wiping %s (%s): %d%%", dev, mode, percent))
+		}
+	}
+
+	if err = bd.Wipe(mode, progress); err != nil {
+		return errors.Wrapf(err, "failed to wipe %s", dev)
+	}
 
 	return nil
 }
@@ -0,0 +1,383 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package manifest
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
+// FetchOptions is the functional options struct of the Fetcher.
+type FetchOptions struct {
+	CABundle []byte
+	Timeout  time.Duration
+	Retries  int
+	Wait     time.Duration
+}
+
+// FetchOption is the functional option func of the Fetcher.
+type FetchOption func(*FetchOptions)
+
+// WithCABundle sets the PEM encoded certificates trusted in addition to the
+// system roots.
+func WithCABundle(o []byte) FetchOption {
+	return func(args *FetchOptions) {
+		args.CABundle = o
+	}
+}
+
+// WithTimeout sets the timeout of each attempt of a download.
+func WithTimeout(o time.Duration) FetchOption {
+	return func(args *FetchOptions) {
+		args.Timeout = o
+	}
+}
+
+// WithRetries sets the number of retries of a failed download.
+func WithRetries(o int) FetchOption {
+	return func(args *FetchOptions) {
+		args.Retries = o
+	}
+}
+
+// WithRetryWait sets the time to wait before the first retry, the time
+// doubles on each retry.
+func WithRetryWait(o time.Duration) FetchOption {
+	return func(args *FetchOptions) {
+		args.Wait = o
+	}
+}
+
+// NewDefaultFetchOptions initializes a FetchOptions struct with default
+// values.
+func NewDefaultFetchOptions(setters ...FetchOption) *FetchOptions {
+	opts := &FetchOptions{
+		CABundle: nil,
+		Timeout:  10 * time.Minute,
+		Retries:  5,
+		Wait:     time.Second,
+	}
+
+	for _, setter := range setters {
+		setter(opts)
+	}
+
+	return opts
+}
+
+// Fetcher downloads the assets. The downloads are verified against the
+// expected SHA-256 digest as they are streamed to the disk, and interrupted
+// downloads are resumed where they stopped.
+type Fetcher struct {
+	client *http.Client
+	opts   *FetchOptions
+}
+
+// NewFetcher initializes and returns a Fetcher.
+func NewFetcher(setters ...FetchOption) (*Fetcher, error) {
+	opts := NewDefaultFetchOptions(setters...)
+
+	transport := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{},
+	}
+
+	if len(opts.CABundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if ok := pool.AppendCertsFromPEM(opts.CABundle); !ok {
+			return nil, errors.New("failed to parse the CA bundle")
+		}
+
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	return &Fetcher{
+		client: &http.Client{Transport: transport, Timeout: opts.Timeout},
+		opts:   opts,
+	}, nil
+}
+
+// NewFetcherFromUserData initializes a Fetcher with the download options set
+// in the userdata.
+func NewFetcherFromUserData(data *userdata.UserData) (*Fetcher, error) {
+	if data == nil || data.Install == nil || data.Install.Download == nil {
+		return NewFetcher()
+	}
+
+	d := data.Install.Download
+	opts := []FetchOption{WithCABundle([]byte(d.CABundle))}
+
+	if d.Retries != 0 {
+		opts = append(opts, WithRetries(int(d.Retries)))
+	}
+
+	if d.Timeout != 0 {
+		opts = append(opts, WithTimeout(time.Duration(d.Timeout)*time.Second))
+	}
+
+	return NewFetcher(opts...)
+}
+
+// Fetch downloads or copies the source to the destination. The destination
+// is only created once the whole source was read, and matches the digest if
+// one is set.
+func (f *Fetcher) Fetch(source, digest, dest string) (err error) {
+	var u *url.URL
+	if u, err = url.Parse(source); err != nil {
+		return err
+	}
+
+	digest = strings.ToLower(strings.TrimPrefix(digest, "sha256:"))
+
+	// The registry credentials are never logged.
+	name := redact(u)
+
+	if err = os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		log.Printf("downloading %s", name)
+		return f.download(source, name, nil, digest, dest)
+	case "oci":
+		var blob *ociBlob
+		if blob, err = f.resolve(u); err != nil {
+			return errors.Wrapf(err, "failed to resolve %s", name)
+		}
+
+		if digest != "" && digest != blob.digest {
+			return errors.Errorf("%s has the digest %s, expected %s", name, blob.digest, digest)
+		}
+
+		log.Printf("downloading %s", name)
+		return f.download(blob.url, name, blob.header, blob.digest, dest)
+	case "file":
+		log.Printf("copying %s to %s", u.Path, dest)
+		return copyFile(u.Path, digest, dest)
+	default:
+		return fmt.Errorf("unsupported path scheme, got %s but supported %s", u.Scheme, "https://|http://|file://|oci://")
+	}
+}
+
+// retry runs the function until it succeeds, the error is permanent, or the
+// retries are exhausted, with an exponential backoff.
+func (f *Fetcher) retry(name string, fn func() (retry bool, err error)) (err error) {
+	wait := f.opts.Wait
+
+	for attempt := 0; attempt <= f.opts.Retries; attempt++ {
+		if attempt > 0 {
+			log.Printf("retrying %s in %s: %v", name, wait, err)
+			time.Sleep(wait)
+			wait *= 2
+		}
+
+		var retry bool
+		if retry, err = fn(); err == nil || !retry {
+			return err
+		}
+	}
+
+	return err
+}
+
+// download fetches the URL to a partial file next to the destination, and
+// renames it once it is complete and verified. The name of the source is
+// used in the logs and the errors instead of the URL.
+func (f *Fetcher) download(u, name string, header http.Header, digest, dest string) (err error) {
+	part := dest + ".part"
+
+	// Only resume the downloads interrupted by this call, a partial file
+	// left by an earlier install might be of another version.
+	if err = os.Remove(part); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var sum string
+
+	err = f.retry(name, func() (retry bool, err error) {
+		sum, retry, err = f.get(u, header, part)
+		return retry, err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to download %s", name)
+	}
+
+	if digest != "" && sum != digest {
+		// nolint: errcheck
+		os.Remove(part)
+		return errors.Errorf("digest mismatch for %s: got %s, expected %s", name, sum, digest)
+	}
+
+	return os.Rename(part, dest)
+}
+
+// get fetches the rest of the URL into the partial file, and returns the
+// digest of the whole file. The server might not support ranges, in which
+// case the download starts over.
+// nolint: gocyclo
+func (f *Fetcher) get(u string, header http.Header, part string) (sum string, retry bool, err error) {
+	var file *os.File
+	if file, err = os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0600); err != nil {
+		return "", false, err
+	}
+	// nolint: errcheck
+	defer file.Close()
+
+	// Hash what was already downloaded, this leaves the offset at the end of
+	// the file.
+	h := sha256.New()
+
+	var offset int64
+	if offset, err = io.Copy(h, file); err != nil {
+		return "", false, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", false, err
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", true, err
+	}
+	// nolint: errcheck
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if offset > 0 {
+			if err = restart(file); err != nil {
+				return "", false, err
+			}
+
+			h.Reset()
+		}
+	case http.StatusPartialContent:
+		// The range might be ignored by a cache or a proxy, the download
+		// starts over unless the content resumes the partial file.
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			if err = restart(file); err != nil {
+				return "", false, err
+			}
+
+			return "", true, errors.Errorf("got the range %q, expected it to start at %d", resp.Header.Get("Content-Range"), offset)
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is already complete.
+		if offset > 0 {
+			return hex.EncodeToString(h.Sum(nil)), false, nil
+		}
+
+		fallthrough
+	default:
+		retry = resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+		return "", retry, errors.Errorf("got %s", resp.Status)
+	}
+
+	if _, err = io.Copy(io.MultiWriter(file, h), resp.Body); err != nil {
+		return "", true, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), false, nil
+}
+
+// contentRangeStart returns the first byte of the Content-Range header in the
+// form "bytes start-end/size".
+func contentRangeStart(header string) (start int64, ok bool) {
+	if !strings.HasPrefix(header, "bytes ") {
+		return 0, false
+	}
+
+	i := strings.IndexByte(header, '-')
+	if i < 0 {
+		return 0, false
+	}
+
+	start, err := strconv.ParseInt(header[len("bytes "):i], 10, 64)
+
+	return start, err == nil
+}
+
+// redact returns the URL without the credentials.
+func redact(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+
+	return redacted.String()
+}
+
+func restart(file *os.File) (err error) {
+	if err = file.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+
+	return err
+}
+
+func copyFile(source, digest, dest string) (err error) {
+	var src *os.File
+	if src, err = os.Open(source); err != nil {
+		return err
+	}
+	// nolint: errcheck
+	defer src.Close()
+
+	part := dest + ".part"
+
+	var dst *os.File
+	if dst, err = os.Create(part); err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(dst, h), src); err != nil {
+		// nolint: errcheck
+		dst.Close()
+		return err
+	}
+
+	if err = dst.Close(); err != nil {
+		return err
+	}
+
+	if sum := hex.EncodeToString(h.Sum(nil)); digest != "" && sum != digest {
+		// nolint: errcheck
+		os.Remove(part)
+		return errors.Errorf("digest mismatch for %s: got %s, expected %s", source, sum, digest)
+	}
+
+	return os.Rename(part, dest)
+}
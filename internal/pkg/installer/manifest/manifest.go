@@ -7,22 +7,24 @@ package manifest
 import (
 	"archive/tar"
 	"compress/gzip"
-	"fmt"
 	"io"
 	"log"
-	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/talos-systems/talos/internal/pkg/encryption"
 	"github.com/talos-systems/talos/pkg/blockdevice"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/btrfs"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/ext4"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/luks"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/swap"
 	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/vfat"
 	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/xfs"
 	"github.com/talos-systems/talos/pkg/blockdevice/table"
 	"github.com/talos-systems/talos/pkg/blockdevice/table/gpt/partition"
+	"github.com/talos-systems/talos/pkg/blockdevice/util"
 	"github.com/talos-systems/talos/pkg/constants"
 	"github.com/talos-systems/talos/pkg/userdata"
 	"github.com/talos-systems/talos/pkg/version"
@@ -62,14 +64,20 @@ type Target struct {
 	Size           uint
 	Force          bool
 	Test           bool
+	Encryption     *userdata.Encryption
+	KeyOptions     []encryption.Option
+	WipeMode       string
 	Assets         []*Asset
+	Fetcher        *Fetcher
 	BlockDevice    *blockdevice.BlockDevice
 }
 
-// Asset represents a file required by a target.
+// Asset represents a file required by a target. The asset is verified
+// against the SHA-256 digest, if set.
 type Asset struct {
 	Source      string
 	Destination string
+	SHA256      string
 }
 
 // NewManifest initializes and returns a Manifest.
@@ -95,6 +103,11 @@ func NewManifest(data *userdata.UserData) (manifest *Manifest, err error) {
 		manifest.Targets[data.Install.Ephemeral.Device] = []*Target{}
 	}
 
+	var fetcher *Fetcher
+	if fetcher, err = NewFetcherFromUserData(data); err != nil {
+		return nil, errors.Wrap(err, "failed to set up the download of the assets")
+	}
+
 	var bootTarget *Target
 	if data.Install.Boot != nil {
 		bootTarget = &Target{
@@ -107,43 +120,70 @@ func NewManifest(data *userdata.UserData) (manifest *Manifest, err error) {
 				{
 					Source:      data.Install.Boot.Kernel,
 					Destination: filepath.Join("/", "default", filepath.Base(data.Install.Boot.Kernel)),
+					SHA256:      data.Install.Boot.KernelSHA256,
 				},
 				{
 					Source:      data.Install.Boot.Initramfs,
 					Destination: filepath.Join("/", "default", filepath.Base(data.Install.Boot.Initramfs)),
+					SHA256:      data.Install.Boot.InitramfsSHA256,
 				},
 			},
+			Fetcher:    fetcher,
+			WipeMode:   data.Install.DeviceWipeMode(data.Install.Boot.WipeMode),
 			MountPoint: constants.BootMountPoint,
 		}
 	}
 
+	// The swap partition is placed before the ephemeral partition, which is
+	// grown to the end of the device on boot.
+	var swapTarget *Target
+	if data.Install.Swap.Partition() {
+		swapTarget = &Target{
+			Device:         data.Install.Ephemeral.Device,
+			Label:          constants.SwapPartitionLabel,
+			FileSystemType: swap.Type,
+			Size:           data.Install.Swap.Size,
+			Force:          data.Install.Force,
+			Test:           false,
+			WipeMode:       data.Install.DeviceWipeMode(data.Install.Ephemeral.WipeMode),
+		}
+	}
+
 	dataTarget := &Target{
 		Device:     data.Install.Ephemeral.Device,
 		Label:      constants.EphemeralPartitionLabel,
 		Size:       data.Install.Ephemeral.Size,
 		Force:      data.Install.Force,
 		Test:       false,
+		Encryption: data.Install.Ephemeral.Encryption,
+		KeyOptions: encryption.UserDataOptions(data),
+		WipeMode:   data.Install.DeviceWipeMode(data.Install.Ephemeral.WipeMode),
 		MountPoint: constants.EphemeralMountPoint,
 	}
 
-	for _, target := range []*Target{bootTarget, dataTarget} {
+	for _, target := range []*Target{bootTarget, swapTarget, dataTarget} {
 		if target == nil {
 			continue
 		}
 		manifest.Targets[target.Device] = append(manifest.Targets[target.Device], target)
 	}
 
-	for _, extra := range data.Install.ExtraDevices {
+	for i, extra := range data.Install.ExtraDevices {
 		if manifest.Targets[extra.Device] == nil {
 			manifest.Targets[extra.Device] = []*Target{}
 		}
 
-		for _, part := range extra.Partitions {
+		for j, part := range extra.Partitions {
 			extraTarget := &Target{
-				Device: extra.Device,
-				Size:   part.Size,
-				Force:  data.Install.Force,
-				Test:   false,
+				Device:         extra.Device,
+				Label:          data.Install.ExtraPartitionLabel(i, j),
+				FileSystemType: part.FileSystemType(),
+				Size:           part.Size,
+				Force:          data.Install.Force,
+				Test:           false,
+				Encryption:     part.Encryption,
+				KeyOptions:     encryption.UserDataOptions(data),
+				WipeMode:       data.Install.DeviceWipeMode(extra.WipeMode),
 			}
 
 			manifest.Targets[extra.Device] = append(manifest.Targets[extra.Device], extraTarget)
@@ -200,6 +240,10 @@ func (t *Target) Partition(bd *blockdevice.BlockDevice) (err error) {
 		// EFI System Partition
 		typeID := "C12A7328-F81F-11D2-BA4B-00A0C93EC93B"
 		opts = append(opts, partition.WithPartitionType(typeID), partition.WithPartitionName(t.Label), partition.WithLegacyBIOSBootableAttribute(true))
+	case constants.SwapPartitionLabel:
+		// Linux Swap
+		typeID := "0657FD6D-A4AB-43C4-84E5-0933C84B4F4F"
+		opts = append(opts, partition.WithPartitionType(typeID), partition.WithPartitionName(t.Label))
 	case constants.EphemeralPartitionLabel:
 		// Ephemeral Partition
 		typeID := "AF3DC60F-8384-7247-8E79-3D69D8477DE4"
@@ -207,6 +251,9 @@ func (t *Target) Partition(bd *blockdevice.BlockDevice) (err error) {
 	default:
 		typeID := "AF3DC60F-8384-7247-8E79-3D69D8477DE4"
 		opts = append(opts, partition.WithPartitionType(typeID))
+		if t.Label != "" {
+			opts = append(opts, partition.WithPartitionName(t.Label))
+		}
 	}
 
 	part, err := pt.Add(uint64(t.Size), opts...)
@@ -218,43 +265,65 @@ func (t *Target) Partition(bd *blockdevice.BlockDevice) (err error) {
 		return err
 	}
 
-	// TODO(andrewrynhard): We should really have a custom type that has all
-	// the methods we need. This switch statement shows up in some form in
-	// multiple places.
-	switch dev := t.Device; {
-	case strings.HasPrefix(dev, "/dev/nvme"):
-		fallthrough
-	case strings.HasPrefix(dev, "/dev/loop"):
-		t.PartitionName = t.Device + "p" + strconv.Itoa(int(part.No()))
-	default:
-		t.PartitionName = t.Device + strconv.Itoa(int(part.No()))
+	if t.PartitionName, err = util.PartPath(t.Device, int(part.No())); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-// Format creates a filesystem on the device/partition.
-func (t *Target) Format() error {
+// Format creates a filesystem on the device/partition. An encrypted partition
+// is formatted with LUKS2 first, and the filesystem is created in the
+// mapping.
+func (t *Target) Format() (err error) {
 	if t.Label == constants.BootPartitionLabel {
 		log.Printf("formatting partition %s - %s as %s\n", t.PartitionName, t.Label, "fat")
 		return vfat.MakeFS(t.PartitionName, vfat.WithLabel(t.Label))
 	}
-	log.Printf("formatting partition %s - %s as %s\n", t.PartitionName, t.Label, "xfs")
+
+	if t.Label == constants.SwapPartitionLabel {
+		log.Printf("formatting partition %s - %s as %s\n", t.PartitionName, t.Label, "swap")
+		return swap.MakeSwap(t.PartitionName, swap.WithLabel(t.Label))
+	}
+
+	device := t.PartitionName
+	if t.Encryption != nil {
+		log.Printf("encrypting partition %s - %s\n", t.PartitionName, t.Label)
+		var name string
+		if name, err = encryption.Format(t.PartitionName, t.Label, t.Encryption, t.KeyOptions...); err != nil {
+			return err
+		}
+		// The mapping is opened again when the partition is mounted.
+		// nolint: errcheck
+		defer luks.Close(name)
+		device = luks.MappedPath(name)
+	}
+
+	switch t.FileSystemType {
+	case "ext4":
+		log.Printf("formatting partition %s - %s as %s\n", device, t.Label, "ext4")
+		return ext4.MakeFS(device, ext4.WithForce(t.Force), ext4.WithLabel(t.Label))
+	case "btrfs":
+		log.Printf("formatting partition %s - %s as %s\n", device, t.Label, "btrfs")
+		return btrfs.MakeFS(device, btrfs.WithForce(t.Force), btrfs.WithLabel(t.Label))
+	}
+	log.Printf("formatting partition %s - %s as %s\n", device, t.Label, "xfs")
 	opts := []xfs.Option{xfs.WithForce(t.Force)}
 	if t.Label != "" {
 		opts = append(opts, xfs.WithLabel(t.Label))
 	}
-	return xfs.MakeFS(t.PartitionName, opts...)
+	return xfs.MakeFS(device, opts...)
 }
 
 // Save handles downloading the necessary assets and extracting them to
 // the appropriate location.
-// nolint: gocyclo
-func (t *Target) Save() error {
-	// Download and extract all artifacts.
-	var sourceFile *os.File
-	var destFile *os.File
-	var err error
+func (t *Target) Save() (err error) {
+	fetcher := t.Fetcher
+	if fetcher == nil {
+		if fetcher, err = NewFetcher(); err != nil {
+			return err
+		}
+	}
 
 	if err = os.MkdirAll(t.MountPoint, os.ModeDir); err != nil {
 		return err
@@ -262,90 +331,38 @@ func (t *Target) Save() error {
 
 	// Extract artifact if necessary, otherwise place at root of partition/filesystem
 	for _, asset := range t.Assets {
-		var u *url.URL
-		if u, err = url.Parse(asset.Source); err != nil {
+		dest := filepath.Join(t.MountPoint, asset.Destination)
+
+		if err = fetcher.Fetch(asset.Source, asset.SHA256, dest); err != nil {
 			return err
 		}
 
-		sourceFile = nil
-		destFile = nil
-
-		// Handle fetching of asset
-		switch u.Scheme {
-		case "http":
-			fallthrough
-		case "https":
-			log.Printf("downloading %s\n", asset.Source)
-			dest := filepath.Join(t.MountPoint, asset.Destination)
-			sourceFile, err = download(u.String(), dest)
-			if err != nil {
-				return err
-			}
-		case "file":
-			source := u.Path
-			dest := filepath.Join(t.MountPoint, asset.Destination)
-
-			sourceFile, err = os.Open(source)
-			if err != nil {
-				return err
-			}
-
-			if err = os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
-				return err
-			}
-
-			destFile, err = os.Create(dest)
-			if err != nil {
-				return err
-			}
-		default:
-			return fmt.Errorf("unsupported path scheme, got %s but supported %s", u.Scheme, "https://|http://|file://")
+		if !strings.HasSuffix(dest, ".tar") && !strings.HasSuffix(dest, ".tar.gz") {
+			continue
 		}
 
-		// Handle extraction/Installation
-		switch {
-		// tar
-		case strings.HasSuffix(sourceFile.Name(), ".tar") || strings.HasSuffix(sourceFile.Name(), ".tar.gz"):
-			log.Printf("extracting %s to %s\n", sourceFile.Name(), t.MountPoint)
+		log.Printf("extracting %s to %s\n", dest, t.MountPoint)
 
-			err = untar(sourceFile, t.MountPoint)
-			if err != nil {
-				log.Printf("failed to extract %s to %s\n", sourceFile.Name(), t.MountPoint)
-				return err
-			}
-
-			if err = sourceFile.Close(); err != nil {
-				log.Printf("failed to close %s", sourceFile.Name())
-				return err
-			}
-
-			if err = os.Remove(sourceFile.Name()); err != nil {
-				log.Printf("failed to remove %s", sourceFile.Name())
-				return err
-			}
-		// single file
-		case strings.HasPrefix(sourceFile.Name(), "/") && destFile != nil:
-			log.Printf("copying %s to %s\n", sourceFile.Name(), destFile.Name())
+		var tarball *os.File
+		if tarball, err = os.Open(dest); err != nil {
+			return err
+		}
 
-			if _, err = io.Copy(destFile, sourceFile); err != nil {
-				log.Printf("failed to copy %s to %s\n", sourceFile.Name(), destFile.Name())
-				return err
-			}
+		if err = untar(tarball, t.MountPoint); err != nil {
+			log.Printf("failed to extract %s to %s\n", dest, t.MountPoint)
+			// nolint: errcheck
+			tarball.Close()
+			return err
+		}
 
-			if err = destFile.Close(); err != nil {
-				log.Printf("failed to close %s", destFile.Name())
-				return err
-			}
+		if err = tarball.Close(); err != nil {
+			log.Printf("failed to close %s", dest)
+			return err
+		}
 
-			if err = sourceFile.Close(); err != nil {
-				log.Printf("failed to close %s", sourceFile.Name())
-				return err
-			}
-		default:
-			if err = sourceFile.Close(); err != nil {
-				log.Printf("failed to close %s", sourceFile.Name())
-				return err
-			}
+		if err = os.Remove(dest); err != nil {
+			log.Printf("failed to remove %s", dest)
+			return err
 		}
 	}
 
@@ -430,41 +447,3 @@ func untar(tarball *os.File, dst string) error {
 		}
 	}
 }
-
-func download(artifact, dest string) (*os.File, error) {
-	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
-		return nil, err
-	}
-	downloadedFile, err := os.Create(dest)
-	if err != nil {
-		return nil, err
-	}
-
-	// Get the data
-	resp, err := http.Get(artifact)
-	if err != nil {
-		return downloadedFile, err
-	}
-
-	// nolint: errcheck
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		// nolint: errcheck
-		downloadedFile.Close()
-		return nil, errors.Errorf("failed to download %s, got %d", artifact, resp.StatusCode)
-	}
-
-	// Write the body to file
-	_, err = io.Copy(downloadedFile, resp.Body)
-	if err != nil {
-		return downloadedFile, err
-	}
-
-	// Reset downloadedFile file position to 0 so we can immediately read from it
-	_, err = downloadedFile.Seek(0, 0)
-
-	// TODO add support for checksum validation of downloaded file
-
-	return downloadedFile, err
-}
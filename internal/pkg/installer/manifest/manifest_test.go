@@ -14,6 +14,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"github.com/talos-systems/talos/pkg/constants"
 	"github.com/talos-systems/talos/pkg/userdata"
 	"gopkg.in/yaml.v2"
 )
@@ -37,6 +38,35 @@ func (suite *manifestSuite) TestNewManifest() {
 	assert.Equal(suite.T(), 2, len(manifests.Targets["/dev/sda"]))
 }
 
+func (suite *manifestSuite) TestNewManifestSwapPartition() {
+	data := &userdata.UserData{}
+	err := yaml.Unmarshal([]byte(testConfig), data)
+	suite.Require().NoError(err)
+
+	data.Install.Swap = &userdata.Swap{Type: "partition", Size: 1 << 30}
+
+	manifests, err := NewManifest(data)
+	suite.Require().NoError(err)
+
+	targets := manifests.Targets["/dev/sda"]
+	suite.Require().Len(targets, 3)
+	suite.Assert().Equal(constants.SwapPartitionLabel, targets[1].Label)
+	suite.Assert().Equal(uint(1<<30), targets[1].Size)
+	suite.Assert().Equal(constants.EphemeralPartitionLabel, targets[2].Label)
+}
+
+func (suite *manifestSuite) TestNewManifestSwapPartitionEncrypted() {
+	data := &userdata.UserData{}
+	err := yaml.Unmarshal([]byte(testConfig), data)
+	suite.Require().NoError(err)
+
+	data.Install.Swap = &userdata.Swap{Type: "partition", Size: 1 << 30}
+	data.Install.Ephemeral.Encryption = &userdata.Encryption{}
+
+	_, err = NewManifest(data)
+	suite.Assert().Error(err)
+}
+
 func (suite *manifestSuite) TestTargetInstall() {
 	// Create Temp dirname for mountpoint
 	dir, err := ioutil.TempDir("", "talostest")
@@ -0,0 +1,289 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ociManifestMediaType    = "application/vnd.oci.image.manifest.v1+json"
+	dockerManifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// ociTitleAnnotation is the annotation with the file name of a layer, as
+	// set by the tools pushing files to a registry, e.g. oras.
+	ociTitleAnnotation = "org.opencontainers.image.title"
+)
+
+// ociReference is a file in an artifact of a registry, in the form
+// oci://registry/repository:tag/file or
+// oci://registry/repository@sha256:digest/file. The file is the layer of the
+// artifact with the title annotation. This way the source of an upgrade is
+// the artifact, and the assets are appended as for the HTTP sources.
+type ociReference struct {
+	registry   string
+	repository string
+	reference  string
+	file       string
+	user       *url.Userinfo
+}
+
+type ociBlob struct {
+	url    string
+	digest string
+	header http.Header
+}
+
+type ociManifest struct {
+	Layers []struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      string            `json:"digest"`
+		Size        int64             `json:"size"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+func parseOCIReference(u *url.URL) (*ociReference, error) {
+	p := strings.Trim(u.Path, "/")
+
+	i := strings.LastIndex(p, "/")
+	if u.Host == "" || i < 0 {
+		return nil, errors.Errorf("expected oci://registry/repository:tag/file, got %s", redact(u))
+	}
+
+	ref := &ociReference{
+		registry: u.Host,
+		file:     p[i+1:],
+		user:     u.User,
+	}
+
+	name := p[:i]
+
+	switch {
+	case strings.Contains(name, "@"):
+		j := strings.Index(name, "@")
+		ref.repository, ref.reference = name[:j], name[j+1:]
+	case strings.Contains(name, ":"):
+		j := strings.LastIndex(name, ":")
+		ref.repository, ref.reference = name[:j], name[j+1:]
+	default:
+		return nil, errors.Errorf("no tag or digest in %s", redact(u))
+	}
+
+	if ref.repository == "" || ref.reference == "" || ref.file == "" {
+		return nil, errors.Errorf("expected oci://registry/repository:tag/file, got %s", redact(u))
+	}
+
+	return ref, nil
+}
+
+// resolve fetches the manifest of the artifact, and returns the blob of the
+// layer with the file.
+func (f *Fetcher) resolve(u *url.URL) (blob *ociBlob, err error) {
+	var ref *ociReference
+	if ref, err = parseOCIReference(u); err != nil {
+		return nil, err
+	}
+
+	base := "https://" + ref.registry + "/v2/" + ref.repository
+	header := http.Header{}
+
+	var body []byte
+
+	err = f.retry(base, func() (retry bool, err error) {
+		body, retry, err = f.getManifest(base+"/manifests/"+ref.reference, ref, header)
+		return retry, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A manifest pulled by digest must match it, since it carries the
+	// digests of the layers.
+	if strings.HasPrefix(ref.reference, "sha256:") {
+		sum := sha256.Sum256(body)
+		if "sha256:"+hex.EncodeToString(sum[:]) != ref.reference {
+			return nil, errors.Errorf("manifest digest mismatch for %s", ref.reference)
+		}
+	}
+
+	var manifest ociManifest
+	if err = json.Unmarshal(body, &manifest); err != nil {
+		return nil, errors.Wrap(err, "invalid manifest")
+	}
+
+	for _, layer := range manifest.Layers {
+		if layer.Annotations[ociTitleAnnotation] != ref.file {
+			continue
+		}
+
+		if !strings.HasPrefix(layer.Digest, "sha256:") {
+			return nil, errors.Errorf("unsupported digest %s", layer.Digest)
+		}
+
+		return &ociBlob{
+			url:    base + "/blobs/" + layer.Digest,
+			digest: strings.TrimPrefix(layer.Digest, "sha256:"),
+			header: header,
+		}, nil
+	}
+
+	return nil, errors.Errorf("no layer titled %s in %s:%s", ref.file, ref.repository, ref.reference)
+}
+
+// getManifest fetches the manifest. The registry might require a token, in
+// which case the header is updated with the token, for the requests of the
+// blobs.
+func (f *Fetcher) getManifest(u string, ref *ociReference, header http.Header) (body []byte, retry bool, err error) {
+	var (
+		req  *http.Request
+		resp *http.Response
+	)
+
+	for {
+		if req, err = http.NewRequest(http.MethodGet, u, nil); err != nil {
+			return nil, false, err
+		}
+
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		req.Header.Set("Accept", ociManifestMediaType+", "+dockerManifestMediaType)
+
+		if resp, err = f.client.Do(req); err != nil {
+			return nil, true, err
+		}
+
+		body, err = ioutil.ReadAll(resp.Body)
+		// nolint: errcheck
+		resp.Body.Close()
+
+		if err != nil {
+			return nil, true, err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return body, false, nil
+		case resp.StatusCode == http.StatusUnauthorized && header.Get("Authorization") == "":
+			var authorization string
+			if authorization, retry, err = f.authorize(resp.Header.Get("WWW-Authenticate"), ref); err != nil {
+				return nil, retry, errors.Wrap(err, "failed to authenticate to the registry")
+			}
+
+			header.Set("Authorization", authorization)
+		default:
+			retry = resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+			return nil, retry, errors.Errorf("failed to get the manifest: %s", resp.Status)
+		}
+	}
+}
+
+// authorize answers the challenge of the registry. The bearer tokens are
+// requested anonymously, or with the credentials of the source URL.
+func (f *Fetcher) authorize(challenge string, ref *ociReference) (authorization string, retry bool, err error) {
+	scheme, params := parseChallenge(challenge)
+
+	var password string
+	if ref.user != nil {
+		password, _ = ref.user.Password()
+	}
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if ref.user == nil {
+			return "", false, errors.New("the registry requires credentials")
+		}
+
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(ref.user.Username(), password)
+
+		return req.Header.Get("Authorization"), false, nil
+	case "bearer":
+	default:
+		return "", false, errors.Errorf("unsupported challenge %q", challenge)
+	}
+
+	u, err := url.Parse(params["realm"])
+	if err != nil || u.Scheme == "" {
+		return "", false, errors.Errorf("invalid realm %q", params["realm"])
+	}
+
+	q := u.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+
+	q.Set("scope", "repository:"+ref.repository+":pull")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", false, err
+	}
+
+	if ref.user != nil {
+		req.SetBasicAuth(ref.user.Username(), password)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", true, err
+	}
+	// nolint: errcheck
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", resp.StatusCode >= http.StatusInternalServerError, errors.Errorf("token request failed: %s", resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", false, errors.Wrap(err, "invalid token response")
+	}
+
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	return "Bearer " + token.Token, false, nil
+}
+
+// parseChallenge parses the WWW-Authenticate header, e.g.
+// Bearer realm="https://auth.example.com/token",service="registry.example.com".
+func parseChallenge(challenge string) (scheme string, params map[string]string) {
+	params = map[string]string{}
+
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	scheme = parts[0]
+
+	if len(parts) < 2 {
+		return scheme, params
+	}
+
+	for _, param := range strings.Split(parts[1], ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		params[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+
+	return scheme, params
+}
@@ -5,7 +5,10 @@
 package manifest
 
 import (
+	"log"
+
 	"github.com/pkg/errors"
+	"github.com/talos-systems/talos/pkg/blockdevice/discovery"
 	"github.com/talos-systems/talos/pkg/blockdevice/probe"
 	"github.com/talos-systems/talos/pkg/constants"
 	"github.com/talos-systems/talos/pkg/userdata"
@@ -17,6 +20,12 @@ func VerifyDataDevice(data *userdata.UserData) (err error) {
 	if data.Install == nil {
 		return errors.New("missing installation definition")
 	}
+	// Resolve the disk selector to the device path
+	if data.Install.Disk != nil {
+		if err = resolveDisk(data.Install); err != nil {
+			return err
+		}
+	}
 	// Set data device to root device if not specified
 	if data.Install.Ephemeral == nil {
 		return errors.New("missing definition")
@@ -26,10 +35,22 @@ func VerifyDataDevice(data *userdata.UserData) (err error) {
 		return errors.New("missing disk")
 	}
 
+	// The swap partition is not encrypted, it would leak the memory of an
+	// encrypted ephemeral partition.
+	if data.Install.Swap.Partition() && data.Install.Ephemeral.Encryption != nil {
+		return errors.New("a swap partition can't be used with an encrypted ephemeral partition")
+	}
+
 	if !data.Install.Force {
 		if err = VerifyDiskAvailability(constants.EphemeralPartitionLabel); err != nil {
 			return errors.Wrap(err, "failed to verify disk availability")
 		}
+
+		if data.Install.Swap.Partition() {
+			if err = VerifyDiskAvailability(constants.SwapPartitionLabel); err != nil {
+				return errors.Wrap(err, "failed to verify disk availability")
+			}
+		}
 	}
 
 	return nil
@@ -72,6 +93,29 @@ func VerifyBootDevice(data *userdata.UserData) (err error) {
 	return nil
 }
 
+// resolveDisk finds the disk selected by its attributes, and installs the
+// ephemeral partition to it.
+func resolveDisk(install *userdata.Install) (err error) {
+	if install.Ephemeral != nil && install.Ephemeral.Device != "" {
+		return errors.New("the ephemeral device conflicts with the disk selector")
+	}
+
+	var disk *discovery.Disk
+	if disk, err = discovery.Find(install.Disk.Selector()); err != nil {
+		return errors.Wrap(err, "failed to select the install disk")
+	}
+
+	log.Printf("selected install disk %s", disk)
+
+	if install.Ephemeral == nil {
+		install.Ephemeral = &userdata.InstallDevice{}
+	}
+
+	install.Ephemeral.Device = disk.Path
+
+	return nil
+}
+
 // VerifyDiskAvailability verifies that no filesystems currently exist with
 // the labels used by the OS.
 func VerifyDiskAvailability(label string) (err error) {
@@ -8,9 +8,11 @@ import (
 	"log"
 
 	"github.com/pkg/errors"
+	"github.com/talos-systems/talos/internal/pkg/encryption"
 	"github.com/talos-systems/talos/internal/pkg/mount"
 	"github.com/talos-systems/talos/pkg/blockdevice/probe"
 	"github.com/talos-systems/talos/pkg/constants"
+	"github.com/talos-systems/talos/pkg/userdata"
 	"golang.org/x/sys/unix"
 )
 
@@ -19,13 +21,15 @@ import (
 // creation and bare metall installs ). This is why we want to look up
 // device by specified disk as well as why we don't want to grow any
 // filesystems.
-func MountPointsForDevice(devpath string) (mountpoints *mount.Points, err error) {
+func MountPointsForDevice(devpath string, data *userdata.UserData) (mountpoints *mount.Points, err error) {
 	mountpoints = mount.NewMountPoints()
 	for _, name := range []string{constants.EphemeralPartitionLabel, constants.BootPartitionLabel} {
+		opts := []mount.Option{}
 		var target string
 		switch name {
 		case constants.EphemeralPartitionLabel:
 			target = constants.EphemeralMountPoint
+			opts = append(opts, mount.WithEncryption(ephemeralEncryption(data), encryption.UserDataOptions(data)...))
 		case constants.BootPartitionLabel:
 			target = constants.BootMountPoint
 		}
@@ -39,7 +43,7 @@ func MountPointsForDevice(devpath string) (mountpoints *mount.Points, err error)
 			}
 			return nil, errors.Errorf("probe device for filesystem %s: %v", name, err)
 		}
-		mountpoint := mount.NewMountPoint(dev.Path, target, dev.SuperBlock.Type(), unix.MS_NOATIME, "")
+		mountpoint := mount.NewMountPoint(dev.Path, target, dev.SuperBlock.Type(), unix.MS_NOATIME, "", opts...)
 		mountpoints.Set(name, mountpoint)
 	}
 
@@ -48,8 +52,9 @@ func MountPointsForDevice(devpath string) (mountpoints *mount.Points, err error)
 
 // MountPointsFromLabels returns the mountpoints required to boot the system.
 // Since this function is called exclusively during boot time, this is when
-// we want to grow the data filesystem.
-func MountPointsFromLabels() (mountpoints *mount.Points, err error) {
+// we want to grow the data filesystem. An encrypted data partition is opened
+// with the key source set in the userdata.
+func MountPointsFromLabels(data *userdata.UserData) (mountpoints *mount.Points, err error) {
 	mountpoints = mount.NewMountPoints()
 	for _, name := range []string{constants.EphemeralPartitionLabel, constants.BootPartitionLabel} {
 		opts := []mount.Option{}
@@ -57,7 +62,7 @@ func MountPointsFromLabels() (mountpoints *mount.Points, err error) {
 		switch name {
 		case constants.EphemeralPartitionLabel:
 			target = constants.EphemeralMountPoint
-			opts = append(opts, mount.WithResize(true))
+			opts = append(opts, mount.WithResize(true), mount.WithEncryption(ephemeralEncryption(data), encryption.UserDataOptions(data)...))
 		case constants.BootPartitionLabel:
 			target = constants.BootMountPoint
 		}
@@ -77,3 +82,12 @@ func MountPointsFromLabels() (mountpoints *mount.Points, err error) {
 	}
 	return mountpoints, nil
 }
+
+// ephemeralEncryption returns the encryption of the data partition, if any.
+func ephemeralEncryption(data *userdata.UserData) *userdata.Encryption {
+	if data == nil || data.Install == nil || data.Install.Ephemeral == nil {
+		return nil
+	}
+
+	return data.Install.Ephemeral.Encryption
+}
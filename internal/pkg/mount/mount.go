@@ -10,8 +10,13 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/talos-systems/talos/internal/pkg/encryption"
 	"github.com/talos-systems/talos/pkg/blockdevice"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/btrfs"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/ext4"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/luks"
 	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/xfs"
+	"github.com/talos-systems/talos/pkg/blockdevice/probe"
 	gptpartition "github.com/talos-systems/talos/pkg/blockdevice/table/gpt/partition"
 	"github.com/talos-systems/talos/pkg/blockdevice/util"
 	"github.com/talos-systems/talos/pkg/constants"
@@ -26,6 +31,11 @@ type Point struct {
 	flags  uintptr
 	data   string
 	*Options
+
+	// mapping is the name of the opened mapping of an encrypted partition,
+	// and mappedFstype the type of the file system in the mapping.
+	mapping      string
+	mappedFstype string
 }
 
 // PointMap represents a unique set of mount points.
@@ -113,7 +123,14 @@ func (p *Point) Mount() (err error) {
 		return errors.Errorf("mount timeout: %v", err)
 	}
 
-	if err = retry(p.source, target, p.fstype, p.flags, p.data); err != nil {
+	source, fstype := p.source, p.fstype
+	if fstype == luks.Type {
+		if source, fstype, err = p.open(); err != nil {
+			return err
+		}
+	}
+
+	if err = retry(source, target, fstype, p.flags, p.data); err != nil {
 		return err
 	}
 
@@ -127,6 +144,29 @@ func (p *Point) Mount() (err error) {
 
 }
 
+// open opens the mapping of the encrypted partition, and returns the mapped
+// device and the type of the file system in it.
+func (p *Point) open() (source, fstype string, err error) {
+	if p.mapping, err = encryption.Open(p.source, p.Encryption, p.KeyOptions...); err != nil {
+		return "", "", err
+	}
+
+	source = luks.MappedPath(p.mapping)
+
+	sb, err := probe.FileSystem(source)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to probe %s", source)
+	}
+
+	if sb == nil {
+		return "", "", errors.Errorf("no file system found in %s", source)
+	}
+
+	p.mappedFstype = sb.Type()
+
+	return source, p.mappedFstype, nil
+}
+
 // Unmount attempts to retry an unmount on EBUSY. It will attempt a
 // retry every 100 milliseconds over the course of 5 seconds.
 func (p *Point) Unmount() (err error) {
@@ -151,6 +191,14 @@ func (p *Point) Unmount() (err error) {
 		return err
 	}
 
+	if p.mapping != "" {
+		if err := luks.Close(p.mapping); err != nil {
+			return errors.Errorf("error closing mapping %s: %v", p.mapping, err)
+		}
+
+		p.mapping = ""
+	}
+
 	return nil
 }
 
@@ -188,7 +236,7 @@ func (p *Point) ResizePartition() (err error) {
 	}
 
 	for _, partition := range pt.Partitions() {
-		if partition.(*gptpartition.Partition).Name == constants.EphemeralPartitionLabel {
+		if gptPartition, ok := partition.(*gptpartition.Partition); ok && gptPartition.Name == constants.EphemeralPartitionLabel {
 			if err := pt.Resize(partition); err != nil {
 				return err
 			}
@@ -209,10 +257,32 @@ func (p *Point) ResizePartition() (err error) {
 }
 
 // GrowFilesystem grows a partition's filesystem to the maximum size allowed.
-// NB: An XFS partition MUST be mounted, or this will fail.
+// NB: An XFS or btrfs partition MUST be mounted, or this will fail.
 func (p *Point) GrowFilesystem() (err error) {
-	if err = xfs.GrowFS(p.Target()); err != nil {
-		return errors.Wrap(err, "xfs_growfs")
+	source, fstype := p.source, p.fstype
+
+	// The mapping must be grown to the size of the resized partition first.
+	if p.mapping != "" {
+		if err = encryption.Resize(p.source, p.mapping, p.Encryption, p.KeyOptions...); err != nil {
+			return errors.Wrap(err, "cryptsetup resize")
+		}
+
+		source, fstype = luks.MappedPath(p.mapping), p.mappedFstype
+	}
+
+	switch fstype {
+	case "ext4":
+		if err = ext4.GrowFS(source); err != nil {
+			return errors.Wrap(err, "resize2fs")
+		}
+	case "btrfs":
+		if err = btrfs.GrowFS(p.Target()); err != nil {
+			return errors.Wrap(err, "btrfs filesystem resize")
+		}
+	default:
+		if err = xfs.GrowFS(p.Target()); err != nil {
+			return errors.Wrap(err, "xfs_growfs")
+		}
 	}
 
 	return nil
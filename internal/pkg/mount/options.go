@@ -4,13 +4,20 @@
 
 package mount
 
+import (
+	"github.com/talos-systems/talos/internal/pkg/encryption"
+	"github.com/talos-systems/talos/pkg/userdata"
+)
+
 // Options is the functional options struct.
 type Options struct {
-	Loopback string
-	Prefix   string
-	ReadOnly bool
-	Shared   bool
-	Resize   bool
+	Loopback   string
+	Prefix     string
+	ReadOnly   bool
+	Shared     bool
+	Resize     bool
+	Encryption *userdata.Encryption
+	KeyOptions []encryption.Option
 }
 
 // Option is the functional option func.
@@ -45,14 +52,25 @@ func WithResize(o bool) Option {
 	}
 }
 
+// WithEncryption sets the source of the key of the partition, the mapping of
+// an encrypted partition is opened before it is mounted. The key options
+// apply to the requests to the key server.
+func WithEncryption(o *userdata.Encryption, keyOptions ...encryption.Option) Option {
+	return func(args *Options) {
+		args.Encryption = o
+		args.KeyOptions = keyOptions
+	}
+}
+
 // NewDefaultOptions initializes a Options struct with default values.
 func NewDefaultOptions(setters ...Option) *Options {
 	opts := &Options{
-		Loopback: "",
-		Prefix:   "",
-		ReadOnly: false,
-		Shared:   false,
-		Resize:   false,
+		Loopback:   "",
+		Prefix:     "",
+		ReadOnly:   false,
+		Shared:     false,
+		Resize:     false,
+		Encryption: nil,
 	}
 
 	for _, setter := range setters {
@@ -8,3 +8,6 @@ package network
 // the host that will be used for dhcp requests
 // TODO replace this with kernel arg
 const DefaultInterface = "eth0"
+
+// ResolvConfPath is the path to the resolver configuration
+const ResolvConfPath = "/etc/resolv.conf"
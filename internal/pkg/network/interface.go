@@ -0,0 +1,33 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package network
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// InterfaceByMAC looks up the name of the network interface of the host by
+// the MAC address.
+func InterfaceByMAC(mac string) (string, error) {
+	hwaddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return "", err
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+
+	for _, iface := range ifaces {
+		if iface.HardwareAddr.String() == hwaddr.String() {
+			return iface.Name, nil
+		}
+	}
+
+	return "", errors.Errorf("no interface found with MAC address %s", mac)
+}
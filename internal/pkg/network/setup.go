@@ -5,7 +5,10 @@
 package network
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"syscall"
 
@@ -63,9 +66,31 @@ func SetupNetwork(data *userdata.UserData) (err error) {
 		}
 	}
 
+	// Nameservers from the userdata take precedence over the ones
+	// received via DHCP
+	if len(data.Networking.OS.Nameservers) > 0 {
+		if err = writeResolvConf(data.Networking.OS.Nameservers, data.Networking.OS.Domainname); err != nil {
+			return errors.Wrap(err, "failed to write resolv.conf")
+		}
+	}
+
 	return nil
 }
 
+func writeResolvConf(nameservers []string, domain string) error {
+	var buf bytes.Buffer
+
+	if domain != "" {
+		fmt.Fprintf(&buf, "search %s\n", domain)
+	}
+
+	for _, nameserver := range nameservers {
+		fmt.Fprintf(&buf, "nameserver %s\n", nameserver)
+	}
+
+	return ioutil.WriteFile(ResolvConfPath, buf.Bytes(), 0644)
+}
+
 // Maybe look at adjusting this to accept an interface value from a kernel arg
 func defaultNetworkSetup() (err error) {
 	log.Println("bringing up lo")
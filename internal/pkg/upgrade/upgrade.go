@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -27,9 +28,14 @@ import (
 	yaml "gopkg.in/yaml.v2"
 )
 
-// NewUpgrade initiates a Talos upgrade
+// NewUpgrade initiates a Talos upgrade. The assets are verified against the
+// digests, which are required unless the assets are pulled from a registry.
 // nolint: gocyclo
-func NewUpgrade(url string) (err error) {
+func NewUpgrade(url, kernelSHA256, initramfsSHA256 string) (err error) {
+	if !strings.HasPrefix(url, "oci://") && (kernelSHA256 == "" || initramfsSHA256 == "") {
+		return errors.Errorf("the SHA-256 digests of the kernel and the initramfs are required to upgrade from %s", url)
+	}
+
 	var hostname string
 	if hostname, err = os.Hostname(); err != nil {
 		return
@@ -50,7 +56,7 @@ func NewUpgrade(url string) (err error) {
 		return err
 	}
 
-	if err = upgradeBoot(url); err != nil {
+	if err = upgradeBoot(url, kernelSHA256, initramfsSHA256, data); err != nil {
 		return err
 	}
 
@@ -82,26 +88,35 @@ func NewUpgrade(url string) (err error) {
 	return err
 }
 
-func upgradeBoot(url string) error {
+func upgradeBoot(url, kernelSHA256, initramfsSHA256 string, data *userdata.UserData) error {
+	// The assets are downloaded with the same options as on install, e.g.
+	// from a registry with a private CA.
+	fetcher, err := manifest.NewFetcherFromUserData(data)
+	if err != nil {
+		return err
+	}
+
 	bootTarget := manifest.Target{
 		Label:      constants.BootPartitionLabel,
 		MountPoint: constants.BootMountPoint,
 		Assets:     []*manifest.Asset{},
+		Fetcher:    fetcher,
 	}
 
 	// Kernel
 	bootTarget.Assets = append(bootTarget.Assets, &manifest.Asset{
 		Source:      url + "/" + constants.KernelAsset,
 		Destination: filepath.Join("/", "default", constants.KernelAsset),
+		SHA256:      kernelSHA256,
 	})
 
 	// Initramfs
 	bootTarget.Assets = append(bootTarget.Assets, &manifest.Asset{
 		Source:      url + "/" + constants.InitramfsAsset,
 		Destination: filepath.Join("/", "default", constants.InitramfsAsset),
+		SHA256:      initramfsSHA256,
 	})
 
-	var err error
 	if err = bootTarget.Save(); err != nil {
 		return err
 	}
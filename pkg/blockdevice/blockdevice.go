@@ -6,13 +6,13 @@
 package blockdevice
 
 import (
-	"bytes"
 	"os"
 	"unsafe"
 
 	"github.com/pkg/errors"
 	"github.com/talos-systems/talos/pkg/blockdevice/table"
 	"github.com/talos-systems/talos/pkg/blockdevice/table/gpt"
+	"github.com/talos-systems/talos/pkg/blockdevice/table/mbr"
 
 	"golang.org/x/sys/unix"
 )
@@ -59,16 +59,22 @@ func Open(devname string, setters ...Option) (bd *BlockDevice, err error) {
 		}
 		bd.table = pt
 	} else {
-		buf := make([]byte, 1)
-		// PMBR protective entry starts at 446. The partition type is at offset
-		// 4 from the start of the PMBR protective entry.
-		_, err = f.ReadAt(buf, 450)
-		if err != nil {
+		buf := make([]byte, 512)
+		if _, err = f.ReadAt(buf, 0); err != nil {
 			return nil, err
 		}
+		// PMBR protective entry starts at 446. The partition type is at offset
+		// 4 from the start of the PMBR protective entry.
+		switch {
 		// For GPT, the partition type should be 0xee (EFI GPT).
-		if bytes.Equal(buf, []byte{0xee}) {
+		case buf[450] == 0xee:
 			bd.table = gpt.NewGPT(devname, f)
+		// Otherwise, the sector might be an MBR. The boot signature is also
+		// found in the boot sectors of the FAT and NTFS file systems, which
+		// are told apart by the BIOS parameter block and the partition
+		// entries.
+		case mbr.Is(buf):
+			bd.table = mbr.NewMBR(devname, f)
 		}
 	}
 
@@ -4,11 +4,102 @@
 
 package blockdevice_test
 
-import "testing"
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
 
-func TestEmpty(t *testing.T) {
-	// added for accurate coverage estimation
-	//
-	// please remove it once any unit-test is added
-	// for this package
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/pkg/blockdevice"
+	gptpartition "github.com/talos-systems/talos/pkg/blockdevice/table/gpt/partition"
+)
+
+const (
+	mib       = 1 << 20
+	imageSize = 64 * mib
+)
+
+type WipeSuite struct {
+	suite.Suite
+
+	path string
+}
+
+func (suite *WipeSuite) SetupTest() {
+	f, err := ioutil.TempFile("", "wipe")
+	suite.Require().NoError(err)
+
+	_, err = f.Write(bytes.Repeat([]byte{0xff}, imageSize))
+	suite.Require().NoError(err)
+	suite.Require().NoError(f.Close())
+
+	suite.path = f.Name()
+}
+
+func (suite *WipeSuite) TearDownTest() {
+	suite.Require().NoError(os.Remove(suite.path))
+}
+
+func (suite *WipeSuite) image() []byte {
+	b, err := ioutil.ReadFile(suite.path)
+	suite.Require().NoError(err)
+
+	return b
+}
+
+func (suite *WipeSuite) TestZero() {
+	bd, err := blockdevice.Open(suite.path)
+	suite.Require().NoError(err)
+	// nolint: errcheck
+	defer bd.Close()
+
+	var done, total uint64
+	suite.Require().NoError(bd.Wipe(blockdevice.WipeZero, func(d, t uint64) { done, total = d, t }))
+
+	suite.Assert().Equal(uint64(imageSize), done)
+	suite.Assert().Equal(uint64(imageSize), total)
+	suite.Assert().Equal(make([]byte, imageSize), suite.image())
+}
+
+func (suite *WipeSuite) TestPartial() {
+	bd, err := blockdevice.Open(suite.path, blockdevice.WithNewGPT(true))
+	suite.Require().NoError(err)
+
+	pt, err := bd.PartitionTable(false)
+	suite.Require().NoError(err)
+
+	part, err := pt.Add(16 * mib)
+	suite.Require().NoError(err)
+	suite.Require().NoError(pt.Write())
+
+	// The last LBA is inclusive.
+	start := int(part.(*gptpartition.Partition).FirstLBA) * 512
+	end := int(part.(*gptpartition.Partition).LastLBA+1) * 512
+
+	suite.Require().NoError(bd.Wipe(blockdevice.WipePartial, nil))
+	suite.Require().NoError(bd.Close())
+
+	b := suite.image()
+	for _, r := range [][2]int{{0, mib}, {imageSize - mib, imageSize}, {start, start + mib}, {end - mib, end}} {
+		suite.Assert().Equal(make([]byte, mib), b[r[0]:r[1]], "%d-%d", r[0], r[1])
+	}
+
+	// The data between the headers is left.
+	suite.Assert().Equal(byte(0xff), b[start+2*mib])
+	suite.Assert().Equal(byte(0xff), b[end+mib])
+}
+
+func (suite *WipeSuite) TestUnknownMethod() {
+	bd, err := blockdevice.Open(suite.path)
+	suite.Require().NoError(err)
+	// nolint: errcheck
+	defer bd.Close()
+
+	suite.Assert().Error(bd.Wipe("shred", nil))
+}
+
+func TestWipeSuite(t *testing.T) {
+	suite.Run(t, new(WipeSuite))
 }
@@ -0,0 +1,248 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package discovery lists the disks of the machine, and selects a disk by its
+// attributes instead of the device path, which depends on the order the
+// kernel enumerates the disks in.
+package discovery
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Bus types.
+const (
+	BusNVMe   = "nvme"
+	BusSATA   = "sata"
+	BusSCSI   = "scsi"
+	BusVirtIO = "virtio"
+	BusUSB    = "usb"
+	BusMMC    = "mmc"
+)
+
+// Disk types.
+const (
+	TypeSSD = "ssd"
+	TypeHDD = "hdd"
+)
+
+// linkDirs are the directories under /dev with the persistent links to the
+// disks.
+var linkDirs = []string{"disk/by-id", "disk/by-path"}
+
+// Disk represents a disk found in sysfs.
+type Disk struct {
+	Name       string
+	Path       string
+	Size       uint64
+	Model      string
+	Serial     string
+	WWN        string
+	Rotational bool
+	Bus        string
+	Links      []string
+	Partitions []*Partition
+}
+
+// Partition represents a partition of a disk found in sysfs.
+type Partition struct {
+	Name   string
+	Path   string
+	Number int32
+	Size   uint64
+}
+
+// Type returns the type of the disk, hdd for rotational disks and ssd
+// otherwise.
+func (d *Disk) Type() string {
+	if d.Rotational {
+		return TypeHDD
+	}
+
+	return TypeSSD
+}
+
+// String implements the Stringer interface.
+func (d *Disk) String() string {
+	return fmt.Sprintf("%s (model %q, serial %q, wwn %q, size %d, %s, %s)", d.Path, d.Model, d.Serial, d.WWN, d.Size, d.Type(), d.Bus)
+}
+
+// List returns the disks of the machine, sorted by name. Virtual block
+// devices, like loop and device mapper devices, and empty drives are skipped.
+func List(setters ...Option) (disks []*Disk, err error) {
+	opts := NewDefaultOptions(setters...)
+
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(filepath.Join(opts.SysfsRoot, "block")); err != nil {
+		return nil, err
+	}
+
+	links := readLinks(opts.DevRoot)
+
+	for _, info := range infos {
+		var disk *Disk
+		if disk, err = readDisk(opts.SysfsRoot, info.Name()); err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", info.Name())
+		}
+
+		if disk == nil {
+			continue
+		}
+
+		disk.Links = links[disk.Name]
+		disks = append(disks, disk)
+	}
+
+	sort.Slice(disks, func(i, j int) bool { return disks[i].Name < disks[j].Name })
+
+	return disks, nil
+}
+
+func readDisk(sysfs, name string) (*Disk, error) {
+	dir := filepath.Join(sysfs, "block", name)
+
+	// Virtual block devices have no backing device.
+	if _, err := os.Stat(filepath.Join(dir, "device")); err != nil {
+		return nil, nil
+	}
+
+	sectors, err := strconv.ParseUint(readAttribute(dir, "size"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid size")
+	}
+
+	if sectors == 0 {
+		return nil, nil
+	}
+
+	disk := &Disk{
+		Name: name,
+		Path: "/dev/" + name,
+		// The size is always in 512 byte sectors.
+		Size:       sectors * 512,
+		Model:      readAttribute(dir, "device/model"),
+		Serial:     readAttribute(dir, "device/serial", "serial"),
+		WWN:        readAttribute(dir, "wwid", "device/wwid"),
+		Rotational: readAttribute(dir, "queue/rotational") == "1",
+	}
+
+	disk.Bus = bus(dir, name)
+
+	if disk.Partitions, err = readPartitions(dir, name); err != nil {
+		return nil, err
+	}
+
+	return disk, nil
+}
+
+// readPartitions lists the partitions the kernel found on the disk. The
+// partitions are the subdirectories of the disk with the partition attribute.
+func readPartitions(dir, name string) (partitions []*Partition, err error) {
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(dir); err != nil {
+		return nil, err
+	}
+
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Name(), name) {
+			continue
+		}
+
+		partdir := filepath.Join(dir, info.Name())
+
+		number := readAttribute(partdir, "partition")
+		if number == "" {
+			continue
+		}
+
+		var n, sectors uint64
+		if n, err = strconv.ParseUint(number, 10, 32); err != nil {
+			return nil, errors.Wrapf(err, "invalid number of partition %s", info.Name())
+		}
+
+		if sectors, err = strconv.ParseUint(readAttribute(partdir, "size"), 10, 64); err != nil {
+			return nil, errors.Wrapf(err, "invalid size of partition %s", info.Name())
+		}
+
+		partitions = append(partitions, &Partition{
+			Name:   info.Name(),
+			Path:   "/dev/" + info.Name(),
+			Number: int32(n),
+			Size:   sectors * 512,
+		})
+	}
+
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].Number < partitions[j].Number })
+
+	return partitions, nil
+}
+
+// readAttribute returns the first of the attributes found, with the padding
+// removed.
+func readAttribute(dir string, names ...string) string {
+	for _, name := range names {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			return strings.TrimSpace(string(b))
+		}
+	}
+
+	return ""
+}
+
+// bus finds the bus the disk is attached to from the path of the device in
+// sysfs.
+func bus(dir, name string) string {
+	path, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		path = dir
+	}
+
+	switch {
+	case strings.HasPrefix(name, "nvme"):
+		return BusNVMe
+	case strings.Contains(path, "/virtio"):
+		return BusVirtIO
+	case strings.Contains(path, "/usb"):
+		return BusUSB
+	case strings.Contains(path, "/ata"):
+		return BusSATA
+	case strings.HasPrefix(name, "mmcblk"):
+		return BusMMC
+	default:
+		return BusSCSI
+	}
+}
+
+// readLinks maps the disk names to the persistent links pointing to them.
+func readLinks(dev string) map[string][]string {
+	links := map[string][]string{}
+
+	for _, dir := range linkDirs {
+		infos, err := ioutil.ReadDir(filepath.Join(dev, dir))
+		if err != nil {
+			continue
+		}
+
+		for _, info := range infos {
+			target, err := os.Readlink(filepath.Join(dev, dir, info.Name()))
+			if err != nil {
+				continue
+			}
+
+			name := filepath.Base(target)
+			links[name] = append(links[name], filepath.Join("/dev", dir, info.Name()))
+		}
+	}
+
+	return links
+}
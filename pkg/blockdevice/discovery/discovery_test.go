@@ -0,0 +1,154 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package discovery_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/pkg/blockdevice/discovery"
+)
+
+type DiscoverySuite struct {
+	suite.Suite
+
+	root  string
+	disks []*discovery.Disk
+}
+
+// disk creates the sysfs directory of the device at the path, with the
+// attributes, and links it in /sys/block.
+func (suite *DiscoverySuite) disk(name, path string, attributes map[string]string) {
+	dir := filepath.Join(suite.root, "sys/devices", path)
+
+	for attribute, value := range attributes {
+		suite.Require().NoError(os.MkdirAll(filepath.Dir(filepath.Join(dir, attribute)), 0755))
+		suite.Require().NoError(ioutil.WriteFile(filepath.Join(dir, attribute), []byte(value+"\n"), 0644))
+	}
+
+	suite.Require().NoError(os.Symlink(filepath.Join("../devices", path), filepath.Join(suite.root, "sys/block", name)))
+}
+
+func (suite *DiscoverySuite) link(dir, link, name string) {
+	suite.Require().NoError(os.MkdirAll(filepath.Join(suite.root, "dev/disk", dir), 0755))
+	suite.Require().NoError(os.Symlink("../../"+name, filepath.Join(suite.root, "dev/disk", dir, link)))
+}
+
+func (suite *DiscoverySuite) SetupTest() {
+	var err error
+
+	suite.root, err = ioutil.TempDir("", "discovery")
+	suite.Require().NoError(err)
+	suite.Require().NoError(os.MkdirAll(filepath.Join(suite.root, "sys/block"), 0755))
+
+	suite.disk("nvme0n1", "pci0000:00/0000:00:01.0/nvme/nvme0/nvme0n1", map[string]string{
+		"size":                "1000215216",
+		"wwid":                "eui.0025388b71b2c3d4",
+		"device/model":        "Samsung SSD 970 EVO 512GB               ",
+		"device/serial":       "S466NX0M123456      ",
+		"queue/rotational":    "0",
+		"nvme0n1p2/partition": "2",
+		"nvme0n1p2/size":      "999165952",
+		"nvme0n1p1/partition": "1",
+		"nvme0n1p1/size":      "1048576",
+	})
+	suite.disk("sda", "pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda", map[string]string{
+		"size":             "7814037168",
+		"device/model":     "ST4000NM0035-1V4",
+		"device/wwid":      "naa.5000c500a0b1c2d3",
+		"queue/rotational": "1",
+	})
+	suite.disk("vda", "pci0000:00/0000:00:04.0/virtio2/block/vda", map[string]string{
+		"size":             "41943040",
+		"serial":           "talos-root",
+		"device/vendor":    "0x1af4",
+		"queue/rotational": "1",
+	})
+	suite.disk("sr0", "pci0000:00/0000:00:1f.2/ata2/host1/target1:0:0/1:0:0:0/block/sr0", map[string]string{
+		"size":         "0",
+		"device/model": "DVD-ROM",
+	})
+	suite.disk("loop0", "virtual/block/loop0", map[string]string{
+		"size": "2048",
+	})
+
+	suite.link("by-id", "nvme-Samsung_SSD_970_EVO_512GB_S466NX0M123456", "nvme0n1")
+	suite.link("by-id", "nvme-Samsung_SSD_970_EVO_512GB_S466NX0M123456-part1", "nvme0n1p1")
+	suite.link("by-id", "wwn-0x5000c500a0b1c2d3", "sda")
+	suite.link("by-path", "pci-0000:00:1f.2-ata-1", "sda")
+
+	suite.disks, err = discovery.List(
+		discovery.WithSysfsRoot(filepath.Join(suite.root, "sys")),
+		discovery.WithDevRoot(filepath.Join(suite.root, "dev")),
+	)
+	suite.Require().NoError(err)
+}
+
+func (suite *DiscoverySuite) TearDownTest() {
+	suite.Require().NoError(os.RemoveAll(suite.root))
+}
+
+func (suite *DiscoverySuite) TestList() {
+	suite.Require().Len(suite.disks, 3)
+
+	nvme := suite.disks[0]
+	suite.Assert().Equal("/dev/nvme0n1", nvme.Path)
+	suite.Assert().Equal(uint64(1000215216*512), nvme.Size)
+	suite.Assert().Equal("Samsung SSD 970 EVO 512GB", nvme.Model)
+	suite.Assert().Equal("S466NX0M123456", nvme.Serial)
+	suite.Assert().Equal(discovery.BusNVMe, nvme.Bus)
+	suite.Assert().Equal(discovery.TypeSSD, nvme.Type())
+	suite.Assert().Equal([]string{"/dev/disk/by-id/nvme-Samsung_SSD_970_EVO_512GB_S466NX0M123456"}, nvme.Links)
+	suite.Require().Len(nvme.Partitions, 2)
+	suite.Assert().Equal(&discovery.Partition{Name: "nvme0n1p1", Path: "/dev/nvme0n1p1", Number: 1, Size: 1048576 * 512}, nvme.Partitions[0])
+	suite.Assert().Equal(int32(2), nvme.Partitions[1].Number)
+
+	sda := suite.disks[1]
+	suite.Assert().Equal(discovery.BusSATA, sda.Bus)
+	suite.Assert().Equal(discovery.TypeHDD, sda.Type())
+	suite.Assert().Equal("naa.5000c500a0b1c2d3", sda.WWN)
+	suite.Assert().Len(sda.Links, 2)
+	suite.Assert().Empty(sda.Partitions)
+
+	vda := suite.disks[2]
+	suite.Assert().Equal(discovery.BusVirtIO, vda.Bus)
+	suite.Assert().Equal("talos-root", vda.Serial)
+}
+
+func (suite *DiscoverySuite) TestSelect() {
+	for _, tc := range []struct {
+		selector discovery.Selector
+		expected string
+	}{
+		{discovery.Selector{Bus: discovery.BusNVMe}, "/dev/nvme0n1"},
+		{discovery.Selector{Type: discovery.TypeHDD, MinSize: 1 << 40}, "/dev/sda"},
+		{discovery.Selector{MaxSize: 100 << 30}, "/dev/vda"},
+		{discovery.Selector{Model: "Samsung SSD 970*"}, "/dev/nvme0n1"},
+		{discovery.Selector{Serial: "talos-root"}, "/dev/vda"},
+		{discovery.Selector{WWN: "0x5000C500A0B1C2D3"}, "/dev/sda"},
+		{discovery.Selector{Link: "/dev/disk/by-path/pci-0000:00:1f.2-ata-*"}, "/dev/sda"},
+	} {
+		disk, err := tc.selector.Select(suite.disks)
+		suite.Require().NoError(err, "%+v", tc.selector)
+		suite.Assert().Equal(tc.expected, disk.Path, "%+v", tc.selector)
+	}
+}
+
+func (suite *DiscoverySuite) TestSelectErrors() {
+	_, err := (&discovery.Selector{Bus: discovery.BusUSB}).Select(suite.disks)
+	suite.Assert().Error(err)
+
+	_, err = (&discovery.Selector{Type: discovery.TypeHDD}).Select(suite.disks)
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), "/dev/sda, /dev/vda")
+}
+
+func TestDiscoverySuite(t *testing.T) {
+	suite.Run(t, new(DiscoverySuite))
+}
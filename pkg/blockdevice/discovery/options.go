@@ -0,0 +1,42 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package discovery
+
+// Options is the functional options struct.
+type Options struct {
+	SysfsRoot string
+	DevRoot   string
+}
+
+// Option is the functional option func.
+type Option func(*Options)
+
+// WithSysfsRoot sets the path sysfs is mounted at.
+func WithSysfsRoot(o string) Option {
+	return func(args *Options) {
+		args.SysfsRoot = o
+	}
+}
+
+// WithDevRoot sets the path devtmpfs is mounted at.
+func WithDevRoot(o string) Option {
+	return func(args *Options) {
+		args.DevRoot = o
+	}
+}
+
+// NewDefaultOptions initializes a Options struct with default values.
+func NewDefaultOptions(setters ...Option) *Options {
+	opts := &Options{
+		SysfsRoot: "/sys",
+		DevRoot:   "/dev",
+	}
+
+	for _, setter := range setters {
+		setter(opts)
+	}
+
+	return opts
+}
@@ -0,0 +1,114 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package discovery
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Selector describes the disk to select. The empty fields match any disk.
+// The model, the serial and the link are shell patterns.
+type Selector struct {
+	MinSize uint64
+	MaxSize uint64
+	Model   string
+	Serial  string
+	WWN     string
+	Type    string
+	Bus     string
+	Link    string
+}
+
+// Match reports whether the disk has all of the attributes of the selector.
+// nolint: gocyclo
+func (s *Selector) Match(d *Disk) bool {
+	switch {
+	case s.MinSize != 0 && d.Size < s.MinSize:
+		return false
+	case s.MaxSize != 0 && d.Size > s.MaxSize:
+		return false
+	case s.Model != "" && !match(s.Model, d.Model):
+		return false
+	case s.Serial != "" && !match(s.Serial, d.Serial):
+		return false
+	case s.WWN != "" && normalizeWWN(s.WWN) != normalizeWWN(d.WWN):
+		return false
+	case s.Type != "" && s.Type != d.Type():
+		return false
+	case s.Bus != "" && s.Bus != d.Bus:
+		return false
+	}
+
+	if s.Link == "" {
+		return true
+	}
+
+	for _, link := range d.Links {
+		if match(s.Link, link) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Select returns the only disk which matches the selector. It is an error
+// if no disk, or more than one disk matches, so that the wrong disk is never
+// picked.
+func (s *Selector) Select(disks []*Disk) (*Disk, error) {
+	var matched []*Disk
+
+	for _, disk := range disks {
+		if s.Match(disk) {
+			matched = append(matched, disk)
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		return nil, errors.New("no disk matches the selector")
+	case 1:
+		return matched[0], nil
+	default:
+		paths := make([]string, 0, len(matched))
+		for _, disk := range matched {
+			paths = append(paths, disk.Path)
+		}
+
+		return nil, errors.Errorf("%d disks match the selector: %s", len(matched), strings.Join(paths, ", "))
+	}
+}
+
+// Find lists the disks of the machine, and selects one of them.
+func Find(s *Selector, setters ...Option) (*Disk, error) {
+	disks, err := List(setters...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the disks")
+	}
+
+	return s.Select(disks)
+}
+
+func match(pattern, value string) bool {
+	// nolint: errcheck
+	ok, _ := path.Match(pattern, value)
+
+	return ok
+}
+
+// normalizeWWN strips the prefixes the WWN is reported with by the different
+// tools, e.g. naa.5000c500a0b1c2d3 in sysfs and 0x5000c500a0b1c2d3 by udev.
+func normalizeWWN(wwn string) string {
+	wwn = strings.ToLower(strings.TrimSpace(wwn))
+
+	for _, prefix := range []string{"naa.", "eui.", "t10.", "0x"} {
+		wwn = strings.TrimPrefix(wwn, prefix)
+	}
+
+	return wwn
+}
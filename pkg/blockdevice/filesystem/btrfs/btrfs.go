@@ -0,0 +1,45 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package btrfs provides an interface to btrfs-progs.
+package btrfs
+
+import (
+	"os/exec"
+)
+
+// GrowFS expands a btrfs filesystem to the maximum possible. The filesystem
+// MUST be mounted, and the mount point is expected, or this will fail.
+func GrowFS(mountpoint string) error {
+	return cmd("btrfs", "filesystem", "resize", "max", mountpoint)
+}
+
+// MakeFS creates a btrfs filesystem on the specified partition.
+func MakeFS(partname string, setters ...Option) error {
+	opts := NewDefaultOptions(setters...)
+
+	args := []string{}
+
+	if opts.Force {
+		args = append(args, "-f")
+	}
+
+	if opts.Label != "" {
+		args = append(args, "-L", opts.Label)
+	}
+
+	args = append(args, partname)
+
+	return cmd("mkfs.btrfs", args...)
+}
+
+func cmd(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	return cmd.Wait()
+}
@@ -0,0 +1,63 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package btrfs
+
+import (
+	"bytes"
+)
+
+const (
+	// Magic is the btrfs magic signature.
+	Magic = "_BHRfS_M"
+)
+
+// SuperBlock represents the btrfs super block. The fields are stored as
+// little endian, so the multi-byte fields are kept as byte arrays.
+type SuperBlock struct {
+	Csum                [32]uint8
+	FSID                [16]uint8
+	Bytenr              [8]uint8
+	Flags               [8]uint8
+	Magic               [8]uint8
+	Generation          [8]uint8
+	Root                [8]uint8
+	ChunkRoot           [8]uint8
+	LogRoot             [8]uint8
+	LogRootTransid      [8]uint8
+	TotalBytes          [8]uint8
+	BytesUsed           [8]uint8
+	RootDirObjectid     [8]uint8
+	NumDevices          [8]uint8
+	Sectorsize          [4]uint8
+	Nodesize            [4]uint8
+	Leafsize            [4]uint8
+	Stripesize          [4]uint8
+	SysChunkArraySize   [4]uint8
+	ChunkRootGeneration [8]uint8
+	CompatFlags         [8]uint8
+	CompatRoFlags       [8]uint8
+	IncompatFlags       [8]uint8
+	CsumType            [2]uint8
+	RootLevel           uint8
+	ChunkRootLevel      uint8
+	LogRootLevel        uint8
+	DevItem             [0x62]uint8
+	Label               [256]uint8
+}
+
+// Is implements the SuperBlocker interface.
+func (sb *SuperBlock) Is() bool {
+	return bytes.Equal(sb.Magic[:], []byte(Magic))
+}
+
+// Offset implements the SuperBlocker interface.
+func (sb *SuperBlock) Offset() int64 {
+	return 0x10000
+}
+
+// Type implements the SuperBlocker interface.
+func (sb *SuperBlock) Type() string {
+	return "btrfs"
+}
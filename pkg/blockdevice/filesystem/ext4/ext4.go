@@ -0,0 +1,45 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package ext4 provides an interface to e2fsprogs.
+package ext4
+
+import (
+	"os/exec"
+)
+
+// GrowFS expands an ext4 filesystem to the maximum possible. The filesystem
+// can be grown while it is mounted.
+func GrowFS(partname string) error {
+	return cmd("resize2fs", partname)
+}
+
+// MakeFS creates an ext4 filesystem on the specified partition.
+func MakeFS(partname string, setters ...Option) error {
+	opts := NewDefaultOptions(setters...)
+
+	args := []string{}
+
+	if opts.Force {
+		args = append(args, "-F")
+	}
+
+	if opts.Label != "" {
+		args = append(args, "-L", opts.Label)
+	}
+
+	args = append(args, partname)
+
+	return cmd("mkfs.ext4", args...)
+}
+
+func cmd(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	return cmd.Wait()
+}
@@ -0,0 +1,68 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package ext4
+
+import (
+	"encoding/binary"
+)
+
+const (
+	// Magic is the ext4 magic number.
+	Magic = 0xef53
+)
+
+// SuperBlock represents the ext4 super block. The fields are stored as
+// little endian, so the multi-byte fields are kept as byte arrays.
+type SuperBlock struct {
+	InodesCount       [4]uint8
+	BlocksCountLo     [4]uint8
+	RBlocksCountLo    [4]uint8
+	FreeBlocksCountLo [4]uint8
+	FreeInodesCount   [4]uint8
+	FirstDataBlock    [4]uint8
+	LogBlockSize      [4]uint8
+	LogClusterSize    [4]uint8
+	BlocksPerGroup    [4]uint8
+	ClustersPerGroup  [4]uint8
+	InodesPerGroup    [4]uint8
+	Mtime             [4]uint8
+	Wtime             [4]uint8
+	MntCount          [2]uint8
+	MaxMntCount       [2]uint8
+	Magic             [2]uint8
+	State             [2]uint8
+	Errors            [2]uint8
+	MinorRevLevel     [2]uint8
+	Lastcheck         [4]uint8
+	Checkinterval     [4]uint8
+	CreatorOS         [4]uint8
+	RevLevel          [4]uint8
+	DefResuid         [2]uint8
+	DefResgid         [2]uint8
+	FirstIno          [4]uint8
+	InodeSize         [2]uint8
+	BlockGroupNr      [2]uint8
+	FeatureCompat     [4]uint8
+	FeatureIncompat   [4]uint8
+	FeatureRoCompat   [4]uint8
+	UUID              [16]uint8
+	VolumeName        [16]uint8
+	LastMounted       [64]uint8
+}
+
+// Is implements the SuperBlocker interface.
+func (sb *SuperBlock) Is() bool {
+	return binary.LittleEndian.Uint16(sb.Magic[:]) == Magic
+}
+
+// Offset implements the SuperBlocker interface.
+func (sb *SuperBlock) Offset() int64 {
+	return 0x400
+}
+
+// Type implements the SuperBlocker interface.
+func (sb *SuperBlock) Type() string {
+	return "ext4"
+}
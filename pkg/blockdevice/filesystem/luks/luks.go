@@ -0,0 +1,67 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package luks provides an interface to cryptsetup.
+package luks
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+)
+
+// MapperDir is the directory of the device mapper devices.
+const MapperDir = "/dev/mapper"
+
+// Format creates a LUKS2 header on the specified partition, the key is the
+// passphrase of the first key slot.
+func Format(partname string, key []byte, setters ...Option) error {
+	opts := NewDefaultOptions(setters...)
+
+	args := []string{"luksFormat", "--type", "luks2", "--batch-mode", "--key-file", "-"}
+
+	if opts.Label != "" {
+		args = append(args, "--label", opts.Label)
+	}
+
+	if opts.UUID != "" {
+		args = append(args, "--uuid", opts.UUID)
+	}
+
+	args = append(args, partname)
+
+	return cmd(key, "cryptsetup", args...)
+}
+
+// Open maps the encrypted partition to /dev/mapper/<name>.
+func Open(partname, name string, key []byte) error {
+	return cmd(key, "cryptsetup", "open", "--type", "luks2", "--key-file", "-", partname, name)
+}
+
+// Resize grows the mapping to the size of the partition, after the partition
+// was resized.
+func Resize(name string, key []byte) error {
+	return cmd(key, "cryptsetup", "resize", "--key-file", "-", name)
+}
+
+// Close removes the mapping.
+func Close(name string) error {
+	return cmd(nil, "cryptsetup", "close", name)
+}
+
+// MappedPath returns the path of the mapping.
+func MappedPath(name string) string {
+	return filepath.Join(MapperDir, name)
+}
+
+func cmd(stdin []byte, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	return cmd.Wait()
+}
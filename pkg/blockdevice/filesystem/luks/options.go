@@ -0,0 +1,42 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package luks
+
+// Options is the functional options struct.
+type Options struct {
+	Label string
+	UUID  string
+}
+
+// Option is the functional option func.
+type Option func(*Options)
+
+// WithLabel sets the label of the LUKS2 header.
+func WithLabel(o string) Option {
+	return func(args *Options) {
+		args.Label = o
+	}
+}
+
+// WithUUID sets the UUID of the LUKS2 header.
+func WithUUID(o string) Option {
+	return func(args *Options) {
+		args.UUID = o
+	}
+}
+
+// NewDefaultOptions initializes a Options struct with default values.
+func NewDefaultOptions(setters ...Option) *Options {
+	opts := &Options{
+		Label: "",
+		UUID:  "",
+	}
+
+	for _, setter := range setters {
+		setter(opts)
+	}
+
+	return opts
+}
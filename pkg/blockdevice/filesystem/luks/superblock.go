@@ -0,0 +1,64 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package luks
+
+import (
+	"bytes"
+)
+
+const (
+	// Magic is the LUKS magic signature.
+	Magic = "LUKS\xba\xbe"
+	// Type is the type of the partitions holding a LUKS header, as reported
+	// by blkid.
+	Type = "crypto_LUKS"
+)
+
+// SuperBlock represents the binary header of LUKS2. The header of LUKS1 has
+// the same magic and version fields, the label is specific to LUKS2.
+type SuperBlock struct {
+	Magic       [6]uint8
+	Version     uint16
+	HeaderSize  uint64
+	SequenceID  uint64
+	Label       [48]uint8
+	ChecksumAlg [32]uint8
+	Salt        [64]uint8
+	UUID        [40]uint8
+	Subsystem   [48]uint8
+}
+
+// Is implements the SuperBlocker interface.
+func (sb *SuperBlock) Is() bool {
+	return bytes.Equal(sb.Magic[:], []byte(Magic))
+}
+
+// Offset implements the SuperBlocker interface.
+func (sb *SuperBlock) Offset() int64 {
+	return 0x0
+}
+
+// Type implements the SuperBlocker interface.
+func (sb *SuperBlock) Type() string {
+	return Type
+}
+
+// GetLabel returns the label of the LUKS2 header, LUKS1 has no label.
+func (sb *SuperBlock) GetLabel() string {
+	if sb.Version != 2 {
+		return ""
+	}
+
+	return string(bytes.TrimRight(sb.Label[:], "\x00"))
+}
+
+// GetUUID returns the UUID of the header.
+func (sb *SuperBlock) GetUUID() string {
+	if sb.Version != 2 {
+		return ""
+	}
+
+	return string(bytes.TrimRight(sb.UUID[:], "\x00"))
+}
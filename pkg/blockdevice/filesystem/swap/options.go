@@ -0,0 +1,43 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package swap
+
+// Options is the functional options struct.
+type Options struct {
+	Label    string
+	Priority int
+}
+
+// Option is the functional option func.
+type Option func(*Options)
+
+// WithLabel sets the label of the swap area.
+func WithLabel(o string) Option {
+	return func(args *Options) {
+		args.Label = o
+	}
+}
+
+// WithPriority sets the priority of the swap area, the areas with the higher
+// priority are used first. A negative priority leaves it to the kernel.
+func WithPriority(o int) Option {
+	return func(args *Options) {
+		args.Priority = o
+	}
+}
+
+// NewDefaultOptions initializes a Options struct with default values.
+func NewDefaultOptions(setters ...Option) *Options {
+	opts := &Options{
+		Label:    "",
+		Priority: -1,
+	}
+
+	for _, setter := range setters {
+		setter(opts)
+	}
+
+	return opts
+}
@@ -0,0 +1,43 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package swap
+
+import (
+	"bytes"
+)
+
+const (
+	// Magic is the signature of a version 1 swap area.
+	Magic = "SWAPSPACE2"
+	// Type is the type of the swap areas, as reported by blkid.
+	Type = "swap"
+)
+
+// SuperBlock represents the header of a swap area, with the signature at the
+// end of the first page. The header is read assuming 4K pages.
+type SuperBlock struct {
+	Version    [4]uint8
+	LastPage   [4]uint8
+	NrBadPages [4]uint8
+	UUID       [16]uint8
+	Label      [16]uint8
+	_          [3018]uint8
+	Magic      [10]uint8
+}
+
+// Is implements the SuperBlocker interface.
+func (sb *SuperBlock) Is() bool {
+	return bytes.Equal(sb.Magic[:], []byte(Magic))
+}
+
+// Offset implements the SuperBlocker interface.
+func (sb *SuperBlock) Offset() int64 {
+	return 0x400
+}
+
+// Type implements the SuperBlocker interface.
+func (sb *SuperBlock) Type() string {
+	return Type
+}
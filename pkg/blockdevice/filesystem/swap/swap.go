@@ -0,0 +1,111 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package swap creates and activates swap areas. The header is written
+// directly, since the rootfs doesn't ship mkswap.
+package swap
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"os"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// swapFlagPrefer is the flag of swapon(2) setting the priority.
+	swapFlagPrefer = 0x8000
+	// swapFlagPrioMask is the mask of the priority in the flags.
+	swapFlagPrioMask = 0x7fff
+	// minPages is the minimum number of pages in a swap area.
+	minPages = 10
+)
+
+// MakeSwap writes the header of a version 1 swap area to the block device or
+// the file. The whole size of the device or the file is used.
+func MakeSwap(path string, setters ...Option) (err error) {
+	opts := NewDefaultOptions(setters...)
+
+	var f *os.File
+	if f, err = os.OpenFile(path, os.O_RDWR, 0); err != nil {
+		return err
+	}
+	// nolint: errcheck
+	defer f.Close()
+
+	var size int64
+	if size, err = f.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+
+	pagesize := os.Getpagesize()
+	pages := size / int64(pagesize)
+	if pages < minPages {
+		return errors.Errorf("%s is too small for a swap area: %d bytes", path, size)
+	}
+
+	page := make([]byte, pagesize)
+
+	binary.LittleEndian.PutUint32(page[0x400:], 1)
+	binary.LittleEndian.PutUint32(page[0x404:], uint32(pages-1))
+
+	uuid := page[0x40c:0x41c]
+	if _, err = rand.Read(uuid); err != nil {
+		return err
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	if len(opts.Label) > 16 {
+		return errors.Errorf("label %q is longer than 16 bytes", opts.Label)
+	}
+	copy(page[0x41c:0x42c], opts.Label)
+
+	copy(page[pagesize-len(Magic):], Magic)
+
+	if _, err = f.WriteAt(page, 0); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// Swapon activates the swap area.
+func Swapon(path string, setters ...Option) error {
+	opts := NewDefaultOptions(setters...)
+
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	var flags uintptr
+	if opts.Priority >= 0 {
+		flags = swapFlagPrefer | uintptr(opts.Priority&swapFlagPrioMask)
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_SWAPON, uintptr(unsafe.Pointer(p)), flags, 0); errno != 0 {
+		return errors.Wrapf(errno, "swapon %s", path)
+	}
+
+	return nil
+}
+
+// Swapoff deactivates the swap area.
+func Swapoff(path string) error {
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_SWAPOFF, uintptr(unsafe.Pointer(p)), 0, 0); errno != 0 {
+		return errors.Wrapf(errno, "swapoff %s", path)
+	}
+
+	return nil
+}
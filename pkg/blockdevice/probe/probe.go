@@ -7,19 +7,22 @@ package probe
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/talos-systems/talos/pkg/blockdevice"
 	"github.com/talos-systems/talos/pkg/blockdevice/filesystem"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/btrfs"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/ext4"
 	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/iso9660"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/luks"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/swap"
 	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/vfat"
 	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/xfs"
+	"github.com/talos-systems/talos/pkg/blockdevice/util"
 )
 
 // ProbedBlockDevice represents a probed block device.
@@ -60,9 +63,13 @@ func FileSystem(path string) (sb filesystem.SuperBlocker, err error) {
 	defer f.Close()
 
 	superblocks := []filesystem.SuperBlocker{
+		&luks.SuperBlock{},
+		&swap.SuperBlock{},
 		&iso9660.SuperBlock{},
 		&vfat.SuperBlock{},
 		&xfs.SuperBlock{},
+		&ext4.SuperBlock{},
+		&btrfs.SuperBlock{},
 	}
 
 	for _, sb := range superblocks {
@@ -72,6 +79,11 @@ func FileSystem(path string) (sb filesystem.SuperBlocker, err error) {
 
 		err = binary.Read(f, binary.BigEndian, sb)
 		if err != nil {
+			// The device is too small to hold this super block.
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				continue
+			}
+
 			return nil, err
 		}
 		if sb.Is() {
@@ -128,11 +140,27 @@ func probe(devpath string) (devpaths []string) {
 	// nolint: errcheck
 	defer bd.Close()
 
-	// A partition table was not found, and we have already checked for
-	// a file system on the block device. Let's check if the block device
-	// has partitions.
+	// Let's check if the block device has partitions. If a partition table
+	// was not found, or it can't be read, the file system might still be on
+	// the block device itself.
 	pt, err := bd.PartitionTable(true)
 	if err != nil {
+		// nolint: errcheck
+		if sb, _ := FileSystem(devpath); sb != nil {
+			devpaths = append(devpaths, devpath)
+		}
+
+		return devpaths
+	}
+
+	// A partition table without partitions doesn't rule out a file system on
+	// the block device itself.
+	if len(pt.Partitions()) == 0 {
+		// nolint: errcheck
+		if sb, _ := FileSystem(devpath); sb != nil {
+			devpaths = append(devpaths, devpath)
+		}
+
 		return devpaths
 	}
 
@@ -140,13 +168,8 @@ func probe(devpath string) (devpaths []string) {
 	name := filepath.Base(devpath)
 	for _, p := range pt.Partitions() {
 		var partpath string
-		switch {
-		case strings.HasPrefix(name, "nvme"):
-			fallthrough
-		case strings.HasPrefix(name, "loop"):
-			partpath = fmt.Sprintf("/dev/%sp%d", name, p.No())
-		default:
-			partpath = fmt.Sprintf("/dev/%s%d", name, p.No())
+		if partpath, err = util.PartPath("/dev/"+name, int(p.No())); err != nil {
+			continue
 		}
 		// nolint: errcheck
 		if sb, _ := FileSystem(partpath); sb != nil {
@@ -184,24 +207,35 @@ func probeFilesystem(devpath string) (probed []*ProbedBlockDevice, err error) {
 
 func filterByLabel(probed []*ProbedBlockDevice, value string) (probe *ProbedBlockDevice, err error) {
 	for _, probe = range probed {
-		switch sb := probe.SuperBlock.(type) {
-		case *iso9660.SuperBlock:
-			trimmed := bytes.Trim(sb.VolumeID[:], " \x00")
-			if bytes.Equal(trimmed, []byte(value)) {
-				return probe, nil
-			}
-		case *vfat.SuperBlock:
-			trimmed := bytes.Trim(sb.Label[:], " \x00")
-			if bytes.Equal(trimmed, []byte(value)) {
-				return probe, nil
-			}
-		case *xfs.SuperBlock:
-			trimmed := bytes.Trim(sb.Fname[:], " \x00")
-			if bytes.Equal(trimmed, []byte(value)) {
-				return probe, nil
-			}
+		if probe.SuperBlock != nil && Label(probe.SuperBlock) == value {
+			return probe, nil
 		}
 	}
 
 	return nil, errors.Errorf("no device found with label %s", value)
 }
+
+// Label returns the label of the file system, or of the LUKS2 header of an
+// encrypted partition.
+func Label(sb filesystem.SuperBlocker) string {
+	var label []byte
+
+	switch sb := sb.(type) {
+	case *luks.SuperBlock:
+		return sb.GetLabel()
+	case *swap.SuperBlock:
+		label = sb.Label[:]
+	case *iso9660.SuperBlock:
+		label = sb.VolumeID[:]
+	case *vfat.SuperBlock:
+		label = sb.Label[:]
+	case *xfs.SuperBlock:
+		label = sb.Fname[:]
+	case *ext4.SuperBlock:
+		label = sb.VolumeName[:]
+	case *btrfs.SuperBlock:
+		label = sb.Label[:]
+	}
+
+	return string(bytes.Trim(label, " \x00"))
+}
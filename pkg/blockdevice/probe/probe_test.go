@@ -4,11 +4,153 @@
 
 package probe_test
 
-import "testing"
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
 
-func TestEmpty(t *testing.T) {
-	// added for accurate coverage estimation
-	//
-	// please remove it once any unit-test is added
-	// for this package
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/btrfs"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/ext4"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/luks"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/swap"
+	"github.com/talos-systems/talos/pkg/blockdevice/filesystem/vfat"
+	"github.com/talos-systems/talos/pkg/blockdevice/probe"
+)
+
+type ProbeSuite struct {
+	suite.Suite
+}
+
+// image writes a file system image of the size, with the data at the
+// offsets.
+func (suite *ProbeSuite) image(size int, data map[int][]byte) string {
+	b := make([]byte, size)
+	for off, d := range data {
+		copy(b[off:], d)
+	}
+
+	f, err := ioutil.TempFile("", "probe")
+	suite.Require().NoError(err)
+
+	_, err = f.Write(b)
+	suite.Require().NoError(err)
+	suite.Require().NoError(f.Close())
+
+	return f.Name()
+}
+
+func (suite *ProbeSuite) TestExt4() {
+	uuid := bytes.Repeat([]byte{0xab}, 16)
+
+	path := suite.image(64*1024, map[int][]byte{
+		0x400 + 0x38: {0x53, 0xef},
+		0x400 + 0x68: uuid,
+		0x400 + 0x78: []byte("DATA"),
+	})
+	// nolint: errcheck
+	defer os.Remove(path)
+
+	sb, err := probe.FileSystem(path)
+	suite.Require().NoError(err)
+	suite.Require().IsType(&ext4.SuperBlock{}, sb)
+	suite.Assert().Equal("ext4", sb.Type())
+	suite.Assert().Equal(uuid, sb.(*ext4.SuperBlock).UUID[:])
+	suite.Assert().Equal("DATA", string(bytes.Trim(sb.(*ext4.SuperBlock).VolumeName[:], "\x00")))
+}
+
+func (suite *ProbeSuite) TestBtrfs() {
+	uuid := bytes.Repeat([]byte{0xcd}, 16)
+
+	path := suite.image(128*1024, map[int][]byte{
+		0x10000 + 0x20:  uuid,
+		0x10000 + 0x40:  []byte(btrfs.Magic),
+		0x10000 + 0x12b: []byte("DATA"),
+	})
+	// nolint: errcheck
+	defer os.Remove(path)
+
+	sb, err := probe.FileSystem(path)
+	suite.Require().NoError(err)
+	suite.Require().IsType(&btrfs.SuperBlock{}, sb)
+	suite.Assert().Equal("btrfs", sb.Type())
+	suite.Assert().Equal(uuid, sb.(*btrfs.SuperBlock).FSID[:])
+	suite.Assert().Equal("DATA", string(bytes.Trim(sb.(*btrfs.SuperBlock).Label[:], "\x00")))
+}
+
+func (suite *ProbeSuite) TestLUKS() {
+	path := suite.image(64*1024, map[int][]byte{
+		0x0:  []byte(luks.Magic),
+		0x6:  {0x00, 0x02},
+		0x18: []byte("EPHEMERAL"),
+		0xa8: []byte("4f2a5e1c-2d3b-4c8e-9a7f-1b2c3d4e5f60"),
+	})
+	// nolint: errcheck
+	defer os.Remove(path)
+
+	sb, err := probe.FileSystem(path)
+	suite.Require().NoError(err)
+	suite.Require().IsType(&luks.SuperBlock{}, sb)
+	suite.Assert().Equal("crypto_LUKS", sb.Type())
+	suite.Assert().Equal("EPHEMERAL", probe.Label(sb))
+	suite.Assert().Equal("4f2a5e1c-2d3b-4c8e-9a7f-1b2c3d4e5f60", sb.(*luks.SuperBlock).GetUUID())
+}
+
+func (suite *ProbeSuite) TestSwap() {
+	path := suite.image(64*1024, nil)
+	// nolint: errcheck
+	defer os.Remove(path)
+
+	suite.Require().NoError(swap.MakeSwap(path, swap.WithLabel("SWAP")))
+
+	sb, err := probe.FileSystem(path)
+	suite.Require().NoError(err)
+	suite.Require().IsType(&swap.SuperBlock{}, sb)
+	suite.Assert().Equal("swap", sb.Type())
+	suite.Assert().Equal("SWAP", probe.Label(sb))
+	suite.Assert().Equal([]byte{1, 0, 0, 0}, sb.(*swap.SuperBlock).Version[:])
+	suite.Assert().Equal([]byte{15, 0, 0, 0}, sb.(*swap.SuperBlock).LastPage[:])
+}
+
+// TestWholeDiskVFAT makes sure a file system written to the whole disk, with
+// the boot signature of the FAT boot sector, isn't taken for a partition
+// table.
+func (suite *ProbeSuite) TestWholeDiskVFAT() {
+	path := suite.image(128*1024, map[int][]byte{
+		0x0:   {0xeb, 0x58, 0x90},
+		0x3:   []byte("mkfs.fat"),
+		0xb:   {0x00, 0x02},
+		0xd:   {0x08},
+		0x47:  []byte("ESP        "),
+		0x52:  []byte("FAT32   "),
+		0x1fe: {0x55, 0xaa},
+	})
+	// nolint: errcheck
+	defer os.Remove(path)
+
+	dev, err := probe.DevForFileSystemLabel(path, "ESP")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(path, dev.Path)
+	suite.Require().IsType(&vfat.SuperBlock{}, dev.SuperBlock)
+
+	_, err = dev.PartitionTable(false)
+	suite.Assert().Error(err)
+}
+
+// TestUnknown makes sure a device too small for some of the super blocks is
+// reported as having no file system.
+func (suite *ProbeSuite) TestUnknown() {
+	path := suite.image(4096, nil)
+	// nolint: errcheck
+	defer os.Remove(path)
+
+	sb, err := probe.FileSystem(path)
+	suite.Require().NoError(err)
+	suite.Assert().Nil(sb)
+}
+
+func TestProbeSuite(t *testing.T) {
+	suite.Run(t, new(ProbeSuite))
 }
@@ -0,0 +1,124 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package smart
+
+import (
+	"os"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	sgIO         = 0x2285
+	sgDxferNone  = -1
+	sgInterface  = 'S'
+	sgTimeoutMs  = 5000
+	senseMaxSize = 32
+
+	// ataPassThrough16 is the SCSI command wrapping the ATA commands.
+	ataPassThrough16 = 0x85
+	ataSMART         = 0xb0
+	ataSMARTStatus   = 0xda
+)
+
+// The LBA mid and high registers returned by SMART RETURN STATUS.
+const (
+	ataSMARTPassedMid = 0x4f
+	ataSMARTPassedHi  = 0xc2
+	ataSMARTFailedMid = 0xf4
+	ataSMARTFailedHi  = 0x2c
+)
+
+// sgIOHdr is struct sg_io_hdr of scsi/sg.h.
+type sgIOHdr struct {
+	interfaceID    int32
+	dxferDirection int32
+	cmdLen         uint8
+	mxSbLen        uint8
+	iovecCount     uint16
+	dxferLen       uint32
+	dxferp         unsafe.Pointer
+	cmdp           unsafe.Pointer
+	sbp            unsafe.Pointer
+	timeout        uint32
+	flags          uint32
+	packID         int32
+	usrPtr         unsafe.Pointer
+	status         uint8
+	maskedStatus   uint8
+	msgStatus      uint8
+	sbLenWr        uint8
+	hostStatus     uint16
+	driverStatus   uint16
+	resid          int32
+	duration       uint32
+	info           uint32
+}
+
+// readATA sends SMART RETURN STATUS through the SCSI layer, which is how the
+// kernel exposes the SATA disks.
+func readATA(path string) (*Health, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY, os.ModeDevice)
+	if err != nil {
+		return nil, err
+	}
+	// nolint: errcheck
+	defer f.Close()
+
+	cdb := [16]byte{
+		0:  ataPassThrough16,
+		1:  3 << 1, // non-data protocol
+		2:  1 << 5, // return the registers in the sense data
+		4:  ataSMARTStatus,
+		10: ataSMARTPassedMid,
+		12: ataSMARTPassedHi,
+		14: ataSMART,
+	}
+
+	sense := make([]byte, senseMaxSize)
+
+	hdr := sgIOHdr{
+		interfaceID:    sgInterface,
+		dxferDirection: sgDxferNone,
+		cmdLen:         uint8(len(cdb)),
+		mxSbLen:        senseMaxSize,
+		cmdp:           unsafe.Pointer(&cdb[0]),
+		sbp:            unsafe.Pointer(&sense[0]),
+		timeout:        sgTimeoutMs,
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), sgIO, uintptr(unsafe.Pointer(&hdr))); errno != 0 {
+		return nil, errors.Wrap(errno, "failed to read the SMART status")
+	}
+
+	return parseATASense(sense[:hdr.sbLenWr])
+}
+
+// parseATASense reads the registers returned by SMART RETURN STATUS from the
+// descriptor or the fixed format sense data.
+func parseATASense(sense []byte) (*Health, error) {
+	var mid, hi byte
+
+	switch {
+	case len(sense) >= 22 && sense[0]&0x7f == 0x72 && sense[8] == 0x09:
+		// The ATA Status Return descriptor.
+		mid, hi = sense[8+9], sense[8+11]
+	case len(sense) >= 12 && sense[0]&0x7f == 0x70:
+		mid, hi = sense[10], sense[11]
+	default:
+		return nil, ErrUnsupported
+	}
+
+	switch {
+	case mid == ataSMARTPassedMid && hi == ataSMARTPassedHi:
+		return &Health{Passed: true}, nil
+	case mid == ataSMARTFailedMid && hi == ataSMARTFailedHi:
+		return &Health{Warnings: []string{"SMART attributes exceed the thresholds"}}, nil
+	default:
+		return nil, ErrUnsupported
+	}
+}
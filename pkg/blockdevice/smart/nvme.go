@@ -0,0 +1,108 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package smart
+
+import (
+	"encoding/binary"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// nvmeIoctlAdminCmd is _IOWR('N', 0x41, struct nvme_admin_cmd).
+	nvmeIoctlAdminCmd = 0xc0484e41
+
+	nvmeAdminGetLogPage = 0x02
+	nvmeLogSMART        = 0x02
+	nvmeLogSMARTSize    = 512
+	nvmeNSIDAll         = 0xffffffff
+)
+
+// Critical warning bits of the SMART/Health Information log.
+var nvmeCriticalWarnings = []string{
+	"available spare is below the threshold",
+	"temperature is outside of the thresholds",
+	"reliability is degraded due to media or internal errors",
+	"media is in read only mode",
+	"volatile memory backup device has failed",
+	"persistent memory region is read only or unreliable",
+}
+
+// nvmeAdminCmd is struct nvme_admin_cmd of linux/nvme_ioctl.h.
+type nvmeAdminCmd struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMs   uint32
+	result      uint32
+}
+
+func readNVMe(path string) (*Health, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY, os.ModeDevice)
+	if err != nil {
+		return nil, err
+	}
+	// nolint: errcheck
+	defer f.Close()
+
+	log := make([]byte, nvmeLogSMARTSize)
+
+	cmd := nvmeAdminCmd{
+		opcode:  nvmeAdminGetLogPage,
+		nsid:    nvmeNSIDAll,
+		addr:    uint64(uintptr(unsafe.Pointer(&log[0]))),
+		dataLen: nvmeLogSMARTSize,
+		// The number of dwords to read, zero based, and the log identifier.
+		cdw10: (nvmeLogSMARTSize/4-1)<<16 | nvmeLogSMART,
+	}
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), nvmeIoctlAdminCmd, uintptr(unsafe.Pointer(&cmd)))
+	runtime.KeepAlive(log)
+
+	if errno != 0 {
+		return nil, errors.Wrap(errno, "failed to read the SMART log")
+	}
+
+	return parseNVMeLog(log), nil
+}
+
+// parseNVMeLog parses the SMART/Health Information log page.
+func parseNVMeLog(log []byte) *Health {
+	h := &Health{
+		Temperature:             int(binary.LittleEndian.Uint16(log[1:3])) - 273,
+		AvailableSpare:          int(log[3]),
+		AvailableSpareThreshold: int(log[4]),
+		PercentageUsed:          int(log[5]),
+		// The counter is 128 bits wide, the high half is ignored.
+		MediaErrors: binary.LittleEndian.Uint64(log[160:168]),
+	}
+
+	for bit, warning := range nvmeCriticalWarnings {
+		if log[0]&(1<<uint(bit)) != 0 {
+			h.Warnings = append(h.Warnings, warning)
+		}
+	}
+
+	h.Passed = len(h.Warnings) == 0
+
+	return h
+}
@@ -0,0 +1,53 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package smart reads the health that the disks report about themselves: the
+// SMART/Health Information log of the NVMe disks, and the SMART status of the
+// ATA disks.
+package smart
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/talos-systems/talos/pkg/blockdevice/discovery"
+)
+
+// ErrUnsupported is returned for the disks which don't report their health,
+// e.g. the virtio disks.
+var ErrUnsupported = errors.New("disk doesn't report its health")
+
+// Health represents the health reported by a disk. The fields which the disk
+// doesn't report are left zero.
+type Health struct {
+	// Passed is false if the disk reports that it is failing, or that its
+	// reliability is degraded.
+	Passed bool
+	// Warnings describes why the disk didn't pass.
+	Warnings []string
+	// Temperature is in degrees Celsius.
+	Temperature int
+	// AvailableSpare is the percentage of the spare capacity left.
+	AvailableSpare int
+	// AvailableSpareThreshold is the percentage of the spare capacity under
+	// which the disk warns.
+	AvailableSpareThreshold int
+	// PercentageUsed is the estimate of the endurance used, it might exceed
+	// 100.
+	PercentageUsed int
+	// MediaErrors is the number of unrecovered data integrity errors.
+	MediaErrors uint64
+}
+
+// Read reads the health of the disk. The bus is one of the buses of the
+// discovery package.
+func Read(path, bus string) (*Health, error) {
+	switch bus {
+	case discovery.BusNVMe:
+		return readNVMe(path)
+	case discovery.BusSATA, discovery.BusSCSI:
+		return readATA(path)
+	default:
+		return nil, ErrUnsupported
+	}
+}
@@ -0,0 +1,71 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package smart
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SMARTSuite struct {
+	suite.Suite
+}
+
+func (suite *SMARTSuite) TestParseNVMeLog() {
+	log := make([]byte, nvmeLogSMARTSize)
+	binary.LittleEndian.PutUint16(log[1:3], 273+41)
+	log[3], log[4], log[5] = 100, 10, 3
+	binary.LittleEndian.PutUint64(log[160:168], 2)
+
+	h := parseNVMeLog(log)
+	suite.Assert().Equal(&Health{
+		Passed:                  true,
+		Temperature:             41,
+		AvailableSpare:          100,
+		AvailableSpareThreshold: 10,
+		PercentageUsed:          3,
+		MediaErrors:             2,
+	}, h)
+
+	log[0] = 1<<0 | 1<<2
+	h = parseNVMeLog(log)
+	suite.Assert().False(h.Passed)
+	suite.Assert().Equal([]string{nvmeCriticalWarnings[0], nvmeCriticalWarnings[2]}, h.Warnings)
+}
+
+func (suite *SMARTSuite) TestParseATASense() {
+	descriptor := func(mid, hi byte) []byte {
+		sense := make([]byte, 22)
+		sense[0], sense[7] = 0x72, 14
+		sense[8], sense[9] = 0x09, 0x0c
+		sense[8+9], sense[8+11] = mid, hi
+
+		return sense
+	}
+
+	h, err := parseATASense(descriptor(ataSMARTPassedMid, ataSMARTPassedHi))
+	suite.Require().NoError(err)
+	suite.Assert().True(h.Passed)
+
+	h, err = parseATASense(descriptor(ataSMARTFailedMid, ataSMARTFailedHi))
+	suite.Require().NoError(err)
+	suite.Assert().False(h.Passed)
+	suite.Assert().NotEmpty(h.Warnings)
+
+	fixed := make([]byte, 18)
+	fixed[0], fixed[10], fixed[11] = 0x70, ataSMARTFailedMid, ataSMARTFailedHi
+	h, err = parseATASense(fixed)
+	suite.Require().NoError(err)
+	suite.Assert().False(h.Passed)
+
+	_, err = parseATASense(nil)
+	suite.Assert().Equal(ErrUnsupported, err)
+}
+
+func TestSMARTSuite(t *testing.T) {
+	suite.Run(t, new(SMARTSuite))
+}
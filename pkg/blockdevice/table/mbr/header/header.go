@@ -0,0 +1,100 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package header provides a library for working with MBR headers.
+package header
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/talos-systems/talos/pkg/serde"
+)
+
+const (
+	// PartitionEntriesOffset is the offset of the partition entries in the
+	// boot record.
+	PartitionEntriesOffset = 446
+	// PartitionEntries is the number of the partition entries in the boot
+	// record.
+	PartitionEntries = 4
+)
+
+// BootSignature terminates every master and extended boot record.
+var BootSignature = []byte{0x55, 0xaa}
+
+// Header represents the fields of a master boot record outside of the
+// partition entries. The bootstrap code is kept as is.
+type Header struct {
+	data []byte
+
+	DiskSignature uint32 // 440
+	Reserved      uint16 // 444
+}
+
+// NewHeader inializes and returns a master boot record header.
+func NewHeader(data []byte) *Header {
+	return &Header{
+		data: data,
+	}
+}
+
+// Bytes implements the table.Header interface.
+func (hdr *Header) Bytes() []byte {
+	return hdr.data
+}
+
+// Fields impements the serde.Serde interface.
+func (hdr *Header) Fields() []*serde.Field {
+	return []*serde.Field{
+		// 4 bytes Disk signature (little endian)
+		{
+			Offset: 440,
+			Length: 4,
+			SerializerFunc: func(offset, length uint32, new []byte, opts interface{}) ([]byte, error) {
+				data := make([]byte, length)
+				binary.LittleEndian.PutUint32(data, hdr.DiskSignature)
+
+				return data, nil
+			},
+			DeserializerFunc: func(contents []byte, opts interface{}) error {
+				hdr.DiskSignature = binary.LittleEndian.Uint32(contents)
+
+				return nil
+			},
+		},
+		// 2 bytes Copy-protected flag, usually zero
+		{
+			Offset: 444,
+			Length: 2,
+			SerializerFunc: func(offset, length uint32, new []byte, opts interface{}) ([]byte, error) {
+				data := make([]byte, length)
+				binary.LittleEndian.PutUint16(data, hdr.Reserved)
+
+				return data, nil
+			},
+			DeserializerFunc: func(contents []byte, opts interface{}) error {
+				hdr.Reserved = binary.LittleEndian.Uint16(contents)
+
+				return nil
+			},
+		},
+		// 2 bytes Boot signature (55h AAh)
+		{
+			Offset: 510,
+			Length: 2,
+			SerializerFunc: func(offset, length uint32, new []byte, opts interface{}) ([]byte, error) {
+				return BootSignature, nil
+			},
+			DeserializerFunc: func(contents []byte, opts interface{}) error {
+				if !bytes.Equal(contents, BootSignature) {
+					return fmt.Errorf("expected boot signature %x, got %x", BootSignature, contents)
+				}
+
+				return nil
+			},
+		},
+	}
+}
@@ -0,0 +1,755 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package mbr provides a library for working with MBR partitions.
+package mbr
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"os"
+	"sort"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"github.com/talos-systems/talos/pkg/blockdevice/lba"
+	"github.com/talos-systems/talos/pkg/blockdevice/table"
+	"github.com/talos-systems/talos/pkg/blockdevice/table/mbr/header"
+	"github.com/talos-systems/talos/pkg/blockdevice/table/mbr/partition"
+	"github.com/talos-systems/talos/pkg/serde"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// alignment is the partition alignment in sectors, 1 MiB with 512 byte
+	// sectors. The EBR of a logical partition takes the aligned sector before
+	// the partition.
+	alignment = 2048
+
+	// maxLogical protects against the loops in the EBR chain.
+	maxLogical = 128
+)
+
+// MBR represents the master boot record partition table.
+type MBR struct {
+	table      table.Table
+	header     *header.Header
+	partitions []table.Partition
+	lba        *lba.LogicalBlockAddresser
+
+	// sectors is the size of the device in sectors.
+	sectors uint64
+
+	devname string
+	f       *os.File
+}
+
+// NewMBR initializes and returns a master boot record partition table.
+func NewMBR(devname string, f *os.File, setters ...interface{}) *MBR {
+	opts := NewDefaultOptions(setters...)
+
+	lba := &lba.LogicalBlockAddresser{
+		PhysicalBlockSize: opts.PhysicalBlockSize,
+		LogicalBlockSize:  opts.LogicalBlockSize,
+	}
+
+	return &MBR{
+		lba:     lba,
+		devname: devname,
+		f:       f,
+	}
+}
+
+// Is reports whether the sector holds a master boot record: it has to be
+// signed, can't be the boot sector of a FAT or NTFS file system, and must
+// have at least one valid partition entry.
+func Is(sector []byte) bool {
+	if len(sector) < 512 || !signed(sector) || hasBPB(sector) {
+		return false
+	}
+
+	valid := false
+
+	for i := 0; i < header.PartitionEntries; i++ {
+		entry := sector[header.PartitionEntriesOffset+i*partition.EntrySize:]
+
+		if entry[0] != 0 && entry[0] != partition.StatusBootable {
+			return false
+		}
+
+		if entry[4] == partition.TypeEmpty {
+			continue
+		}
+
+		if binary.LittleEndian.Uint32(entry[8:]) == 0 || binary.LittleEndian.Uint32(entry[12:]) == 0 {
+			return false
+		}
+
+		valid = true
+	}
+
+	return valid
+}
+
+// hasBPB reports whether the sector starts with the BIOS parameter block of a
+// FAT or NTFS boot sector: a jump instruction followed by a sane sector and
+// cluster size.
+func hasBPB(sector []byte) bool {
+	if !(sector[0] == 0xeb && sector[2] == 0x90) && sector[0] != 0xe9 {
+		return false
+	}
+
+	switch binary.LittleEndian.Uint16(sector[11:]) {
+	case 512, 1024, 2048, 4096:
+	default:
+		return false
+	}
+
+	cluster := sector[13]
+
+	return cluster != 0 && cluster&(cluster-1) == 0
+}
+
+// Bytes returns the partition table as a byte slice.
+func (mbr *MBR) Bytes() []byte {
+	return mbr.table
+}
+
+// Type returns the partition type.
+func (mbr *MBR) Type() table.Type {
+	return table.MBR
+}
+
+// Header returns the header.
+func (mbr *MBR) Header() table.Header {
+	return mbr.header
+}
+
+// Partitions returns the partitions, the primary partitions first, followed
+// by the logical ones.
+func (mbr *MBR) Partitions() []table.Partition {
+	return mbr.partitions
+}
+
+// Read reads the partition table, including the chain of the extended boot
+// records.
+func (mbr *MBR) Read() error {
+	if err := mbr.readSize(); err != nil {
+		return err
+	}
+
+	data, err := mbr.readSector(0)
+	if err != nil {
+		return err
+	}
+
+	hdr := header.NewHeader(data)
+	if err = serde.De(hdr, data, 0, nil); err != nil {
+		return errors.Errorf("failed to deserialize the header: %v", err)
+	}
+
+	partitions := []table.Partition{}
+
+	var extended *partition.Partition
+
+	for i := 0; i < header.PartitionEntries; i++ {
+		var prt *partition.Partition
+		if prt, err = mbr.deserializePartition(data, i, 0); err != nil {
+			return err
+		}
+
+		if prt.IsEmpty() {
+			continue
+		}
+
+		prt.Number = int32(i) + 1
+
+		if prt.IsExtended() {
+			if extended != nil {
+				return errors.New("more than one extended partition")
+			}
+
+			extended = prt
+		}
+
+		partitions = append(partitions, prt)
+	}
+
+	if extended != nil {
+		var logical []table.Partition
+		if logical, err = mbr.readLogical(extended); err != nil {
+			return err
+		}
+
+		partitions = append(partitions, logical...)
+	}
+
+	mbr.table = data
+	mbr.header = hdr
+	mbr.partitions = partitions
+
+	return nil
+}
+
+func (mbr *MBR) readLogical(extended *partition.Partition) ([]table.Partition, error) {
+	partitions := []table.Partition{}
+
+	ebr := extended.FirstLBA
+
+	for i := 0; ; i++ {
+		if i == maxLogical {
+			return nil, errors.Errorf("more than %d extended boot records", maxLogical)
+		}
+
+		data, err := mbr.readSector(uint64(ebr))
+		if err != nil {
+			return nil, err
+		}
+
+		if !signed(data) {
+			return nil, errors.Errorf("extended boot record at LBA %d is missing the boot signature", ebr)
+		}
+
+		var prt *partition.Partition
+		if prt, err = mbr.deserializePartition(data, 0, ebr); err != nil {
+			return nil, err
+		}
+
+		// the first EBR is empty if there are no logical partitions
+		if !prt.IsEmpty() {
+			if prt.FirstLBA <= ebr || prt.LastLBA() > extended.LastLBA() {
+				return nil, errors.Errorf("logical partition %d is outside of the extended partition", len(partitions)+5)
+			}
+
+			prt.EBR = ebr
+			prt.Number = int32(len(partitions)) + 5
+			partitions = append(partitions, prt)
+		}
+
+		var next *partition.Partition
+		if next, err = mbr.deserializePartition(data, 1, 0); err != nil {
+			return nil, err
+		}
+
+		if next.IsEmpty() {
+			return partitions, nil
+		}
+
+		// the link to the next EBR is relative to the extended partition
+		if next.FirstLBA == 0 || next.FirstLBA+extended.FirstLBA <= ebr {
+			return nil, errors.Errorf("extended boot record at LBA %d links backwards", ebr)
+		}
+
+		ebr = extended.FirstLBA + next.FirstLBA
+	}
+}
+
+// Write writes the partition table to disk.
+func (mbr *MBR) Write() error {
+	data := make([]byte, mbr.lba.PhysicalBlockSize)
+	copy(data, mbr.table)
+
+	if err := serde.Ser(mbr.header, data, 0, nil); err != nil {
+		return errors.Errorf("failed to serialize the header: %v", err)
+	}
+
+	clearEntries(data)
+
+	var (
+		extended *partition.Partition
+		logical  []*partition.Partition
+	)
+
+	for _, p := range mbr.partitions {
+		prt, ok := p.(*partition.Partition)
+		if !ok {
+			return errors.Errorf("partition is not a master boot record partition")
+		}
+
+		switch {
+		case prt.IsLogical():
+			logical = append(logical, prt)
+		default:
+			if prt.IsExtended() {
+				extended = prt
+			}
+
+			if err := serializePartition(prt, data, int(prt.Number)-1, 0); err != nil {
+				return err
+			}
+		}
+	}
+
+	if extended == nil && len(logical) > 0 {
+		return errors.New("logical partitions require an extended partition")
+	}
+
+	if extended != nil {
+		if err := mbr.writeLogical(extended, logical); err != nil {
+			return errors.Errorf("failed to write extended boot records: %v", err)
+		}
+	}
+
+	if err := mbr.writeSector(0, data); err != nil {
+		return errors.Errorf("failed to write master boot record: %v", err)
+	}
+
+	return mbr.Read()
+}
+
+// writeLogical writes the chain of the extended boot records. The first EBR
+// is always at the start of the extended partition, it is left empty if
+// there are no logical partitions.
+func (mbr *MBR) writeLogical(extended *partition.Partition, logical []*partition.Partition) error {
+	if len(logical) == 0 {
+		data := make([]byte, mbr.lba.PhysicalBlockSize)
+		copy(data[510:], header.BootSignature)
+
+		return mbr.writeSector(uint64(extended.FirstLBA), data)
+	}
+
+	logical[0].EBR = extended.FirstLBA
+
+	for i, prt := range logical {
+		data := make([]byte, mbr.lba.PhysicalBlockSize)
+		copy(data[510:], header.BootSignature)
+
+		if err := serializePartition(prt, data, 0, prt.EBR); err != nil {
+			return err
+		}
+
+		if i+1 < len(logical) {
+			next := logical[i+1]
+			link := &partition.Partition{
+				Type:     partition.TypeExtended,
+				FirstLBA: next.EBR - extended.FirstLBA,
+				Sectors:  next.LastLBA() - next.EBR + 1,
+				IsNew:    true,
+			}
+
+			if err := serializePartition(link, data, 1, 0); err != nil {
+				return err
+			}
+		}
+
+		if err := mbr.writeSector(uint64(prt.EBR), data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// New creates a new partition table with no partitions. The bootstrap code
+// of the existing master boot record is kept. The table is written to disk
+// by Write.
+func (mbr *MBR) New() (table.PartitionTable, error) {
+	if err := mbr.readSize(); err != nil {
+		return nil, err
+	}
+
+	data, err := mbr.readSector(0)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, 4)
+	if _, err = rand.Read(signature); err != nil {
+		return nil, errors.Wrap(err, "failed to generate the disk signature")
+	}
+
+	mbr.table = data
+	mbr.header = header.NewHeader(data)
+	mbr.header.DiskSignature = binary.LittleEndian.Uint32(signature)
+	mbr.partitions = []table.Partition{}
+
+	return mbr, nil
+}
+
+// Repair updates the size of the device. The master boot record has no
+// backup, so there is nothing else to repair.
+func (mbr *MBR) Repair() error {
+	return mbr.readSize()
+}
+
+// Add adds a partition. The partition is added as a primary partition,
+// unless partition.WithLogical is specified.
+// nolint: gocyclo
+func (mbr *MBR) Add(size uint64, setters ...interface{}) (table.Partition, error) {
+	opts := partition.NewDefaultOptions(setters...)
+
+	blockSize := uint64(mbr.PhysicalBlockSize())
+	sectors := (size + blockSize - 1) / blockSize
+
+	if sectors == 0 {
+		return nil, errors.Errorf("requested partition size %d is too small", size)
+	}
+
+	prt := &partition.Partition{
+		IsNew: !opts.Test,
+		Type:  opts.Type,
+	}
+
+	if opts.Bootable {
+		prt.Status = partition.StatusBootable
+	}
+
+	var start, last uint64
+
+	extended := mbr.extended()
+
+	if opts.Logical {
+		if extended == nil {
+			return nil, errors.New("logical partitions require an extended partition")
+		}
+
+		if prt.IsExtended() {
+			return nil, errors.New("an extended partition can't be logical")
+		}
+
+		ebr := uint64(extended.FirstLBA)
+
+		logical := mbr.logical()
+		if len(logical) > 0 {
+			ebr = align(uint64(logical[len(logical)-1].LastLBA()) + 1)
+		}
+
+		start = ebr + alignment
+		last = uint64(extended.LastLBA())
+
+		prt.EBR = uint32(ebr)
+		prt.Number = int32(len(logical)) + 5
+	} else {
+		if prt.IsExtended() && extended != nil {
+			return nil, errors.New("only one extended partition is allowed")
+		}
+
+		number := mbr.freeSlot()
+		if number == 0 {
+			return nil, errors.Errorf("all %d primary partition entries are in use", header.PartitionEntries)
+		}
+
+		start = alignment
+		for _, p := range mbr.primary() {
+			if end := uint64(p.LastLBA()) + 1; end > start {
+				start = end
+			}
+		}
+
+		start = align(start)
+		last = mbr.sectors - 1
+
+		prt.Number = number
+	}
+
+	if last > math.MaxUint32 {
+		last = math.MaxUint32
+	}
+
+	if start > last || last-start+1 < sectors {
+		var available uint64
+		if start <= last {
+			available = (last - start + 1) * blockSize
+		}
+
+		return nil, errors.Errorf("requested partition size %d is too big, largest available is %d", size, available)
+	}
+
+	prt.FirstLBA = uint32(start)
+	prt.Sectors = uint32(sectors)
+
+	mbr.partitions = append(mbr.partitions, prt)
+	mbr.sort()
+
+	return prt, nil
+}
+
+// Resize grows the partition to the start of the next partition, or to the
+// end of the device. Logical partitions are grown up to the end of the
+// extended partition.
+func (mbr *MBR) Resize(p table.Partition) error {
+	prt, ok := p.(*partition.Partition)
+	if !ok {
+		return errors.Errorf("partition is not a master boot record partition")
+	}
+
+	if mbr.find(prt.Number) == nil {
+		return errors.Errorf("unknown partition %d", prt.Number)
+	}
+
+	var last uint64
+
+	if prt.IsLogical() {
+		last = uint64(mbr.extended().LastLBA())
+
+		for _, l := range mbr.logical() {
+			if l.EBR > prt.FirstLBA && uint64(l.EBR)-1 < last {
+				last = uint64(l.EBR) - 1
+			}
+		}
+	} else {
+		last = mbr.sectors - 1
+
+		for _, l := range mbr.primary() {
+			if l.FirstLBA > prt.FirstLBA && uint64(l.FirstLBA)-1 < last {
+				last = uint64(l.FirstLBA) - 1
+			}
+		}
+	}
+
+	if last > math.MaxUint32 {
+		last = math.MaxUint32
+	}
+
+	if last < uint64(prt.LastLBA()) {
+		return errors.Errorf("partition %d can't be shrunk", prt.Number)
+	}
+
+	prt.Sectors = uint32(last - uint64(prt.FirstLBA) + 1)
+	prt.IsResized = true
+
+	return nil
+}
+
+// Delete deletes a partition. The logical partitions which follow the
+// deleted one are renumbered. The extended partition can only be deleted
+// once it holds no logical partitions.
+func (mbr *MBR) Delete(p table.Partition) error {
+	prt, ok := p.(*partition.Partition)
+	if !ok {
+		return errors.Errorf("partition is not a master boot record partition")
+	}
+
+	if prt.IsExtended() && len(mbr.logical()) > 0 {
+		return errors.New("the extended partition holds logical partitions")
+	}
+
+	partitions := make([]table.Partition, 0, len(mbr.partitions))
+	found := false
+
+	for _, p := range mbr.partitions {
+		current := p.(*partition.Partition)
+
+		switch {
+		case current.Number == prt.Number:
+			found = true
+			continue
+		case found && prt.IsLogical() && current.IsLogical():
+			current.Number--
+		}
+
+		partitions = append(partitions, current)
+	}
+
+	if !found {
+		return errors.Errorf("unknown partition %d", prt.Number)
+	}
+
+	mbr.partitions = partitions
+
+	return nil
+}
+
+// PhysicalBlockSize returns the physical block size.
+func (mbr *MBR) PhysicalBlockSize() int {
+	return mbr.lba.PhysicalBlockSize
+}
+
+func (mbr *MBR) primary() (partitions []*partition.Partition) {
+	for _, p := range mbr.partitions {
+		if prt := p.(*partition.Partition); !prt.IsLogical() {
+			partitions = append(partitions, prt)
+		}
+	}
+
+	return partitions
+}
+
+func (mbr *MBR) logical() (partitions []*partition.Partition) {
+	for _, p := range mbr.partitions {
+		if prt := p.(*partition.Partition); prt.IsLogical() {
+			partitions = append(partitions, prt)
+		}
+	}
+
+	return partitions
+}
+
+func (mbr *MBR) extended() *partition.Partition {
+	for _, prt := range mbr.primary() {
+		if prt.IsExtended() {
+			return prt
+		}
+	}
+
+	return nil
+}
+
+func (mbr *MBR) find(number int32) *partition.Partition {
+	for _, p := range mbr.partitions {
+		if prt := p.(*partition.Partition); prt.Number == number {
+			return prt
+		}
+	}
+
+	return nil
+}
+
+// freeSlot returns the number of the first unused primary partition entry,
+// or 0 if all of them are in use.
+func (mbr *MBR) freeSlot() int32 {
+	for number := int32(1); number <= header.PartitionEntries; number++ {
+		if mbr.find(number) == nil {
+			return number
+		}
+	}
+
+	return 0
+}
+
+func (mbr *MBR) sort() {
+	sort.SliceStable(mbr.partitions, func(i, j int) bool {
+		return mbr.partitions[i].No() < mbr.partitions[j].No()
+	})
+}
+
+func (mbr *MBR) readSize() error {
+	size, err := mbr.f.Seek(0, 2)
+	if err != nil {
+		return err
+	}
+
+	if _, err = mbr.f.Seek(0, 0); err != nil {
+		return err
+	}
+
+	mbr.sectors = uint64(size) / uint64(mbr.lba.PhysicalBlockSize)
+
+	return nil
+}
+
+func (mbr *MBR) readSector(sector uint64) ([]byte, error) {
+	data := mbr.lba.Make(1)
+
+	read, err := mbr.f.ReadAt(data, int64(sector)*int64(mbr.lba.PhysicalBlockSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if read != len(data) {
+		return nil, errors.Errorf("expected a read of %d bytes, got %d", len(data), read)
+	}
+
+	return data, nil
+}
+
+func (mbr *MBR) writeSector(sector uint64, data []byte) error {
+	written, err := mbr.f.WriteAt(data, int64(sector)*int64(mbr.lba.PhysicalBlockSize))
+	if err != nil {
+		return err
+	}
+
+	if written != len(data) {
+		return errors.Errorf("expected a write of %d bytes, got %d", len(data), written)
+	}
+
+	return nil
+}
+
+// deserializePartition reads the partition entry of the boot record at the
+// LBA base, the first LBA is converted to the absolute one.
+func (mbr *MBR) deserializePartition(data []byte, index int, base uint32) (*partition.Partition, error) {
+	offset := uint32(header.PartitionEntriesOffset + index*partition.EntrySize)
+
+	prt := partition.NewPartition(data[offset : offset+partition.EntrySize])
+	if err := serde.De(prt, data, offset, nil); err != nil {
+		return nil, errors.Errorf("failed to deserialize the partitions: %v", err)
+	}
+
+	if prt.IsEmpty() {
+		return prt, nil
+	}
+
+	if prt.Sectors == 0 {
+		return nil, errors.Errorf("partition entry %d is empty", index+1)
+	}
+
+	prt.FirstLBA += base
+
+	if uint64(prt.FirstLBA)+uint64(prt.Sectors) > mbr.sectors {
+		return nil, errors.Errorf("partition entry %d ends beyond the end of the device", index+1)
+	}
+
+	return prt, nil
+}
+
+// serializePartition writes the partition entry of the boot record at the
+// LBA base, the first LBA is converted to the relative one.
+func serializePartition(prt *partition.Partition, data []byte, index int, base uint32) error {
+	entry := *prt
+	entry.FirstLBA -= base
+
+	offset := uint32(header.PartitionEntriesOffset + index*partition.EntrySize)
+	if err := serde.Ser(&entry, data, offset, nil); err != nil {
+		return errors.Errorf("failed to serialize the partitions: %v", err)
+	}
+
+	return nil
+}
+
+func clearEntries(data []byte) {
+	entries := data[header.PartitionEntriesOffset : header.PartitionEntriesOffset+header.PartitionEntries*partition.EntrySize]
+	for i := range entries {
+		entries[i] = 0
+	}
+}
+
+func signed(data []byte) bool {
+	return data[510] == header.BootSignature[0] && data[511] == header.BootSignature[1]
+}
+
+func align(lba uint64) uint64 {
+	return (lba + alignment - 1) / alignment * alignment
+}
+
+// InformKernelOfAdd invokes the BLKPG_ADD_PARTITION ioctl.
+func (mbr *MBR) InformKernelOfAdd(partition table.Partition) error {
+	return inform(mbr.f.Fd(), partition, unix.BLKPG_ADD_PARTITION, int64(mbr.lba.PhysicalBlockSize))
+}
+
+// InformKernelOfResize invokes the BLKPG_RESIZE_PARTITION ioctl.
+func (mbr *MBR) InformKernelOfResize(partition table.Partition) error {
+	return inform(mbr.f.Fd(), partition, unix.BLKPG_RESIZE_PARTITION, int64(mbr.lba.PhysicalBlockSize))
+}
+
+// InformKernelOfDelete invokes the BLKPG_DEL_PARTITION ioctl.
+func (mbr *MBR) InformKernelOfDelete(partition table.Partition) error {
+	return inform(mbr.f.Fd(), partition, unix.BLKPG_DEL_PARTITION, int64(mbr.lba.PhysicalBlockSize))
+}
+
+func inform(fd uintptr, partition table.Partition, op int32, blocksize int64) error {
+	arg := &unix.BlkpgIoctlArg{
+		Op: op,
+		Data: (*byte)(unsafe.Pointer(&unix.BlkpgPartition{
+			Start:  partition.Start() * blocksize,
+			Length: partition.Length() * blocksize,
+			Pno:    partition.No(),
+		})),
+	}
+
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		fd,
+		unix.BLKPG,
+		uintptr(unsafe.Pointer(arg)),
+	)
+
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mbr_test
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/pkg/blockdevice/table"
+	"github.com/talos-systems/talos/pkg/blockdevice/table/mbr"
+	"github.com/talos-systems/talos/pkg/blockdevice/table/mbr/partition"
+)
+
+const (
+	sectorSize = 512
+	// diskSectors is a 64 MiB disk image.
+	diskSectors = 131072
+	mib         = 1024 * 1024
+)
+
+type MBRSuite struct {
+	suite.Suite
+
+	f *os.File
+}
+
+func (suite *MBRSuite) SetupTest() {
+	var err error
+
+	suite.f, err = ioutil.TempFile("", "mbr")
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.f.Truncate(diskSectors * sectorSize))
+}
+
+func (suite *MBRSuite) TearDownTest() {
+	suite.Require().NoError(suite.f.Close())
+	suite.Require().NoError(os.Remove(suite.f.Name()))
+}
+
+// reopen reads the partition table back from the disk image.
+func (suite *MBRSuite) reopen() *mbr.MBR {
+	pt := mbr.NewMBR(suite.f.Name(), suite.f)
+	suite.Require().NoError(pt.Read())
+
+	return pt
+}
+
+// writeEntry writes a partition entry the way partitioning tools do.
+func (suite *MBRSuite) writeEntry(sector uint64, index int, status, typ byte, first, sectors uint32) {
+	entry := make([]byte, 16)
+	entry[0] = status
+	entry[4] = typ
+	binary.LittleEndian.PutUint32(entry[8:], first)
+	binary.LittleEndian.PutUint32(entry[12:], sectors)
+
+	_, err := suite.f.WriteAt(entry, int64(sector)*sectorSize+446+int64(index)*16)
+	suite.Require().NoError(err)
+
+	_, err = suite.f.WriteAt([]byte{0x55, 0xaa}, int64(sector)*sectorSize+510)
+	suite.Require().NoError(err)
+}
+
+func (suite *MBRSuite) assertPartition(p table.Partition, number int32, first, sectors uint32, typ byte) {
+	prt, ok := p.(*partition.Partition)
+	suite.Require().True(ok)
+	suite.Assert().Equal(number, prt.Number)
+	suite.Assert().Equal(first, prt.FirstLBA, "partition %d", number)
+	suite.Assert().Equal(sectors, prt.Sectors, "partition %d", number)
+	suite.Assert().Equal(typ, prt.Type, "partition %d", number)
+}
+
+// TestReadFixture reads a table with a primary, an extended and two logical
+// partitions, laid out as fdisk does.
+func (suite *MBRSuite) TestReadFixture() {
+	suite.writeEntry(0, 0, partition.StatusBootable, partition.TypeLinux, 2048, 8192)
+	suite.writeEntry(0, 1, 0, partition.TypeExtended, 10240, 40960)
+	// the first EBR, the logical partition is relative to the EBR, the link
+	// to the next EBR is relative to the extended partition
+	suite.writeEntry(10240, 0, 0, partition.TypeLinux, 2048, 4096)
+	suite.writeEntry(10240, 1, 0, partition.TypeExtended, 6144, 6144)
+	suite.writeEntry(16384, 0, 0, 0x82, 2048, 4096)
+
+	pt := suite.reopen()
+	suite.Assert().Equal(table.MBR, pt.Type())
+
+	partitions := pt.Partitions()
+	suite.Require().Len(partitions, 4)
+
+	suite.assertPartition(partitions[0], 1, 2048, 8192, partition.TypeLinux)
+	suite.Assert().True(partitions[0].(*partition.Partition).Bootable())
+	suite.assertPartition(partitions[1], 2, 10240, 40960, partition.TypeExtended)
+	suite.assertPartition(partitions[2], 5, 12288, 4096, partition.TypeLinux)
+	suite.assertPartition(partitions[3], 6, 18432, 4096, 0x82)
+	suite.Assert().Equal(uint32(16384), partitions[3].(*partition.Partition).EBR)
+}
+
+func (suite *MBRSuite) TestRoundTrip() {
+	pt, err := mbr.NewMBR(suite.f.Name(), suite.f).New()
+	suite.Require().NoError(err)
+
+	_, err = pt.Add(8*mib, partition.WithBootable(true))
+	suite.Require().NoError(err)
+	_, err = pt.Add(32*mib, partition.WithPartitionType(partition.TypeExtendedLBA))
+	suite.Require().NoError(err)
+	_, err = pt.Add(4*mib, partition.WithLogical(true))
+	suite.Require().NoError(err)
+	_, err = pt.Add(4*mib, partition.WithLogical(true), partition.WithPartitionType(0x82))
+	suite.Require().NoError(err)
+	_, err = pt.Add(8*mib, partition.WithPartitionType(0x0c))
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(pt.Write())
+
+	signature := pt.Header().Bytes()[440:444]
+	suite.Assert().NotEqual([]byte{0, 0, 0, 0}, signature)
+
+	partitions := suite.reopen().Partitions()
+	suite.Require().Len(partitions, 5)
+
+	suite.assertPartition(partitions[0], 1, 2048, 16384, partition.TypeLinux)
+	suite.Assert().True(partitions[0].(*partition.Partition).Bootable())
+	suite.assertPartition(partitions[1], 2, 18432, 65536, partition.TypeExtendedLBA)
+	suite.assertPartition(partitions[2], 3, 83968, 16384, 0x0c)
+	suite.assertPartition(partitions[3], 5, 20480, 8192, partition.TypeLinux)
+	suite.assertPartition(partitions[4], 6, 30720, 8192, 0x82)
+}
+
+func (suite *MBRSuite) TestDeleteAndResize() {
+	pt, err := mbr.NewMBR(suite.f.Name(), suite.f).New()
+	suite.Require().NoError(err)
+
+	_, err = pt.Add(8*mib, partition.WithPartitionType(partition.TypeExtendedLBA))
+	suite.Require().NoError(err)
+	first, err := pt.Add(1*mib, partition.WithLogical(true))
+	suite.Require().NoError(err)
+	_, err = pt.Add(1*mib, partition.WithLogical(true))
+	suite.Require().NoError(err)
+	suite.Require().NoError(pt.Write())
+
+	pt = suite.reopen()
+	suite.Require().Error(pt.Delete(pt.Partitions()[0]), "the extended partition holds logical partitions")
+	suite.Require().NoError(pt.Delete(pt.Partitions()[1]))
+	suite.Require().NoError(pt.Write())
+
+	// the remaining logical partition is renumbered and its EBR is moved to
+	// the start of the extended partition
+	pt = suite.reopen()
+	partitions := pt.Partitions()
+	suite.Require().Len(partitions, 2)
+	suite.assertPartition(partitions[1], 5, first.(*partition.Partition).FirstLBA+4096, 2048, partition.TypeLinux)
+	suite.Assert().Equal(partitions[0].(*partition.Partition).FirstLBA, partitions[1].(*partition.Partition).EBR)
+
+	suite.Require().NoError(pt.Resize(partitions[1]))
+	suite.Require().NoError(pt.Resize(partitions[0]))
+	suite.Require().NoError(pt.Write())
+
+	partitions = suite.reopen().Partitions()
+	suite.assertPartition(partitions[0], 1, 2048, diskSectors-2048, partition.TypeExtendedLBA)
+	suite.Assert().Equal(uint32(2048+16384-1), partitions[1].(*partition.Partition).LastLBA())
+}
+
+func (suite *MBRSuite) TestAddErrors() {
+	pt, err := mbr.NewMBR(suite.f.Name(), suite.f).New()
+	suite.Require().NoError(err)
+
+	_, err = pt.Add(mib, partition.WithLogical(true))
+	suite.Assert().Error(err, "no extended partition")
+
+	_, err = pt.Add(128 * mib)
+	suite.Assert().Error(err, "too big")
+
+	for i := 0; i < 4; i++ {
+		_, err = pt.Add(mib)
+		suite.Require().NoError(err)
+	}
+
+	_, err = pt.Add(mib)
+	suite.Assert().Error(err, "no free entries")
+}
+
+// TestFileSystemBootSector makes sure a boot sector of a file system without
+// a partition table isn't mistaken for one.
+func (suite *MBRSuite) TestFileSystemBootSector() {
+	sector := make([]byte, sectorSize)
+	for i := range sector {
+		sector[i] = byte(i)
+	}
+	copy(sector[510:], []byte{0x55, 0xaa})
+
+	_, err := suite.f.WriteAt(sector, 0)
+	suite.Require().NoError(err)
+
+	suite.Assert().Error(mbr.NewMBR(suite.f.Name(), suite.f).Read())
+}
+
+// TestIs checks the detection of the master boot record.
+func (suite *MBRSuite) TestIs() {
+	sector := func(f func([]byte)) []byte {
+		b := make([]byte, sectorSize)
+		copy(b[510:], []byte{0x55, 0xaa})
+		f(b)
+
+		return b
+	}
+
+	entry := func(b []byte) {
+		b[446+4] = partition.TypeLinux
+		binary.LittleEndian.PutUint32(b[446+8:], 2048)
+		binary.LittleEndian.PutUint32(b[446+12:], 4096)
+	}
+
+	suite.Assert().True(mbr.Is(sector(entry)))
+	suite.Assert().False(mbr.Is(sector(func(b []byte) {})), "no entries")
+	suite.Assert().False(mbr.Is(sector(func(b []byte) {
+		entry(b)
+		b[446] = 0x12
+	})), "invalid status")
+	suite.Assert().False(mbr.Is(sector(func(b []byte) {
+		entry(b)
+		copy(b, []byte{0xeb, 0x58, 0x90})
+		copy(b[11:], []byte{0x00, 0x02, 0x08})
+	})), "FAT boot sector")
+
+	unsigned := sector(entry)
+	unsigned[511] = 0
+	suite.Assert().False(mbr.Is(unsigned), "no signature")
+}
+
+func TestMBRSuite(t *testing.T) {
+	suite.Run(t, new(MBRSuite))
+}
@@ -0,0 +1,44 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package mbr
+
+// Options is the functional options struct.
+type Options struct {
+	PhysicalBlockSize int
+	LogicalBlockSize  int
+}
+
+// Option is the functional option func.
+type Option func(*Options)
+
+// WithPhysicalBlockSize sets the physical block size.
+func WithPhysicalBlockSize(o int) Option {
+	return func(args *Options) {
+		args.PhysicalBlockSize = o
+	}
+}
+
+// WithLogicalBlockSize sets the logical block size.
+func WithLogicalBlockSize(o int) Option {
+	return func(args *Options) {
+		args.LogicalBlockSize = o
+	}
+}
+
+// NewDefaultOptions initializes a Options struct with default values.
+func NewDefaultOptions(setters ...interface{}) *Options {
+	opts := &Options{
+		PhysicalBlockSize: 512,
+		LogicalBlockSize:  512,
+	}
+
+	for _, setter := range setters {
+		if s, ok := setter.(Option); ok {
+			s(opts)
+		}
+	}
+
+	return opts
+}
@@ -0,0 +1,218 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package partition provides a library for working with MBR partitions.
+package partition
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/talos-systems/talos/pkg/serde"
+)
+
+const (
+	// EntrySize is the size of a partition entry in bytes.
+	EntrySize = 16
+
+	// StatusBootable marks the partition as active.
+	StatusBootable = 0x80
+
+	// TypeEmpty is the type of an unused partition entry.
+	TypeEmpty = 0x00
+	// TypeExtended is the CHS addressed extended partition type.
+	TypeExtended = 0x05
+	// TypeExtendedLBA is the LBA addressed extended partition type.
+	TypeExtendedLBA = 0x0f
+	// TypeLinuxExtended is the Linux extended partition type.
+	TypeLinuxExtended = 0x85
+	// TypeLinux is the Linux native partition type.
+	TypeLinux = 0x83
+)
+
+// lbaCHS is the CHS address written for the LBA addressed partitions.
+var lbaCHS = [3]byte{0xfe, 0xff, 0xff}
+
+// Partition represents a partition entry in a master boot record, or in an
+// extended boot record for the logical partitions.
+type Partition struct {
+	data []byte
+
+	Status   byte    // 0
+	FirstCHS [3]byte // 1
+	Type     byte    // 4
+	LastCHS  [3]byte // 5
+	FirstLBA uint32  // 8
+	Sectors  uint32  // 12
+
+	Number int32
+
+	// EBR is the LBA of the extended boot record of the logical partition.
+	// FirstLBA is always absolute, it is converted to and from the offset
+	// relative to the EBR when the table is written and read.
+	EBR uint32
+
+	IsNew     bool
+	IsResized bool
+}
+
+// NewPartition initializes and returns a new partition.
+func NewPartition(data []byte) *Partition {
+	return &Partition{
+		data: data,
+	}
+}
+
+// Bytes returns the partition as a byte slice.
+func (prt *Partition) Bytes() []byte {
+	return prt.data
+}
+
+// Start returns the partition's starting LBA.
+func (prt *Partition) Start() int64 {
+	return int64(prt.FirstLBA)
+}
+
+// Length returns the partition's length in LBA.
+func (prt *Partition) Length() int64 {
+	return int64(prt.Sectors)
+}
+
+// No returns the partition's number.
+func (prt *Partition) No() int32 {
+	return prt.Number
+}
+
+// LastLBA returns the partition's last LBA, inclusive.
+func (prt *Partition) LastLBA() uint32 {
+	return prt.FirstLBA + prt.Sectors - 1
+}
+
+// IsEmpty reports whether the partition entry is unused.
+func (prt *Partition) IsEmpty() bool {
+	return prt.Type == TypeEmpty
+}
+
+// IsExtended reports whether the partition is an extended partition.
+func (prt *Partition) IsExtended() bool {
+	switch prt.Type {
+	case TypeExtended, TypeExtendedLBA, TypeLinuxExtended:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsLogical reports whether the partition is a logical partition inside the
+// extended partition.
+func (prt *Partition) IsLogical() bool {
+	return prt.Number > 4
+}
+
+// Bootable reports whether the partition is marked as active.
+func (prt *Partition) Bootable() bool {
+	return prt.Status == StatusBootable
+}
+
+// Fields implements the serder.Serde interface.
+func (prt *Partition) Fields() []*serde.Field {
+	return []*serde.Field{
+		// 1 byte Status (0x80 active, 0x00 inactive)
+		{
+			Offset: 0,
+			Length: 1,
+			SerializerFunc: func(offset, length uint32, new []byte, opts interface{}) ([]byte, error) {
+				return []byte{prt.Status}, nil
+			},
+			DeserializerFunc: func(contents []byte, opts interface{}) error {
+				if contents[0] != 0x00 && contents[0] != StatusBootable {
+					return fmt.Errorf("invalid partition status %#x", contents[0])
+				}
+
+				prt.Status = contents[0]
+
+				return nil
+			},
+		},
+		// 3 bytes CHS address of the first sector
+		{
+			Offset: 1,
+			Length: 3,
+			SerializerFunc: func(offset, length uint32, new []byte, opts interface{}) ([]byte, error) {
+				if prt.IsNew {
+					return lbaCHS[:], nil
+				}
+
+				return prt.FirstCHS[:], nil
+			},
+			DeserializerFunc: func(contents []byte, opts interface{}) error {
+				copy(prt.FirstCHS[:], contents)
+
+				return nil
+			},
+		},
+		// 1 byte Partition type
+		{
+			Offset: 4,
+			Length: 1,
+			SerializerFunc: func(offset, length uint32, new []byte, opts interface{}) ([]byte, error) {
+				return []byte{prt.Type}, nil
+			},
+			DeserializerFunc: func(contents []byte, opts interface{}) error {
+				prt.Type = contents[0]
+
+				return nil
+			},
+		},
+		// 3 bytes CHS address of the last sector
+		{
+			Offset: 5,
+			Length: 3,
+			SerializerFunc: func(offset, length uint32, new []byte, opts interface{}) ([]byte, error) {
+				if prt.IsNew || prt.IsResized {
+					return lbaCHS[:], nil
+				}
+
+				return prt.LastCHS[:], nil
+			},
+			DeserializerFunc: func(contents []byte, opts interface{}) error {
+				copy(prt.LastCHS[:], contents)
+
+				return nil
+			},
+		},
+		// 4 bytes LBA of the first sector (little endian)
+		{
+			Offset: 8,
+			Length: 4,
+			SerializerFunc: func(offset, length uint32, new []byte, opts interface{}) ([]byte, error) {
+				data := make([]byte, length)
+				binary.LittleEndian.PutUint32(data, prt.FirstLBA)
+
+				return data, nil
+			},
+			DeserializerFunc: func(contents []byte, opts interface{}) error {
+				prt.FirstLBA = binary.LittleEndian.Uint32(contents)
+
+				return nil
+			},
+		},
+		// 4 bytes Number of sectors (little endian)
+		{
+			Offset: 12,
+			Length: 4,
+			SerializerFunc: func(offset, length uint32, new []byte, opts interface{}) ([]byte, error) {
+				data := make([]byte, length)
+				binary.LittleEndian.PutUint32(data, prt.Sectors)
+
+				return data, nil
+			},
+			DeserializerFunc: func(contents []byte, opts interface{}) error {
+				prt.Sectors = binary.LittleEndian.Uint32(contents)
+
+				return nil
+			},
+		},
+	}
+}
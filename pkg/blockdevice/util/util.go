@@ -5,6 +5,7 @@
 package util
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -18,6 +19,8 @@ func PartNo(partname string) (partno string, err error) {
 	case strings.HasPrefix(p, "nvme"):
 		fallthrough
 	case strings.HasPrefix(p, "loop"):
+		fallthrough
+	case strings.HasPrefix(p, "mmcblk"):
 		idx := strings.LastIndex(partname, "p")
 		return partname[idx+1:], nil
 	case strings.HasPrefix(p, "sd"):
@@ -45,7 +48,9 @@ func DevnameFromPartname(partname string) (devname string, err error) {
 	case strings.HasPrefix(p, "nvme"):
 		fallthrough
 	case strings.HasPrefix(p, "loop"):
-		return strings.TrimRight(p, "p"+partno), nil
+		fallthrough
+	case strings.HasPrefix(p, "mmcblk"):
+		return strings.TrimSuffix(p, "p"+partno), nil
 	case strings.HasPrefix(p, "sd"):
 		fallthrough
 	case strings.HasPrefix(p, "hd"):
@@ -53,8 +58,24 @@ func DevnameFromPartname(partname string) (devname string, err error) {
 	case strings.HasPrefix(p, "vd"):
 		fallthrough
 	case strings.HasPrefix(p, "xvd"):
-		return strings.TrimRight(partname, partno), nil
+		return strings.TrimSuffix(partname, partno), nil
 	default:
 		return "", errors.Errorf("could not determine dev name from partition name: %s", partname)
 	}
 }
+
+// PartPath returns the path of the partition of the device. The kernel
+// separates the partition number with a p if the name of the device ends
+// with a digit, e.g. /dev/nvme0n1p1, /dev/loop0p1 and /dev/mmcblk0p1, but
+// /dev/sda1.
+func PartPath(devpath string, partno int) (string, error) {
+	if devpath == "" {
+		return "", errors.Errorf("could not determine the path of partition %d without a device", partno)
+	}
+
+	if last := devpath[len(devpath)-1]; last >= '0' && last <= '9' {
+		return devpath + "p" + strconv.Itoa(partno), nil
+	}
+
+	return devpath + strconv.Itoa(partno), nil
+}
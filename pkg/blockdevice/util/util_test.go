@@ -2,7 +2,7 @@
  * License, v. 2.0. If a copy of the MPL was not distributed with this
  * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
 
-//nolint: scopelint
+// nolint: scopelint
 package util
 
 import (
@@ -109,6 +109,13 @@ func Test_PartNo(t *testing.T) {
 			},
 			want: "4",
 		},
+		{
+			name: "mmcblk0p2",
+			args: args{
+				devname: "mmcblk0p2",
+			},
+			want: "2",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -186,6 +193,30 @@ func Test_DevnameFromPartname(t *testing.T) {
 			},
 			want: "loop4",
 		},
+		{
+			name: "nvme0n1p1",
+			args: args{
+				devname: "nvme0n1p1",
+				partno:  "1",
+			},
+			want: "nvme0n1",
+		},
+		{
+			name: "sdb11",
+			args: args{
+				devname: "sdb11",
+				partno:  "11",
+			},
+			want: "sdb",
+		},
+		{
+			name: "mmcblk0p1",
+			args: args{
+				devname: "mmcblk0p1",
+				partno:  "1",
+			},
+			want: "mmcblk0",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -196,3 +227,76 @@ func Test_DevnameFromPartname(t *testing.T) {
 		})
 	}
 }
+
+func Test_PartPath(t *testing.T) {
+	type args struct {
+		devpath string
+		partno  int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "sda",
+			args: args{
+				devpath: "/dev/sda",
+				partno:  1,
+			},
+			want: "/dev/sda1",
+		},
+		{
+			name: "xvdb",
+			args: args{
+				devpath: "/dev/xvdb",
+				partno:  12,
+			},
+			want: "/dev/xvdb12",
+		},
+		{
+			name: "nvme0n1",
+			args: args{
+				devpath: "/dev/nvme0n1",
+				partno:  2,
+			},
+			want: "/dev/nvme0n1p2",
+		},
+		{
+			name: "loop0",
+			args: args{
+				devpath: "/dev/loop0",
+				partno:  1,
+			},
+			want: "/dev/loop0p1",
+		},
+		{
+			name: "mmcblk1",
+			args: args{
+				devpath: "/dev/mmcblk1",
+				partno:  3,
+			},
+			want: "/dev/mmcblk1p3",
+		},
+		{
+			name: "empty",
+			args: args{
+				devpath: "",
+				partno:  1,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PartPath(tt.args.devpath, tt.args.partno)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PartPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PartPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package blockdevice
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"github.com/talos-systems/talos/pkg/blockdevice/table"
+	gptpartition "github.com/talos-systems/talos/pkg/blockdevice/table/gpt/partition"
+	mbrpartition "github.com/talos-systems/talos/pkg/blockdevice/table/mbr/partition"
+
+	"golang.org/x/sys/unix"
+)
+
+// Wipe methods.
+const (
+	// WipeZero overwrites the whole device with zeroes.
+	WipeZero = "zero"
+	// WipeDiscard discards all of the blocks of the device. Most SSDs read
+	// the discarded blocks as zeroes, but the data might still be recovered
+	// from the flash.
+	WipeDiscard = "discard"
+	// WipeSecureDiscard discards all of the blocks of the device, and the
+	// copies of the blocks left by the flash translation layer. Not all
+	// devices support it.
+	WipeSecureDiscard = "secure-discard"
+	// WipePartial zeroes the first and the last MiB of the device and of each
+	// of its partitions, which removes the partition tables, and the
+	// signatures of the file systems and of the LUKS headers.
+	WipePartial = "partial"
+)
+
+// WipeMethods lists the supported wipe methods.
+var WipeMethods = []string{WipeZero, WipeDiscard, WipeSecureDiscard, WipePartial}
+
+// The ioctls are missing from x/sys/unix.
+const (
+	blkDiscard    = 0x1277
+	blkSecDiscard = 0x127d
+)
+
+const (
+	// wipeChunk is the size of the ranges wiped at once, the progress is
+	// reported after each range.
+	wipeChunk = 1 << 30
+	// wipeHeader is the size of the ranges zeroed by the partial wipe.
+	wipeHeader = 1 << 20
+)
+
+// WipeProgress is called as the wipe progresses, with the bytes wiped so
+// far, and the bytes to wipe in total.
+type WipeProgress func(done, total uint64)
+
+// Wipe wipes the block device with the method. The progress is reported
+// after each GiB, if set.
+func (bd *BlockDevice) Wipe(method string, progress WipeProgress) (err error) {
+	if progress == nil {
+		progress = func(done, total uint64) {}
+	}
+
+	var size uint64
+	if size, err = bd.wipeSize(); err != nil {
+		return errors.Wrap(err, "failed to get the size of the device")
+	}
+
+	switch method {
+	case WipeZero, WipeDiscard, WipeSecureDiscard:
+		if err = bd.wipeRange(method, 0, size, 0, size, progress); err != nil {
+			return err
+		}
+	case WipePartial:
+		ranges := bd.headerRanges(size)
+
+		var total, done uint64
+		for _, r := range ranges {
+			total += r[1]
+		}
+
+		for _, r := range ranges {
+			if err = bd.wipeRange(WipeZero, r[0], r[1], done, total, progress); err != nil {
+				return err
+			}
+
+			done += r[1]
+		}
+	default:
+		return errors.Errorf("unknown wipe method %q", method)
+	}
+
+	return bd.f.Sync()
+}
+
+// headerRanges returns the offsets and the lengths of the first and the last
+// MiB of the device, and of each partition of the partition table.
+func (bd *BlockDevice) headerRanges(size uint64) (ranges [][2]uint64) {
+	add := func(start, length uint64) {
+		if length <= 2*wipeHeader {
+			ranges = append(ranges, [2]uint64{start, length})
+			return
+		}
+
+		ranges = append(ranges, [2]uint64{start, wipeHeader}, [2]uint64{start + length - wipeHeader, wipeHeader})
+	}
+
+	add(0, size)
+
+	if bd.table == nil {
+		return ranges
+	}
+
+	// The partitions are wiped on a best effort basis, the partition table
+	// might be corrupted.
+	if err := bd.table.Read(); err != nil {
+		return ranges
+	}
+
+	sector := uint64(bd.sectorSize())
+
+	for _, p := range bd.table.Partitions() {
+		start, length := partitionRange(p, sector)
+		if length == 0 || start+length > size {
+			continue
+		}
+
+		add(start, length)
+	}
+
+	return ranges
+}
+
+// partitionRange returns the offset and the length of the partition in bytes.
+func partitionRange(p table.Partition, sector uint64) (start, length uint64) {
+	switch p := p.(type) {
+	case *gptpartition.Partition:
+		// The last LBA is inclusive.
+		return p.FirstLBA * sector, (p.LastLBA - p.FirstLBA + 1) * sector
+	case *mbrpartition.Partition:
+		return uint64(p.Start()) * sector, uint64(p.Length()) * sector
+	default:
+		return 0, 0
+	}
+}
+
+func (bd *BlockDevice) wipeRange(method string, offset, length, done, total uint64, progress WipeProgress) (err error) {
+	zeroes := make([]byte, wipeHeader)
+
+	for n := uint64(0); n < length; {
+		chunk := length - n
+		if chunk > wipeChunk {
+			chunk = wipeChunk
+		}
+
+		switch method {
+		case WipeDiscard:
+			err = bd.discard(blkDiscard, offset+n, chunk)
+		case WipeSecureDiscard:
+			err = bd.discard(blkSecDiscard, offset+n, chunk)
+		default:
+			err = bd.zero(zeroes, offset+n, chunk)
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "failed to wipe at offset %d", offset+n)
+		}
+
+		n += chunk
+		progress(done+n, total)
+	}
+
+	return nil
+}
+
+func (bd *BlockDevice) discard(ioctl uintptr, offset, length uint64) error {
+	r := [2]uint64{offset, length}
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, bd.f.Fd(), ioctl, uintptr(unsafe.Pointer(&r[0]))); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func (bd *BlockDevice) zero(zeroes []byte, offset, length uint64) error {
+	for n := uint64(0); n < length; {
+		b := zeroes
+		if length-n < uint64(len(b)) {
+			b = b[:length-n]
+		}
+
+		if _, err := bd.f.WriteAt(b, int64(offset+n)); err != nil {
+			return err
+		}
+
+		n += uint64(len(b))
+	}
+
+	return nil
+}
+
+// wipeSize returns the size of the device, or of the image file.
+func (bd *BlockDevice) wipeSize() (uint64, error) {
+	st, err := bd.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if st.Mode().IsRegular() {
+		return uint64(st.Size()), nil
+	}
+
+	return bd.Size()
+}
+
+// sectorSize returns the logical sector size, the unit of the partition
+// table, which is 512 bytes for the image files.
+func (bd *BlockDevice) sectorSize() int {
+	size, err := unix.IoctlGetInt(int(bd.f.Fd()), unix.BLKSSZGET)
+	if err != nil || size == 0 {
+		return 512
+	}
+
+	return size
+}
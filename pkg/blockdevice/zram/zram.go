@@ -0,0 +1,94 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package zram sets up the compressed RAM block devices.
+package zram
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Algorithms are the compression algorithms the kernel might support, the
+// algorithms available are listed in comp_algorithm of the device.
+var Algorithms = []string{"lzo", "lzo-rle", "lz4", "lz4hc", "zstd", "842", "deflate"}
+
+// Create sets up an unused zram device of the size, in bytes, compressed with
+// the algorithm, and returns the path of the device. The kernel default is
+// used if the algorithm is empty.
+func Create(size uint64, algorithm string, setters ...Option) (path string, err error) {
+	opts := NewDefaultOptions(setters...)
+
+	var name string
+	if name, err = unused(opts); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(opts.SysfsRoot, "block", name)
+
+	// The algorithm can't be changed once the size is set.
+	if algorithm != "" {
+		if err = write(filepath.Join(dir, "comp_algorithm"), algorithm); err != nil {
+			return "", errors.Wrapf(err, "failed to set the compression algorithm of %s", name)
+		}
+	}
+
+	if err = write(filepath.Join(dir, "disksize"), strconv.FormatUint(size, 10)); err != nil {
+		return "", errors.Wrapf(err, "failed to set the size of %s", name)
+	}
+
+	return filepath.Join(opts.DevRoot, name), nil
+}
+
+// unused returns the name of a zram device without a size, a device is added
+// if all of them are in use.
+func unused(opts *Options) (name string, err error) {
+	var matches []string
+	if matches, err = filepath.Glob(filepath.Join(opts.SysfsRoot, "block", "zram*")); err != nil {
+		return "", err
+	}
+
+	sort.Strings(matches)
+
+	for _, match := range matches {
+		var size string
+		if size, err = read(filepath.Join(match, "disksize")); err != nil {
+			return "", err
+		}
+
+		if size == "0" {
+			return filepath.Base(match), nil
+		}
+	}
+
+	var id string
+	if id, err = read(filepath.Join(opts.SysfsRoot, "class", "zram-control", "hot_add")); err != nil {
+		if os.IsNotExist(err) && len(matches) == 0 {
+			return "", errors.New("zram is not supported by the kernel")
+		}
+
+		return "", errors.Wrap(err, "failed to add a zram device")
+	}
+
+	return "zram" + id, nil
+}
+
+func read(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+func write(path, value string) error {
+	return ioutil.WriteFile(path, []byte(value), 0644)
+}
@@ -0,0 +1,75 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package zram_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/pkg/blockdevice/zram"
+)
+
+type ZramSuite struct {
+	suite.Suite
+
+	root string
+}
+
+// device creates the sysfs directory of the zram device, with the size.
+func (suite *ZramSuite) device(name, size string) {
+	dir := filepath.Join(suite.root, "block", name)
+
+	suite.Require().NoError(os.MkdirAll(dir, 0755))
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "disksize"), []byte(size+"\n"), 0644))
+	suite.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "comp_algorithm"), []byte("lzo [lzo-rle] lz4 zstd\n"), 0644))
+}
+
+func (suite *ZramSuite) SetupTest() {
+	var err error
+
+	suite.root, err = ioutil.TempDir("", "zram")
+	suite.Require().NoError(err)
+}
+
+func (suite *ZramSuite) TearDownTest() {
+	suite.Require().NoError(os.RemoveAll(suite.root))
+}
+
+func (suite *ZramSuite) TestCreate() {
+	suite.device("zram0", "1073741824")
+	suite.device("zram1", "0")
+
+	path, err := zram.Create(512<<20, "zstd", zram.WithSysfsRoot(suite.root), zram.WithDevRoot("/dev"))
+	suite.Require().NoError(err)
+	suite.Assert().Equal("/dev/zram1", path)
+
+	b, err := ioutil.ReadFile(filepath.Join(suite.root, "block/zram1/comp_algorithm"))
+	suite.Require().NoError(err)
+	suite.Assert().Equal("zstd", string(b))
+
+	b, err = ioutil.ReadFile(filepath.Join(suite.root, "block/zram1/disksize"))
+	suite.Require().NoError(err)
+	suite.Assert().Equal("536870912", string(b))
+}
+
+func (suite *ZramSuite) TestUnsupported() {
+	suite.Require().NoError(os.MkdirAll(filepath.Join(suite.root, "block"), 0755))
+
+	_, err := zram.Create(512<<20, "", zram.WithSysfsRoot(suite.root))
+	suite.Assert().EqualError(err, "zram is not supported by the kernel")
+
+	suite.device("zram0", "1073741824")
+
+	_, err = zram.Create(512<<20, "", zram.WithSysfsRoot(suite.root))
+	suite.Assert().Error(err)
+}
+
+func TestZramSuite(t *testing.T) {
+	suite.Run(t, new(ZramSuite))
+}
@@ -17,6 +17,18 @@ const (
 	// to the user data.
 	KernelParamUserData = "talos.userdata"
 
+	// KernelParamUserDataVerify is the kernel parameter name for specifying
+	// the trusted user data signing keys, it can be repeated.
+	KernelParamUserDataVerify = "talos.userdata.verify"
+
+	// KernelParamUserDataIdentity is the kernel parameter name for specifying
+	// the identity used to decrypt the user data, it can be repeated.
+	KernelParamUserDataIdentity = "talos.userdata.identity"
+
+	// KernelParamUserDataKeyring is the kernel parameter name for specifying
+	// the path to the user data keyring file.
+	KernelParamUserDataKeyring = "talos.userdata.keyring"
+
 	// KernelParamPlatform is the kernel parameter name for specifying the
 	// platform.
 	KernelParamPlatform = "talos.platform"
@@ -29,6 +41,11 @@ const (
 	// initial interface used to bootstrap the node
 	KernelParamDefaultInterface = "talos.interface"
 
+	// KernelParamMaintenance is the kernel parameter name for enabling the
+	// maintenance mode: on boot failure the node stays up instead of
+	// rebooting.
+	KernelParamMaintenance = "talos.maintenance"
+
 	// KernelCurrentRoot is the kernel parameter name for specifying the
 	// current root partition.
 	KernelCurrentRoot = "talos.root"
@@ -52,6 +69,18 @@ const (
 	// the data path.
 	EphemeralMountPoint = "/var"
 
+	// ExtraPartitionLabelPrefix is the prefix of the labels of the partitions
+	// of the extra devices, followed by the hash of the device and the mount
+	// point.
+	ExtraPartitionLabelPrefix = "EXTRA"
+
+	// SwapPartitionLabel is the label of the swap partition on the ephemeral
+	// device.
+	SwapPartitionLabel = "SWAP"
+
+	// SwapFile is the path of the swap file on the ephemeral partition.
+	SwapFile = "/var/swapfile"
+
 	// RootMountPoint is the label of the partition to use for mounting at
 	// the root path.
 	RootMountPoint = "/"
@@ -111,6 +140,18 @@ const (
 	// UserDataPath is the path to the downloaded user data.
 	UserDataPath = "/var/userdata.yaml"
 
+	// UserDataCachePath is the path to the copy of the user data fetched at
+	// boot, used when the user data sources are unreachable.
+	UserDataCachePath = "/var/userdata.cache.yaml"
+
+	// UserDataKeyringPath is the default path to the keyring with the user
+	// data verification keys and identities. The file is expected to be
+	// baked into the initramfs and readable by the owner only.
+	UserDataKeyringPath = "/etc/talos/userdata.keyring"
+
+	// BootReportPath is the path to the report of the last boot sequence.
+	BootReportPath = "/var/log/boot-report.json"
+
 	// UserDataCIData is the volume label for NoCloud cloud-init.
 	// See https://cloudinit.readthedocs.io/en/latest/topics/datasources/nocloud.html#datasource-nocloud.
 	UserDataCIData = "cidata"
@@ -121,6 +162,14 @@ const (
 	// VMwareGuestInfoUserDataKey is the guestinfo key used to provide a user data file.
 	VMwareGuestInfoUserDataKey = "talos.userdata"
 
+	// VMwareGuestInfoRegionKey is the guestinfo key used to provide the region
+	// of the machine.
+	VMwareGuestInfoRegionKey = "talos.region"
+
+	// VMwareGuestInfoZoneKey is the guestinfo key used to provide the zone of
+	// the machine.
+	VMwareGuestInfoZoneKey = "talos.zone"
+
 	// AuditPolicyPathInitramfs is the path to the audit-policy.yaml relative to initramfs.
 	AuditPolicyPathInitramfs = "/etc/kubernetes/audit-policy.yaml"
 
@@ -13,6 +13,8 @@ import (
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 // Registrator describes the set of methods required in order for a concrete
@@ -83,12 +85,17 @@ func NewDefaultOptions(setters ...Option) *Options {
 }
 
 // NewServer builds grpc server and binds it to the Registrator
+//
+// Every server also implements the standard gRPC health protocol, so that
+// the server can be health checked.
 func NewServer(r Registrator, setters ...Option) *grpc.Server {
 	opts := NewDefaultOptions(setters...)
 
 	server := grpc.NewServer(opts.ServerOptions...)
 	r.Register(server)
 
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
 	return server
 }
 
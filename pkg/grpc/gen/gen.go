@@ -65,9 +65,21 @@ func (g *Generator) Certificate(in *proto.CertificateRequest) (resp *proto.Certi
 	return resp, err
 }
 
+// IdentityOption configures the certificate request.
+type IdentityOption func(*proto.CertificateRequest)
+
+// WithInstanceIdentity attaches the PKCS7 signature of the platform instance
+// identity document to the certificate request as the proof of the machine
+// identity.
+func WithInstanceIdentity(signature []byte) IdentityOption {
+	return func(req *proto.CertificateRequest) {
+		req.InstanceIdentity = signature
+	}
+}
+
 // Identity creates a CSR and sends it to trustd for signing.
 // A signed certificate is returned.
-func (g *Generator) Identity(data *userdata.UserData) (err error) {
+func (g *Generator) Identity(data *userdata.UserData, opts ...IdentityOption) (err error) {
 	if data.Security == nil {
 		data.Security = &userdata.Security{}
 	}
@@ -80,6 +92,10 @@ func (g *Generator) Identity(data *userdata.UserData) (err error) {
 		Csr: csr.X509CertificateRequestPEM,
 	}
 
+	for _, opt := range opts {
+		opt(req)
+	}
+
 	return poll(g, req, data.Security.OS)
 }
 
@@ -6,6 +6,10 @@ package net
 
 import (
 	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
 // IPAddrs finds and returns a list of non-loopback IPv4 addresses of the
@@ -41,3 +45,42 @@ func FormatAddress(addr string) string {
 	}
 	return addr
 }
+
+// FormatCIDR converts the address and the netmask into the CIDR notation.
+// The netmask might be either dotted (255.255.255.0), IPv6 (ffff:ffff::) or
+// the prefix length (24). If the address is already in the CIDR notation,
+// the netmask is ignored.
+func FormatCIDR(address, netmask string) (string, error) {
+	if strings.Contains(address, "/") {
+		if _, _, err := net.ParseCIDR(address); err != nil {
+			return "", err
+		}
+
+		return address, nil
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return "", errors.Errorf("invalid IP address %q", address)
+	}
+
+	if ones, err := strconv.Atoi(netmask); err == nil {
+		return ip.String() + "/" + strconv.Itoa(ones), nil
+	}
+
+	mask := net.ParseIP(netmask)
+	if mask == nil {
+		return "", errors.Errorf("invalid netmask %q", netmask)
+	}
+
+	if mask4 := mask.To4(); mask4 != nil && ip.To4() != nil {
+		mask = mask4
+	}
+
+	ones, bits := net.IPMask(mask).Size()
+	if bits == 0 {
+		return "", errors.Errorf("non-canonical netmask %q", netmask)
+	}
+
+	return ip.String() + "/" + strconv.Itoa(ones), nil
+}
@@ -23,3 +23,25 @@ func TestFormatAddress(t *testing.T) {
 	assert.Equal(t, FormatAddress("192.168.1.1"), "192.168.1.1")
 	assert.Equal(t, FormatAddress("alpha.beta.gamma.com"), "alpha.beta.gamma.com")
 }
+
+func TestFormatCIDR(t *testing.T) {
+	for _, tt := range []struct {
+		address, netmask, expected string
+	}{
+		{"10.0.0.5", "255.255.255.0", "10.0.0.5/24"},
+		{"10.0.0.5", "16", "10.0.0.5/16"},
+		{"0.0.0.0", "0.0.0.0", "0.0.0.0/0"},
+		{"2001:db8::5", "ffff:ffff:ffff:ffff::", "2001:db8::5/64"},
+		{"2001:db8::5/64", "", "2001:db8::5/64"},
+	} {
+		cidr, err := FormatCIDR(tt.address, tt.netmask)
+		assert.NilError(t, err)
+		assert.Equal(t, cidr, tt.expected)
+	}
+
+	_, err := FormatCIDR("10.0.0.5", "255.0.255.0")
+	assert.ErrorContains(t, err, "non-canonical netmask")
+
+	_, err = FormatCIDR("yolo", "24")
+	assert.ErrorContains(t, err, "invalid IP address")
+}
@@ -13,8 +13,6 @@ import (
 	"net/http"
 	"net/url"
 	"time"
-
-	yaml "gopkg.in/yaml.v2"
 )
 
 const b64 = "base64"
@@ -75,30 +73,56 @@ func WithMaxWait(wait float64) Option {
 }
 
 // Download initializes a UserData struct from a remote URL.
-// nolint: gocyclo
 func Download(udURL string, opts ...Option) (data *UserData, err error) {
-	u, err := url.Parse(udURL)
+	dataBytes, err := Fetch(udURL, opts...)
 	if err != nil {
 		return data, err
 	}
 
+	if data, err = Decode(dataBytes); err != nil {
+		return data, err
+	}
+
+	return data, data.Validate()
+}
+
+// Fetch downloads the raw user data from a remote URL. The http, https and
+// tftp schemes are supported.
+// nolint: gocyclo
+func Fetch(udURL string, opts ...Option) (dataBytes []byte, err error) {
+	u, err := url.Parse(udURL)
+	if err != nil {
+		return nil, err
+	}
+
 	dlOpts := downloadDefaults()
 	for _, opt := range opts {
 		opt(dlOpts)
 	}
 
-	req, err := http.NewRequest("GET", u.String(), nil)
-	if err != nil {
-		return data, err
-	}
+	var fetch func() ([]byte, error)
 
-	for k, v := range dlOpts.Headers {
-		req.Header.Set(k, v)
+	switch u.Scheme {
+	case "http", "https":
+		var req *http.Request
+		req, err = http.NewRequest("GET", u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range dlOpts.Headers {
+			req.Header.Set(k, v)
+		}
+
+		fetch = func() ([]byte, error) { return download(req) }
+	case "tftp":
+		fetch = func() ([]byte, error) { return downloadTFTP(u) }
+	default:
+		return nil, fmt.Errorf("unsupported user data URL scheme %q", u.Scheme)
 	}
 
-	var dataBytes []byte
 	for attempt := 0; attempt < dlOpts.Retries; attempt++ {
-		dataBytes, err = download(req)
+		dataBytes, err = fetch()
 		if err != nil {
 			log.Printf("download failed: %+v", err)
 			backoff(float64(attempt), dlOpts.Wait)
@@ -112,19 +136,15 @@ func Download(udURL string, opts ...Option) (data *UserData, err error) {
 			var baseBytes []byte
 			baseBytes, err = base64.StdEncoding.DecodeString(string(dataBytes))
 			if err != nil {
-				return data, err
+				return nil, err
 			}
 			dataBytes = baseBytes
 		}
 
-		data = &UserData{}
-		if err := yaml.Unmarshal(dataBytes, data); err != nil {
-			return data, fmt.Errorf("unmarshal user data: %s", err.Error())
-		}
-		return data, data.Validate()
+		return dataBytes, nil
 	}
 
-	return data, fmt.Errorf("failed to download userdata from: %s", u.String())
+	return nil, fmt.Errorf("failed to download userdata from: %s", u.String())
 }
 
 // download handles the actual http request
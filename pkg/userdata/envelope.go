@@ -0,0 +1,111 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/talos-systems/talos/internal/pkg/kernel"
+	"github.com/talos-systems/talos/pkg/constants"
+	"github.com/talos-systems/talos/pkg/userdata/envelope"
+)
+
+// Decode opens the user data envelope, if any, and unmarshals the user data.
+//
+// The keys to verify and decrypt the envelope are read from the kernel
+// command line and the keyring file, see LoadKeyring.
+func Decode(b []byte) (data *UserData, err error) {
+	keyring, err := LoadKeyring(kernel.ProcCmdline())
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeWithKeyring(b, keyring)
+}
+
+// DecodeWithKeyring opens the user data envelope using the keyring, migrates
+// the user data to the current version and unmarshals it.
+func DecodeWithKeyring(b []byte, keyring *envelope.Keyring) (data *UserData, err error) {
+	if b, err = keyring.Open(b); err != nil {
+		return nil, errors.Wrap(err, "open user data envelope")
+	}
+
+	return unmarshal(b)
+}
+
+// DecodeStrict is Decode rejecting the unknown keys of the user data, for
+// the documents which are submitted rather than booted.
+func DecodeStrict(b []byte) (data *UserData, err error) {
+	keyring, err := LoadKeyring(kernel.ProcCmdline())
+	if err != nil {
+		return nil, err
+	}
+
+	if b, err = keyring.Open(b); err != nil {
+		return nil, errors.Wrap(err, "open user data envelope")
+	}
+
+	return unmarshalStrict(b)
+}
+
+// LoadKeyring collects the user data verification keys and identities from
+// the kernel parameters and the keyring file.
+//
+// The keyring file is read from the path specified by the
+// talos.userdata.keyring kernel parameter, or from the default path if it
+// exists. As there is nothing to seal the file with, it is refused unless it
+// is accessible by the owner only.
+func LoadKeyring(cmdline *kernel.Cmdline) (*envelope.Keyring, error) {
+	keyring := &envelope.Keyring{}
+
+	for _, param := range []string{constants.KernelParamUserDataVerify, constants.KernelParamUserDataIdentity} {
+		values := cmdline.Get(param)
+		if values == nil {
+			continue
+		}
+
+		for i := 0; values.Get(i) != nil; i++ {
+			if err := keyring.Add(*values.Get(i)); err != nil {
+				return nil, errors.Wrapf(err, "invalid %s kernel parameter", param)
+			}
+		}
+	}
+
+	path := constants.UserDataKeyringPath
+	required := false
+
+	if p := cmdline.Get(constants.KernelParamUserDataKeyring).First(); p != nil {
+		path = *p
+		required = true
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err) && !required:
+		return keyring, nil
+	case err != nil:
+		return nil, errors.Wrap(err, "failed to read user data keyring")
+	case info.Mode().Perm()&0077 != 0:
+		return nil, errors.Errorf("user data keyring %q must not be accessible by group or others, mode is %s", path, info.Mode().Perm())
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read user data keyring")
+	}
+
+	fromFile, err := envelope.ParseKeyring(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse user data keyring %q", path)
+	}
+
+	keyring.VerificationKeys = append(keyring.VerificationKeys, fromFile.VerificationKeys...)
+	keyring.Identities = append(keyring.Identities, fromFile.Identities...)
+
+	return keyring, nil
+}
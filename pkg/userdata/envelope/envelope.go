@@ -0,0 +1,277 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+// Package envelope implements signed and encrypted user data envelopes.
+//
+// Signed envelopes are PEM blocks wrapping the user data with an ed25519 or
+// ECDSA signature of the body. Encrypted envelopes are JWE (RFC 7516) in the
+// compact serialization, encrypted to a P-256 recipient with ECDH-ES+A256KW
+// and A256GCM. Envelopes nest: the recommended layout is a signed envelope
+// around an encrypted one, so the signature is verified before anything is
+// decrypted.
+package envelope
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ed25519"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	// SignedBlockType is the PEM block type of the signed envelope.
+	SignedBlockType = "TALOS SIGNED USERDATA"
+
+	// AlgorithmEd25519 is the ed25519 signature algorithm.
+	AlgorithmEd25519 = "ed25519"
+	// AlgorithmECDSASHA256 is the ECDSA with SHA-256 signature algorithm.
+	AlgorithmECDSASHA256 = "ecdsa-sha256"
+
+	headerAlgorithm = "Algorithm"
+	headerKeyID     = "Key-Id"
+	headerSignature = "Signature"
+
+	// context is mixed into the signed messages, so the signatures can't be
+	// reused by another protocol.
+	context = "talos-userdata-v1"
+)
+
+var (
+	// ErrUnsigned is returned when the user data is required to be signed,
+	// but it is not.
+	ErrUnsigned = errors.New("user data is not signed")
+	// ErrUnknownKey is returned when the user data is signed by a key which
+	// is not trusted.
+	ErrUnknownKey = errors.New("user data is signed by an unknown key")
+	// ErrBadSignature is returned when the signature doesn't match the user
+	// data.
+	ErrBadSignature = errors.New("user data signature is invalid")
+	// ErrNoIdentity is returned when the user data is encrypted to a
+	// recipient with no matching identity.
+	ErrNoIdentity = errors.New("no identity to decrypt the user data")
+)
+
+// Keyring holds the keys to open the envelopes.
+type Keyring struct {
+	// VerificationKeys are the trusted signers. If any are set, the user
+	// data must be signed by one of them.
+	VerificationKeys []crypto.PublicKey
+	// Identities decrypt the user data.
+	Identities []*Identity
+}
+
+// Empty reports whether the keyring holds no keys.
+func (k *Keyring) Empty() bool {
+	return k == nil || (len(k.VerificationKeys) == 0 && len(k.Identities) == 0)
+}
+
+// IsEnvelope reports whether the data is wrapped into an envelope.
+func IsEnvelope(data []byte) bool {
+	if parseEncrypted(data) != nil {
+		return true
+	}
+
+	block, _ := pem.Decode(data)
+
+	return block != nil && block.Type == SignedBlockType
+}
+
+// Open unwraps the envelopes, verifying the signatures and decrypting the
+// payload, and returns the user data.
+//
+// Plain user data is returned as is unless the keyring holds verification
+// keys.
+func (k *Keyring) Open(data []byte) ([]byte, error) {
+	if k == nil {
+		k = &Keyring{}
+	}
+
+	signed := false
+
+	for {
+		if object := parseEncrypted(data); object != nil {
+			var err error
+			if data, err = k.decrypt(object); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		block, rest := pem.Decode(data)
+		if block == nil || block.Type != SignedBlockType {
+			break
+		}
+
+		if len(bytes.TrimSpace(rest)) != 0 {
+			return nil, errors.New("unexpected data after the user data envelope")
+		}
+
+		if err := k.verify(block); err != nil {
+			return nil, err
+		}
+
+		signed = true
+		data = block.Bytes
+	}
+
+	if len(k.VerificationKeys) > 0 && !signed {
+		return nil, ErrUnsigned
+	}
+
+	return data, nil
+}
+
+// Sign wraps the payload into the signed envelope. The key should be either
+// ed25519.PrivateKey or *ecdsa.PrivateKey.
+func Sign(payload []byte, key crypto.Signer) ([]byte, error) {
+	block := &pem.Block{
+		Type:    SignedBlockType,
+		Headers: map[string]string{},
+		Bytes:   payload,
+	}
+
+	var (
+		signature []byte
+		err       error
+	)
+
+	message := signedMessage(payload)
+
+	switch key.(type) {
+	case ed25519.PrivateKey:
+		block.Headers[headerAlgorithm] = AlgorithmEd25519
+		signature, err = key.Sign(rand.Reader, message, crypto.Hash(0))
+	case *ecdsa.PrivateKey:
+		block.Headers[headerAlgorithm] = AlgorithmECDSASHA256
+		digest := sha256.Sum256(message)
+		signature, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		return nil, errors.Errorf("unsupported signing key %T", key)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign user data")
+	}
+
+	if block.Headers[headerKeyID], err = KeyID(key.Public()); err != nil {
+		return nil, err
+	}
+
+	block.Headers[headerSignature] = base64.StdEncoding.EncodeToString(signature)
+
+	return pem.EncodeToMemory(block), nil
+}
+
+// Encrypt wraps the payload into the envelope encrypted to the recipient.
+func Encrypt(payload []byte, recipient *Recipient) ([]byte, error) {
+	encrypter, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{
+		Algorithm: jose.ECDH_ES_A256KW,
+		Key:       recipient.key,
+		KeyID:     recipient.KeyID(),
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := encrypter.Encrypt(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt user data")
+	}
+
+	s, err := object.CompactSerialize()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(s + "\n"), nil
+}
+
+func (k *Keyring) verify(block *pem.Block) error {
+	if len(k.VerificationKeys) == 0 {
+		return errors.New("user data is signed, but no verification keys are configured")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(block.Headers[headerSignature])
+	if err != nil {
+		return errors.Wrap(err, "failed to decode the signature")
+	}
+
+	algorithm := block.Headers[headerAlgorithm]
+	keyID := block.Headers[headerKeyID]
+	message := signedMessage(block.Bytes)
+
+	for _, key := range k.VerificationKeys {
+		if id, err := KeyID(key); err != nil || id != keyID {
+			continue
+		}
+
+		var ok bool
+
+		switch pub := key.(type) {
+		case ed25519.PublicKey:
+			ok = algorithm == AlgorithmEd25519 && ed25519.Verify(pub, message, signature)
+		case *ecdsa.PublicKey:
+			ok = algorithm == AlgorithmECDSASHA256 && verifyECDSA(pub, message, signature)
+		}
+
+		if !ok {
+			return ErrBadSignature
+		}
+
+		return nil
+	}
+
+	return errors.Wrapf(ErrUnknownKey, "key id %q", keyID)
+}
+
+func (k *Keyring) decrypt(object *jose.JSONWebEncryption) ([]byte, error) {
+	if algorithm := object.Header.Algorithm; algorithm != string(jose.ECDH_ES_A256KW) {
+		return nil, errors.Errorf("unsupported encryption algorithm %q", algorithm)
+	}
+
+	for _, identity := range k.Identities {
+		if identity.Recipient().KeyID() != object.Header.KeyID {
+			continue
+		}
+
+		payload, err := object.Decrypt(identity.key)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decrypt user data")
+		}
+
+		return payload, nil
+	}
+
+	return nil, errors.Wrapf(ErrNoIdentity, "recipient %q", object.Header.KeyID)
+}
+
+// parseEncrypted returns the JWE if the data is the encrypted envelope.
+func parseEncrypted(data []byte) *jose.JSONWebEncryption {
+	s := strings.TrimSpace(string(data))
+
+	// the compact serialization is five base64url parts separated by dots
+	if strings.Count(s, ".") != 4 || strings.ContainsAny(s, " \t\r\n{") {
+		return nil
+	}
+
+	object, err := jose.ParseEncrypted(s)
+	if err != nil {
+		return nil
+	}
+
+	return object
+}
+
+func signedMessage(payload []byte) []byte {
+	return append([]byte(context+"\n"), payload...)
+}
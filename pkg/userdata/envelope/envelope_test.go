@@ -0,0 +1,188 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package envelope_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/pkg/userdata/envelope"
+)
+
+var payload = []byte("version: \"1\"\nsecurity: {}\n")
+
+type EnvelopeSuite struct {
+	suite.Suite
+}
+
+func (suite *EnvelopeSuite) signingKeys() []crypto.Signer {
+	ed, err := envelope.GenerateSigningKey()
+	suite.Require().NoError(err)
+
+	ec, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	suite.Require().NoError(err)
+
+	return []crypto.Signer{ed, ec}
+}
+
+func (suite *EnvelopeSuite) TestPlain() {
+	b, err := (&envelope.Keyring{}).Open(payload)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(payload, b)
+
+	b, err = (*envelope.Keyring)(nil).Open(payload)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(payload, b)
+}
+
+func (suite *EnvelopeSuite) TestSignAndVerify() {
+	for _, key := range suite.signingKeys() {
+		signed, err := envelope.Sign(payload, key)
+		suite.Require().NoError(err)
+		suite.Assert().True(envelope.IsEnvelope(signed))
+
+		keyring := &envelope.Keyring{VerificationKeys: []crypto.PublicKey{key.Public()}}
+
+		b, err := keyring.Open(signed)
+		suite.Require().NoError(err)
+		suite.Assert().Equal(payload, b)
+
+		// plain user data is refused once verification keys are configured
+		_, err = keyring.Open(payload)
+		suite.Assert().Equal(envelope.ErrUnsigned, errors.Cause(err))
+
+		// signed user data can't be used without verification keys
+		_, err = (&envelope.Keyring{}).Open(signed)
+		suite.Assert().Error(err)
+	}
+}
+
+func (suite *EnvelopeSuite) TestUnknownKey() {
+	keys := suite.signingKeys()
+
+	signed, err := envelope.Sign(payload, keys[0])
+	suite.Require().NoError(err)
+
+	_, err = (&envelope.Keyring{VerificationKeys: []crypto.PublicKey{keys[1].Public()}}).Open(signed)
+	suite.Assert().Equal(envelope.ErrUnknownKey, errors.Cause(err))
+}
+
+func (suite *EnvelopeSuite) TestBadSignature() {
+	keys := suite.signingKeys()
+
+	signed, err := envelope.Sign(payload, keys[0])
+	suite.Require().NoError(err)
+
+	forged, err := envelope.Sign([]byte("version: \"1\"\n"), keys[0])
+	suite.Require().NoError(err)
+
+	// swap the signed body, keeping the headers
+	signedEnd := bytes.Index(signed, []byte("\n\n")) + 2
+	forgedEnd := bytes.Index(forged, []byte("\n\n")) + 2
+	tampered := append(append([]byte{}, signed[:signedEnd]...), forged[forgedEnd:]...)
+
+	_, err = (&envelope.Keyring{VerificationKeys: []crypto.PublicKey{keys[0].Public()}}).Open(tampered)
+	suite.Assert().Equal(envelope.ErrBadSignature, errors.Cause(err))
+}
+
+func (suite *EnvelopeSuite) TestEncryptAndDecrypt() {
+	identity, err := envelope.GenerateIdentity()
+	suite.Require().NoError(err)
+
+	encrypted, err := envelope.Encrypt(payload, identity.Recipient())
+	suite.Require().NoError(err)
+	suite.Assert().NotContains(string(encrypted), "security")
+	suite.Assert().True(envelope.IsEnvelope(encrypted))
+
+	b, err := (&envelope.Keyring{Identities: []*envelope.Identity{identity}}).Open(encrypted)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(payload, b)
+
+	other, err := envelope.GenerateIdentity()
+	suite.Require().NoError(err)
+
+	_, err = (&envelope.Keyring{Identities: []*envelope.Identity{other}}).Open(encrypted)
+	suite.Assert().Equal(envelope.ErrNoIdentity, errors.Cause(err))
+}
+
+func (suite *EnvelopeSuite) TestSignedAndEncrypted() {
+	key := suite.signingKeys()[0]
+
+	identity, err := envelope.GenerateIdentity()
+	suite.Require().NoError(err)
+
+	encrypted, err := envelope.Encrypt(payload, identity.Recipient())
+	suite.Require().NoError(err)
+
+	signed, err := envelope.Sign(encrypted, key)
+	suite.Require().NoError(err)
+
+	keyring := &envelope.Keyring{
+		VerificationKeys: []crypto.PublicKey{key.Public()},
+		Identities:       []*envelope.Identity{identity},
+	}
+
+	b, err := keyring.Open(signed)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(payload, b)
+
+	// encrypted, but unsigned
+	_, err = keyring.Open(encrypted)
+	suite.Assert().Equal(envelope.ErrUnsigned, errors.Cause(err))
+}
+
+func (suite *EnvelopeSuite) TestKeyEncoding() {
+	identity, err := envelope.GenerateIdentity()
+	suite.Require().NoError(err)
+
+	lines := []string{"# user data keys", ""}
+
+	for _, key := range suite.signingKeys() {
+		var s string
+		s, err = envelope.MarshalVerificationKey(key.Public())
+		suite.Require().NoError(err)
+
+		lines = append(lines, s)
+
+		var b []byte
+		b, err = envelope.MarshalSigningKey(key)
+		suite.Require().NoError(err)
+
+		var parsed crypto.Signer
+		parsed, err = envelope.ParseSigningKey(b)
+		suite.Require().NoError(err)
+		suite.Assert().Equal(key.Public(), parsed.Public())
+	}
+
+	lines = append(lines, identity.String())
+
+	keyring, err := envelope.ParseKeyring([]byte(strings.Join(lines, "\n")))
+	suite.Require().NoError(err)
+	suite.Assert().Len(keyring.VerificationKeys, 2)
+	suite.Require().Len(keyring.Identities, 1)
+	suite.Assert().Equal(identity.Recipient(), keyring.Identities[0].Recipient())
+
+	recipient, err := envelope.ParseRecipient(identity.Recipient().String())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(identity.Recipient(), recipient)
+
+	_, err = envelope.ParseKeyring([]byte("ed25519:AAAA\n"))
+	suite.Assert().Error(err)
+
+	_, err = envelope.ParseVerificationKey("rsa:AAAA")
+	suite.Assert().Error(err)
+}
+
+func TestEnvelopeSuite(t *testing.T) {
+	suite.Run(t, new(EnvelopeSuite))
+}
@@ -0,0 +1,324 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package envelope
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ed25519"
+)
+
+// The textual key encodings are short enough to be passed on the kernel
+// command line.
+const (
+	// PrefixEd25519 prefixes the ed25519 verification keys.
+	PrefixEd25519 = "ed25519:"
+	// PrefixECDSA prefixes the ECDSA verification keys.
+	PrefixECDSA = "ecdsa:"
+	// PrefixRecipient prefixes the P-256 recipients.
+	PrefixRecipient = "p256:"
+	// PrefixIdentity prefixes the P-256 identities.
+	PrefixIdentity = "p256-identity:"
+
+	// Ed25519PrivateKeyBlockType is the PEM block type of the ed25519 signing
+	// key, the block holds the private key seed.
+	Ed25519PrivateKeyBlockType = "ED25519 PRIVATE KEY"
+	// ECPrivateKeyBlockType is the PEM block type of the ECDSA signing key.
+	ECPrivateKeyBlockType = "EC PRIVATE KEY"
+)
+
+// Recipient is the P-256 public key the user data is encrypted to.
+type Recipient struct {
+	key *ecdsa.PublicKey
+}
+
+// String returns the textual encoding of the recipient.
+func (r *Recipient) String() string {
+	return PrefixRecipient + base64.RawStdEncoding.EncodeToString(elliptic.Marshal(r.key.Curve, r.key.X, r.key.Y))
+}
+
+// KeyID returns the short fingerprint of the recipient.
+func (r *Recipient) KeyID() string {
+	return fingerprint(elliptic.Marshal(r.key.Curve, r.key.X, r.key.Y))
+}
+
+// Identity is the P-256 private key the user data is decrypted with.
+type Identity struct {
+	key *ecdsa.PrivateKey
+}
+
+// GenerateIdentity creates a random identity.
+func GenerateIdentity() (*Identity, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{key: key}, nil
+}
+
+// Recipient returns the public key of the identity.
+func (i *Identity) Recipient() *Recipient {
+	return &Recipient{key: &i.key.PublicKey}
+}
+
+// String returns the textual encoding of the identity.
+func (i *Identity) String() string {
+	// the scalar is padded to the fixed length
+	b := i.key.D.Bytes()
+	d := make([]byte, 32)
+	copy(d[len(d)-len(b):], b)
+
+	return PrefixIdentity + base64.RawStdEncoding.EncodeToString(d)
+}
+
+// ParseRecipient decodes the textual encoding of the recipient.
+func ParseRecipient(s string) (*Recipient, error) {
+	b, err := decodeKey(s, PrefixRecipient, 65)
+	if err != nil {
+		return nil, err
+	}
+
+	x, y := elliptic.Unmarshal(elliptic.P256(), b)
+	if x == nil {
+		return nil, errors.New("invalid \"p256\" key")
+	}
+
+	return &Recipient{key: &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}, nil
+}
+
+// ParseIdentity decodes the textual encoding of the identity.
+func ParseIdentity(s string) (*Identity, error) {
+	b, err := decodeKey(s, PrefixIdentity, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	curve := elliptic.P256()
+
+	d := new(big.Int).SetBytes(b)
+	if d.Sign() == 0 || d.Cmp(curve.Params().N) >= 0 {
+		return nil, errors.New("invalid \"p256-identity\" key")
+	}
+
+	key := &ecdsa.PrivateKey{D: d}
+	key.Curve = curve
+	key.X, key.Y = curve.ScalarBaseMult(b)
+
+	return &Identity{key: key}, nil
+}
+
+// ParseVerificationKey decodes the textual encoding of the ed25519 or ECDSA
+// public key.
+func ParseVerificationKey(s string) (crypto.PublicKey, error) {
+	switch {
+	case strings.HasPrefix(s, PrefixEd25519):
+		b, err := decodeKey(s, PrefixEd25519, ed25519.PublicKeySize)
+		if err != nil {
+			return nil, err
+		}
+
+		return ed25519.PublicKey(b), nil
+	case strings.HasPrefix(s, PrefixECDSA):
+		b, err := decodeKey(s, PrefixECDSA, -1)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := x509.ParsePKIXPublicKey(b)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse ECDSA public key")
+		}
+
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, errors.Errorf("unsupported public key %T", key)
+		}
+
+		return pub, nil
+	default:
+		return nil, errors.Errorf("unknown verification key format %q", s)
+	}
+}
+
+// MarshalVerificationKey returns the textual encoding of the public key.
+func MarshalVerificationKey(key crypto.PublicKey) (string, error) {
+	switch pub := key.(type) {
+	case ed25519.PublicKey:
+		return PrefixEd25519 + base64.RawStdEncoding.EncodeToString(pub), nil
+	case *ecdsa.PublicKey:
+		b, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return "", err
+		}
+
+		return PrefixECDSA + base64.RawStdEncoding.EncodeToString(b), nil
+	default:
+		return "", errors.Errorf("unsupported verification key %T", key)
+	}
+}
+
+// KeyID returns the short fingerprint of the verification key.
+func KeyID(key crypto.PublicKey) (string, error) {
+	switch pub := key.(type) {
+	case ed25519.PublicKey:
+		return fingerprint(pub), nil
+	case *ecdsa.PublicKey:
+		b, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return "", err
+		}
+
+		return fingerprint(b), nil
+	default:
+		return "", errors.Errorf("unsupported verification key %T", key)
+	}
+}
+
+// GenerateSigningKey creates the ed25519 signing key.
+func GenerateSigningKey() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+
+	return key, err
+}
+
+// MarshalSigningKey returns the PEM encoding of the ed25519 or ECDSA private
+// key.
+func MarshalSigningKey(key crypto.Signer) ([]byte, error) {
+	switch priv := key.(type) {
+	case ed25519.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{Type: Ed25519PrivateKeyBlockType, Bytes: priv.Seed()}), nil
+	case *ecdsa.PrivateKey:
+		b, err := x509.MarshalECPrivateKey(priv)
+		if err != nil {
+			return nil, err
+		}
+
+		return pem.EncodeToMemory(&pem.Block{Type: ECPrivateKeyBlockType, Bytes: b}), nil
+	default:
+		return nil, errors.Errorf("unsupported signing key %T", key)
+	}
+}
+
+// ParseSigningKey decodes the PEM encoded ed25519 or ECDSA private key, as
+// generated by `osctl gen signing-key` or `osctl gen key`.
+func ParseSigningKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode PEM encoded signing key")
+	}
+
+	switch block.Type {
+	case Ed25519PrivateKeyBlockType:
+		if len(block.Bytes) != ed25519.SeedSize {
+			return nil, errors.New("invalid ed25519 private key")
+		}
+
+		return ed25519.NewKeyFromSeed(block.Bytes), nil
+	case ECPrivateKeyBlockType:
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse ECDSA private key")
+		}
+
+		return key, nil
+	default:
+		return nil, errors.Errorf("unsupported signing key type %q", block.Type)
+	}
+}
+
+// Add decodes the textual key encoding and adds the key to the keyring.
+func (k *Keyring) Add(s string) error {
+	if strings.HasPrefix(s, PrefixIdentity) {
+		identity, err := ParseIdentity(s)
+		if err != nil {
+			return err
+		}
+
+		k.Identities = append(k.Identities, identity)
+
+		return nil
+	}
+
+	key, err := ParseVerificationKey(s)
+	if err != nil {
+		return err
+	}
+
+	k.VerificationKeys = append(k.VerificationKeys, key)
+
+	return nil
+}
+
+// ParseKeyring decodes the keyring file: one key per line, empty lines and
+// lines starting with '#' are ignored.
+func ParseKeyring(data []byte) (*Keyring, error) {
+	k := &Keyring{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+
+		if err := k.Add(s); err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+	}
+
+	return k, scanner.Err()
+}
+
+func decodeKey(s, prefix string, size int) ([]byte, error) {
+	if !strings.HasPrefix(s, prefix) {
+		return nil, errors.Errorf("expected %q prefix", prefix)
+	}
+
+	b, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(strings.TrimPrefix(s, prefix), "="))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %q key", strings.TrimSuffix(prefix, ":"))
+	}
+
+	if size >= 0 && len(b) != size {
+		return nil, errors.Errorf("invalid %q key length %d", strings.TrimSuffix(prefix, ":"), len(b))
+	}
+
+	return b, nil
+}
+
+func fingerprint(b []byte) string {
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:8])
+}
+
+func verifyECDSA(pub *ecdsa.PublicKey, message, signature []byte) bool {
+	var sig struct {
+		R, S *big.Int
+	}
+
+	if rest, err := asn1.Unmarshal(signature, &sig); err != nil || len(rest) != 0 {
+		return false
+	}
+
+	digest := sha256.Sum256(message)
+
+	return ecdsa.Verify(pub, digest[:], sig.R, sig.S)
+}
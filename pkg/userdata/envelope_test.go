@@ -0,0 +1,76 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/talos-systems/talos/internal/pkg/kernel"
+	"github.com/talos-systems/talos/pkg/userdata/envelope"
+)
+
+type keyringSuite struct {
+	suite.Suite
+}
+
+func TestKeyringSuite(t *testing.T) {
+	suite.Run(t, new(keyringSuite))
+}
+
+func (suite *keyringSuite) TestLoadKeyring() {
+	key, err := envelope.GenerateSigningKey()
+	suite.Require().NoError(err)
+
+	verify, err := envelope.MarshalVerificationKey(key.Public())
+	suite.Require().NoError(err)
+
+	identity, err := envelope.GenerateIdentity()
+	suite.Require().NoError(err)
+
+	dir, err := ioutil.TempDir("", "talos")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	path := filepath.Join(dir, "keyring")
+	suite.Require().NoError(ioutil.WriteFile(path, []byte(identity.String()+"\n"), 0600))
+
+	keyring, err := LoadKeyring(kernel.NewCmdline("talos.userdata.verify=" + verify + " talos.userdata.keyring=" + path))
+	suite.Require().NoError(err)
+	suite.Assert().Len(keyring.VerificationKeys, 1)
+	suite.Assert().Len(keyring.Identities, 1)
+
+	encrypted, err := envelope.Encrypt([]byte("version: \"1\"\n"), identity.Recipient())
+	suite.Require().NoError(err)
+
+	signed, err := envelope.Sign(encrypted, key)
+	suite.Require().NoError(err)
+
+	data, err := DecodeWithKeyring(signed, keyring)
+	suite.Require().NoError(err)
+	suite.Assert().Equal(CurrentVersion, data.Version)
+
+	_, err = DecodeWithKeyring([]byte("version: \"1\"\n"), keyring)
+	suite.Assert().Equal(envelope.ErrUnsigned, errors.Cause(err))
+
+	// the keyring must be private
+	suite.Require().NoError(os.Chmod(path, 0644))
+
+	_, err = LoadKeyring(kernel.NewCmdline("talos.userdata.keyring=" + path))
+	suite.Assert().Error(err)
+
+	// the explicitly specified keyring must exist
+	_, err = LoadKeyring(kernel.NewCmdline("talos.userdata.keyring=" + filepath.Join(dir, "missing")))
+	suite.Assert().Error(err)
+
+	keyring, err = LoadKeyring(kernel.NewCmdline(""))
+	suite.Require().NoError(err)
+	suite.Assert().True(keyring.Empty())
+}
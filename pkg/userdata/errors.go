@@ -13,6 +13,48 @@ var (
 	ErrRequiredSection = errors.New("required userdata section")
 	// ErrInvalidVersion denotes that the config file version is invalid
 	ErrInvalidVersion = errors.New("invalid config version")
+	// ErrUnsupportedSection denotes that the section is not supported in the
+	// validation mode
+	ErrUnsupportedSection = errors.New("userdata section is not supported in this mode")
+	// ErrInvalidMode denotes that the validation mode is unknown
+	ErrInvalidMode = errors.New("invalid validation mode")
+
+	// Install
+
+	// ErrInvalidMountPoint denotes that the mount point is not an absolute
+	// path
+	ErrInvalidMountPoint = errors.New("mount point must be an absolute path")
+	// ErrDuplicateMountPoint denotes that the mount point is used by another
+	// partition
+	ErrDuplicateMountPoint = errors.New("mount point is used by another partition")
+	// ErrUnsupportedFileSystem denotes that the file system of a partition
+	// is not supported
+	ErrUnsupportedFileSystem = errors.New("unsupported file system")
+	// ErrInvalidDiskSelector denotes that the disk selector is empty, or an
+	// attribute of the selector is invalid
+	ErrInvalidDiskSelector = errors.New("invalid disk selector")
+	// ErrConflictingDisk denotes that the disk is set both by the path and by
+	// the disk selector
+	ErrConflictingDisk = errors.New("device path conflicts with the disk selector")
+	// ErrInvalidEncryption denotes that the encryption of a partition does
+	// not have exactly one valid source of the key
+	ErrInvalidEncryption = errors.New("invalid encryption key source")
+	// ErrInvalidChecksum denotes that the checksum of an asset is not a hex
+	// encoded SHA-256 digest
+	ErrInvalidChecksum = errors.New("checksum must be a hex encoded SHA-256 digest")
+	// ErrUnsupportedAssetSource denotes that the scheme of the source of an
+	// asset is not supported
+	ErrUnsupportedAssetSource = errors.New("asset source must be an http, https, file or oci URL")
+	// ErrUnsupportedWipeMode denotes that the wipe mode of a device is not
+	// supported
+	ErrUnsupportedWipeMode = errors.New("wipe mode must be one of zero, discard, secure-discard or partial")
+	// ErrConflictingWipeMode denotes that the partitions sharing a device are
+	// set to be wiped differently
+	ErrConflictingWipeMode = errors.New("wipe mode conflicts with another partition of the device")
+	// ErrInvalidSwap denotes that the type or the size of a swap area is
+	// invalid, or that the swap partition would not be encrypted along with
+	// the ephemeral partition
+	ErrInvalidSwap = errors.New("invalid swap configuration")
 
 	// Security
 
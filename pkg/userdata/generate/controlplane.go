@@ -5,7 +5,7 @@
 package generate
 
 const controlPlaneTempl = `#!talos
-version: ""
+version: "1"
 security:
   os:
     ca:
@@ -44,14 +44,14 @@ func (suite *GenerateSuite) TestGenerateInitSuccess() {
 	dataString, err := generate.Userdata(generate.TypeInit, input)
 	suite.Require().NoError(err)
 	data := &userdata.UserData{}
-	err = yaml.Unmarshal([]byte(dataString), data)
+	err = yaml.UnmarshalStrict([]byte(dataString), data)
 	suite.Require().NoError(err)
 
 	inputv6.IP = net.ParseIP("2001:db8::1")
 	dataString, err = generate.Userdata(generate.TypeInit, inputv6)
 	suite.Require().NoError(err)
 	data = &userdata.UserData{}
-	err = yaml.Unmarshal([]byte(dataString), data)
+	err = yaml.UnmarshalStrict([]byte(dataString), data)
 	suite.Require().NoError(err)
 }
 
@@ -62,14 +62,14 @@ func (suite *GenerateSuite) TestGenerateControlPlaneSuccess() {
 	dataString, err := generate.Userdata(generate.TypeControlPlane, input)
 	suite.Require().NoError(err)
 	data := &userdata.UserData{}
-	err = yaml.Unmarshal([]byte(dataString), data)
+	err = yaml.UnmarshalStrict([]byte(dataString), data)
 	suite.Require().NoError(err)
 
 	inputv6.IP = net.ParseIP("2001:db8::2")
 	dataString, err = generate.Userdata(generate.TypeControlPlane, inputv6)
 	suite.Require().NoError(err)
 	data = &userdata.UserData{}
-	err = yaml.Unmarshal([]byte(dataString), data)
+	err = yaml.UnmarshalStrict([]byte(dataString), data)
 	suite.Require().NoError(err)
 }
 
@@ -77,13 +77,13 @@ func (suite *GenerateSuite) TestGenerateWorkerSuccess() {
 	dataString, err := generate.Userdata(generate.TypeJoin, input)
 	suite.Require().NoError(err)
 	data := &userdata.UserData{}
-	err = yaml.Unmarshal([]byte(dataString), data)
+	err = yaml.UnmarshalStrict([]byte(dataString), data)
 	suite.Require().NoError(err)
 
 	dataString, err = generate.Userdata(generate.TypeJoin, inputv6)
 	suite.Require().NoError(err)
 	data = &userdata.UserData{}
-	err = yaml.Unmarshal([]byte(dataString), data)
+	err = yaml.UnmarshalStrict([]byte(dataString), data)
 	suite.Require().NoError(err)
 }
 
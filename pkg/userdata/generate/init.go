@@ -5,7 +5,7 @@
 package generate
 
 const initTempl = `#!talos
-version: ""
+version: "1"
 security:
   os:
     ca:
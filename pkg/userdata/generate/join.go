@@ -5,7 +5,7 @@
 package generate
 
 const workerTempl = `#!talos
-version: ""
+version: "1"
 security: null
 services:
   init:
@@ -4,22 +4,85 @@
 
 package userdata
 
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/xerrors"
+
+	"github.com/talos-systems/talos/pkg/blockdevice"
+	"github.com/talos-systems/talos/pkg/blockdevice/discovery"
+	"github.com/talos-systems/talos/pkg/blockdevice/zram"
+	"github.com/talos-systems/talos/pkg/constants"
+)
+
 // Install represents the installation options for preparing a node.
 type Install struct {
+	Disk            *DiskSelector  `yaml:"disk,omitempty"`
 	Boot            *BootDevice    `yaml:"boot,omitempty"`
 	Ephemeral       *InstallDevice `yaml:"ephemeral,omitempty"`
 	ExtraDevices    []*ExtraDevice `yaml:"extraDevices,omitempty"`
 	ExtraKernelArgs []string       `yaml:"extraKernelArgs,omitempty"`
+	Swap            *Swap          `yaml:"swap,omitempty"`
+	Download        *AssetDownload `yaml:"download,omitempty"`
 	Wipe            bool           `yaml:"wipe"`
+	WipeMode        string         `yaml:"wipeMode,omitempty"`
 	Force           bool           `yaml:"force"`
 }
 
+// DiskSelector selects the disk to install to by its attributes, instead of
+// the device path. The disk is used for the ephemeral and the boot partitions
+// unless their devices are set. Exactly one disk must match all of the
+// attributes.
+type DiskSelector struct {
+	MinSize uint   `yaml:"minSize,omitempty"`
+	MaxSize uint   `yaml:"maxSize,omitempty"`
+	Model   string `yaml:"model,omitempty"`
+	Serial  string `yaml:"serial,omitempty"`
+	WWN     string `yaml:"wwn,omitempty"`
+	Type    string `yaml:"type,omitempty"`
+	Bus     string `yaml:"bus,omitempty"`
+	Link    string `yaml:"link,omitempty"`
+}
+
+// Selector returns the selector used to find the disk.
+func (d *DiskSelector) Selector() *discovery.Selector {
+	return &discovery.Selector{
+		MinSize: uint64(d.MinSize),
+		MaxSize: uint64(d.MaxSize),
+		Model:   d.Model,
+		Serial:  d.Serial,
+		WWN:     d.WWN,
+		Type:    d.Type,
+		Bus:     d.Bus,
+		Link:    d.Link,
+	}
+}
+
 // BootDevice represents the install options specific to the boot partition.
 type BootDevice struct {
 	InstallDevice `yaml:",inline"`
 
-	Kernel    string `yaml:"kernel"`
-	Initramfs string `yaml:"initramfs"`
+	Kernel          string `yaml:"kernel"`
+	KernelSHA256    string `yaml:"kernelSHA256,omitempty"`
+	Initramfs       string `yaml:"initramfs"`
+	InitramfsSHA256 string `yaml:"initramfsSHA256,omitempty"`
+}
+
+// AssetDownload represents the options for downloading the install and upgrade
+// assets. The CA bundle is PEM encoded, and is trusted in addition to the
+// system roots. The timeout is in seconds, and applies to each attempt.
+type AssetDownload struct {
+	CABundle string `yaml:"caBundle,omitempty"`
+	Retries  uint   `yaml:"retries,omitempty"`
+	Timeout  uint   `yaml:"timeout,omitempty"`
 }
 
 // RootDevice represents the install options specific to the root partition.
@@ -31,8 +94,22 @@ type RootDevice struct {
 
 // InstallDevice represents the specific directions for each partition.
 type InstallDevice struct {
-	Device string `yaml:"device,omitempty"`
-	Size   uint   `yaml:"size,omitempty"`
+	Device     string      `yaml:"device,omitempty"`
+	Size       uint        `yaml:"size,omitempty"`
+	Encryption *Encryption `yaml:"encryption,omitempty"`
+	WipeMode   string      `yaml:"wipeMode,omitempty"`
+}
+
+// Encryption represents the source of the key of a LUKS2 encrypted
+// partition. Exactly one of the sources must be set.
+//
+// The key file is read from a file system on the boot media, in the form
+// file://LABEL/path/to/key. The key server is sent a POST request with the
+// UUID and the label of the partition, and replies with the key.
+type Encryption struct {
+	Passphrase string `yaml:"passphrase,omitempty"`
+	KeyFile    string `yaml:"keyFile,omitempty"`
+	KeyServer  string `yaml:"keyServer,omitempty"`
 }
 
 // ExtraDevice represents the options available for partitioning, formatting,
@@ -40,10 +117,444 @@ type InstallDevice struct {
 type ExtraDevice struct {
 	Device     string                  `yaml:"device,omitempty"`
 	Partitions []*ExtraDevicePartition `yaml:"partitions,omitempty"`
+	WipeMode   string                  `yaml:"wipeMode,omitempty"`
 }
 
 // ExtraDevicePartition represents the options for a device partition.
 type ExtraDevicePartition struct {
-	Size       uint   `yaml:"size,omitempty"`
-	MountPoint string `yaml:"mountpoint,omitempty"`
+	Size       uint        `yaml:"size,omitempty"`
+	MountPoint string      `yaml:"mountpoint,omitempty"`
+	FileSystem string      `yaml:"filesystem,omitempty"`
+	Encryption *Encryption `yaml:"encryption,omitempty"`
+}
+
+// Swap represents the swap space of the node. The swap area of the size, in
+// bytes, is either a file on the ephemeral partition or a partition of the
+// ephemeral device, a file by default. The zram devices are compressed swap
+// areas in memory, used before the swap area on the disk.
+type Swap struct {
+	Type       string        `yaml:"type,omitempty"`
+	Size       uint          `yaml:"size,omitempty"`
+	Swappiness *int          `yaml:"swappiness,omitempty"`
+	Zram       []*ZramDevice `yaml:"zram,omitempty"`
+}
+
+// ZramDevice represents a zram device used as swap. The size, in bytes, is
+// the size of the uncompressed data.
+type ZramDevice struct {
+	Size      uint   `yaml:"size"`
+	Algorithm string `yaml:"algorithm,omitempty"`
+}
+
+const (
+	// SwapFile is the type of a swap file on the ephemeral partition.
+	SwapFile = "file"
+	// SwapPartition is the type of a swap partition on the ephemeral device.
+	SwapPartition = "partition"
+)
+
+// Enabled reports whether any swap area is configured.
+func (s *Swap) Enabled() bool {
+	return s != nil && (s.Size > 0 || len(s.Zram) > 0)
+}
+
+// Partition reports whether the swap area on the disk is a partition.
+func (s *Swap) Partition() bool {
+	return s != nil && s.Size > 0 && s.Type == SwapPartition
+}
+
+// File reports whether the swap area on the disk is a file.
+func (s *Swap) File() bool {
+	return s != nil && s.Size > 0 && (s.Type == "" || s.Type == SwapFile)
+}
+
+// FileSystemType returns the file system of the partition, xfs is the
+// default.
+func (p *ExtraDevicePartition) FileSystemType() string {
+	if p.FileSystem == "" {
+		return "xfs"
+	}
+
+	return p.FileSystem
+}
+
+// ExtraPartitionLabel returns the GPT name and the file system label of the
+// partition of the extra device. The label is derived from the device and the
+// mount point, so it doesn't change when the partitions are added, removed or
+// reordered in the userdata. The label fits the 12 characters of an xfs label.
+func (i *Install) ExtraPartitionLabel(device, partition int) string {
+	extra := i.ExtraDevices[device]
+	sum := sha256.Sum256([]byte(extra.Device + "\x00" + extra.Partitions[partition].MountPoint))
+
+	return constants.ExtraPartitionLabelPrefix + strings.ToUpper(hex.EncodeToString(sum[:]))[:7]
+}
+
+// DeviceWipeMode returns the method used to wipe a device before the install,
+// given the wipe mode of the device. The device is wiped with its own mode if
+// set, otherwise with the mode of the install if wipe is set, zeroing the
+// whole device by default. An empty mode means the device is not wiped.
+func (i *Install) DeviceWipeMode(mode string) string {
+	switch {
+	case mode != "":
+		return mode
+	case !i.Wipe:
+		return ""
+	case i.WipeMode != "":
+		return i.WipeMode
+	default:
+		return blockdevice.WipeZero
+	}
+}
+
+// InstallCheck defines the function type for checks
+type InstallCheck func(*Install) error
+
+// Validate triggers the specified validation checks to run
+func (i *Install) Validate(checks ...InstallCheck) error {
+	var result *multierror.Error
+
+	for _, check := range checks {
+		result = multierror.Append(result, check(i))
+	}
+
+	return result.ErrorOrNil()
+}
+
+// CheckInstallEphemeralDevice ensures that the device to install to has been
+// specified, either by the path or by the disk selector
+func CheckInstallEphemeralDevice() InstallCheck {
+	return func(i *Install) error {
+		var result *multierror.Error
+
+		if i.Disk == nil && (i.Ephemeral == nil || i.Ephemeral.Device == "") {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.ephemeral.device", "", ErrRequiredSection))
+		}
+
+		return result.ErrorOrNil()
+	}
+}
+
+// CheckInstallDisk ensures that the disk selector is valid
+// nolint: gocyclo
+func CheckInstallDisk() InstallCheck {
+	return func(i *Install) error {
+		var result *multierror.Error
+
+		d := i.Disk
+		if d == nil {
+			return nil
+		}
+
+		if *d == (DiskSelector{}) {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.disk", "", ErrInvalidDiskSelector))
+		}
+
+		if d.MaxSize != 0 && d.MinSize > d.MaxSize {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.disk.minSize", strconv.FormatUint(uint64(d.MinSize), 10), ErrInvalidDiskSelector))
+		}
+
+		for key, pattern := range map[string]string{"model": d.Model, "serial": d.Serial, "link": d.Link} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.disk."+key, pattern, ErrInvalidDiskSelector))
+			}
+		}
+
+		if d.Link != "" && !strings.HasPrefix(d.Link, "/dev/disk/by-id/") && !strings.HasPrefix(d.Link, "/dev/disk/by-path/") {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.disk.link", d.Link, ErrInvalidDiskSelector))
+		}
+
+		switch d.Type {
+		case "", discovery.TypeSSD, discovery.TypeHDD:
+		default:
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.disk.type", d.Type, ErrInvalidDiskSelector))
+		}
+
+		switch d.Bus {
+		case "", discovery.BusNVMe, discovery.BusSATA, discovery.BusSCSI, discovery.BusVirtIO, discovery.BusUSB, discovery.BusMMC:
+		default:
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.disk.bus", d.Bus, ErrInvalidDiskSelector))
+		}
+
+		if i.Ephemeral != nil && i.Ephemeral.Device != "" {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.ephemeral.device", i.Ephemeral.Device, ErrConflictingDisk))
+		}
+
+		return result.ErrorOrNil()
+	}
+}
+
+// CheckInstallExtraDevices ensures that the extra devices and the unique mount
+// points of the partitions are specified, and that the file systems are
+// supported
+func CheckInstallExtraDevices() InstallCheck {
+	return func(i *Install) error {
+		var result *multierror.Error
+
+		mountpoints := map[string]struct{}{}
+
+		for idx, extra := range i.ExtraDevices {
+			path := "install.extraDevices[" + strconv.Itoa(idx) + "]"
+
+			if extra.Device == "" {
+				result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", path+".device", "", ErrRequiredSection))
+			}
+
+			for pidx, partition := range extra.Partitions {
+				if !filepath.IsAbs(partition.MountPoint) {
+					result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", path+".partitions["+strconv.Itoa(pidx)+"].mountpoint", partition.MountPoint, ErrInvalidMountPoint))
+				}
+
+				if _, ok := mountpoints[partition.MountPoint]; ok {
+					result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", path+".partitions["+strconv.Itoa(pidx)+"].mountpoint", partition.MountPoint, ErrDuplicateMountPoint))
+				}
+				mountpoints[partition.MountPoint] = struct{}{}
+
+				switch partition.FileSystemType() {
+				case "xfs", "ext4", "btrfs":
+				default:
+					result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", path+".partitions["+strconv.Itoa(pidx)+"].filesystem", partition.FileSystem, ErrUnsupportedFileSystem))
+				}
+			}
+		}
+
+		return result.ErrorOrNil()
+	}
+}
+
+// CheckInstallEncryption ensures that the encrypted partitions have exactly
+// one source of the key, and that the boot partition is not encrypted
+func CheckInstallEncryption() InstallCheck {
+	return func(i *Install) error {
+		var result *multierror.Error
+
+		if i.Boot != nil && i.Boot.Encryption != nil {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.boot.encryption", "", ErrUnsupportedSection))
+		}
+
+		if i.Ephemeral != nil && i.Ephemeral.Encryption != nil {
+			result = multierror.Append(result, i.Ephemeral.Encryption.check("install.ephemeral.encryption"))
+		}
+
+		for idx, extra := range i.ExtraDevices {
+			for pidx, partition := range extra.Partitions {
+				if partition.Encryption != nil {
+					result = multierror.Append(result, partition.Encryption.check("install.extraDevices["+strconv.Itoa(idx)+"].partitions["+strconv.Itoa(pidx)+"].encryption"))
+				}
+			}
+		}
+
+		return result.ErrorOrNil()
+	}
+}
+
+func (e *Encryption) check(path string) error {
+	var result *multierror.Error
+
+	sources := 0
+	for _, source := range []string{e.Passphrase, e.KeyFile, e.KeyServer} {
+		if source != "" {
+			sources++
+		}
+	}
+
+	if sources != 1 {
+		result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", path, "", ErrInvalidEncryption))
+	}
+
+	if e.KeyFile != "" {
+		if _, _, err := e.KeyFileLocation(); err != nil {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", path+".keyFile", e.KeyFile, ErrInvalidEncryption))
+		}
+	}
+
+	if e.KeyServer != "" {
+		// The node is authenticated with its trustd credentials, and the key
+		// is in the response, so the key server must be reached over TLS.
+		if u, err := url.Parse(e.KeyServer); err != nil || u.Scheme != "https" || u.Host == "" {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", path+".keyServer", e.KeyServer, ErrInvalidEncryption))
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+// KeyFileLocation returns the label of the file system holding the key file,
+// and the path of the key file in the file system.
+func (e *Encryption) KeyFileLocation() (label, path string, err error) {
+	u, err := url.Parse(e.KeyFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	if u.Scheme != "file" || u.Host == "" || u.Path == "" || u.Path == "/" {
+		return "", "", xerrors.Errorf("key file must be in the form file://LABEL/path: %q", e.KeyFile)
+	}
+
+	return u.Host, u.Path, nil
+}
+
+// CheckInstallDownload ensures that the checksums of the boot assets are
+// SHA-256 digests, that the sources are supported, and that the CA bundle
+// holds certificates
+func CheckInstallDownload() InstallCheck {
+	return func(i *Install) error {
+		var result *multierror.Error
+
+		if i.Boot != nil {
+			for key, source := range map[string]string{"kernel": i.Boot.Kernel, "initramfs": i.Boot.Initramfs} {
+				if source == "" {
+					continue
+				}
+
+				if u, err := url.Parse(source); err != nil || !supportedAssetScheme(u.Scheme) {
+					result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.boot."+key, source, ErrUnsupportedAssetSource))
+				}
+			}
+
+			for key, digest := range map[string]string{"kernelSHA256": i.Boot.KernelSHA256, "initramfsSHA256": i.Boot.InitramfsSHA256} {
+				if digest == "" {
+					continue
+				}
+
+				if b, err := hex.DecodeString(strings.TrimPrefix(digest, "sha256:")); err != nil || len(b) != sha256.Size {
+					result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.boot."+key, digest, ErrInvalidChecksum))
+				}
+			}
+		}
+
+		if i.Download != nil && i.Download.CABundle != "" {
+			if ok := x509.NewCertPool().AppendCertsFromPEM([]byte(i.Download.CABundle)); !ok {
+				result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.download.caBundle", "", ErrInvalidCert))
+			}
+		}
+
+		return result.ErrorOrNil()
+	}
+}
+
+// CheckInstallWipe ensures that the wipe modes are supported, and that the
+// partitions sharing a device are wiped the same way
+func CheckInstallWipe() InstallCheck {
+	return func(i *Install) error {
+		var result *multierror.Error
+
+		modes := map[string]string{"install.wipeMode": i.WipeMode}
+		devices := map[string]string{}
+
+		add := func(path, device, mode string) {
+			modes[path] = mode
+
+			if device == "" {
+				return
+			}
+
+			mode = i.DeviceWipeMode(mode)
+			if other, ok := devices[device]; ok && other != mode {
+				result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", path, mode, ErrConflictingWipeMode))
+			}
+
+			devices[device] = mode
+		}
+
+		if i.Ephemeral != nil {
+			add("install.ephemeral.wipeMode", i.Ephemeral.Device, i.Ephemeral.WipeMode)
+		}
+
+		if i.Boot != nil {
+			// The boot partition is on the ephemeral device by default.
+			device := i.Boot.Device
+			if device == "" && i.Ephemeral != nil {
+				device = i.Ephemeral.Device
+			}
+
+			add("install.boot.wipeMode", device, i.Boot.WipeMode)
+		}
+
+		for idx, extra := range i.ExtraDevices {
+			add("install.extraDevices["+strconv.Itoa(idx)+"].wipeMode", extra.Device, extra.WipeMode)
+		}
+
+		for path, mode := range modes {
+			if mode != "" && !supportedWipeMode(mode) {
+				result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", path, mode, ErrUnsupportedWipeMode))
+			}
+		}
+
+		return result.ErrorOrNil()
+	}
+}
+
+// CheckInstallSwap ensures that the swap area on the disk and the zram
+// devices are valid. A swap partition is not encrypted, so it can't be
+// used with an encrypted ephemeral partition.
+func CheckInstallSwap() InstallCheck {
+	return func(i *Install) error {
+		var result *multierror.Error
+
+		s := i.Swap
+		if s == nil {
+			return nil
+		}
+
+		switch s.Type {
+		case "", SwapFile:
+		case SwapPartition:
+			if i.Ephemeral != nil && i.Ephemeral.Encryption != nil {
+				result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.swap.type", s.Type, ErrInvalidSwap))
+			}
+		default:
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.swap.type", s.Type, ErrInvalidSwap))
+		}
+
+		if s.Type != "" && s.Size == 0 {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.swap.size", "0", ErrInvalidSwap))
+		}
+
+		if s.Swappiness != nil && (*s.Swappiness < 0 || *s.Swappiness > 200) {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install.swap.swappiness", strconv.Itoa(*s.Swappiness), ErrInvalidSwap))
+		}
+
+		for idx, z := range s.Zram {
+			path := "install.swap.zram[" + strconv.Itoa(idx) + "]"
+
+			if z.Size == 0 {
+				result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", path+".size", "0", ErrInvalidSwap))
+			}
+
+			if z.Algorithm != "" && !supportedZramAlgorithm(z.Algorithm) {
+				result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", path+".algorithm", z.Algorithm, ErrInvalidSwap))
+			}
+		}
+
+		return result.ErrorOrNil()
+	}
+}
+
+func supportedZramAlgorithm(algorithm string) bool {
+	for _, a := range zram.Algorithms {
+		if algorithm == a {
+			return true
+		}
+	}
+
+	return false
+}
+
+func supportedWipeMode(mode string) bool {
+	for _, m := range blockdevice.WipeMethods {
+		if mode == m {
+			return true
+		}
+	}
+
+	return false
+}
+
+func supportedAssetScheme(scheme string) bool {
+	switch scheme {
+	case "http", "https", "file", "oci":
+		return true
+	default:
+		return false
+	}
 }
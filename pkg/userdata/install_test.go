@@ -0,0 +1,198 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import "strings"
+
+func (suite *validateSuite) TestValidateInstallDisk() {
+	var err error
+
+	install := &Install{Disk: &DiskSelector{}}
+	err = install.Validate(CheckInstallDisk())
+	suite.Assert().True(containsError(err, ErrInvalidDiskSelector), "%v", err)
+
+	install.Disk = &DiskSelector{MinSize: 256 << 30, Type: "ssd", Bus: "nvme", Model: "Samsung*"}
+	suite.Require().NoError(install.Validate(CheckInstallEphemeralDevice(), CheckInstallDisk()))
+
+	for _, disk := range []*DiskSelector{
+		{MinSize: 2, MaxSize: 1},
+		{Type: "flash"},
+		{Bus: "ide"},
+		{Model: "Samsung["},
+		{Link: "/dev/sda"},
+	} {
+		install.Disk = disk
+		err = install.Validate(CheckInstallDisk())
+		suite.Assert().True(containsError(err, ErrInvalidDiskSelector), "%+v: %v", disk, err)
+	}
+
+	install.Disk = &DiskSelector{Link: "/dev/disk/by-path/pci-0000:00:1f.2-ata-1"}
+	install.Ephemeral = &InstallDevice{Device: "/dev/sda"}
+	err = install.Validate(CheckInstallDisk())
+	suite.Assert().True(containsError(err, ErrConflictingDisk), "%v", err)
+}
+
+func (suite *validateSuite) TestExtraPartitionLabel() {
+	install := &Install{
+		ExtraDevices: []*ExtraDevice{
+			{Device: "/dev/sdb", Partitions: []*ExtraDevicePartition{{MountPoint: "/var/lib/extra"}, {MountPoint: "/var/lib/other"}}},
+			{Device: "/dev/nvme0n1", Partitions: []*ExtraDevicePartition{{MountPoint: "/var/lib/data"}}},
+		},
+	}
+
+	suite.Assert().Equal("EXTRAC0289D8", install.ExtraPartitionLabel(0, 0))
+	suite.Assert().Equal("EXTRA666BD02", install.ExtraPartitionLabel(0, 1))
+	suite.Assert().Equal("EXTRA265BDAB", install.ExtraPartitionLabel(1, 0))
+
+	// The labels don't depend on the position of the partitions.
+	install.ExtraDevices[0].Partitions = install.ExtraDevices[0].Partitions[1:]
+	suite.Assert().Equal("EXTRA666BD02", install.ExtraPartitionLabel(0, 0))
+	install.ExtraDevices = install.ExtraDevices[1:]
+	suite.Assert().Equal("EXTRA265BDAB", install.ExtraPartitionLabel(0, 0))
+}
+
+func (suite *validateSuite) TestDuplicateMountPoint() {
+	install := &Install{
+		ExtraDevices: []*ExtraDevice{
+			{Device: "/dev/sdb", Partitions: []*ExtraDevicePartition{{MountPoint: "/var/lib/extra"}}},
+			{Device: "/dev/sdc", Partitions: []*ExtraDevicePartition{{MountPoint: "/var/lib/extra"}}},
+		},
+	}
+
+	err := install.Validate(CheckInstallExtraDevices())
+	suite.Assert().True(containsError(err, ErrDuplicateMountPoint), "%v", err)
+}
+
+func (suite *validateSuite) TestExtraDeviceRequired() {
+	install := &Install{
+		ExtraDevices: []*ExtraDevice{
+			{Partitions: []*ExtraDevicePartition{{MountPoint: "/var/lib/extra"}}},
+		},
+	}
+
+	err := install.Validate(CheckInstallExtraDevices())
+	suite.Assert().True(containsError(err, ErrRequiredSection), "%v", err)
+}
+
+func (suite *validateSuite) TestValidateInstallEncryption() {
+	var err error
+
+	install := &Install{
+		Ephemeral: &InstallDevice{Encryption: &Encryption{Passphrase: "secret"}},
+		ExtraDevices: []*ExtraDevice{
+			{Device: "/dev/sdb", Partitions: []*ExtraDevicePartition{
+				{MountPoint: "/var/lib/extra", Encryption: &Encryption{KeyFile: "file://ESP/keys/extra"}},
+				{MountPoint: "/var/lib/other", Encryption: &Encryption{KeyServer: "https://keys.example.com/v1/key"}},
+			}},
+		},
+	}
+	suite.Require().NoError(install.Validate(CheckInstallEncryption()))
+
+	for _, encryption := range []*Encryption{
+		{},
+		{Passphrase: "secret", KeyServer: "https://keys.example.com/v1/key"},
+		{KeyFile: "/keys/ephemeral"},
+		{KeyFile: "file://ESP"},
+		{KeyServer: "ftp://keys.example.com"},
+		{KeyServer: "http://keys.example.com/v1/key"},
+	} {
+		install.Ephemeral.Encryption = encryption
+		err = install.Validate(CheckInstallEncryption())
+		suite.Assert().True(containsError(err, ErrInvalidEncryption), "%+v: %v", encryption, err)
+	}
+
+	install = &Install{Boot: &BootDevice{InstallDevice: InstallDevice{Encryption: &Encryption{Passphrase: "secret"}}}}
+	err = install.Validate(CheckInstallEncryption())
+	suite.Assert().True(containsError(err, ErrUnsupportedSection), "%v", err)
+}
+
+func (suite *validateSuite) TestValidateInstallDownload() {
+	var err error
+
+	install := &Install{
+		Boot: &BootDevice{
+			Kernel:          "oci://registry.example.com/talos/boot:v0.2.0/vmlinuz",
+			Initramfs:       "https://assets.example.com/initramfs.xz",
+			InitramfsSHA256: "sha256:" + strings.Repeat("ab", 32),
+		},
+	}
+	suite.Require().NoError(install.Validate(CheckInstallDownload()))
+
+	install.Boot.KernelSHA256 = "abcd"
+	install.Boot.Initramfs = "ftp://assets.example.com/initramfs.xz"
+	err = install.Validate(CheckInstallDownload())
+	suite.Assert().True(containsError(err, ErrInvalidChecksum), "%v", err)
+	suite.Assert().True(containsError(err, ErrUnsupportedAssetSource), "%v", err)
+
+	install = &Install{Download: &AssetDownload{CABundle: "not a certificate"}}
+	err = install.Validate(CheckInstallDownload())
+	suite.Assert().True(containsError(err, ErrInvalidCert), "%v", err)
+}
+
+func (suite *validateSuite) TestValidateInstallWipe() {
+	var err error
+
+	install := &Install{
+		Wipe:         true,
+		WipeMode:     "partial",
+		Ephemeral:    &InstallDevice{Device: "/dev/sda"},
+		Boot:         &BootDevice{},
+		ExtraDevices: []*ExtraDevice{{Device: "/dev/nvme0n1", WipeMode: "secure-discard"}},
+	}
+	suite.Require().NoError(install.Validate(CheckInstallWipe()))
+	suite.Assert().Equal("partial", install.DeviceWipeMode(install.Ephemeral.WipeMode))
+	suite.Assert().Equal("secure-discard", install.DeviceWipeMode(install.ExtraDevices[0].WipeMode))
+
+	install.Wipe = false
+	suite.Assert().Equal("", install.DeviceWipeMode(""))
+
+	install.Wipe = true
+	install.WipeMode = "shred"
+	install.Boot.WipeMode = "discard"
+	err = install.Validate(CheckInstallWipe())
+	suite.Assert().True(containsError(err, ErrUnsupportedWipeMode), "%v", err)
+	suite.Assert().True(containsError(err, ErrConflictingWipeMode), "%v", err)
+}
+
+func (suite *validateSuite) TestValidateInstallSwap() {
+	var err error
+
+	swappiness := 10
+
+	install := &Install{
+		Ephemeral: &InstallDevice{Device: "/dev/sda"},
+		Swap: &Swap{
+			Type:       "partition",
+			Size:       2 << 30,
+			Swappiness: &swappiness,
+			Zram:       []*ZramDevice{{Size: 1 << 30, Algorithm: "zstd"}, {Size: 512 << 20}},
+		},
+	}
+	suite.Require().NoError(install.Validate(CheckInstallSwap()))
+	suite.Assert().True(install.Swap.Enabled())
+	suite.Assert().True(install.Swap.Partition())
+	suite.Assert().False(install.Swap.File())
+
+	install.Swap = nil
+	suite.Assert().False(install.Swap.Enabled())
+	suite.Require().NoError(install.Validate(CheckInstallSwap()))
+
+	for _, swap := range []*Swap{
+		{Type: "zswap", Size: 1 << 30},
+		{Type: "file"},
+		{Zram: []*ZramDevice{{}}},
+		{Zram: []*ZramDevice{{Size: 1 << 30, Algorithm: "gzip"}}},
+		{Size: 1 << 30, Swappiness: &[]int{201}[0]},
+	} {
+		install.Swap = swap
+		err = install.Validate(CheckInstallSwap())
+		suite.Assert().True(containsError(err, ErrInvalidSwap), "%+v: %v", swap, err)
+	}
+
+	install.Swap = &Swap{Type: "partition", Size: 1 << 30}
+	install.Ephemeral.Encryption = &Encryption{Passphrase: "secret"}
+	err = install.Validate(CheckInstallSwap())
+	suite.Assert().True(containsError(err, ErrInvalidSwap), "%v", err)
+}
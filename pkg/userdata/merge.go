@@ -0,0 +1,94 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/xerrors"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/talos-systems/talos/internal/pkg/kernel"
+)
+
+// OpenEnvelope opens the user data envelope, if any, with the keys from the
+// kernel command line and the keyring file, and returns the plain document.
+func OpenEnvelope(b []byte) ([]byte, error) {
+	keyring, err := LoadKeyring(kernel.ProcCmdline())
+	if err != nil {
+		return nil, err
+	}
+
+	if b, err = keyring.Open(b); err != nil {
+		return nil, errors.Wrap(err, "open user data envelope")
+	}
+
+	return b, nil
+}
+
+// Merge deep-merges the plain user data documents in order and unmarshals the
+// result. Each document is migrated to the current version first. Maps are
+// merged key by key, any other value in a later document replaces the
+// earlier one, except for null values, which are ignored. Lists are replaced
+// as a whole.
+func Merge(docs ...[]byte) (data *UserData, err error) {
+	var merged yaml.MapSlice
+
+	for i, b := range docs {
+		if b, _, err = Migrate(b); err != nil {
+			return nil, xerrors.Errorf("user data layer %d: %w", i, err)
+		}
+
+		var doc yaml.MapSlice
+		if err = yaml.Unmarshal(b, &doc); err != nil {
+			return nil, xerrors.Errorf("unmarshal user data layer %d: %w", i, err)
+		}
+
+		merged = mergeMaps(merged, doc)
+	}
+
+	b, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshal(b)
+}
+
+func mergeMaps(base, overlay yaml.MapSlice) yaml.MapSlice {
+	for _, item := range overlay {
+		if item.Value == nil {
+			continue
+		}
+
+		key, ok := item.Key.(string)
+		if !ok {
+			base = append(base, item)
+			continue
+		}
+
+		existing, baseIsMap := getKey(base, key).(yaml.MapSlice)
+		if overlayMap, ok := item.Value.(yaml.MapSlice); ok && baseIsMap {
+			item.Value = mergeMaps(existing, overlayMap)
+		}
+
+		base = setKeyInPlace(base, key, item.Value)
+	}
+
+	return base
+}
+
+// setKeyInPlace is setKey which appends the new keys instead of prepending
+// them, so the merged document keeps the order of the layers.
+func setKeyInPlace(doc yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i := range doc {
+		if doc[i].Key == key {
+			doc[i].Value = value
+
+			return doc
+		}
+	}
+
+	return append(doc, yaml.MapItem{Key: key, Value: value})
+}
@@ -0,0 +1,84 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type mergeSuite struct {
+	suite.Suite
+}
+
+func TestMergeSuite(t *testing.T) {
+	suite.Run(t, new(mergeSuite))
+}
+
+const mergeBase = `version: "1"
+networking:
+  os:
+    devices:
+    - interface: eth0
+      dhcp: true
+    nameservers:
+    - 1.1.1.1
+services:
+  trustd:
+    username: test
+    password: test
+    endpoints:
+    - 1.2.3.4
+`
+
+func (suite *mergeSuite) TestOverlay() {
+	data, err := Merge([]byte(mergeBase), []byte(`version: "1"
+networking:
+  os:
+    hostname: node-1
+    nameservers:
+    - 8.8.8.8
+services:
+  trustd:
+    password: secret
+    endpoints: null
+files:
+- path: /var/etc/node
+  contents: node-1
+`))
+	suite.Require().NoError(err)
+
+	suite.Assert().Equal(CurrentVersion, data.Version)
+	suite.Assert().Equal("node-1", data.Networking.OS.Hostname)
+	suite.Assert().Equal([]string{"8.8.8.8"}, data.Networking.OS.Nameservers)
+	suite.Require().Len(data.Networking.OS.Devices, 1)
+	suite.Assert().Equal("eth0", data.Networking.OS.Devices[0].Interface)
+	suite.Assert().Equal("test", data.Services.Trustd.Username)
+	suite.Assert().Equal("secret", data.Services.Trustd.Password)
+	suite.Assert().Equal([]string{"1.2.3.4"}, data.Services.Trustd.Endpoints)
+	suite.Require().Len(data.Files, 1)
+	suite.Assert().Equal("/var/etc/node", data.Files[0].Path)
+}
+
+func (suite *mergeSuite) TestMigratesLayers() {
+	data, err := Merge([]byte(mergeBase), []byte(`install:
+  extraDevices:
+  - device: /dev/sdb
+    partitions:
+    - mountpoint: /var/lib/extra
+`))
+	suite.Require().NoError(err)
+
+	suite.Assert().Equal(CurrentVersion, data.Version)
+	suite.Assert().Equal("/var/lib/extra", data.Install.ExtraDevices[0].Partitions[0].MountPoint)
+}
+
+// TestUnknownKey checks that the unknown keys of a layer are ignored at boot.
+func (suite *mergeSuite) TestUnknownKey() {
+	data, err := Merge([]byte(mergeBase), []byte("netwroking: {}\n"))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(CurrentVersion, data.Version)
+}
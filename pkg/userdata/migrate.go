@@ -0,0 +1,164 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import (
+	"bytes"
+	"log"
+	"strconv"
+
+	"golang.org/x/xerrors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// CurrentVersion is the latest user data schema version, the UserData struct
+// always represents this version.
+const CurrentVersion Version = "1"
+
+// Migration converts the user data document from the version it is
+// registered for to the next version. The document is migrated before it is
+// unmarshaled, so migrations operate on the generic YAML representation.
+type Migration func(doc yaml.MapSlice) (yaml.MapSlice, error)
+
+// migrations are keyed by the version they migrate from. A migration is
+// only added along with a change of the layout of the document.
+var migrations = map[Version]Migration{}
+
+// Migrate converts the user data document to the current version by applying
+// the registered migrations one by one. The document is returned unchanged
+// if it is already at the current version. Documents with no version are
+// treated as version "1".
+//
+// Comments are not preserved, except for the leading "#!" line.
+func Migrate(b []byte) (out []byte, from Version, err error) {
+	var doc yaml.MapSlice
+	if err = yaml.Unmarshal(b, &doc); err != nil {
+		return nil, "", xerrors.Errorf("unmarshal user data: %w", err)
+	}
+
+	from = documentVersion(doc)
+	if from == CurrentVersion {
+		return b, from, nil
+	}
+
+	if from == "" {
+		from = "1"
+		doc = setKey(doc, "version", string(from))
+	}
+
+	for version := from; version != CurrentVersion; {
+		migration, ok := migrations[version]
+		if !ok {
+			return nil, from, xerrors.Errorf("[%s] %q: %w", "version", version, ErrInvalidVersion)
+		}
+
+		if doc, err = migration(doc); err != nil {
+			return nil, from, xerrors.Errorf("migrate user data from version %q: %w", version, err)
+		}
+
+		version = nextVersion(version)
+		doc = setKey(doc, "version", string(version))
+	}
+
+	if out, err = yaml.Marshal(doc); err != nil {
+		return nil, from, err
+	}
+
+	if bytes.HasPrefix(b, []byte("#!")) {
+		shebang := b[:bytes.IndexByte(append(b, '\n'), '\n')]
+		out = append(append(append([]byte{}, shebang...), '\n'), out...)
+	}
+
+	return out, from, nil
+}
+
+// unmarshal migrates the user data document to the current version and
+// unmarshals it. The unknown keys are logged and ignored, so the documents
+// which booted before keep booting, the keys are rejected by osctl validate
+// and by the API, see unmarshalStrict.
+func unmarshal(b []byte) (data *UserData, err error) {
+	if data, err = unmarshalStrict(b); err == nil {
+		return data, nil
+	}
+
+	strictErr := err
+
+	b, _, err = Migrate(b)
+	if err != nil {
+		return nil, err
+	}
+
+	data = &UserData{}
+	if err = yaml.Unmarshal(b, data); err != nil {
+		return nil, xerrors.Errorf("unmarshal user data: %w", err)
+	}
+
+	log.Printf("WARNING: ignoring the unknown keys of the user data: %v", strictErr)
+
+	return data, nil
+}
+
+// unmarshalStrict migrates the user data document to the current version and
+// unmarshals it. Unknown and duplicate keys are rejected, so a misspelled
+// section fails loudly instead of being ignored.
+func unmarshalStrict(b []byte) (data *UserData, err error) {
+	b, from, err := Migrate(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if from != CurrentVersion {
+		log.Printf("migrated user data from version %q to %q", from, CurrentVersion)
+	}
+
+	data = &UserData{}
+	if err = yaml.UnmarshalStrict(b, data); err != nil {
+		return nil, xerrors.Errorf("unmarshal user data: %w", err)
+	}
+
+	return data, nil
+}
+
+func documentVersion(doc yaml.MapSlice) Version {
+	switch v := getKey(doc, "version").(type) {
+	case string:
+		return Version(v)
+	case int:
+		return Version(strconv.Itoa(v))
+	default:
+		return ""
+	}
+}
+
+func nextVersion(v Version) Version {
+	n, err := strconv.Atoi(string(v))
+	if err != nil {
+		return ""
+	}
+
+	return Version(strconv.Itoa(n + 1))
+}
+
+func getKey(doc yaml.MapSlice, key string) interface{} {
+	for _, item := range doc {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+
+	return nil
+}
+
+func setKey(doc yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i := range doc {
+		if doc[i].Key == key {
+			doc[i].Value = value
+
+			return doc
+		}
+	}
+
+	return append(yaml.MapSlice{{Key: key, Value: value}}, doc...)
+}
@@ -0,0 +1,62 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/xerrors"
+)
+
+type migrateSuite struct {
+	suite.Suite
+}
+
+func TestMigrateSuite(t *testing.T) {
+	suite.Run(t, new(migrateSuite))
+}
+
+// TestGolden migrates testdata/migrate/v<N>.yaml for every version and
+// compares the result with the document of the current version.
+func (suite *migrateSuite) TestGolden() {
+	expected, err := ioutil.ReadFile(filepath.Join("testdata", "migrate", "v"+string(CurrentVersion)+".yaml"))
+	suite.Require().NoError(err)
+
+	files, err := filepath.Glob(filepath.Join("testdata", "migrate", "v*.yaml"))
+	suite.Require().NoError(err)
+	suite.Require().Len(files, len(migrations)+1, "every version should have a golden file")
+
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		suite.Require().NoError(err)
+
+		out, from, err := Migrate(b)
+		suite.Require().NoError(err, file)
+		suite.Assert().Equal(Version(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "v"), ".yaml")), from)
+		suite.Assert().Equal(string(expected), string(out), file)
+
+		data, err := unmarshal(b)
+		suite.Require().NoError(err, file)
+		suite.Assert().Equal(CurrentVersion, data.Version)
+		suite.Assert().Equal("/var/lib/etcd", data.Install.ExtraDevices[0].Partitions[0].MountPoint)
+	}
+}
+
+func (suite *migrateSuite) TestUnversioned() {
+	out, from, err := Migrate([]byte("install:\n  wipe: true\n"))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(Version("1"), from)
+	suite.Assert().Equal("version: \""+string(CurrentVersion)+"\"\ninstall:\n  wipe: true\n", string(out))
+}
+
+func (suite *migrateSuite) TestUnknownVersion() {
+	_, _, err := Migrate([]byte("version: \"99\"\n"))
+	suite.Require().Error(err)
+	suite.Assert().True(xerrors.Is(err, ErrInvalidVersion))
+}
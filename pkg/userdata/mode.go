@@ -0,0 +1,82 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import (
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/xerrors"
+)
+
+// Mode is the kind of environment the user data is validated for.
+type Mode string
+
+const (
+	// ModeCloud is a cloud instance booted from the prebuilt image, the
+	// install section is optional.
+	ModeCloud Mode = "cloud"
+	// ModeContainer is a node running in a container, there are no disks to
+	// install to and the networking is managed by the container runtime.
+	ModeContainer Mode = "container"
+	// ModeBareMetal is a bare-metal machine, or a VM booted from the ISO or PXE,
+	// which installs to the local disk.
+	ModeBareMetal Mode = "bare-metal"
+)
+
+// Modes lists the supported validation modes.
+var Modes = []Mode{ModeCloud, ModeContainer, ModeBareMetal}
+
+// modeAliases are the alternative names of the modes.
+var modeAliases = map[string]Mode{
+	"metal": ModeBareMetal,
+}
+
+// ParseMode validates the string representation of the mode, or of its
+// alias.
+func ParseMode(s string) (Mode, error) {
+	for _, mode := range Modes {
+		if string(mode) == s {
+			return mode, nil
+		}
+	}
+
+	if mode, ok := modeAliases[s]; ok {
+		return mode, nil
+	}
+
+	return "", xerrors.Errorf("[%s] %q: %w", "mode", s, ErrInvalidMode)
+}
+
+// ValidateMode runs the generic checks, and the checks specific to the
+// environment the user data is used in.
+func (data *UserData) ValidateMode(mode Mode) error {
+	var result *multierror.Error
+
+	result = multierror.Append(result, data.Validate())
+
+	switch mode {
+	case ModeCloud:
+		if data.Install != nil {
+			result = multierror.Append(result, data.Install.Validate(CheckInstallDisk(), CheckInstallExtraDevices(), CheckInstallEncryption(), CheckInstallDownload(), CheckInstallWipe(), CheckInstallSwap()))
+		}
+	case ModeContainer:
+		if data.Install != nil {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install", "", ErrUnsupportedSection))
+		}
+
+		if data.Networking != nil && data.Networking.OS != nil && len(data.Networking.OS.Devices) > 0 {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "networking.os.devices", "", ErrUnsupportedSection))
+		}
+	case ModeBareMetal:
+		if data.Install == nil {
+			result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "install", "", ErrRequiredSection))
+		} else {
+			result = multierror.Append(result, data.Install.Validate(CheckInstallEphemeralDevice(), CheckInstallDisk(), CheckInstallExtraDevices(), CheckInstallEncryption(), CheckInstallDownload(), CheckInstallWipe(), CheckInstallSwap()))
+		}
+	default:
+		result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "mode", mode, ErrInvalidMode))
+	}
+
+	return result.ErrorOrNil()
+}
@@ -0,0 +1,80 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/xerrors"
+)
+
+type modeSuite struct {
+	suite.Suite
+}
+
+func TestModeSuite(t *testing.T) {
+	suite.Run(t, new(modeSuite))
+}
+
+func containsError(err, target error) bool {
+	merr, ok := err.(*multierror.Error)
+	if !ok {
+		return xerrors.Is(err, target)
+	}
+
+	for _, err := range merr.Errors {
+		if xerrors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (suite *modeSuite) TestValidateMode() {
+	data, err := unmarshal([]byte(testConfig))
+	suite.Require().NoError(err)
+
+	suite.Assert().NoError(data.ValidateMode(ModeBareMetal))
+	suite.Assert().NoError(data.ValidateMode(ModeCloud))
+
+	err = data.ValidateMode(ModeContainer)
+	suite.Assert().True(containsError(err, ErrUnsupportedSection), "%v", err)
+
+	data.Install.Ephemeral = nil
+	err = data.ValidateMode(ModeBareMetal)
+	suite.Assert().True(containsError(err, ErrRequiredSection), "%v", err)
+
+	data.Install.ExtraDevices = []*ExtraDevice{{Device: "/dev/sdb", Partitions: []*ExtraDevicePartition{{MountPoint: "var/lib/etcd"}}}}
+	err = data.ValidateMode(ModeCloud)
+	suite.Assert().True(containsError(err, ErrInvalidMountPoint), "%v", err)
+
+	data.Install.ExtraDevices[0].Partitions[0] = &ExtraDevicePartition{MountPoint: "/var/lib/etcd", FileSystem: "ext4"}
+	suite.Assert().NoError(data.ValidateMode(ModeCloud))
+
+	data.Install.ExtraDevices[0].Partitions[0].FileSystem = "ntfs"
+	err = data.ValidateMode(ModeCloud)
+	suite.Assert().True(containsError(err, ErrUnsupportedFileSystem), "%v", err)
+
+	data.Install = nil
+	suite.Assert().NoError(data.ValidateMode(ModeContainer))
+	err = data.ValidateMode(ModeBareMetal)
+	suite.Assert().True(containsError(err, ErrRequiredSection), "%v", err)
+}
+
+func (suite *modeSuite) TestParseMode() {
+	mode, err := ParseMode("bare-metal")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(ModeBareMetal, mode)
+
+	mode, err = ParseMode("metal")
+	suite.Require().NoError(err)
+	suite.Assert().Equal(ModeBareMetal, mode)
+
+	_, err = ParseMode("vm")
+	suite.Assert().True(containsError(err, ErrInvalidMode), "%v", err)
+}
@@ -0,0 +1,158 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/talos-systems/talos/pkg/crypto/x509"
+	"github.com/talos-systems/talos/pkg/userdata/token"
+)
+
+// SchemaID is the identifier of the generated JSON Schema.
+const SchemaID = "https://talos.dev/userdata.schema.json"
+
+// schemaOverrides describe the types with the custom YAML (un)marshaling.
+var schemaOverrides = map[reflect.Type]map[string]interface{}{
+	reflect.TypeOf(x509.PEMEncodedCertificateAndKey{}): {
+		"type": "object",
+		"properties": map[string]interface{}{
+			"crt": map[string]interface{}{"type": "string", "description": "base64 encoded PEM certificate"},
+			"key": map[string]interface{}{"type": "string", "description": "base64 encoded PEM private key"},
+		},
+		"additionalProperties": false,
+	},
+	reflect.TypeOf(token.Token{}): {
+		"type":        "string",
+		"description": "UUIDv1 token",
+	},
+	reflect.TypeOf(Version("")): {
+		"type": "string",
+		"enum": []string{string(CurrentVersion)},
+	},
+}
+
+// JSONSchema generates the JSON Schema (draft-07) of the current user data
+// version. Unknown keys are not allowed, the same as with the strict
+// decoding.
+func JSONSchema() ([]byte, error) {
+	schema, err := typeSchema(reflect.TypeOf(UserData{}), "")
+	if err != nil {
+		return nil, err
+	}
+
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["$id"] = SchemaID
+	schema["title"] = "Talos userdata"
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// nolint: gocyclo
+func typeSchema(t reflect.Type, path string) (map[string]interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if override, ok := schemaOverrides[t]; ok {
+		schema := map[string]interface{}{}
+		for k, v := range override {
+			schema[k] = v
+		}
+
+		return schema, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string"}, nil
+		}
+
+		items, err := typeSchema(t.Elem(), path+"[]")
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		values, err := typeSchema(t.Elem(), path+"{}")
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		if err := structProperties(t, path, properties); err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported type %s", path, t)
+	}
+}
+
+// structProperties follows the yaml.v2 field naming rules: the tag name, or
+// the lowercased field name, inline structs are flattened.
+func structProperties(t reflect.Type, path string, properties map[string]interface{}) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		inline := false
+
+		for _, flag := range tag[1:] {
+			if flag == "inline" {
+				inline = true
+			}
+		}
+
+		if inline {
+			if err := structProperties(field.Type, path, properties); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		name := tag[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		schema, err := typeSchema(field.Type, strings.TrimPrefix(path+"."+name, "."))
+		if err != nil {
+			return err
+		}
+
+		properties[name] = schema
+	}
+
+	return nil
+}
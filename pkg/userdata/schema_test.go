@@ -0,0 +1,100 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type schemaSuite struct {
+	suite.Suite
+}
+
+func TestSchemaSuite(t *testing.T) {
+	suite.Run(t, new(schemaSuite))
+}
+
+// allowed walks the YAML document along the schema and returns the first key
+// the schema doesn't allow.
+func allowed(schema map[string]interface{}, doc interface{}, path string) error {
+	switch doc := doc.(type) {
+	case map[interface{}]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+
+		for k, v := range doc {
+			key := fmt.Sprint(k)
+
+			property, ok := properties[key].(map[string]interface{})
+			if !ok {
+				property = additional
+			}
+
+			if property == nil {
+				return fmt.Errorf("%s.%s is not allowed", path, key)
+			}
+
+			if err := allowed(property, v, path+"."+key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+
+		for _, item := range doc {
+			if err := allowed(items, item, path+"[]"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (suite *schemaSuite) schema() map[string]interface{} {
+	b, err := JSONSchema()
+	suite.Require().NoError(err)
+
+	var schema map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(b, &schema))
+
+	return schema
+}
+
+func (suite *schemaSuite) TestSchemaCoversGolden() {
+	schema := suite.schema()
+	suite.Assert().Equal(SchemaID, schema["$id"])
+	suite.Assert().Equal(false, schema["additionalProperties"])
+
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "migrate", "v"+string(CurrentVersion)+".yaml"))
+	suite.Require().NoError(err)
+
+	var doc interface{}
+	suite.Require().NoError(yaml.Unmarshal(b, &doc))
+	suite.Assert().NoError(allowed(schema, doc, ""))
+
+	suite.Require().NoError(yaml.Unmarshal([]byte("netwroking:\n  os: {}\n"), &doc))
+	suite.Assert().EqualError(allowed(schema, doc, ""), ".netwroking is not allowed")
+}
+
+func (suite *schemaSuite) TestStrictDecoding() {
+	b := []byte("version: \"" + string(CurrentVersion) + "\"\nnetwroking:\n  os: {}\ninstall:\n  wipe: true\n")
+
+	_, err := unmarshalStrict(b)
+	suite.Require().Error(err)
+	suite.Assert().Contains(err.Error(), "line 2: field netwroking not found")
+
+	// the documents which booted before keep booting
+	data, err := unmarshal(b)
+	suite.Require().NoError(err)
+	suite.Assert().True(data.Install.Wipe)
+}
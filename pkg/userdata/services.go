@@ -36,6 +36,7 @@ type Services struct {
 	OSD     *OSD     `yaml:"osd"`
 	CRT     *CRT     `yaml:"crt"`
 	NTPd    *NTPd    `yaml:"ntp"`
+	Udevd   *Udevd   `yaml:"udevd,omitempty"`
 }
 
 // Validate triggers the specified validation checks to run
@@ -74,6 +75,11 @@ type OSD struct {
 	CommonServiceOptions `yaml:",inline"`
 }
 
+// Udevd describes the configuration of the udevd service.
+type Udevd struct {
+	CommonServiceOptions `yaml:",inline"`
+}
+
 // Proxyd describes the configuration of the proxyd service.
 type Proxyd struct {
 	CommonServiceOptions `yaml:",inline"`
@@ -87,6 +93,10 @@ type CRT struct {
 // CommonServiceOptions represents the set of options common to all services.
 type CommonServiceOptions struct {
 	Env Env `yaml:"env,omitempty"`
+	// CrashLoopAction is the action taken when the service is crash looping:
+	// "keep-trying" (default), "give-up" or "reboot". The services run once,
+	// e.g. kubeadm, take the action on the first failure.
+	CrashLoopAction string `yaml:"crashLoopAction,omitempty"`
 }
 
 // NTPd describes the configuration of the ntp service.
@@ -100,6 +110,9 @@ type NTPd struct {
 type Kubelet struct {
 	CommonServiceOptions `yaml:",inline"`
 	ExtraMounts          []specs.Mount `yaml:"extraMounts"`
+	// TopologyLabels enables labeling the node with the region and the zone
+	// reported by the platform.
+	TopologyLabels bool `yaml:"topologyLabels,omitempty"`
 }
 
 // Trustd describes the configuration of the Root of Trust (RoT) service. The
@@ -116,6 +129,42 @@ type Trustd struct {
 	Endpoints     []string `yaml:"endpoints,omitempty"`
 	CertSANs      []string `yaml:"certSANs,omitempty"`
 	BootstrapNode string   `yaml:"bootstrapNode,omitempty"`
+	// InstanceIdentity restricts the certificates to the nodes which prove
+	// their identity with the instance identity document signed by the
+	// platform.
+	InstanceIdentity *InstanceIdentity `yaml:"instanceIdentity,omitempty"`
+}
+
+// InstanceIdentity describes the requirements for the instance identity
+// document presented to trustd.
+type InstanceIdentity struct {
+	// Required rejects the certificate requests without the document.
+	Required bool `yaml:"required,omitempty"`
+	// AccountIDs lists the accounts the instances are allowed to run in.
+	// Setting it implies Required.
+	AccountIDs []string `yaml:"accountIDs,omitempty"`
+}
+
+// Enforced returns true if the certificate requests must carry the instance
+// identity document.
+func (i *InstanceIdentity) Enforced() bool {
+	return i != nil && (i.Required || len(i.AccountIDs) > 0)
+}
+
+// AccountAllowed returns true if the instances of the account are allowed
+// to request certificates.
+func (i *InstanceIdentity) AccountAllowed(accountID string) bool {
+	if i == nil || len(i.AccountIDs) == 0 {
+		return true
+	}
+
+	for _, id := range i.AccountIDs {
+		if id == accountID {
+			return true
+		}
+	}
+
+	return false
 }
 
 // TrustdCheck defines the function type for checks
@@ -0,0 +1,118 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// TFTP opcodes, see RFC 1350.
+const (
+	tftpRRQ   = 1
+	tftpDATA  = 3
+	tftpACK   = 4
+	tftpERROR = 5
+
+	tftpBlockSize = 512
+	tftpTimeout   = 5 * time.Second
+	tftpMaxSize   = 16 * 1024 * 1024
+)
+
+// downloadTFTP reads the file from the TFTP server in octet mode. The server
+// answers from a new port, the rest of the transfer is bound to it.
+// nolint: gocyclo
+func downloadTFTP(u *url.URL) (data []byte, err error) {
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "69")
+	}
+
+	server, err := net.ResolveUDPAddr("udp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	// nolint: errcheck
+	defer conn.Close()
+
+	var rrq bytes.Buffer
+	rrq.Write([]byte{0, tftpRRQ})
+	rrq.WriteString(u.Path)
+	rrq.WriteByte(0)
+	rrq.WriteString("octet")
+	rrq.WriteByte(0)
+
+	if _, err = conn.WriteToUDP(rrq.Bytes(), server); err != nil {
+		return nil, err
+	}
+
+	var (
+		buf    = make([]byte, 4+tftpBlockSize)
+		block  uint16
+		remote *net.UDPAddr
+	)
+
+	for {
+		if err = conn.SetReadDeadline(time.Now().Add(tftpTimeout)); err != nil {
+			return nil, err
+		}
+
+		var (
+			n    int
+			addr *net.UDPAddr
+		)
+		if n, addr, err = conn.ReadFromUDP(buf); err != nil {
+			return nil, fmt.Errorf("read tftp response: %v", err)
+		}
+
+		if remote != nil && (!addr.IP.Equal(remote.IP) || addr.Port != remote.Port) {
+			continue
+		}
+
+		if n < 4 {
+			return nil, fmt.Errorf("short tftp packet from %s", addr)
+		}
+
+		switch binary.BigEndian.Uint16(buf[0:2]) {
+		case tftpDATA:
+		case tftpERROR:
+			return nil, fmt.Errorf("tftp error %d: %s", binary.BigEndian.Uint16(buf[2:4]), bytes.TrimRight(buf[4:n], "\x00"))
+		default:
+			return nil, fmt.Errorf("unexpected tftp opcode %d", binary.BigEndian.Uint16(buf[0:2]))
+		}
+
+		remote = addr
+
+		// a retransmitted block is acknowledged again, but not appended
+		if received := binary.BigEndian.Uint16(buf[2:4]); received == block+1 {
+			block = received
+			data = append(data, buf[4:n]...)
+		}
+
+		if len(data) > tftpMaxSize {
+			return nil, fmt.Errorf("tftp file exceeds %d bytes", tftpMaxSize)
+		}
+
+		ack := []byte{0, tftpACK, 0, 0}
+		binary.BigEndian.PutUint16(ack[2:], block)
+
+		if _, err = conn.WriteToUDP(ack, remote); err != nil {
+			return nil, err
+		}
+
+		if n < 4+tftpBlockSize {
+			return data, nil
+		}
+	}
+}
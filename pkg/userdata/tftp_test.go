@@ -0,0 +1,106 @@
+/* This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/. */
+
+package userdata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type tftpSuite struct {
+	suite.Suite
+}
+
+func TestTFTPSuite(t *testing.T) {
+	suite.Run(t, new(tftpSuite))
+}
+
+// testTFTPServer serves the files for a single read request, the transfer
+// runs on a separate port as with the real servers.
+func testTFTPServer(suite *tftpSuite, files map[string][]byte) string {
+	listener, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	suite.Require().NoError(err)
+
+	go func() {
+		// nolint: errcheck
+		defer listener.Close()
+
+		buf := make([]byte, 516)
+
+		n, client, err := listener.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		path := string(buf[2 : 2+bytes.IndexByte(buf[2:n], 0)])
+
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			return
+		}
+		// nolint: errcheck
+		defer conn.Close()
+
+		data, ok := files[path]
+		if !ok {
+			// nolint: errcheck
+			conn.WriteToUDP(append([]byte{0, tftpERROR, 0, 1}, "File not found\x00"...), client)
+			return
+		}
+
+		for block := 1; ; block++ {
+			end := block * tftpBlockSize
+			if end > len(data) {
+				end = len(data)
+			}
+
+			packet := []byte{0, tftpDATA, 0, 0}
+			binary.BigEndian.PutUint16(packet[2:], uint16(block))
+			packet = append(packet, data[(block-1)*tftpBlockSize:end]...)
+
+			if _, err = conn.WriteToUDP(packet, client); err != nil {
+				return
+			}
+
+			if _, _, err = conn.ReadFromUDP(buf); err != nil {
+				return
+			}
+
+			if end-(block-1)*tftpBlockSize < tftpBlockSize {
+				return
+			}
+		}
+	}()
+
+	return listener.LocalAddr().String()
+}
+
+func (suite *tftpSuite) TestFetch() {
+	// exactly two blocks, so the transfer ends with an empty block
+	contents := []byte(strings.Repeat("a", 2*tftpBlockSize))
+
+	addr := testTFTPServer(suite, map[string][]byte{"/talos/userdata.yaml": contents})
+
+	b, err := Fetch("tftp://"+addr+"/talos/userdata.yaml", WithRetries(1))
+	suite.Require().NoError(err)
+	suite.Assert().Equal(contents, b)
+}
+
+func (suite *tftpSuite) TestNotFound() {
+	addr := testTFTPServer(suite, nil)
+
+	_, err := Fetch("tftp://"+addr+"/missing.yaml", WithRetries(1))
+	suite.Assert().Error(err)
+}
+
+func (suite *tftpSuite) TestUnsupportedScheme() {
+	_, err := Fetch("ftp://example.com/userdata.yaml")
+	suite.Assert().Error(err)
+}
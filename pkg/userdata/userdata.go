@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	stdlibnet "net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,8 +19,6 @@ import (
 	"github.com/talos-systems/talos/pkg/crypto/x509"
 	"github.com/talos-systems/talos/pkg/net"
 	"golang.org/x/xerrors"
-
-	yaml "gopkg.in/yaml.v2"
 )
 
 // UserData represents the user data.
@@ -49,6 +48,12 @@ func (data *UserData) Validate() error {
 		for _, dev := range data.Networking.OS.Devices {
 			result = multierror.Append(result, dev.Validate(CheckDeviceInterface(), CheckDeviceAddressing(), CheckDeviceRoutes()))
 		}
+
+		for idx, nameserver := range data.Networking.OS.Nameservers {
+			if ip := stdlibnet.ParseIP(nameserver); ip == nil {
+				result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "networking.os.nameservers["+strconv.Itoa(idx)+"]", nameserver, ErrInvalidAddress))
+			}
+		}
 	}
 
 	switch {
@@ -78,9 +83,10 @@ type Networking struct {
 
 // OSNet represents the network interfaces present on the host
 type OSNet struct {
-	Devices    []Device `yaml:"devices"`
-	Hostname   string   `yaml:"hostname"`
-	Domainname string   `yaml:"domainname"`
+	Devices     []Device `yaml:"devices"`
+	Hostname    string   `yaml:"hostname"`
+	Domainname  string   `yaml:"domainname"`
+	Nameservers []string `yaml:"nameservers,omitempty"`
 }
 
 // File represents a file to write to disk.
@@ -135,20 +141,25 @@ func (data *UserData) NewIdentityCSR() (csr *x509.CertificateSigningRequest, err
 	return csr, nil
 }
 
-// Open is a convenience function that reads the user data from disk, and
-// unmarshals it.
+// Open is a convenience function that reads the user data from disk, migrates
+// it to the current version, and unmarshals it.
 func Open(p string) (data *UserData, err error) {
 	fileBytes, err := ioutil.ReadFile(p)
 	if err != nil {
 		return nil, fmt.Errorf("read user data: %v", err)
 	}
 
-	data = &UserData{}
-	if err = yaml.Unmarshal(fileBytes, data); err != nil {
-		return nil, fmt.Errorf("unmarshal user data: %v", err)
+	return unmarshal(fileBytes)
+}
+
+// OpenStrict is Open rejecting the unknown keys of the user data.
+func OpenStrict(p string) (data *UserData, err error) {
+	fileBytes, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("read user data: %v", err)
 	}
 
-	return data, nil
+	return unmarshalStrict(fileBytes)
 }
 
 type certTest struct {
@@ -19,7 +19,7 @@ func (v Version) Validate() error {
 		result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "version", "", ErrRequiredSection))
 	}
 
-	if v != "1" {
+	if v != CurrentVersion {
 		result = multierror.Append(result, xerrors.Errorf("[%s] %q: %w", "version", v, ErrInvalidVersion))
 	}
 